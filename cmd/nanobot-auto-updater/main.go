@@ -22,6 +22,7 @@ import (
 	"github.com/HQGroup/nanobot-auto-updater/internal/network"
 	"github.com/HQGroup/nanobot-auto-updater/internal/notification"
 	"github.com/HQGroup/nanobot-auto-updater/internal/notifier"
+	"github.com/HQGroup/nanobot-auto-updater/internal/scheduler"
 	"github.com/HQGroup/nanobot-auto-updater/internal/selfupdate"
 	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
 )
@@ -206,6 +207,44 @@ func main() {
 		logger,
 	)
 
+	// updateScheduler runs cron-scheduled nanobot updates (update_schedule config).
+	// Created in createComponents (needs the InstanceManager), stopped via AppComponents.UpdateScheduler.
+	var updateScheduler *scheduler.UpdateScheduler
+
+	// newHealthMonitor creates the health monitor of the instances of im: process state and the
+	// health_check.liveness probes, whose results are shown in the instance status.
+	newHealthMonitor := func(im *instance.InstanceManager, hc config.HealthCheckConfig) *health.HealthMonitor {
+		hm := health.NewHealthMonitor(
+			func() []health.InstanceStatus {
+				statuses := im.GetInstanceStatuses()
+				result := make([]health.InstanceStatus, len(statuses))
+				for i, s := range statuses {
+					result[i] = health.InstanceStatus{
+						Name:    s.Name,
+						Port:    s.Port,
+						Running: s.Running,
+						PID:     s.PID,
+					}
+					if s.StartedAt != nil {
+						result[i].StartedAt = *s.StartedAt
+					}
+					if s.LastOutputAt != nil {
+						result[i].LastOutputAt = *s.LastOutputAt
+					}
+				}
+				return result
+			},
+			hc.Interval,
+			logger,
+		)
+		hm.SetLiveness(hc.Liveness)
+		hm.SetOnLiveness(im.SetLiveness)
+		hm.SetRestart(func(ctx context.Context, name string) error {
+			return im.RestartInstance(ctx, name, instance.InitiatorHealthCheck)
+		})
+		return hm
+	}
+
 	// createComponents creates the API server, health monitor, and instance manager.
 	// These packages (api, health, instance) cannot be imported by the lifecycle package
 	// due to circular import constraints.
@@ -225,6 +264,12 @@ func main() {
 		im := instance.NewInstanceManager(cfg, logger, concreteNotif)
 		instanceManager = im
 
+		// Create update scheduler (shares the TriggerUpdate lock with the API)
+		updateScheduler = scheduler.NewUpdateScheduler(cfg.UpdateSchedule, im, concreteUpdateLogger, concreteNotif, scheduler.DefaultUpdateTimeout, logger)
+		if schedErr := updateScheduler.Start(); schedErr != nil {
+			logger.Error("Failed to start update scheduler", "error", schedErr)
+		}
+
 		// Create API server (conditional, can fail)
 		if cfg.API.Port != 0 {
 			apiSrv, apiErr := api.NewServer(&cfg.API, im, cfg, version, logger, concreteUpdateLogger, concreteNotif, selfUpdater, func() string {
@@ -239,6 +284,9 @@ func main() {
 			}
 			apiServer = apiSrv
 
+			// Scheduled updates wait for approval like API-triggered ones (approval.enabled)
+			updateScheduler.SetApprovals(apiSrv.Approvals())
+
 			// Start API server in goroutine
 			go func() {
 				logger.Info("API server starting", "port", cfg.API.Port)
@@ -250,24 +298,7 @@ func main() {
 
 		// Create health monitor (conditional)
 		if len(cfg.Instances) > 0 {
-			hm := health.NewHealthMonitor(
-				func() []health.InstanceStatus {
-					statuses := im.GetInstanceStatuses()
-					result := make([]health.InstanceStatus, len(statuses))
-					for i, s := range statuses {
-						result[i] = health.InstanceStatus{
-							Name:    s.Name,
-							Port:    s.Port,
-							Running: s.Running,
-							PID:     s.PID,
-						}
-					}
-					return result
-				},
-				cfg.HealthCheck.Interval,
-				logger,
-			)
-			healthMonitor = hm
+			healthMonitor = newHealthMonitor(im, cfg.HealthCheck)
 		}
 
 		return
@@ -293,6 +324,9 @@ func main() {
 
 			onReady := func(components *lifecycle.AppComponents) {
 				hotReloadComponents = components
+				if updateScheduler != nil {
+					components.UpdateScheduler = updateScheduler
+				}
 
 				callbacks := &config.HotReloadCallbacks{
 					OnMonitorChange: func(newCfg *config.Config) {
@@ -350,23 +384,7 @@ func main() {
 							hotReloadComponents.HealthMonitor.Stop()
 						}
 						im := hotReloadComponents.InstanceManager.(*instance.InstanceManager)
-						hm := health.NewHealthMonitor(
-							func() []health.InstanceStatus {
-								statuses := im.GetInstanceStatuses()
-								result := make([]health.InstanceStatus, len(statuses))
-								for i, s := range statuses {
-									result[i] = health.InstanceStatus{
-										Name:    s.Name,
-										Port:    s.Port,
-										Running: s.Running,
-										PID:     s.PID,
-									}
-								}
-								return result
-							},
-							newCfg.HealthCheck.Interval,
-							logger,
-						)
+						hm := newHealthMonitor(im, newCfg.HealthCheck)
 						hotReloadComponents.HealthMonitor = hm
 						go hm.Start()
 						slog.Info("hot reload: health monitor rebuilt")
@@ -385,11 +403,23 @@ func main() {
 							"old_count", len(im.GetInstanceNames()),
 							"new_count", len(newCfg.Instances),
 						)
+						// 旧实例被整体停止, 不能被其崩溃监督重启
+						im.DisableRestarts()
 						stopCtx, stopCancel := context.WithTimeout(context.Background(), 30*time.Second)
 						lifecycle.StopAllNanobots(stopCtx, 5*time.Second, logger)
 						stopCancel()
 						newIM := instance.NewInstanceManager(newCfg, logger, notif)
 						hotReloadComponents.InstanceManager = newIM
+						// 健康检查 (包括存活检查的重启) 跟随新的实例
+						if hotReloadComponents.HealthMonitor != nil {
+							hotReloadComponents.HealthMonitor.Stop()
+							hm := newHealthMonitor(newIM, newCfg.HealthCheck)
+							hotReloadComponents.HealthMonitor = hm
+							go hm.Start()
+						}
+						if updateScheduler != nil {
+							updateScheduler.SetTrigger(newIM)
+						}
 						startCtx, startCancel := context.WithTimeout(context.Background(), 5*time.Minute)
 						newIM.StartAllInstances(startCtx)
 						startCancel()
@@ -397,6 +427,20 @@ func main() {
 							"instance_count", len(newCfg.Instances),
 						)
 					},
+
+					OnUpdateScheduleChange: func(newCfg *config.Config) {
+						if updateScheduler == nil {
+							return
+						}
+						if err := updateScheduler.Reload(newCfg.UpdateSchedule); err != nil {
+							slog.Error("hot reload: failed to reschedule updates", "error", err)
+							return
+						}
+						slog.Info("hot reload: update schedule reloaded",
+							"enabled", newCfg.UpdateSchedule.Enabled,
+							"cron", newCfg.UpdateSchedule.Cron,
+						)
+					},
 				}
 
 				config.WatchConfig(cfg, logger, callbacks)
@@ -421,6 +465,9 @@ func main() {
 		// AppStartup already cleaned up partial components via rollback
 		os.Exit(1)
 	}
+	if updateScheduler != nil {
+		components.UpdateScheduler = updateScheduler
+	}
 
 	// Console mode: wait for shutdown signal (D-06, D-11)
 	sigChan := make(chan os.Signal, 1)
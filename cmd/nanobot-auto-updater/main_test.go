@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
 	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
 	"github.com/HQGroup/nanobot-auto-updater/internal/logging"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
 )
 
 // TestVersionFlag verifies --version exits immediately with version string
@@ -20,13 +22,17 @@ func TestVersionFlag(t *testing.T) {
 	}
 
 	// Build the binary first (from project root)
-	buildCmd := exec.Command("go", "build", "-o", "test-updater.exe", ".")
+	binary := filepath.Join(t.TempDir(), "test-updater")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	buildCmd := exec.Command("go", "build", "-o", binary, ".")
 	if err := buildCmd.Run(); err != nil {
 		t.Fatalf("Failed to build binary: %v", err)
 	}
 
 	// Run with --version flag
-	cmd := exec.Command("./test-updater.exe", "--version")
+	cmd := exec.Command(binary, "--version")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		t.Fatalf("Expected --version to exit cleanly, got error: %v", err)
@@ -38,7 +44,7 @@ func TestVersionFlag(t *testing.T) {
 	}
 }
 
-// TestHelpFlag verifies -h/--help shows usage information including JSON format
+// TestHelpFlag verifies -h/--help shows usage information including the flags
 func TestHelpFlag(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
@@ -66,9 +72,9 @@ func TestHelpFlag(t *testing.T) {
 				t.Errorf("Expected help output to contain 'Usage:' or 'Options:', got: %s", outputStr)
 			}
 
-			// Verify JSON output format documentation is present
-			if !strings.Contains(outputStr, "JSON Output Format") {
-				t.Errorf("Expected help output to contain 'JSON Output Format', got: %s", outputStr)
+			// Verify the flags are documented
+			if !strings.Contains(outputStr, "--config") {
+				t.Errorf("Expected help output to contain '--config', got: %s", outputStr)
 			}
 		})
 	}
@@ -95,93 +101,13 @@ func TestInvalidCronFlag(t *testing.T) {
 	}
 }
 
-// TestUpdateNowFlag verifies --update-now flag is parsed correctly
-func TestUpdateNowFlag(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
-
-	// Note: This test will actually try to run the updater, so we can't verify full execution
-	// But we can verify the flag is accepted without immediate error
-	cmd := exec.Command("go", "run", ".", "--update-now", "--config", "nonexistent.yaml")
-
-	// We expect it to fail due to missing config or uv check, but not flag parsing
-	output, _ := cmd.CombinedOutput()
-	outputStr := string(output)
-
-	// Should not complain about unknown flag
-	if strings.Contains(outputStr, "unknown flag") || strings.Contains(outputStr, "unknown shorthand") {
-		t.Errorf("Flag parsing failed: %s", outputStr)
-	}
-}
-
-// TestTimeoutFlag verifies --timeout flag is parsed correctly
-func TestTimeoutFlag(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
-
-	tests := []struct {
-		name    string
-		timeout string
-		valid   bool
-	}{
-		{"valid minutes", "5m", true},
-		{"valid seconds", "300s", true},
-		{"valid combined", "2m30s", true},
-		{"invalid format", "invalid", false},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			cmd := exec.Command("go", "run", ".", "--timeout", tt.timeout, "--help")
-			output, err := cmd.CombinedOutput()
-
-			if tt.valid {
-				// Valid timeout should work with --help
-				if err != nil {
-					t.Errorf("Expected valid timeout to work, got error: %v, output: %s", err, string(output))
-				}
-			} else {
-				// Invalid timeout should fail
-				if err == nil {
-					t.Errorf("Expected invalid timeout to fail, but it succeeded")
-				}
-			}
-		})
-	}
-}
-
-// TestTimeoutDefault verifies default timeout is 5 minutes
-func TestTimeoutDefault(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping integration test in short mode")
-	}
-
-	// This test verifies the default value is set correctly in the flag definition
-	// The actual behavior is tested through integration tests
-	expectedDefault := 5 * time.Minute
-
-	// We verify this by checking that the help output shows the default
-	cmd := exec.Command("go", "run", ".", "--help")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		t.Fatalf("Failed to run help: %v", err)
-	}
-
-	// Check that default is shown as 5m0s (Go's time.Duration string representation)
-	if !strings.Contains(string(output), "5m0s") {
-		t.Errorf("Expected default timeout '5m0s' in help output, got: %s", string(output))
-	}
-
-	_ = expectedDefault // Use the variable to avoid compiler warning
-}
-
-func init() {
-	// Ensure tests run on Windows only (scheduler has build constraint)
-	if runtime.GOOS != "windows" {
-		panic("Tests only valid on Windows")
+// testLogDir returns the log directory of the integration tests.
+// The log file stays open, so Windows cannot remove a t.TempDir() at cleanup.
+func testLogDir(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		return "./logs"
 	}
+	return t.TempDir()
 }
 
 // TestMultiInstanceConfigLoading 验证多实例配置加载
@@ -258,7 +184,7 @@ func TestScheduledMultiInstanceUpdate(t *testing.T) {
 	}
 
 	// 创建 logger
-	logger := logging.NewLogger("./logs")
+	logger := logging.NewLogger(testLogDir(t))
 
 	// 创建 InstanceManager
 	manager := instance.NewInstanceManager(cfg, logger, nil)
@@ -267,7 +193,7 @@ func TestScheduledMultiInstanceUpdate(t *testing.T) {
 	ctx := context.Background()
 
 	// 执行更新
-	result, err := manager.UpdateAll(ctx)
+	result, err := manager.UpdateAll(ctx, updater.Target{}, true)
 
 	// 验证双层错误检查
 	if err != nil {
@@ -310,7 +236,7 @@ func TestUpdateNowMultiInstance(t *testing.T) {
 	}
 
 	// 创建 logger
-	logger := logging.NewLogger("./logs")
+	logger := logging.NewLogger(testLogDir(t))
 
 	// 创建 InstanceManager
 	manager := instance.NewInstanceManager(cfg, logger, nil)
@@ -321,7 +247,7 @@ func TestUpdateNowMultiInstance(t *testing.T) {
 	defer cancel()
 
 	// 执行更新
-	result, err := manager.UpdateAll(ctx)
+	result, err := manager.UpdateAll(ctx, updater.Target{}, true)
 
 	// 验证双层错误检查
 	if err != nil {
@@ -360,7 +286,7 @@ func TestMultiInstanceLongRunning(t *testing.T) {
 	}
 
 	// 创建 logger
-	logger := logging.NewLogger("./logs")
+	logger := logging.NewLogger(testLogDir(t))
 
 	// 创建 InstanceManager
 	manager := instance.NewInstanceManager(cfg, logger, nil)
@@ -379,7 +305,7 @@ func TestMultiInstanceLongRunning(t *testing.T) {
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 
-		result, err := manager.UpdateAll(ctx)
+		result, err := manager.UpdateAll(ctx, updater.Target{}, true)
 		if err != nil {
 			t.Logf("Iteration %d: UV update failed: %v", i+1, err)
 		} else if result.HasErrors() {
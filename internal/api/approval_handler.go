@@ -0,0 +1,294 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/approval"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// SetApprovals enables the approval workflow: POST /api/v1/trigger-update creates a pending
+// update in store instead of running it. A nil or disabled store keeps updates direct.
+func (h *TriggerHandler) SetApprovals(store *approval.Store) {
+	h.approvals = store
+}
+
+// approvalAcceptedResponse is the 202 response of POST /api/v1/trigger-update with approval.enabled
+type approvalAcceptedResponse struct {
+	UpdateID   string          `json:"update_id"`
+	Phase      string          `json:"phase"`       // Always "pending_approval"
+	ApproveURL string          `json:"approve_url"` // POST (Bearer token) to approve
+	Approval   approval.Update `json:"approval"`
+	Note       string          `json:"note,omitempty"` // Set when the request joined an update already pending approval
+}
+
+// requestApproval creates a pending update for req, with the update check (new version,
+// changelog link) of the plan. The update runs once approved at POST /api/v1/updates/{id}/approve.
+// While an update for the same target is pending, that update is returned. ?wait is ignored.
+func (h *TriggerHandler) requestApproval(w http.ResponseWriter, r *http.Request, req updateRequest) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeout)
+	defer cancel()
+
+	// A failed check does not prevent the request, the approver just sees no version
+	var check *updater.CheckResult
+	plan, err := h.instanceManager.Plan(ctx, req.Target, req.Force)
+	switch {
+	case errors.Is(err, updater.ErrInvalidTarget):
+		writeJSONError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	case err != nil:
+		h.logger.Warn("Update check for approval failed", "error", err, "target", req.Target.String())
+	default:
+		check = plan.Check
+	}
+
+	update, created := h.approvals.Create("", approval.Request{
+		Target:      req.Target,
+		Force:       req.Force,
+		TriggeredBy: req.source(),
+		Check:       check,
+	})
+	var note string
+	if !created {
+		note = "An update for this target is already pending approval"
+		if req.Force {
+			note += ", it now reinstalls even when nanobot is current (force)"
+		}
+	}
+	w.Header().Set("X-Update-ID", update.ID)
+	writeJSON(w, http.StatusAccepted, approvalAcceptedResponse{
+		UpdateID:   update.ID,
+		Phase:      "pending_approval",
+		ApproveURL: "/api/v1/updates/" + update.ID + "/approve",
+		Approval:   update,
+		Note:       note,
+	}, h.logger)
+}
+
+// ApprovalHandler handles the approval endpoints: GET /api/v1/approvals,
+// POST /api/v1/updates/{id}/approve, POST /api/v1/updates/{id}/reject and the confirmation
+// page of the notification link, GET /api/v1/updates/{id}/approve?token=.
+// Approve and reject accept either the Bearer token or the link token of that update.
+type ApprovalHandler struct {
+	store    *approval.Store
+	trigger  *TriggerHandler
+	getToken func() string
+	logger   *slog.Logger
+}
+
+// NewApprovalHandler creates a new ApprovalHandler. Approved updates are started through trigger.
+func NewApprovalHandler(store *approval.Store, trigger *TriggerHandler, getToken func() string, logger *slog.Logger) *ApprovalHandler {
+	return &ApprovalHandler{
+		store:    store,
+		trigger:  trigger,
+		getToken: getToken,
+		logger:   logger.With("source", "api-approval"),
+	}
+}
+
+// ApprovalListResponse is the JSON response of GET /api/v1/approvals
+type ApprovalListResponse struct {
+	Enabled   bool              `json:"enabled"`   // approval.enabled
+	Approvals []approval.Update `json:"approvals"` // Pending and recently decided updates, newest first
+}
+
+// HandleList handles GET /api/v1/approvals
+func (h *ApprovalHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ApprovalListResponse{
+		Enabled:   h.store.Enabled(),
+		Approvals: h.store.List(),
+	}, h.logger)
+}
+
+// HandleApprove handles POST /api/v1/updates/{id}/approve: approves the pending update and starts
+// it as a background update job with the same update_id (202, like POST /api/v1/trigger-update).
+// 404 for an unknown id, 409 when it was already decided or has expired, or the update queue is full.
+// Posted from the link confirmation page (?token=) an HTML page is returned instead.
+func (h *ApprovalHandler) HandleApprove(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	by, ok := h.authorize(w, r, id)
+	if !ok {
+		return
+	}
+
+	update, err := h.store.Approve(id, by)
+	if err != nil {
+		h.writeDecisionError(w, by, err)
+		return
+	}
+
+	req := updateRequest{Target: update.Target, Force: update.Force, triggeredBy: update.TriggeredBy}
+	w.Header().Set("X-Update-ID", id)
+	if by != decidedByLink {
+		h.trigger.dispatch(w, r, id, req, nil)
+		return
+	}
+	if job := h.trigger.startJob(id, req, nil); job == nil {
+		writeApprovalPage(w, http.StatusTooManyRequests, approvalPage{Title: "更新未开始", Message: "更新已批准, 但更新队列已满, 请稍后重新触发更新。", Update: update})
+		return
+	}
+	writeApprovalPage(w, http.StatusOK, approvalPage{Title: "更新已批准", Message: "更新已开始, 进度见 /api/v1/updates/" + id, Update: update})
+}
+
+// HandleReject handles POST /api/v1/updates/{id}/reject with an optional reason
+// ({"reason":"..."} or the reason form field of the confirmation page).
+func (h *ApprovalHandler) HandleReject(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	by, ok := h.authorize(w, r, id)
+	if !ok {
+		return
+	}
+
+	var reason string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		reason = r.PostFormValue("reason")
+	} else if r.Body != nil {
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			writeJSONError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+			return
+		}
+		reason = body.Reason
+	}
+
+	update, err := h.store.Reject(id, by, strings.TrimSpace(reason))
+	if err != nil {
+		h.writeDecisionError(w, by, err)
+		return
+	}
+	if by == decidedByLink {
+		writeApprovalPage(w, http.StatusOK, approvalPage{Title: "更新已拒绝", Message: "该更新不会执行。", Update: update})
+		return
+	}
+	writeJSON(w, http.StatusOK, update, h.logger)
+}
+
+// HandlePage handles GET /api/v1/updates/{id}/approve?token=: the page opened from the
+// notification link. It only shows the update with approve/reject buttons, so link previews
+// and prefetching cannot approve anything.
+func (h *ApprovalHandler) HandlePage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	token := r.URL.Query().Get("token")
+	if !h.store.ValidToken(id, token) {
+		h.logger.Warn("Invalid approval link", "update_id", id)
+		writeApprovalPage(w, http.StatusNotFound, approvalPage{Title: "链接无效", Message: "审批链接无效, 或该更新已审批、已拒绝或已过期。"})
+		return
+	}
+	update, _ := h.store.Get(id)
+	writeApprovalPage(w, http.StatusOK, approvalPage{Title: "Nanobot 更新待审批", Update: update, Token: token, Pending: true})
+}
+
+// decidedByLink and decidedByAPI are recorded as approval.Update.DecidedBy
+const (
+	decidedByLink = "link"
+	decidedByAPI  = "api"
+)
+
+// authorize accepts the Bearer token ("api") or the link token of update id ("link").
+// Writes 401 and returns false otherwise.
+func (h *ApprovalHandler) authorize(w http.ResponseWriter, r *http.Request, id string) (string, bool) {
+	if r.Header.Get("Authorization") == "" {
+		if token := r.URL.Query().Get("token"); token != "" {
+			if h.store.ValidToken(id, token) {
+				return decidedByLink, true
+			}
+			h.logger.Warn("Invalid approval token", "update_id", id, "path", r.URL.Path)
+			writeApprovalPage(w, http.StatusUnauthorized, approvalPage{Title: "链接无效", Message: "审批链接无效, 或该更新已审批、已拒绝或已过期。"})
+			return "", false
+		}
+	}
+	if err := validateBearerToken(r, h.getToken()); err != nil {
+		code, message := "unauthorized", err.Error()
+		var authErr *authError
+		if errors.As(err, &authErr) {
+			message = authErr.Message()
+		}
+		h.logger.Warn("Authentication failed", "error", code, "message", message, "path", r.URL.Path, "method", r.Method)
+		writeJSONError(w, http.StatusUnauthorized, code, message)
+		return "", false
+	}
+	return decidedByAPI, true
+}
+
+// writeDecisionError answers a failed approve/reject: 404 unknown id, 409 no longer pending
+func (h *ApprovalHandler) writeDecisionError(w http.ResponseWriter, by string, err error) {
+	status, code := http.StatusInternalServerError, "internal_error"
+	switch {
+	case errors.Is(err, approval.ErrNotFound):
+		status, code = http.StatusNotFound, "not_found"
+	case errors.Is(err, approval.ErrNotPending):
+		status, code = http.StatusConflict, "conflict"
+	}
+	if by == decidedByLink {
+		writeApprovalPage(w, status, approvalPage{Title: "无法审批", Message: err.Error()})
+		return
+	}
+	writeJSONError(w, status, code, err.Error())
+}
+
+// approvalPage is the data of approvalPageTemplate
+type approvalPage struct {
+	Title   string
+	Message string
+	Update  approval.Update
+	Token   string // Link token, posted back by the approve/reject forms
+	Pending bool   // Show the approve/reject forms
+}
+
+var approvalPageTemplate = template.Must(template.New("approval").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 36em; margin: 2em auto; padding: 0 1em; }
+th { text-align: left; padding-right: 1em; }
+form { display: inline-block; margin: 1em 1em 0 0; }
+button { font-size: 1.1em; padding: .4em 1.2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{with .Update}}{{if .ID}}<table>
+<tr><th>更新 ID</th><td>{{.ID}}</td></tr>
+<tr><th>目标</th><td>{{.TargetName}}</td></tr>
+{{if .NewVersion}}<tr><th>新版本</th><td>{{.NewVersion}}</td></tr>{{end}}
+{{if .Check}}{{if .Check.InstalledVersion}}<tr><th>已安装版本</th><td>{{.Check.InstalledVersion}}</td></tr>{{end}}{{end}}
+{{if .ChangelogURL}}<tr><th>变更</th><td><a href="{{.ChangelogURL}}">{{.ChangelogURL}}</a></td></tr>{{end}}
+<tr><th>触发来源</th><td>{{.TriggeredBy}}</td></tr>
+<tr><th>过期时间</th><td>{{.ExpiresAt.Local.Format "2006-01-02 15:04"}}</td></tr>
+</table>{{end}}{{end}}
+{{if .Pending}}
+<form method="post" action="/api/v1/updates/{{.Update.ID}}/approve?token={{.Token}}">
+<button type="submit">批准并开始更新</button>
+</form>
+<form method="post" action="/api/v1/updates/{{.Update.ID}}/reject?token={{.Token}}">
+<input type="text" name="reason" placeholder="拒绝原因 (可选)">
+<button type="submit">拒绝</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// writeApprovalPage renders the approval page with the given status
+func writeApprovalPage(w http.ResponseWriter, status int, page approvalPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := approvalPageTemplate.Execute(w, page); err != nil {
+		fmt.Fprintf(w, "<p>%s</p>", template.HTMLEscapeString(err.Error()))
+	}
+}
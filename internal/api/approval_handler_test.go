@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/approval"
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+const testApprovalBearer = "test-token-12345678901234567890"
+
+// linkNotifier captures the approval link sent with the notification
+type linkNotifier struct {
+	url string
+}
+
+func (n *linkNotifier) Notify(title, message string) error { return nil }
+
+func (n *linkNotifier) NotifyWithURL(title, message, link, urlTitle string) error {
+	n.url = link
+	return nil
+}
+
+func newTestApprovalHandler(t *testing.T, mock *mockTriggerUpdater) (*ApprovalHandler, *TriggerHandler, *updatelog.UpdateLogger, *linkNotifier) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+	notif := &linkNotifier{}
+	store := approval.NewStore(config.ApprovalConfig{Enabled: true, Timeout: time.Hour, PublicURL: "https://bot.example.com"}, ul, notif, logger)
+	trigger := newTestHandler(logger, ul, mock, nil)
+	trigger.SetApprovals(store)
+	return NewApprovalHandler(store, trigger, func() string { return testApprovalBearer }, logger), trigger, ul, notif
+}
+
+// requestPendingUpdate calls POST /api/v1/trigger-update with approval enabled
+func requestPendingUpdate(t *testing.T, trigger *TriggerHandler) approvalAcceptedResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	trigger.Handle(rec, httptest.NewRequest("POST", "/api/v1/trigger-update", strings.NewReader(`{"ref":"v0.2.0"}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("trigger-update status = %d, want 202: %s", rec.Code, rec.Body.String())
+	}
+	var resp approvalAcceptedResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp
+}
+
+func decideRequest(method, action, id, query, bearer string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/updates/"+id+"/"+action+query, body)
+	req.SetPathValue("id", id)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	return req
+}
+
+func TestApprovalHandler_ApproveStartsUpdate(t *testing.T) {
+	mock := &mockTriggerUpdater{
+		result: &instance.UpdateResult{Target: "ref:v0.2.0", Stopped: []string{"gateway"}, Started: []string{"gateway"}},
+		plan:   &instance.UpdateResult{Check: &updater.CheckResult{SourceType: updater.SourceGit, LatestCommit: "2222222222", ChangelogURL: "https://github.com/HKUDS/nanobot/commit/2222222222"}},
+	}
+	handler, trigger, ul, _ := newTestApprovalHandler(t, mock)
+
+	pending := requestPendingUpdate(t, trigger)
+	if pending.Phase != "pending_approval" || pending.Approval.NewVersion != "2222222" || pending.Approval.ChangelogURL == "" {
+		t.Fatalf("response = %+v, want a pending update with the new commit and changelog", pending)
+	}
+	if mock.calls != 0 {
+		t.Fatalf("TriggerUpdate calls = %d before approval, want 0", mock.calls)
+	}
+
+	// 没有令牌时拒绝
+	rec := httptest.NewRecorder()
+	handler.HandleApprove(rec, decideRequest("POST", "approve", pending.UpdateID, "", "", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("approve without token = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleApprove(rec, decideRequest("POST", "approve", pending.UpdateID, "", testApprovalBearer, nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("approve status = %d, want 202: %s", rec.Code, rec.Body.String())
+	}
+	waitJobDone(t, trigger, pending.UpdateID)
+	if mock.calls != 1 || mock.lastTarget.Ref != "v0.2.0" {
+		t.Errorf("TriggerUpdate calls = %d target = %+v, want one update to v0.2.0", mock.calls, mock.lastTarget)
+	}
+
+	// pending_approval, approved 和更新本身的记录共享同一 ID
+	var operations []string
+	for _, log := range ul.GetAll() {
+		if log.ID == pending.UpdateID {
+			operations = append(operations, log.Operation+"/"+string(log.Status))
+		}
+	}
+	want := []string{"update-approval/pending_approval", "update-approval/approved", "nanobot-update/success"}
+	if strings.Join(operations, ",") != strings.Join(want, ",") {
+		t.Errorf("records = %v, want %v", operations, want)
+	}
+	if log, _ := ul.Get(pending.UpdateID); log.TriggeredBy != "api-trigger" {
+		t.Errorf("update triggered_by = %q, want api-trigger", log.TriggeredBy)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleApprove(rec, decideRequest("POST", "approve", pending.UpdateID, "", testApprovalBearer, nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("second approve = %d, want 409", rec.Code)
+	}
+}
+
+func TestApprovalHandler_ForceRequestUpgradesPendingUpdate(t *testing.T) {
+	mock := &mockTriggerUpdater{result: &instance.UpdateResult{Target: "ref:v0.2.0"}, plan: &instance.UpdateResult{}}
+	handler, trigger, _, _ := newTestApprovalHandler(t, mock)
+
+	pending := requestPendingUpdate(t, trigger)
+	if pending.Approval.Force || pending.Note != "" {
+		t.Fatalf("response = %+v, want a new pending update without force", pending)
+	}
+
+	// 同一目标带 force 的请求合并到等待中的更新并升级为强制重新安装
+	rec := httptest.NewRecorder()
+	trigger.Handle(rec, httptest.NewRequest("POST", "/api/v1/trigger-update", strings.NewReader(`{"ref":"v0.2.0","force":true}`)))
+	var forced approvalAcceptedResponse
+	if err := json.NewDecoder(rec.Body).Decode(&forced); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if rec.Code != http.StatusAccepted || forced.UpdateID != pending.UpdateID || !forced.Approval.Force || !strings.Contains(forced.Note, "force") {
+		t.Fatalf("force request = %d %+v, want the pending %s upgraded to force with a note", rec.Code, forced, pending.UpdateID)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleApprove(rec, decideRequest("POST", "approve", pending.UpdateID, "", testApprovalBearer, nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("approve status = %d, want 202: %s", rec.Code, rec.Body.String())
+	}
+	waitJobDone(t, trigger, pending.UpdateID)
+	if mock.calls != 1 || !mock.lastForce {
+		t.Errorf("TriggerUpdate calls = %d force = %v, want one forced update", mock.calls, mock.lastForce)
+	}
+}
+
+func TestApprovalHandler_LinkFlow(t *testing.T) {
+	mock := &mockTriggerUpdater{result: &instance.UpdateResult{Target: "ref:v0.2.0"}, plan: &instance.UpdateResult{}}
+	handler, trigger, _, notif := newTestApprovalHandler(t, mock)
+
+	pending := requestPendingUpdate(t, trigger)
+	link, err := url.Parse(notif.url)
+	if err != nil || link.Path != "/api/v1/updates/"+pending.UpdateID+"/approve" || link.Query().Get("token") == "" {
+		t.Fatalf("notification link = %q, want the approve link with a token", notif.url)
+	}
+	query := "?token=" + link.Query().Get("token")
+
+	// 打开链接只显示确认页面, 不会审批
+	rec := httptest.NewRecorder()
+	handler.HandlePage(rec, decideRequest("GET", "approve", pending.UpdateID, query, "", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `method="post"`) || mock.calls != 0 {
+		t.Fatalf("confirmation page = %d (calls %d): %s", rec.Code, mock.calls, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	handler.HandlePage(rec, decideRequest("GET", "approve", pending.UpdateID, "?token=wrong", "", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("page with a wrong token = %d, want 404", rec.Code)
+	}
+
+	// 确认页面的拒绝表单
+	form := strings.NewReader("reason=" + url.QueryEscape("not today"))
+	req := decideRequest("POST", "reject", pending.UpdateID, query, "", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	handler.HandleReject(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("reject via link = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if update, _ := handler.store.Get(pending.UpdateID); update.State != approval.StateRejected || update.DecidedBy != "link" || update.RejectReason != "not today" {
+		t.Errorf("update = %+v, want rejected via link with its reason", update)
+	}
+
+	// 令牌在决定后失效
+	rec = httptest.NewRecorder()
+	handler.HandleApprove(rec, decideRequest("POST", "approve", pending.UpdateID, query, "", nil))
+	if rec.Code != http.StatusUnauthorized || mock.calls != 0 {
+		t.Errorf("approve with a used token = %d (calls %d), want 401 and no update", rec.Code, mock.calls)
+	}
+}
+
+func TestApprovalHandler_ListAndUnknownID(t *testing.T) {
+	handler, trigger, _, _ := newTestApprovalHandler(t, &mockTriggerUpdater{plan: &instance.UpdateResult{}})
+	pending := requestPendingUpdate(t, trigger)
+
+	rec := httptest.NewRecorder()
+	handler.HandleList(rec, httptest.NewRequest("GET", "/api/v1/approvals", nil))
+	var list ApprovalListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if !list.Enabled || len(list.Approvals) != 1 || list.Approvals[0].ID != pending.UpdateID || list.Approvals[0].TargetName != "ref:v0.2.0" {
+		t.Errorf("list = %+v, want the pending ref:v0.2.0 update", list)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleReject(rec, decideRequest("POST", "reject", "missing", "", testApprovalBearer, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("reject unknown id = %d, want 404", rec.Code)
+	}
+}
@@ -63,13 +63,103 @@ func (h *HelpHandler) getEndpoints() map[string]EndpointInfo {
 			Method:      "POST",
 			Path:        "/api/v1/trigger-update",
 			Auth:        "required",
-			Description: "触发更新流程（需要 Bearer Token 认证）",
+			Description: "触发更新流程（需要 Bearer Token 认证）; 可选 JSON body 指定目标版本: {\"ref\":\"v0.1.4\"}, {\"commit\":\"abc1234\"} 或 {\"pypi_version\":\"0.1.3\"}; 已是最新版本时跳过, {\"force\":true} 或 ?force=true 强制重新安装; ?dry_run=true 只返回更新计划; 更新在后台执行并立即返回 202 和 update_id, ?wait=true 等待结果 (最长 api.timeout)",
+		},
+		"trigger_update_upload": {
+			Method:      "POST",
+			Path:        "/api/v1/trigger-update/upload",
+			Auth:        "required",
+			Description: "离线更新: multipart 上传 nanobot wheel/sdist (file 字段) 和期望的 SHA-256 (sha256 字段); 校验通过后暂存到 updater.upload_dir 并通过 uv tool install --force <file> 执行 停止 -> 安装 -> 启动, 不使用配置的更新源; 校验失败返回 400 并记录到更新日志; 返回 202 和 update_id, ?wait=true 等待结果; 更新日志 source_type 为 upload",
+		},
+		"update_job": {
+			Method:      "GET",
+			Path:        "/api/v1/updates/{id}",
+			Auth:        "required",
+			Description: "查询后台更新任务: 当前阶段 (queued/checking/staging/stopping/installing/swapping/starting/verifying/rolling_back/completed/failed/cancelled, 排队等待时为 queued)、进度 (当前实例、已完成/剩余数、耗时、安装心跳)、各实例进度, 结束后包含更新结果",
+		},
+		"update_job_events": {
+			Method:      "GET",
+			Path:        "/api/v1/updates/{id}/events",
+			Auth:        "required",
+			Description: "SSE 推送后台更新任务的进度: 连接时及每次变化发送 progress 事件, 结束时发送包含结果的 done 事件",
+		},
+		"update_progress": {
+			Method:      "GET",
+			Path:        "/api/v1/update/progress",
+			Auth:        "required",
+			Description: "正在进行 (或最近一次) 的 API 更新任务的进度, 格式同 /api/v1/updates/{id}; 尚未触发过更新时 phase 为 idle",
+		},
+		"update_job_cancel": {
+			Method:      "DELETE",
+			Path:        "/api/v1/updates/{id}",
+			Auth:        "required",
+			Description: "取消后台更新任务, 在下一阶段开始前生效: 已停止的实例会重新启动; 安装开始后不能取消 (409)",
+		},
+		"update_queue": {
+			Method:      "GET",
+			Path:        "/api/v1/updates/queue",
+			Auth:        "required",
+			Description: "更新进行中到达的请求 (API、定时更新) 进入更新队列而不是返回 409: 与队尾目标相同的请求合并为一次后续运行 (update_ids 列出合并的请求), 目标不同的请求按顺序排队; 返回等待中的更新 (不含正在执行的更新); 队列已满 (10 项) 时新请求返回 409",
+		},
+		"update_queue_drop": {
+			Method:      "DELETE",
+			Path:        "/api/v1/updates/queue/{id}",
+			Auth:        "required",
+			Description: "从更新队列移除等待中的更新, 合并到它的请求以 cancelled 结束; 已开始执行或不存在时返回 404",
+		},
+		"approvals": {
+			Method:      "GET",
+			Path:        "/api/v1/approvals",
+			Auth:        "required",
+			Description: "更新审批 (approval.enabled): 定时更新和 trigger-update 创建待审批的更新 (202, phase=pending_approval), 批准后才执行; 返回待审批和最近已决定 (approved/rejected/expired) 的更新, 含新版本和变更链接",
+		},
+		"update_approve": {
+			Method:      "POST",
+			Path:        "/api/v1/updates/{id}/approve",
+			Auth:        "required",
+			Description: "批准待审批的更新并在后台开始执行 (Bearer Token 或审批链接的 ?token=), update_id 与审批 ID 相同 (202); Pushover 通知中的审批链接 (approval.public_url) 先打开确认页面 (GET), 确认后才批准; 已决定或已过期时返回 409",
+		},
+		"update_reject": {
+			Method:      "POST",
+			Path:        "/api/v1/updates/{id}/reject",
+			Auth:        "required",
+			Description: "拒绝待审批的更新 (Bearer Token 或审批链接的 ?token=), 可选 JSON body {\"reason\":\"...\"}; 未在 approval.timeout 内审批的更新自动过期; 所有状态都记录在 update-logs (operation=update-approval)",
+		},
+		"update_plan": {
+			Method:      "GET",
+			Path:        "/api/v1/update/plan",
+			Auth:        "required",
+			Description: "预览更新计划（dry run）: 将停止的实例及 PID、将安装的源和 spec、已安装版本、是否有新版本以及预检问题（如 uv 未安装）; 参数同 trigger-update (ref/commit/pypi_version/force), 不停止也不安装",
+		},
+		"nanobot_check": {
+			Method:      "GET",
+			Path:        "/api/v1/nanobot/check",
+			Auth:        "required",
+			Description: "检查是否有可用的 nanobot 更新（比较已安装版本/提交与 PyPI 最新版本和 git 源 HEAD）; 可选 ref/commit/pypi_version 参数",
+		},
+		"nanobot_repo": {
+			Method:      "GET",
+			Path:        "/api/v1/nanobot/repo",
+			Auth:        "required",
+			Description: "本地 nanobot 源码仓库 (updater.repo_path) 的当前分支、HEAD 提交 (hash 和标题) 以及是否有未提交修改; 每次安装成功后自动 git pull 并把 HEAD 记录到更新日志 (repo_commit/repo_subject); 未配置时返回 404",
 		},
 		"update_logs": {
 			Method:      "GET",
 			Path:        "/api/v1/update-logs",
 			Auth:        "required",
-			Description: "Query update log history (supports limit and offset parameters)",
+			Description: "Query update log history (supports limit and offset parameters); every attempt is recorded with operation (nanobot-update/self-update/instance-restart/instance-start/instance-stop), status (incl. failed/rejected/timeout), error and reject_reason",
+		},
+		"update_output": {
+			Method:      "GET",
+			Path:        "/api/v1/update-logs/{id}/output",
+			Auth:        "required",
+			Description: "更新过程中 uv/git 命令的完整输出（纯文本）; 实时输出见 /api/v1/logs/_updater/stream",
+		},
+		"update_instance_logs": {
+			Method:      "GET",
+			Path:        "/api/v1/update-logs/{id}/instances/{name}/logs",
+			Auth:        "required",
+			Description: "某次更新中实例启动期间产生的日志行 (按 LogBuffer 序号范围, 仅保存在内存中; 已被覆盖时 truncated=true, 更新器重启后返回 410)",
 		},
 		"help": {
 			Method:      "GET",
@@ -93,7 +183,7 @@ func (h *HelpHandler) getEndpoints() map[string]EndpointInfo {
 			Method:      "GET",
 			Path:        "/api/v1/instances/status",
 			Auth:        "optional",
-			Description: "实例状态列表（名称、端口、运行状态）",
+			Description: "实例状态列表（状态机 state、发起方、启动时间、运行时长、最近退出码和原因、崩溃监督的重启/崩溃计数、存活检查结果和最近输出时间）",
 		},
 		"logs_ui": {
 			Method:      "GET",
@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
@@ -18,21 +19,57 @@ import (
 // instanceConfigRequest is the JSON body for create/update/copy requests.
 // startup_timeout is in seconds (uint32) per D-06.
 type instanceConfigRequest struct {
-	Name           string `json:"name"`
-	Port           uint32 `json:"port"`
-	StartCommand   string `json:"start_command"`
-	StartupTimeout uint32 `json:"startup_timeout"` // seconds, converted to time.Duration internally
-	AutoStart      *bool  `json:"auto_start"`
+	Name           string                 `json:"name"`
+	Port           uint32                 `json:"port"`
+	StartCommand   string                 `json:"start_command"`
+	StartupTimeout uint32                 `json:"startup_timeout"` // seconds, converted to time.Duration internally
+	AutoStart      *bool                  `json:"auto_start"`
+	Channel        string                 `json:"channel,omitempty"`     // updater.channels entry, empty for the global install
+	Canary         bool                   `json:"canary,omitempty"`      // Start first after an update and soak before the others
+	Restart        *instanceRestartJSON   `json:"restart,omitempty"`     // Crash supervisor, omitted = never restart
+	Readiness      *instanceReadinessJSON `json:"readiness,omitempty"`   // Readiness probes, omitted = 2s liveness check
+	Env            map[string]string      `json:"env,omitempty"`         // Extra environment variables, ${VAR} is expanded at start
+	EnvFile        string                 `json:"env_file,omitempty"`    // KEY=VALUE file read at start
+	WorkingDir     string                 `json:"working_dir,omitempty"` // Working directory, empty = working directory of the service
+	OwnHome        bool                   `json:"own_home,omitempty"`    // Own HOME directory so ~/.nanobot is not shared
 }
 
 // instanceConfigResponse is the JSON response for a single instance config.
 // startup_timeout is in seconds (uint32) per D-06.
 type instanceConfigResponse struct {
-	Name           string `json:"name"`
-	Port           uint32 `json:"port"`
-	StartCommand   string `json:"start_command"`
-	StartupTimeout uint32 `json:"startup_timeout"`
-	AutoStart      *bool  `json:"auto_start"`
+	Name           string                 `json:"name"`
+	Port           uint32                 `json:"port"`
+	StartCommand   string                 `json:"start_command"`
+	StartupTimeout uint32                 `json:"startup_timeout"`
+	AutoStart      *bool                  `json:"auto_start"`
+	Channel        string                 `json:"channel,omitempty"`
+	Canary         bool                   `json:"canary,omitempty"`
+	Restart        *instanceRestartJSON   `json:"restart,omitempty"`
+	Readiness      *instanceReadinessJSON `json:"readiness,omitempty"`
+	Env            map[string]string      `json:"env,omitempty"`
+	EnvFile        string                 `json:"env_file,omitempty"`
+	WorkingDir     string                 `json:"working_dir,omitempty"`
+	OwnHome        bool                   `json:"own_home,omitempty"`
+	HomeDir        string                 `json:"home_dir,omitempty"` // Resolved own HOME directory (read-only)
+}
+
+// instanceRestartJSON is the restart config of an instance (config.RestartConfig).
+// Durations are in seconds like startup_timeout, zero values use the defaults.
+type instanceRestartJSON struct {
+	Policy          string `json:"policy,omitempty"` // never / on-failure / always
+	Backoff         uint32 `json:"backoff,omitempty"`
+	MaxBackoff      uint32 `json:"max_backoff,omitempty"`
+	CrashLoopCount  int    `json:"crash_loop_count,omitempty"`
+	CrashLoopWindow uint32 `json:"crash_loop_window,omitempty"`
+}
+
+// instanceReadinessJSON is the readiness config of an instance (config.ReadinessConfig).
+// interval is in milliseconds, zero uses the default.
+type instanceReadinessJSON struct {
+	TCP        bool   `json:"tcp,omitempty"`
+	HTTP       string `json:"http,omitempty"` // path on the instance port or full URL
+	LogPattern string `json:"log_pattern,omitempty"`
+	IntervalMs uint32 `json:"interval_ms,omitempty"`
 }
 
 // validationErrorDetail represents a single field validation error.
@@ -86,17 +123,20 @@ func (e *notFoundError) Error() string {
 //     Removes the nanobot config directory for the deleted instance.
 //     Failure is non-blocking (logged as warning).
 //
+// The dirs arguments are the own HOME and working directory of the instances (instanceDirs),
+// which the nanobot config path is resolved against.
+//
 // Optional onStopInstance (for targeted instance stop on delete):
 //   - onStopInstance: Called when an instance is deleted to stop only that instance
 //     by PID, instead of killing all nanobot processes system-wide.
 type InstanceConfigHandler struct {
 	getConfig        func() *config.Config // injected for testability; production uses config.GetCurrentConfig
 	logger           *slog.Logger
-	onCreateInstance func(name string, port uint32, startCommand string) error                                                                                                                                  // Phase 52: nanobot config creation
-	onCopyInstance   func(sourceName string, sourceStartCommand string, targetName string, targetPort uint32, targetStartCommand string) error                                                                  // Phase 52: nanobot config clone
-	onDeleteInstance func(name string, startCommand string) error                                                                                                                                               // Phase 52: nanobot config directory cleanup
-	onUpdateInstance func(name string, oldPort uint32, oldStartCommand string, newPort uint32, newStartCommand string) error                                                                                     // nanobot config sync on update
-	onStopInstance   func(ctx context.Context, name string) error                                                                                                                                                // targeted instance stop by PID
+	onCreateInstance func(name string, port uint32, startCommand string, dirs nanobot.InstanceDirs) error                                                                                                 // Phase 52: nanobot config creation
+	onCopyInstance   func(sourceName, sourceStartCommand string, sourceDirs nanobot.InstanceDirs, targetName string, targetPort uint32, targetStartCommand string, targetDirs nanobot.InstanceDirs) error // Phase 52: nanobot config clone
+	onDeleteInstance func(name, startCommand string, dirs nanobot.InstanceDirs) error                                                                                                                     // Phase 52: nanobot config directory cleanup
+	onUpdateInstance func(name string, oldPort uint32, oldStartCommand string, oldDirs nanobot.InstanceDirs, newPort uint32, newStartCommand string, newDirs nanobot.InstanceDirs) error                  // nanobot config sync on update
+	onStopInstance   func(ctx context.Context, name string) error                                                                                                                                         // targeted instance stop by PID
 }
 
 // NewInstanceConfigHandler creates a new InstanceConfigHandler.
@@ -109,27 +149,27 @@ func NewInstanceConfigHandler(getConfig func() *config.Config, logger *slog.Logg
 }
 
 // SetOnCreateInstance sets the callback invoked after creating a new instance.
-// The callback receives the instance name, port, and startCommand.
-func (h *InstanceConfigHandler) SetOnCreateInstance(fn func(name string, port uint32, startCommand string) error) {
+// The callback receives the instance name, port, startCommand and directories.
+func (h *InstanceConfigHandler) SetOnCreateInstance(fn func(name string, port uint32, startCommand string, dirs nanobot.InstanceDirs) error) {
 	h.onCreateInstance = fn
 }
 
 // SetOnCopyInstance sets the callback invoked after copying an instance.
 // The callback receives the source instance info and target instance info.
-func (h *InstanceConfigHandler) SetOnCopyInstance(fn func(sourceName string, sourceStartCommand string, targetName string, targetPort uint32, targetStartCommand string) error) {
+func (h *InstanceConfigHandler) SetOnCopyInstance(fn func(sourceName, sourceStartCommand string, sourceDirs nanobot.InstanceDirs, targetName string, targetPort uint32, targetStartCommand string, targetDirs nanobot.InstanceDirs) error) {
 	h.onCopyInstance = fn
 }
 
 // SetOnDeleteInstance sets the callback invoked after deleting an instance.
-// The callback receives the instance name and startCommand for path resolution.
-func (h *InstanceConfigHandler) SetOnDeleteInstance(fn func(name string, startCommand string) error) {
+// The callback receives the instance name, startCommand and directories for path resolution.
+func (h *InstanceConfigHandler) SetOnDeleteInstance(fn func(name, startCommand string, dirs nanobot.InstanceDirs) error) {
 	h.onDeleteInstance = fn
 }
 
 // SetOnUpdateInstance sets the callback invoked after updating an instance.
-// The callback receives the instance name, old port/startCommand, and new port/startCommand
-// so the nanobot config can be synced (port, workspace, config path changes).
-func (h *InstanceConfigHandler) SetOnUpdateInstance(fn func(name string, oldPort uint32, oldStartCommand string, newPort uint32, newStartCommand string) error) {
+// The callback receives the instance name, old port/startCommand/directories, and new
+// port/startCommand/directories so the nanobot config can be synced (port, workspace, config path changes).
+func (h *InstanceConfigHandler) SetOnUpdateInstance(fn func(name string, oldPort uint32, oldStartCommand string, oldDirs nanobot.InstanceDirs, newPort uint32, newStartCommand string, newDirs nanobot.InstanceDirs) error) {
 	h.onUpdateInstance = fn
 }
 
@@ -149,9 +189,120 @@ func toResponse(ic config.InstanceConfig) instanceConfigResponse {
 		StartCommand:   ic.StartCommand,
 		StartupTimeout: uint32(ic.StartupTimeout.Seconds()),
 		AutoStart:      ic.AutoStart,
+		Channel:        ic.Channel,
+		Canary:         ic.Canary,
+		Restart:        toRestartJSON(ic.Restart),
+		Readiness:      toReadinessJSON(ic.Readiness),
+		Env:            redactEnv(ic.Env),
+		EnvFile:        ic.EnvFile,
+		WorkingDir:     ic.WorkingDir,
+		OwnHome:        ic.OwnHome,
+		HomeDir:        ic.HomeDir(),
+	}
+}
+
+// instanceDirs returns the directories the nanobot config path of ic is resolved against
+func instanceDirs(ic config.InstanceConfig) nanobot.InstanceDirs {
+	return nanobot.InstanceDirs{HomeDir: ic.HomeDir(), WorkingDir: ic.WorkingDir}
+}
+
+// toRestartJSON converts a RestartConfig to its JSON form, nil when nothing is configured.
+func toRestartJSON(rc config.RestartConfig) *instanceRestartJSON {
+	if rc == (config.RestartConfig{}) {
+		return nil
+	}
+	return &instanceRestartJSON{
+		Policy:          rc.Policy,
+		Backoff:         uint32(rc.Backoff.Seconds()),
+		MaxBackoff:      uint32(rc.MaxBackoff.Seconds()),
+		CrashLoopCount:  rc.CrashLoopCount,
+		CrashLoopWindow: uint32(rc.CrashLoopWindow.Seconds()),
+	}
+}
+
+// toRestartConfig converts the JSON restart config to a RestartConfig.
+func toRestartConfig(r *instanceRestartJSON) config.RestartConfig {
+	if r == nil {
+		return config.RestartConfig{}
+	}
+	return config.RestartConfig{
+		Policy:          r.Policy,
+		Backoff:         time.Duration(r.Backoff) * time.Second,
+		MaxBackoff:      time.Duration(r.MaxBackoff) * time.Second,
+		CrashLoopCount:  r.CrashLoopCount,
+		CrashLoopWindow: time.Duration(r.CrashLoopWindow) * time.Second,
 	}
 }
 
+// toReadinessJSON converts a ReadinessConfig to its JSON form, nil when nothing is configured.
+func toReadinessJSON(rc config.ReadinessConfig) *instanceReadinessJSON {
+	if rc == (config.ReadinessConfig{}) {
+		return nil
+	}
+	return &instanceReadinessJSON{
+		TCP:        rc.TCP,
+		HTTP:       rc.HTTP,
+		LogPattern: rc.LogPattern,
+		IntervalMs: uint32(rc.Interval.Milliseconds()),
+	}
+}
+
+// toReadinessConfig converts the JSON readiness config to a ReadinessConfig.
+func toReadinessConfig(r *instanceReadinessJSON) config.ReadinessConfig {
+	if r == nil {
+		return config.ReadinessConfig{}
+	}
+	return config.ReadinessConfig{
+		TCP:        r.TCP,
+		HTTP:       r.HTTP,
+		LogPattern: r.LogPattern,
+		Interval:   time.Duration(r.IntervalMs) * time.Millisecond,
+	}
+}
+
+// maskedEnvValue replaces env values in responses, which often hold tokens and API keys.
+// Sending it back in a request keeps the current value of the variable.
+const maskedEnvValue = "********"
+
+// redactEnv returns a copy of env with every value replaced by maskedEnvValue
+func redactEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(env))
+	for k := range env {
+		redacted[k] = maskedEnvValue
+	}
+	return redacted
+}
+
+// restoreMaskedEnv replaces masked values in env with the current value of the same variable
+// (the stored instance, or the source instance of a copy). A masked variable without a current
+// value is a validation error.
+func restoreMaskedEnv(env, current map[string]string) []validationErrorDetail {
+	var missing []string
+	for k, v := range env {
+		if v != maskedEnvValue {
+			continue
+		}
+		if old, ok := current[k]; ok {
+			env[k] = old
+		} else {
+			missing = append(missing, k)
+		}
+	}
+
+	sort.Strings(missing)
+	details := make([]validationErrorDetail, 0, len(missing))
+	for _, k := range missing {
+		details = append(details, validationErrorDetail{
+			Field:   "env." + k,
+			Message: fmt.Sprintf("Variable %q has no current value to keep, send the actual value", k),
+		})
+	}
+	return details
+}
+
 // toInstanceConfig converts a JSON request to an internal InstanceConfig.
 // StartupTimeout is converted from seconds to time.Duration.
 func toInstanceConfig(req instanceConfigRequest) config.InstanceConfig {
@@ -160,6 +311,14 @@ func toInstanceConfig(req instanceConfigRequest) config.InstanceConfig {
 		Port:         req.Port,
 		StartCommand: req.StartCommand,
 		AutoStart:    req.AutoStart,
+		Channel:      req.Channel,
+		Canary:       req.Canary,
+		Restart:      toRestartConfig(req.Restart),
+		Readiness:    toReadinessConfig(req.Readiness),
+		Env:          req.Env,
+		EnvFile:      req.EnvFile,
+		WorkingDir:   req.WorkingDir,
+		OwnHome:      req.OwnHome,
 	}
 	if req.StartupTimeout > 0 {
 		ic.StartupTimeout = time.Duration(req.StartupTimeout) * time.Second
@@ -191,7 +350,8 @@ func findInstanceByName(cfg *config.Config, name string) (int, *config.InstanceC
 
 // validateInstanceConfig collects ALL validation errors (field + uniqueness) in one pass.
 // excludeIndex is the index of the instance being updated (to exclude it from uniqueness checks).
-func validateInstanceConfig(ic *config.InstanceConfig, instances []config.InstanceConfig, excludeIndex int) []validationErrorDetail {
+// updaterCfg provides the updater.channels the instance may reference and the update strategy.
+func validateInstanceConfig(ic *config.InstanceConfig, instances []config.InstanceConfig, updaterCfg *config.UpdaterConfig, excludeIndex int) []validationErrorDetail {
 	var details []validationErrorDetail
 
 	// Field validation via InstanceConfig.Validate()
@@ -230,6 +390,26 @@ func validateInstanceConfig(ic *config.InstanceConfig, instances []config.Instan
 		}
 	}
 
+	// Channel must reference an updater.channels entry
+	if ic.Channel != "" {
+		if _, ok := updaterCfg.Channel(ic.Channel); !ok {
+			details = append(details, validationErrorDetail{
+				Field:   "channel",
+				Message: fmt.Sprintf("Channel %q is not configured in updater.channels", ic.Channel),
+			})
+		}
+	}
+
+	// staged 策略只能切换不带路径的可执行文件
+	if updaterCfg.IsStaged() {
+		if err := ic.ValidateStaged(); err != nil {
+			details = append(details, validationErrorDetail{
+				Field:   "start_command",
+				Message: err.Error(),
+			})
+		}
+	}
+
 	return details
 }
 
@@ -285,7 +465,8 @@ func (h *InstanceConfigHandler) HandleCreate(w http.ResponseWriter, r *http.Requ
 	ic := toInstanceConfig(req)
 
 	err := config.UpdateConfig(func(cfg *config.Config) error {
-		details := validateInstanceConfig(&ic, cfg.Instances, -1)
+		details := restoreMaskedEnv(ic.Env, nil)
+		details = append(details, validateInstanceConfig(&ic, cfg.Instances, &cfg.Updater, -1)...)
 		if len(details) > 0 {
 			return &validationError{details: details}
 		}
@@ -306,7 +487,7 @@ func (h *InstanceConfigHandler) HandleCreate(w http.ResponseWriter, r *http.Requ
 
 	// Phase 52: Create nanobot config directory with default config (NC-01)
 	if h.onCreateInstance != nil {
-		if err := h.onCreateInstance(ic.Name, ic.Port, ic.StartCommand); err != nil {
+		if err := h.onCreateInstance(ic.Name, ic.Port, ic.StartCommand, instanceDirs(ic)); err != nil {
 			h.logger.Warn("Failed to create nanobot config for new instance",
 				"name", ic.Name, "error", err)
 			// Non-blocking: instance is created, nanobot config can be fixed via PUT endpoint
@@ -344,6 +525,7 @@ func (h *InstanceConfigHandler) HandleCreate(w http.ResponseWriter, r *http.Requ
 	// Capture old values before update for nanobot config sync
 	var oldPort uint32
 	var oldStartCommand string
+	var oldDirs nanobot.InstanceDirs
 
 	err := config.UpdateConfig(func(cfg *config.Config) error {
 		existingIndex, existingIC := findInstanceByName(cfg, pathName)
@@ -354,8 +536,11 @@ func (h *InstanceConfigHandler) HandleCreate(w http.ResponseWriter, r *http.Requ
 		// Capture old values before overwriting
 		oldPort = existingIC.Port
 		oldStartCommand = existingIC.StartCommand
+		oldDirs = instanceDirs(*existingIC)
 
-		details := validateInstanceConfig(&ic, cfg.Instances, existingIndex)
+		// 掩码值表示保留当前值 (GET 响应不返回 env 的值)
+		details := restoreMaskedEnv(ic.Env, existingIC.Env)
+		details = append(details, validateInstanceConfig(&ic, cfg.Instances, &cfg.Updater, existingIndex)...)
 		if len(details) > 0 {
 			return &validationError{details: details}
 		}
@@ -382,7 +567,7 @@ func (h *InstanceConfigHandler) HandleCreate(w http.ResponseWriter, r *http.Requ
 
 	// Sync nanobot config when port or startCommand changed
 	if h.onUpdateInstance != nil {
-		if err := h.onUpdateInstance(ic.Name, oldPort, oldStartCommand, ic.Port, ic.StartCommand); err != nil {
+		if err := h.onUpdateInstance(ic.Name, oldPort, oldStartCommand, oldDirs, ic.Port, ic.StartCommand, instanceDirs(ic)); err != nil {
 			h.logger.Warn("Failed to sync nanobot config for updated instance",
 				"name", ic.Name, "error", err)
 			// Non-blocking: instance is updated, nanobot config can be synced manually
@@ -400,6 +585,7 @@ func (h *InstanceConfigHandler) HandleDelete(w http.ResponseWriter, r *http.Requ
 
 	// Capture instance info before UpdateConfig removes it
 	var deletedStartCommand string
+	var deletedDirs nanobot.InstanceDirs
 
 	err := config.UpdateConfig(func(cfg *config.Config) error {
 		index, ic := findInstanceByName(cfg, name)
@@ -407,6 +593,7 @@ func (h *InstanceConfigHandler) HandleDelete(w http.ResponseWriter, r *http.Requ
 			return &notFoundError{name: name}
 		}
 		deletedStartCommand = ic.StartCommand
+		deletedDirs = instanceDirs(*ic)
 		cfg.Instances = append(cfg.Instances[:index], cfg.Instances[index+1:]...)
 		return nil
 	})
@@ -436,11 +623,11 @@ func (h *InstanceConfigHandler) HandleDelete(w http.ResponseWriter, r *http.Requ
 	// Phase 52: Clean up nanobot config directory for deleted instance.
 	// Skip cleanup if other instances share the same config path (default gateway).
 	if h.onDeleteInstance != nil {
-		skipCleanup := h.shouldSkipConfigCleanup(name, deletedStartCommand)
+		skipCleanup := h.shouldSkipConfigCleanup(name, deletedStartCommand, deletedDirs)
 		if skipCleanup {
 			h.logger.Info("Skipping nanobot config cleanup: other instances share the same config path",
 				"name", name, "start_command", deletedStartCommand)
-		} else if err := h.onDeleteInstance(name, deletedStartCommand); err != nil {
+		} else if err := h.onDeleteInstance(name, deletedStartCommand, deletedDirs); err != nil {
 			h.logger.Warn("Failed to clean up nanobot config for deleted instance",
 				"name", name, "error", err)
 			// Non-blocking: instance is deleted from config.yaml, orphaned dir can be cleaned manually
@@ -460,14 +647,14 @@ func (h *InstanceConfigHandler) HandleDelete(w http.ResponseWriter, r *http.Requ
 // the same nanobot config path or directory as the deleted instance. This prevents
 // deleting a shared config file (e.g., ~/.nanobot/config.json) or a shared config
 // directory that other instances depend on.
-func (h *InstanceConfigHandler) shouldSkipConfigCleanup(deletedName, deletedStartCommand string) bool {
+func (h *InstanceConfigHandler) shouldSkipConfigCleanup(deletedName, deletedStartCommand string, deletedDirs nanobot.InstanceDirs) bool {
 	cfg := h.getConfig()
 	if cfg == nil {
 		return false
 	}
 
 	// Resolve the deleted instance's config path
-	deletedPath, err := nanobot.ParseConfigPath(deletedStartCommand, deletedName)
+	deletedPath, err := nanobot.ParseConfigPath(deletedStartCommand, deletedName, deletedDirs)
 	if err != nil {
 		return false
 	}
@@ -476,7 +663,7 @@ func (h *InstanceConfigHandler) shouldSkipConfigCleanup(deletedName, deletedStar
 
 	// Check if any remaining instance resolves to the same path or shares the same directory
 	for _, ic := range cfg.Instances {
-		otherPath, err := nanobot.ParseConfigPath(ic.StartCommand, ic.Name)
+		otherPath, err := nanobot.ParseConfigPath(ic.StartCommand, ic.Name, instanceDirs(ic))
 		if err != nil {
 			continue
 		}
@@ -511,6 +698,7 @@ func (h *InstanceConfigHandler) HandleCopy(w http.ResponseWriter, r *http.Reques
 
 	var clonedInstance config.InstanceConfig
 	var sourceStartCommand string
+	var sourceDirs nanobot.InstanceDirs
 
 	err = config.UpdateConfig(func(cfg *config.Config) error {
 		sourceIndex, sourceIC := findInstanceByName(cfg, sourceName)
@@ -520,6 +708,7 @@ func (h *InstanceConfigHandler) HandleCopy(w http.ResponseWriter, r *http.Reques
 
 		// Capture sourceStartCommand before any modifications
 		sourceStartCommand = sourceIC.StartCommand
+		sourceDirs = instanceDirs(*sourceIC)
 
 		// Clone the source instance config
 		clonedInstance = *sourceIC
@@ -574,19 +763,52 @@ func (h *InstanceConfigHandler) HandleCopy(w http.ResponseWriter, r *http.Reques
 		if req.AutoStart != nil {
 			clonedInstance.AutoStart = req.AutoStart
 		}
+		if req.Channel != "" {
+			clonedInstance.Channel = req.Channel
+		}
+		if req.Canary {
+			clonedInstance.Canary = true
+		}
+		if req.Restart != nil {
+			clonedInstance.Restart = toRestartConfig(req.Restart)
+		}
+		if req.Readiness != nil {
+			clonedInstance.Readiness = toReadinessConfig(req.Readiness)
+		}
+		var envDetails []validationErrorDetail
+		if req.Env != nil {
+			envDetails = restoreMaskedEnv(req.Env, sourceIC.Env)
+			clonedInstance.Env = req.Env
+		}
+		if req.EnvFile != "" {
+			clonedInstance.EnvFile = req.EnvFile
+		}
+		if req.WorkingDir != "" {
+			clonedInstance.WorkingDir = req.WorkingDir
+		}
+		if req.OwnHome {
+			clonedInstance.OwnHome = true
+		}
 
-		// Deep copy AutoStart pointer for the cloned instance
+		// Deep copy AutoStart pointer and Env map for the cloned instance
 		if clonedInstance.AutoStart != nil {
 			val := *clonedInstance.AutoStart
 			clonedInstance.AutoStart = &val
 		}
+		if clonedInstance.Env != nil {
+			env := make(map[string]string, len(clonedInstance.Env))
+			for k, v := range clonedInstance.Env {
+				env[k] = v
+			}
+			clonedInstance.Env = env
+		}
 
 		// Prevent config path collision: if the copy resolves to the same config file
 		// as the source, auto-generate a unique --config path for the copy.
 		// This prevents CloneConfig from silently overwriting the source's config
 		// (which would corrupt the source instance's port, workspace, and skills).
-		sourceCfgPath, _ := nanobot.ParseConfigPath(sourceStartCommand, sourceName)
-		targetCfgPath, _ := nanobot.ParseConfigPath(clonedInstance.StartCommand, clonedInstance.Name)
+		sourceCfgPath, _ := nanobot.ParseConfigPath(sourceStartCommand, sourceName, sourceDirs)
+		targetCfgPath, _ := nanobot.ParseConfigPath(clonedInstance.StartCommand, clonedInstance.Name, instanceDirs(clonedInstance))
 		if sourceCfgPath == targetCfgPath {
 			uniqueConfigPath := fmt.Sprintf("~/.nanobot-%s/config.json", clonedInstance.Name)
 			newStartCmd := nanobot.UpdateStartCommandConfig(clonedInstance.StartCommand, uniqueConfigPath)
@@ -595,7 +817,7 @@ func (h *InstanceConfigHandler) HandleCopy(w http.ResponseWriter, r *http.Reques
 				"instance", clonedInstance.Name, "config_path", uniqueConfigPath)
 		}
 
-		details := validateInstanceConfig(&clonedInstance, cfg.Instances, -1)
+		details := append(envDetails, validateInstanceConfig(&clonedInstance, cfg.Instances, &cfg.Updater, -1)...)
 		if len(details) > 0 {
 			return &validationError{details: details}
 		}
@@ -624,7 +846,7 @@ func (h *InstanceConfigHandler) HandleCopy(w http.ResponseWriter, r *http.Reques
 	// Note: Only gateway.port and agents.defaults.workspace are updated in the cloned config.
 	// nanobot config.json has no top-level "name" field.
 	if h.onCopyInstance != nil {
-		if err := h.onCopyInstance(sourceName, sourceStartCommand, clonedInstance.Name, clonedInstance.Port, clonedInstance.StartCommand); err != nil {
+		if err := h.onCopyInstance(sourceName, sourceStartCommand, sourceDirs, clonedInstance.Name, clonedInstance.Port, clonedInstance.StartCommand, instanceDirs(clonedInstance)); err != nil {
 			h.logger.Warn("Failed to clone nanobot config for copied instance",
 				"source", sourceName, "target", clonedInstance.Name, "error", err)
 			// Non-blocking: instance is copied, nanobot config can be fixed via PUT endpoint
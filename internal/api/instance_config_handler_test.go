@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/nanobot"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -600,7 +602,7 @@ func TestHandleCreate_InvokesOnCreateCallback(t *testing.T) {
 	var callbackPort uint32
 	var callbackStartCommand string
 
-	handler.SetOnCreateInstance(func(name string, port uint32, startCommand string) error {
+	handler.SetOnCreateInstance(func(name string, port uint32, startCommand string, dirs nanobot.InstanceDirs) error {
 		callbackCalled = true
 		callbackName = name
 		callbackPort = port
@@ -626,7 +628,7 @@ func TestHandleCreate_InvokesOnCreateCallback(t *testing.T) {
 func TestHandleCreate_CallbackFailureNonBlocking(t *testing.T) {
 	handler, token := setupIntegrationTest(t)
 
-	handler.SetOnCreateInstance(func(name string, port uint32, startCommand string) error {
+	handler.SetOnCreateInstance(func(name string, port uint32, startCommand string, dirs nanobot.InstanceDirs) error {
 		return fmt.Errorf("simulated nanobot config creation failure")
 	})
 
@@ -654,7 +656,7 @@ func TestHandleCopy_InvokesOnCopyCallback(t *testing.T) {
 	var callbackTargetName string
 	var callbackTargetPort uint32
 
-	handler.SetOnCopyInstance(func(sourceName string, sourceStartCommand string, targetName string, targetPort uint32, targetStartCommand string) error {
+	handler.SetOnCopyInstance(func(sourceName, sourceStartCommand string, sourceDirs nanobot.InstanceDirs, targetName string, targetPort uint32, targetStartCommand string, targetDirs nanobot.InstanceDirs) error {
 		callbackCalled = true
 		callbackSourceName = sourceName
 		callbackTargetName = targetName
@@ -685,7 +687,7 @@ func TestHandleDelete_InvokesOnDeleteCallback(t *testing.T) {
 	var callbackName string
 	var callbackStartCommand string
 
-	handler.SetOnDeleteInstance(func(name string, startCommand string) error {
+	handler.SetOnDeleteInstance(func(name, startCommand string, dirs nanobot.InstanceDirs) error {
 		callbackCalled = true
 		callbackName = name
 		callbackStartCommand = startCommand
@@ -709,7 +711,7 @@ func TestHandleDelete_InvokesOnDeleteCallback(t *testing.T) {
 func TestHandleDelete_CallbackFailureNonBlocking(t *testing.T) {
 	handler, token := setupIntegrationTest(t)
 
-	handler.SetOnDeleteInstance(func(name string, startCommand string) error {
+	handler.SetOnDeleteInstance(func(name, startCommand string, dirs nanobot.InstanceDirs) error {
 		return fmt.Errorf("simulated nanobot config cleanup failure")
 	})
 
@@ -728,3 +730,333 @@ func TestHandleDelete_CallbackFailureNonBlocking(t *testing.T) {
 	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
 	assert.Contains(t, response["message"], "deleted")
 }
+
+func TestHandleCreate_Channel(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	initialYAML := `api:
+  port: 9999
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "test-existing"
+    port: 18790
+    start_command: "nanobot gateway"
+updater:
+  channels:
+    - name: "canary"
+      install_dir: "/opt/nanobot/canary"
+      ref: "main"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(initialYAML), 0644))
+
+	cfg, err := config.Load(configPath)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.WatchConfig(cfg, logger, &config.HotReloadCallbacks{})
+	t.Cleanup(func() { config.StopWatch() })
+
+	handler := NewInstanceConfigHandler(config.GetCurrentConfig, logger)
+	token := "test-token-123456789012345678901"
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/instance-configs", withAuth(handler.HandleCreate, token))
+
+	body := `{"name":"canary-bot","port":18791,"start_command":"nanobot gateway","channel":"canary","canary":true}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response instanceConfigResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, "canary", response.Channel)
+	assert.True(t, response.Canary)
+
+	persisted, err := config.Load(configPath)
+	require.NoError(t, err)
+	require.Len(t, persisted.Instances, 2)
+	assert.Equal(t, "canary", persisted.Instances[1].Channel)
+	assert.True(t, persisted.Instances[1].Canary)
+	assert.False(t, persisted.Instances[0].Canary)
+
+	body = `{"name":"other-bot","port":18792,"start_command":"nanobot gateway","channel":"missing"}`
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"channel"`)
+}
+
+func TestHandleCreate_StagedRequiresBareExecutable(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	initialYAML := `api:
+  port: 9999
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "test-existing"
+    port: 18790
+    start_command: "nanobot gateway"
+updater:
+  strategy: staged
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(initialYAML), 0644))
+
+	cfg, err := config.Load(configPath)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.WatchConfig(cfg, logger, &config.HotReloadCallbacks{})
+	t.Cleanup(func() { config.StopWatch() })
+
+	handler := NewInstanceConfigHandler(config.GetCurrentConfig, logger)
+	token := "test-token-123456789012345678901"
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/instance-configs", withAuth(handler.HandleCreate, token))
+
+	// staged 策略切换槽位后, 带路径的可执行文件仍是旧版本
+	body := `{"name":"pinned","port":18791,"start_command":"/root/.local/bin/nanobot gateway"}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, rec.Body.String())
+
+	var response validationErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "start_command", response.Errors[0].Field)
+}
+
+func TestHandleCreate_Restart(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	initialYAML := `api:
+  port: 9999
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "test-existing"
+    port: 18790
+    start_command: "nanobot gateway"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(initialYAML), 0644))
+
+	cfg, err := config.Load(configPath)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.WatchConfig(cfg, logger, &config.HotReloadCallbacks{})
+	t.Cleanup(func() { config.StopWatch() })
+
+	handler := NewInstanceConfigHandler(config.GetCurrentConfig, logger)
+	token := "test-token-123456789012345678901"
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/instance-configs", withAuth(handler.HandleCreate, token))
+
+	body := `{"name":"supervised","port":18791,"start_command":"nanobot gateway","restart":{"policy":"on-failure","backoff":2,"crash_loop_count":3}}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response instanceConfigResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.NotNil(t, response.Restart)
+	assert.Equal(t, instanceRestartJSON{Policy: "on-failure", Backoff: 2, CrashLoopCount: 3}, *response.Restart)
+
+	persisted, err := config.Load(configPath)
+	require.NoError(t, err)
+	require.Len(t, persisted.Instances, 2)
+	assert.Equal(t, config.RestartConfig{Policy: "on-failure", Backoff: 2 * time.Second, CrashLoopCount: 3}, persisted.Instances[1].Restart)
+	assert.Equal(t, config.RestartConfig{}, persisted.Instances[0].Restart)
+
+	body = `{"name":"other-bot","port":18792,"start_command":"nanobot gateway","restart":{"policy":"sometimes"}}`
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "restart.policy")
+}
+
+func TestHandleCreate_Readiness(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	initialYAML := `api:
+  port: 9999
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "test-existing"
+    port: 18790
+    start_command: "nanobot gateway"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(initialYAML), 0644))
+
+	cfg, err := config.Load(configPath)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.WatchConfig(cfg, logger, &config.HotReloadCallbacks{})
+	t.Cleanup(func() { config.StopWatch() })
+
+	handler := NewInstanceConfigHandler(config.GetCurrentConfig, logger)
+	token := "test-token-123456789012345678901"
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/instance-configs", withAuth(handler.HandleCreate, token))
+
+	body := `{"name":"probed","port":18791,"start_command":"nanobot gateway","readiness":{"tcp":true,"log_pattern":"commands registered","interval_ms":250}}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response instanceConfigResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.NotNil(t, response.Readiness)
+	assert.Equal(t, instanceReadinessJSON{TCP: true, LogPattern: "commands registered", IntervalMs: 250}, *response.Readiness)
+
+	persisted, err := config.Load(configPath)
+	require.NoError(t, err)
+	require.Len(t, persisted.Instances, 2)
+	assert.Equal(t, config.ReadinessConfig{TCP: true, LogPattern: "commands registered", Interval: 250 * time.Millisecond}, persisted.Instances[1].Readiness)
+	assert.Nil(t, toReadinessJSON(persisted.Instances[0].Readiness))
+
+	body = `{"name":"other-bot","port":18792,"start_command":"nanobot gateway","readiness":{"log_pattern":"(unclosed"}}`
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "readiness.log_pattern")
+}
+
+func TestHandleCreate_EnvAndHome(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	initialYAML := `api:
+  port: 9999
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "test-existing"
+    port: 18790
+    start_command: "nanobot gateway"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(initialYAML), 0644))
+
+	cfg, err := config.Load(configPath)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.WatchConfig(cfg, logger, &config.HotReloadCallbacks{})
+	t.Cleanup(func() { config.StopWatch() })
+
+	handler := NewInstanceConfigHandler(config.GetCurrentConfig, logger)
+	var createdHome string
+	handler.SetOnCreateInstance(func(name string, port uint32, startCommand string, dirs nanobot.InstanceDirs) error {
+		createdHome = dirs.HomeDir
+		return nil
+	})
+	token := "test-token-123456789012345678901"
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/instance-configs", withAuth(handler.HandleCreate, token))
+	mux.Handle("POST /api/v1/instance-configs/{name}/copy", withAuth(handler.HandleCopy, token))
+
+	body := `{"name":"isolated","port":18791,"start_command":"nanobot gateway","env":{"OPENAI_API_KEY":"${OPENAI_KEY}"},"env_file":"isolated.env","working_dir":"/srv/isolated","own_home":true}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var response instanceConfigResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, map[string]string{"OPENAI_API_KEY": maskedEnvValue}, response.Env, "env values are not returned")
+	assert.Equal(t, "isolated.env", response.EnvFile)
+	assert.Equal(t, "/srv/isolated", response.WorkingDir)
+	assert.True(t, response.OwnHome)
+	assert.Equal(t, "homes", filepath.Base(filepath.Dir(response.HomeDir)))
+	assert.Equal(t, response.HomeDir, createdHome, "nanobot config is created in the own HOME")
+
+	// 复制实例时覆盖 env, 其余字段沿用源实例
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs/isolated/copy", token,
+		strings.NewReader(`{"name":"isolated-2","port":18792,"env":{"LOG_LEVEL":"debug"}}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	persisted, err := config.Load(configPath)
+	require.NoError(t, err)
+	require.Len(t, persisted.Instances, 3)
+	created := persisted.Instances[1]
+	assert.Equal(t, map[string]string{"OPENAI_API_KEY": "${OPENAI_KEY}"}, created.Env, "env names keep their case")
+	assert.Equal(t, "isolated.env", created.EnvFile)
+	assert.Equal(t, "/srv/isolated", created.WorkingDir)
+	assert.True(t, created.OwnHome)
+	copied := persisted.Instances[2]
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "debug"}, copied.Env)
+	assert.Equal(t, "/srv/isolated", copied.WorkingDir)
+	assert.True(t, copied.OwnHome)
+	assert.NotEqual(t, created.HomeDir(), copied.HomeDir())
+
+	body = `{"name":"bad-env","port":18793,"start_command":"nanobot gateway","env":{"BAD-NAME":"x"}}`
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs", token, strings.NewReader(body)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "env")
+}
+
+func TestInstanceConfigEnv_MaskedValuesAreKept(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	initialYAML := `api:
+  port: 9999
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "bot"
+    port: 18790
+    start_command: "nanobot gateway"
+    env:
+      TELEGRAM_TOKEN: "123:secret"
+      LOG_LEVEL: "info"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(initialYAML), 0644))
+
+	cfg, err := config.Load(configPath)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.WatchConfig(cfg, logger, &config.HotReloadCallbacks{})
+	t.Cleanup(func() { config.StopWatch() })
+
+	handler := NewInstanceConfigHandler(config.GetCurrentConfig, logger)
+	token := "test-token-123456789012345678901"
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/v1/instance-configs/{name}", withAuth(handler.HandleGet, token))
+	mux.Handle("PUT /api/v1/instance-configs/{name}", withAuth(handler.HandleUpdate, token))
+	mux.Handle("POST /api/v1/instance-configs/{name}/copy", withAuth(handler.HandleCopy, token))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("GET", "/api/v1/instance-configs/bot", token, nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "secret")
+	var got instanceConfigResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, map[string]string{"TELEGRAM_TOKEN": maskedEnvValue, "LOG_LEVEL": maskedEnvValue}, got.Env)
+
+	// 回传 GET 的结果 (掩码值) 只修改 LOG_LEVEL, TELEGRAM_TOKEN 保持不变
+	got.Env["LOG_LEVEL"] = "debug"
+	body, err := json.Marshal(got)
+	require.NoError(t, err)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("PUT", "/api/v1/instance-configs/bot", token, bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "secret")
+
+	persisted, err := config.Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"TELEGRAM_TOKEN": "123:secret", "LOG_LEVEL": "debug"}, persisted.Instances[0].Env)
+
+	// 复制时掩码值取源实例的值
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instance-configs/bot/copy", token,
+		strings.NewReader(`{"name":"bot-2","port":18791,"start_command":"nanobot gateway --config ~/.nanobot-bot-2/config.json","env":{"TELEGRAM_TOKEN":"********"}}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	persisted, err = config.Load(configPath)
+	require.NoError(t, err)
+	require.Len(t, persisted.Instances, 2)
+	assert.Equal(t, map[string]string{"TELEGRAM_TOKEN": "123:secret"}, persisted.Instances[1].Env)
+
+	// 没有当前值的变量不能使用掩码值
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("PUT", "/api/v1/instance-configs/bot", token,
+		strings.NewReader(`{"port":18790,"start_command":"nanobot gateway","env":{"NEW_KEY":"********"}}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "env.NEW_KEY")
+}
@@ -8,15 +8,19 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
 )
 
 // InstanceLifecycleHandler handles start/stop operations for individual instances.
 // LC-01, LC-02: Start and stop endpoints for instance lifecycle control.
 // LC-03: Update-lock coordination prevents races with TriggerUpdate/SelfUpdate.
 type InstanceLifecycleHandler struct {
-	im     *instance.InstanceManager
-	logger *slog.Logger
+	im           *instance.InstanceManager
+	updateLogger *updatelog.UpdateLogger // Records every start/stop attempt (optional, see SetUpdateLogger)
+	logger       *slog.Logger
 }
 
 // NewInstanceLifecycleHandler creates a new InstanceLifecycleHandler.
@@ -27,6 +31,48 @@ func NewInstanceLifecycleHandler(im *instance.InstanceManager, logger *slog.Logg
 	}
 }
 
+// SetUpdateLogger records every start/stop attempt (including rejections and failures)
+// in the update log with operation "instance-start" / "instance-stop".
+func (h *InstanceLifecycleHandler) SetUpdateLogger(ul *updatelog.UpdateLogger) {
+	h.updateLogger = ul
+}
+
+// recordRejected records an attempt refused before the instance was touched
+func (h *InstanceLifecycleHandler) recordRejected(operation, name, reason string) {
+	h.record(updatelog.BuildRejectedLog(uuid.New().String(), operation, "api-instance-lifecycle",
+		time.Now().UTC(), reason, []updatelog.InstanceUpdateDetail{{Name: name, Status: "rejected"}}))
+}
+
+// recordResult records a finished start/stop attempt; err is nil on success.
+// logFrom is the LogBuffer sequence number taken before a start (ignored for stop).
+func (h *InstanceLifecycleHandler) recordResult(operation string, inst *instance.InstanceLifecycle, startTime time.Time, logFrom int64, err error) {
+	endTime := time.Now().UTC()
+	detail := updatelog.InstanceUpdateDetail{Name: inst.Name(), Port: inst.Port(), Status: "success"}
+	if err != nil {
+		detail.Status = "failed"
+		detail.ErrorMessage = err.Error()
+	}
+	if operation == updatelog.OperationInstanceStart {
+		detail.StartDuration = endTime.Sub(startTime).Milliseconds()
+		detail.LogBufferID = inst.GetLogBuffer().ID()
+		detail.LogStartIndex = int(logFrom)
+		detail.LogEndIndex = int(inst.GetLogBuffer().NextSeq())
+	} else {
+		detail.StopDuration = endTime.Sub(startTime).Milliseconds()
+	}
+	h.record(updatelog.BuildOperationLog(uuid.New().String(), operation, "api-instance-lifecycle",
+		startTime, endTime, []updatelog.InstanceUpdateDetail{detail}, err))
+}
+
+func (h *InstanceLifecycleHandler) record(log updatelog.UpdateLog) {
+	if h.updateLogger == nil {
+		return
+	}
+	if err := h.updateLogger.Record(log); err != nil {
+		h.logger.Error("Failed to record instance operation log", "error", err, "operation", log.Operation)
+	}
+}
+
 // HandleStart handles POST /api/v1/instances/{name}/start
 // Starts a stopped instance. Returns 409 if already running or if an update is in progress.
 func (h *InstanceLifecycleHandler) HandleStart(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +84,7 @@ func (h *InstanceLifecycleHandler) HandleStart(w http.ResponseWriter, r *http.Re
 
 	// Update-lock guard: prevents races with TriggerUpdate and SelfUpdate (review HIGH-1)
 	if !h.im.TryLockUpdate() {
+		h.recordRejected(updatelog.OperationInstanceStart, name, "update already in progress")
 		writeJSONError(w, http.StatusConflict, "conflict", "An update is already in progress, cannot start instance")
 		return
 	}
@@ -50,15 +97,20 @@ func (h *InstanceLifecycleHandler) HandleStart(w http.ResponseWriter, r *http.Re
 	}
 
 	if inst.IsRunning() {
+		h.recordRejected(updatelog.OperationInstanceStart, name, "instance already running")
 		writeJSONError(w, http.StatusConflict, "conflict", fmt.Sprintf("Instance %q is already running", name))
 		return
 	}
 
 	// Outer timeout > startup_timeout to let inner logic control its own deadline
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := inst.StartContext(instance.WithInitiator(r.Context(), instance.InitiatorAPI))
 	defer cancel()
 
-	if err := inst.StartAfterUpdate(ctx); err != nil {
+	startTime := time.Now().UTC()
+	logFrom := inst.GetLogBuffer().NextSeq()
+	err = inst.StartAfterUpdate(ctx)
+	h.recordResult(updatelog.OperationInstanceStart, inst, startTime, logFrom, err)
+	if err != nil {
 		h.logger.Error("Failed to start instance", "instance", name, "error", err)
 		writeJSONError(w, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to start instance %q: %v", name, err))
 		return
@@ -83,6 +135,7 @@ func (h *InstanceLifecycleHandler) HandleStop(w http.ResponseWriter, r *http.Req
 
 	// Update-lock guard: prevents races with TriggerUpdate and SelfUpdate (review HIGH-1)
 	if !h.im.TryLockUpdate() {
+		h.recordRejected(updatelog.OperationInstanceStop, name, "update already in progress")
 		writeJSONError(w, http.StatusConflict, "conflict", "An update is already in progress, cannot stop instance")
 		return
 	}
@@ -95,15 +148,19 @@ func (h *InstanceLifecycleHandler) HandleStop(w http.ResponseWriter, r *http.Req
 	}
 
 	if !inst.IsRunning() {
+		h.recordRejected(updatelog.OperationInstanceStop, name, "instance not running")
 		writeJSONError(w, http.StatusConflict, "conflict", fmt.Sprintf("Instance %q is not running", name))
 		return
 	}
 
 	// Outer timeout > inner stopTimeout (5s) to let inner logic control its own deadline
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(instance.WithInitiator(context.Background(), instance.InitiatorAPI), 30*time.Second)
 	defer cancel()
 
-	if err := inst.StopForUpdate(ctx); err != nil {
+	startTime := time.Now().UTC()
+	err = inst.StopForUpdate(ctx)
+	h.recordResult(updatelog.OperationInstanceStop, inst, startTime, 0, err)
+	if err != nil {
 		h.logger.Error("Failed to stop instance", "instance", name, "error", err)
 		writeJSONError(w, http.StatusInternalServerError, "internal_error", fmt.Sprintf("Failed to stop instance %q: %v", name, err))
 		return
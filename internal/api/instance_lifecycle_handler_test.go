@@ -13,6 +13,7 @@ import (
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
 	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -37,10 +38,8 @@ func setupLifecycleTest(t *testing.T) (*InstanceLifecycleHandler, *instance.Inst
 			{
 				Name:           "test-existing",
 				Port:           18790,
-				// Use cmd /c to run ping (stays alive ~30 seconds on Windows).
-				// Include " --port 18790" in a REM comment to satisfy containsPortFlag()
-				// and prevent auto-append of --port flag to the actual command.
-				StartCommand:   `cmd /c "ping -n 30 127.0.0.1 & rem --port 18790"`,
+				// Long-running placeholder command, see lifecycleTestStartCommand
+				StartCommand:   lifecycleTestStartCommand,
 				StartupTimeout: 5 * time.Second,
 				AutoStart:      boolPtr(true),
 			},
@@ -356,3 +355,38 @@ func TestHandleStop_UpdateInProgress(t *testing.T) {
 	assert.Equal(t, "conflict", response["error"])
 	assert.Contains(t, response["message"], "update is already in progress")
 }
+
+// --- Update log tests ---
+
+func TestLifecycle_RejectedAttemptsAreRecorded(t *testing.T) {
+	handler, im, token := setupLifecycleTest(t)
+	ul := updatelog.NewUpdateLogger(slog.New(slog.NewTextHandler(io.Discard, nil)), "")
+	handler.SetUpdateLogger(ul)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/instances/{name}/start", withAuth(handler.HandleStart, token))
+	mux.Handle("POST /api/v1/instances/{name}/stop", withAuth(handler.HandleStop, token))
+
+	// Stop while not running
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instances/test-existing/stop", token, nil))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// Start while an update holds the lock
+	require.True(t, im.TryLockUpdate(), "Should acquire update lock")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("POST", "/api/v1/instances/test-existing/start", token, nil))
+	im.UnlockUpdate()
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	logs := ul.GetAll()
+	require.Len(t, logs, 2)
+	assert.Equal(t, updatelog.OperationInstanceStop, logs[0].Operation)
+	assert.Equal(t, updatelog.StatusRejected, logs[0].Status)
+	assert.Equal(t, "instance not running", logs[0].RejectReason)
+	assert.Equal(t, updatelog.OperationInstanceStart, logs[1].Operation)
+	assert.Equal(t, "update already in progress", logs[1].RejectReason)
+	assert.Equal(t, "api-instance-lifecycle", logs[1].TriggeredBy)
+	require.Len(t, logs[1].Instances, 1)
+	assert.Equal(t, "test-existing", logs[1].Instances[0].Name)
+}
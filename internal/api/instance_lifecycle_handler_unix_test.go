@@ -0,0 +1,8 @@
+//go:build !windows
+
+package api
+
+// lifecycleTestStartCommand uses sh -c to run sleep (stays alive ~30 seconds).
+// Include " --port 18790" in a shell comment to satisfy containsPortFlag()
+// and prevent auto-append of --port flag to the actual command.
+const lifecycleTestStartCommand = `sh -c "sleep 30 # --port 18790"`
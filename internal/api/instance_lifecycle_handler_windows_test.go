@@ -0,0 +1,8 @@
+//go:build windows
+
+package api
+
+// lifecycleTestStartCommand uses cmd /c to run ping (stays alive ~30 seconds on Windows).
+// Include " --port 18790" in a REM comment to satisfy containsPortFlag()
+// and prevent auto-append of --port flag to the actual command.
+const lifecycleTestStartCommand = `cmd /c "ping -n 30 127.0.0.1 & rem --port 18790"`
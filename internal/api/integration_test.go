@@ -49,7 +49,7 @@ func TestE2E_TriggerUpdate_RecordsTo_QueryReturns(t *testing.T) {
 	queryHandler := NewQueryHandler(ul, logger)
 
 	// Step 1: Trigger an update
-	triggerReq := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	triggerReq := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	triggerRec := httptest.NewRecorder()
 	triggerHandler.Handle(triggerRec, triggerReq)
 
@@ -136,7 +136,7 @@ func TestE2E_UpdateID_Consistency(t *testing.T) {
 	// Trigger 3 sequential updates and collect update_ids
 	updateIDs := make([]string, 3)
 	for i := 0; i < 3; i++ {
-		req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+		req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 		rec := httptest.NewRecorder()
 		triggerHandler.Handle(rec, req)
 
@@ -218,7 +218,7 @@ func TestE2E_NonBlocking_FileWriteFailure(t *testing.T) {
 	triggerHandler := newTestHandler(logger, ul, mock, nil)
 
 	// Trigger an update
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 	triggerHandler.Handle(rec, req)
 
@@ -340,7 +340,7 @@ func TestE2E_LoadFromFile_StartupRecovery(t *testing.T) {
 	}
 
 	// Step 2: Trigger a new update
-	triggerReq := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	triggerReq := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	triggerRec := httptest.NewRecorder()
 	triggerHandler.Handle(triggerRec, triggerReq)
 
@@ -453,7 +453,7 @@ func TestE2E_Notification_StartNotification(t *testing.T) {
 	recordingNotif := &recordingNotifier{}
 	handler := newTestHandler(logger, ul, mock, recordingNotif)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -514,7 +514,7 @@ func TestE2E_Notification_CompletionNotification(t *testing.T) {
 	recordingNotif := &recordingNotifier{}
 	handler := newTestHandler(logger, ul, mock, recordingNotif)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -581,7 +581,7 @@ func TestE2E_Notification_NonBlocking(t *testing.T) {
 	recordingNotif := &recordingNotifier{shouldError: true}
 	handler := newTestHandler(logger, ul, mock, recordingNotif)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -647,7 +647,7 @@ func TestE2E_Notification_GracefulDegradation(t *testing.T) {
 	// Pass nil notifier directly
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
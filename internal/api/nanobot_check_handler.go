@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// nanobotCheckTimeout bounds uv, PyPI and git remote lookups for a single check request
+const nanobotCheckTimeout = 60 * time.Second
+
+// NanobotUpdateChecker compares the installed nanobot with upstream.
+// Satisfied by *instance.InstanceManager via duck typing.
+type NanobotUpdateChecker interface {
+	CheckUpdate(ctx context.Context, target updater.Target) (*updater.CheckResult, error)
+}
+
+// NanobotCheckHandler handles GET /api/v1/nanobot/check requests
+type NanobotCheckHandler struct {
+	checker NanobotUpdateChecker
+	logger  *slog.Logger
+}
+
+// NewNanobotCheckHandler creates a new NanobotCheckHandler
+func NewNanobotCheckHandler(checker NanobotUpdateChecker, logger *slog.Logger) *NanobotCheckHandler {
+	return &NanobotCheckHandler{
+		checker: checker,
+		logger:  logger.With("source", "api-nanobot-check"),
+	}
+}
+
+// Handle handles GET /api/v1/nanobot/check
+// Compares the installed version/commit with the latest PyPI release and the HEAD commit of the
+// configured git source. Optional ?ref=, ?commit= or ?pypi_version= check a specific target instead.
+// Nothing is installed or stopped.
+func (h *NanobotCheckHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	target := updater.Target{
+		Ref:         query.Get("ref"),
+		Commit:      query.Get("commit"),
+		PyPIVersion: query.Get("pypi_version"),
+	}
+	if err := target.Validate(); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), nanobotCheckTimeout)
+	defer cancel()
+
+	result, err := h.checker.CheckUpdate(ctx, target)
+	if err != nil {
+		if errors.Is(err, updater.ErrInvalidTarget) {
+			writeJSONError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		h.logger.Error("Failed to check nanobot version", "error", err, "target", target.String())
+		writeJSONError(w, http.StatusInternalServerError, "internal_error",
+			fmt.Sprintf("Failed to check nanobot version: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Error("Failed to encode response", "error", err)
+	}
+}
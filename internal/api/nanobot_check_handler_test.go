@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+type mockUpdateChecker struct {
+	result     *updater.CheckResult
+	err        error
+	lastTarget updater.Target
+	calls      int
+}
+
+func (m *mockUpdateChecker) CheckUpdate(ctx context.Context, target updater.Target) (*updater.CheckResult, error) {
+	m.lastTarget = target
+	m.calls++
+	return m.result, m.err
+}
+
+func TestNanobotCheckHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mock := &mockUpdateChecker{result: &updater.CheckResult{
+		Target:           "latest",
+		Source:           "github",
+		SourceType:       updater.SourceGit,
+		InstalledVersion: "0.1.4",
+		InstalledCommit:  "1111111111111111111111111111111111111111",
+		LatestVersion:    "0.1.4",
+		LatestCommit:     "2222222222222222222222222222222222222222",
+		UpdateAvailable:  true,
+		Reason:           "new commit 2222222 (installed 1111111)",
+	}}
+	handler := NewNanobotCheckHandler(mock, logger)
+
+	req := httptest.NewRequest("GET", "/api/v1/nanobot/check", nil)
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !mock.lastTarget.IsLatest() {
+		t.Errorf("target = %+v, want latest", mock.lastTarget)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	for _, key := range []string{"installed_version", "installed_commit", "latest_version", "latest_commit", "update_available", "reason", "source"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("response missing %q: %v", key, body)
+		}
+	}
+	if body["update_available"] != true {
+		t.Errorf("update_available = %v, want true", body["update_available"])
+	}
+}
+
+func TestNanobotCheckHandler_Target(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mock := &mockUpdateChecker{result: &updater.CheckResult{}}
+	handler := NewNanobotCheckHandler(mock, logger)
+
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("GET", "/api/v1/nanobot/check?pypi_version=0.1.3", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if mock.lastTarget != (updater.Target{PyPIVersion: "0.1.3"}) {
+		t.Errorf("target = %+v, want pypi_version 0.1.3", mock.lastTarget)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("GET", "/api/v1/nanobot/check?ref=bad%20ref", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid target status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if mock.calls != 1 {
+		t.Errorf("CheckUpdate called %d times, want 1", mock.calls)
+	}
+}
+
+func TestNanobotCheckHandler_Error(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mock := &mockUpdateChecker{err: errors.New("uv tool list failed: executable file not found")}
+	handler := NewNanobotCheckHandler(mock, logger)
+
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("GET", "/api/v1/nanobot/check", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Status code = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
@@ -58,7 +58,7 @@ func (h *NanobotConfigHandler) HandleGet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	configPath, err := nanobot.ParseConfigPath(ic.StartCommand, ic.Name)
+	configPath, err := nanobot.ParseConfigPath(ic.StartCommand, ic.Name, instanceDirs(*ic))
 	if err != nil {
 		h.logger.Error("Failed to parse nanobot config path", "instance", ic.Name, "error", err)
 		writeJSONError(w, http.StatusInternalServerError, "internal_error", "Failed to resolve nanobot config path")
@@ -71,7 +71,7 @@ func (h *NanobotConfigHandler) HandleGet(w http.ResponseWriter, r *http.Request)
 			// LAZY-CREATION FALLBACK: auto-create default nanobot config for known instance
 			h.logger.Warn("Nanobot config missing for known instance, auto-creating default config",
 				"instance", ic.Name, "path", configPath)
-			if createErr := h.manager.CreateDefaultConfig(ic.Name, ic.Port, ic.StartCommand); createErr != nil {
+			if createErr := h.manager.CreateDefaultConfig(ic.Name, ic.Port, ic.StartCommand, instanceDirs(*ic)); createErr != nil {
 				h.logger.Error("Failed to auto-create nanobot config", "instance", ic.Name, "error", createErr)
 				writeJSONError(w, http.StatusInternalServerError, "internal_error", "Failed to create nanobot config")
 				return
@@ -135,7 +135,7 @@ func (h *NanobotConfigHandler) HandlePut(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	configPath, err := nanobot.ParseConfigPath(ic.StartCommand, ic.Name)
+	configPath, err := nanobot.ParseConfigPath(ic.StartCommand, ic.Name, instanceDirs(*ic))
 	if err != nil {
 		h.logger.Error("Failed to parse nanobot config path", "instance", ic.Name, "error", err)
 		writeJSONError(w, http.StatusInternalServerError, "internal_error", "Failed to resolve nanobot config path")
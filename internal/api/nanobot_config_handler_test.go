@@ -82,6 +82,35 @@ func TestHandleGetNanobotConfig_Success(t *testing.T) {
 	assert.Equal(t, float64(18790), gateway["port"])
 }
 
+func TestHandleGetNanobotConfig_RelativeToWorkingDir(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := nanobot.NewConfigManager(logger)
+	workDir := t.TempDir()
+	require.NoError(t, manager.WriteConfig(filepath.Join(workDir, "conf", "config.json"), map[string]interface{}{
+		"gateway": map[string]interface{}{"port": float64(18790)},
+		"marker":  "working-dir",
+	}))
+
+	// 相对的 --config 相对于实例进程的工作目录, 而不是服务的工作目录
+	cfg := &config.Config{Instances: []config.InstanceConfig{
+		{Name: "rel", Port: 18790, StartCommand: "nanobot gateway --config conf/config.json", WorkingDir: workDir},
+	}}
+	handler := NewNanobotConfigHandler(manager, func() *config.Config { return cfg }, logger)
+	token := "test-token-123456789012345678901"
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/v1/instances/{name}/nanobot-config", withAuth(handler.HandleGet, token))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authenticatedRequest("GET", "/api/v1/instances/rel/nanobot-config", token, nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	configData, ok := response["config"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "working-dir", configData["marker"])
+}
+
 func TestHandleGetNanobotConfig_InstanceNotFound(t *testing.T) {
 	handler, token, _ := setupNanobotConfigTest(t)
 
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// nanobotRepoTimeout bounds the git commands of a single repo status request
+const nanobotRepoTimeout = 15 * time.Second
+
+// NanobotRepoReader reports the state of the local nanobot source repository (updater.repo_path).
+// Satisfied by *instance.InstanceManager via duck typing.
+type NanobotRepoReader interface {
+	RepoStatus(ctx context.Context) (*updater.RepoStatus, error)
+}
+
+// NanobotRepoHandler handles GET /api/v1/nanobot/repo requests
+type NanobotRepoHandler struct {
+	reader NanobotRepoReader
+	logger *slog.Logger
+}
+
+// NewNanobotRepoHandler creates a new NanobotRepoHandler
+func NewNanobotRepoHandler(reader NanobotRepoReader, logger *slog.Logger) *NanobotRepoHandler {
+	return &NanobotRepoHandler{
+		reader: reader,
+		logger: logger.With("source", "api-nanobot-repo"),
+	}
+}
+
+// Handle handles GET /api/v1/nanobot/repo
+// Returns the current branch, HEAD commit (hash and subject) and dirty state of updater.repo_path.
+// 404 when repo_path is not configured; the repository is synced after every successful install.
+func (h *NanobotRepoHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), nanobotRepoTimeout)
+	defer cancel()
+
+	status, err := h.reader.RepoStatus(ctx)
+	if err != nil {
+		if errors.Is(err, updater.ErrRepoNotConfigured) {
+			writeJSONError(w, http.StatusNotFound, "not_configured", err.Error())
+			return
+		}
+		h.logger.Error("Failed to read nanobot repo status", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal_error",
+			fmt.Sprintf("Failed to read nanobot repo status: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, status, h.logger)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+type mockRepoReader struct {
+	status *updater.RepoStatus
+	err    error
+}
+
+func (m *mockRepoReader) RepoStatus(ctx context.Context) (*updater.RepoStatus, error) {
+	return m.status, m.err
+}
+
+func TestNanobotRepoHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewNanobotRepoHandler(&mockRepoReader{status: &updater.RepoStatus{
+		Path:    "/opt/nanobot",
+		Branch:  "main",
+		Commit:  "2222222222222222222222222222222222222222",
+		Subject: "Release 0.2.0",
+		Dirty:   true,
+	}}, logger)
+
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("GET", "/api/v1/nanobot/repo", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	if body["branch"] != "main" || body["commit"] != "2222222222222222222222222222222222222222" || body["dirty"] != true {
+		t.Errorf("response = %v, want branch main, the commit and dirty", body)
+	}
+}
+
+func TestNanobotRepoHandler_Errors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+		code string
+	}{
+		{"not configured", updater.ErrRepoNotConfigured, http.StatusNotFound, "not_configured"},
+		{"git failure", errors.New("git status failed: exit status 128"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewNanobotRepoHandler(&mockRepoReader{err: tt.err}, logger)
+			rec := httptest.NewRecorder()
+			handler.Handle(rec, httptest.NewRequest("GET", "/api/v1/nanobot/repo", nil))
+
+			if rec.Code != tt.want {
+				t.Fatalf("Status code = %d, want %d", rec.Code, tt.want)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode response JSON: %v", err)
+			}
+			if body["error"] != tt.code {
+				t.Errorf("error = %q, want %q", body["error"], tt.code)
+			}
+		})
+	}
+}
@@ -2,10 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
 	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
 )
 
@@ -22,9 +25,15 @@ type PaginationMeta struct {
 	Limit  int `json:"limit"`
 }
 
+// LogBufferSource returns the LogBuffer of an instance (implemented by instance.InstanceManager)
+type LogBufferSource interface {
+	GetLogBuffer(instanceName string) (*logbuffer.LogBuffer, error)
+}
+
 // QueryHandler handles GET /api/v1/update-logs requests
 type QueryHandler struct {
 	updateLogger *updatelog.UpdateLogger
+	logBuffers   LogBufferSource // Instance logs for /update-logs/{id}/instances/{name}/logs (optional, see SetLogBuffers)
 	logger       *slog.Logger
 }
 
@@ -35,6 +44,11 @@ func NewQueryHandler(ul *updatelog.UpdateLogger, logger *slog.Logger) *QueryHand
 	}
 }
 
+// SetLogBuffers enables GET /api/v1/update-logs/{id}/instances/{name}/logs
+func (h *QueryHandler) SetLogBuffers(src LogBufferSource) {
+	h.logBuffers = src
+}
+
 func (h *QueryHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	// Validate method
 	if r.Method != http.MethodGet {
@@ -91,3 +105,107 @@ func (h *QueryHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		h.logger.Error("Failed to encode query response", "error", err)
 	}
 }
+
+// HandleOutput handles GET /api/v1/update-logs/{id}/output: the full uv/git command output
+// of an update as plain text (one "<timestamp> [stdout|stderr] <line>" per line)
+func (h *QueryHandler) HandleOutput(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET method is supported")
+		return
+	}
+	if h.updateLogger == nil {
+		h.logger.Error("UpdateLogger is nil")
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", "Update logger not available")
+		return
+	}
+
+	id := r.PathValue("id")
+	output, err := h.updateLogger.GetOutput(id)
+	if errors.Is(err, updatelog.ErrOutputNotFound) {
+		writeJSONError(w, http.StatusNotFound, "not_found", "No output recorded for update "+id)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to read update output", "error", err, "update_id", id)
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(output)); err != nil {
+		h.logger.Error("Failed to write update output", "error", err, "update_id", id)
+	}
+}
+
+// InstanceLogLine is one instance log line in the response of
+// GET /api/v1/update-logs/{id}/instances/{name}/logs
+type InstanceLogLine struct {
+	Seq       int64     `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"` // stdout/stderr
+	Content   string    `json:"content"`
+}
+
+// InstanceLogsResponse is the JSON response of GET /api/v1/update-logs/{id}/instances/{name}/logs
+type InstanceLogsResponse struct {
+	UpdateID      string            `json:"update_id"`
+	Instance      string            `json:"instance"`
+	LogStartIndex int               `json:"log_start_index"`
+	LogEndIndex   int               `json:"log_end_index"`
+	Truncated     bool              `json:"truncated"` // Some lines were already overwritten in the instance LogBuffer (5000 lines)
+	Lines         []InstanceLogLine `json:"lines"`
+}
+
+// HandleInstanceLogs handles GET /api/v1/update-logs/{id}/instances/{name}/logs: the log lines
+// an instance produced while it was started during that update. Instance logs are kept in
+// memory only, so the lines are gone (410) once the updater or the instance LogBuffer was recreated.
+func (h *QueryHandler) HandleInstanceLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET method is supported")
+		return
+	}
+	if h.updateLogger == nil || h.logBuffers == nil {
+		h.logger.Error("UpdateLogger or instance log buffers not available")
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", "Update logger not available")
+		return
+	}
+
+	id, name := r.PathValue("id"), r.PathValue("name")
+	record, ok := h.updateLogger.Get(id)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Update log not found: "+id)
+		return
+	}
+	var detail *updatelog.InstanceUpdateDetail
+	for i := range record.Instances {
+		if record.Instances[i].Name == name {
+			detail = &record.Instances[i]
+			break
+		}
+	}
+	if detail == nil || detail.LogBufferID == "" {
+		writeJSONError(w, http.StatusNotFound, "not_found", "No instance logs recorded for "+name+" in update "+id)
+		return
+	}
+
+	buf, err := h.logBuffers.GetLogBuffer(name)
+	if err != nil || buf.ID() != detail.LogBufferID {
+		writeJSONError(w, http.StatusGone, "gone", "Instance logs of update "+id+" are no longer available")
+		return
+	}
+
+	entries, complete := buf.Range(int64(detail.LogStartIndex), int64(detail.LogEndIndex))
+	resp := InstanceLogsResponse{
+		UpdateID:      id,
+		Instance:      name,
+		LogStartIndex: detail.LogStartIndex,
+		LogEndIndex:   detail.LogEndIndex,
+		Truncated:     !complete,
+		Lines:         make([]InstanceLogLine, 0, len(entries)),
+	}
+	for _, e := range entries {
+		resp.Lines = append(resp.Lines, InstanceLogLine{Seq: e.Seq, Timestamp: e.Timestamp, Source: e.Source, Content: e.Content})
+	}
+	writeJSON(w, http.StatusOK, resp, h.logger)
+}
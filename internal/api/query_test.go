@@ -2,14 +2,18 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
 	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
 )
 
@@ -420,3 +424,102 @@ func TestQueryHandler_NewestFirst(t *testing.T) {
 		t.Errorf("Expected data[2].ID='oldest-uuid', got '%s'", response.Data[2].ID)
 	}
 }
+
+// GET /api/v1/update-logs/{id}/output returns the recorded uv/git output as plain text
+func TestQueryHandler_Output(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ul := updatelog.NewUpdateLogger(logger, filepath.Join(t.TempDir(), "updates.jsonl"))
+	output := "2026-03-10T02:30:00.000Z [stdout] $ uv tool install --force nanobot-ai\n" +
+		"2026-03-10T02:30:01.000Z [stderr] error: No solution found when resolving dependencies\n"
+	if err := ul.RecordOutput("3f2a", output); err != nil {
+		t.Fatalf("RecordOutput failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/update-logs/{id}/output", NewQueryHandler(ul, logger).HandleOutput)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/update-logs/3f2a/output", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Body.String() != output {
+		t.Errorf("body = %q, want recorded output", rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/update-logs/unknown/output", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown id: status code = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// fakeLogBuffers serves fixed LogBuffers by instance name
+type fakeLogBuffers map[string]*logbuffer.LogBuffer
+
+func (f fakeLogBuffers) GetLogBuffer(name string) (*logbuffer.LogBuffer, error) {
+	buf, ok := f[name]
+	if !ok {
+		return nil, fmt.Errorf("instance %s not found", name)
+	}
+	return buf, nil
+}
+
+func TestQueryHandler_InstanceLogs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	buf := logbuffer.NewLogBuffer(logger)
+	buf.Write(logbuffer.LogEntry{Timestamp: time.Now(), Source: "stdout", Content: "before update"})
+	from := buf.NextSeq()
+	buf.Write(logbuffer.LogEntry{Timestamp: time.Now(), Source: "stdout", Content: "gateway started"})
+	buf.Write(logbuffer.LogEntry{Timestamp: time.Now(), Source: "stderr", Content: "warning: slow start"})
+	to := buf.NextSeq()
+	buf.Write(logbuffer.LogEntry{Timestamp: time.Now(), Source: "stdout", Content: "after update"})
+
+	ul := updatelog.NewUpdateLogger(logger, "")
+	if err := ul.Record(updatelog.UpdateLog{
+		ID: "update-1", Status: updatelog.StatusSuccess, TriggeredBy: "api-trigger",
+		Instances: []updatelog.InstanceUpdateDetail{
+			{Name: "gateway", Status: "success", LogBufferID: buf.ID(), LogStartIndex: int(from), LogEndIndex: int(to)},
+			{Name: "restarted", Status: "success", LogBufferID: "old-buffer", LogStartIndex: 0, LogEndIndex: 3},
+		},
+	}); err != nil {
+		t.Fatalf("Record() error: %v", err)
+	}
+
+	handler := newTestQueryHandler(ul)
+	handler.SetLogBuffers(fakeLogBuffers{"gateway": buf, "restarted": logbuffer.NewLogBuffer(logger)})
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/update-logs/{id}/instances/{name}/logs", handler.HandleInstanceLogs)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/api/v1/update-logs/update-1/instances/gateway/logs")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp InstanceLogsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	if resp.Truncated || len(resp.Lines) != 2 || resp.Lines[0].Content != "gateway started" || resp.Lines[1].Source != "stderr" {
+		t.Errorf("response = %+v, want exactly the 2 lines written during the update", resp)
+	}
+
+	// LogBuffer recreated since the update (e.g. updater restarted)
+	if rec := get("/api/v1/update-logs/update-1/instances/restarted/logs"); rec.Code != http.StatusGone {
+		t.Errorf("recreated buffer: status = %d, want %d", rec.Code, http.StatusGone)
+	}
+	if rec := get("/api/v1/update-logs/update-1/instances/unknown/logs"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown instance: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := get("/api/v1/update-logs/missing/instances/gateway/logs"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown update: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -11,9 +11,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/HQGroup/nanobot-auto-updater/internal/lifecycle"
 	"github.com/HQGroup/nanobot-auto-updater/internal/selfupdate"
-	"golang.org/x/sys/windows"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
 )
 
 // SelfUpdateChecker is the interface for checking and executing self-updates.
@@ -58,8 +60,9 @@ type SelfUpdateHandler struct {
 	updater         SelfUpdateChecker
 	version         string
 	instanceManager UpdateMutex
-	status          atomic.Value // stores *SelfUpdateStatus
-	notifier        Notifier     // SAFE-02: Pushover notification sender
+	status          atomic.Value            // stores *SelfUpdateStatus
+	notifier        Notifier                // SAFE-02: Pushover notification sender
+	updateLogger    *updatelog.UpdateLogger // Records every self-update attempt (optional, see SetUpdateLogger)
 	logger          *slog.Logger
 	// restartFn is called after successful update to restart the process.
 	// In production this spawns a new process and calls os.Exit(0).
@@ -81,6 +84,23 @@ func NewSelfUpdateHandler(updater SelfUpdateChecker, version string, im UpdateMu
 	return h
 }
 
+// SetUpdateLogger records every self-update attempt (including rejections and failures)
+// in the update log with operation "self-update".
+func (h *SelfUpdateHandler) SetUpdateLogger(ul *updatelog.UpdateLogger) {
+	h.updateLogger = ul
+}
+
+// record persists a self-update log record; failures are only logged
+func (h *SelfUpdateHandler) record(log updatelog.UpdateLog) {
+	if h.updateLogger == nil {
+		return
+	}
+	log.PreviousVersion = h.version
+	if err := h.updateLogger.Record(log); err != nil {
+		h.logger.Error("Failed to record self-update log", "error", err, "update_id", log.ID)
+	}
+}
+
 // defaultRestartFn is the production restart implementation.
 // Service mode (D-01): exits with code 1 to trigger SCM recovery policy auto-restart.
 // Console mode (D-02): spawns a new process and exits with code 0 (self-spawn).
@@ -95,10 +115,7 @@ func defaultRestartFn(exePath string) {
 
 	// D-02: Console mode — original self-spawn behavior (unchanged)
 	cmd := exec.Command(exePath, os.Args[1:]...)
-	cmd.SysProcAttr = &windows.SysProcAttr{
-		HideWindow:    true,
-		CreationFlags: windows.CREATE_NO_WINDOW | windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS,
-	}
+	lifecycle.SetDetached(cmd)
 	// Log before cmd.Start since we won't be around after os.Exit
 	slog.Info("self-spawn restart initiated", "exe", exePath)
 	if err := cmd.Start(); err != nil {
@@ -151,9 +168,14 @@ func (h *SelfUpdateHandler) HandleCheck(w http.ResponseWriter, r *http.Request)
 // HandleUpdate handles POST /api/v1/self-update requests.
 // Executes self-update asynchronously, returns 202 Accepted (D-01, D-04).
 func (h *SelfUpdateHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	updateID := uuid.New().String()
+	startTime := time.Now().UTC()
+
 	// Try to acquire lock first (D-02: shared with trigger-update)
 	if !h.instanceManager.TryLockUpdate() {
-		h.logger.Warn("Self-update rejected: update already in progress")
+		h.logger.Warn("Self-update rejected: update already in progress", "update_id", updateID)
+		h.record(updatelog.BuildRejectedLog(updateID, updatelog.OperationSelfUpdate, "api-self-update",
+			startTime, "update already in progress", nil))
 		writeJSONError(w, http.StatusConflict, "conflict",
 			"An update is already in progress. Please try again later.")
 		return
@@ -167,8 +189,9 @@ func (h *SelfUpdateHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusAccepted)
 
 	response := map[string]string{
-		"status":  "accepted",
-		"message": "Self-update started",
+		"status":    "accepted",
+		"message":   "Self-update started",
+		"update_id": updateID, // Attempt is recorded in /api/v1/update-logs under this id
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		h.logger.Error("Failed to encode update response", "error", err)
@@ -206,6 +229,8 @@ func (h *SelfUpdateHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 					Status: "failed",
 					Error:  fmt.Sprintf("panic: %v", r),
 				})
+				h.record(updatelog.BuildOperationLog(updateID, updatelog.OperationSelfUpdate, "api-self-update",
+					startTime, time.Now().UTC(), nil, fmt.Errorf("panic: %v", r)))
 				// Send failure notification for panic (D-03)
 				if h.notifier != nil {
 					title := "Nanobot 自更新失败"
@@ -235,6 +260,8 @@ func (h *SelfUpdateHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 				Status: "failed",
 				Error:  err.Error(),
 			})
+			h.record(updatelog.BuildOperationLog(updateID, updatelog.OperationSelfUpdate, "api-self-update",
+				startTime, time.Now().UTC(), nil, err))
 			// Send failure notification (D-03)
 			if h.notifier != nil {
 				title := "Nanobot 自更新失败"
@@ -269,6 +296,12 @@ func (h *SelfUpdateHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 			targetVersion = "unknown"
 		}
 
+		// Record before restarting: the process exits in restartFn
+		successLog := updatelog.BuildOperationLog(updateID, updatelog.OperationSelfUpdate, "api-self-update",
+			startTime, time.Now().UTC(), nil, nil)
+		successLog.Target = targetVersion
+		h.record(successLog)
+
 		// Write update success marker (D-04)
 		exePath, _ := os.Executable()
 		if exePath != "" {
@@ -14,6 +14,7 @@ import (
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
 	"github.com/HQGroup/nanobot-auto-updater/internal/selfupdate"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
 )
 
 // mockSelfUpdateChecker is a mock implementation of SelfUpdateChecker for testing.
@@ -632,3 +633,49 @@ func TestSelfUpdateCheck_ProgressIdle(t *testing.T) {
 		t.Errorf("progress.download_percent = %d, want %d", response.Progress.DownloadPercent, 0)
 	}
 }
+
+// TestSelfUpdateUpdate_RecordsAttempts verifies rejected and failed self-updates are
+// recorded in the update log with operation "self-update"
+func TestSelfUpdateUpdate_RecordsAttempts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+	mutex := &mockUpdateMutex{}
+	handler := newTestSelfUpdateHandler(&mockSelfUpdateChecker{updateErr: errors.New("download failed")}, mutex, nil)
+	handler.SetUpdateLogger(ul)
+
+	// Rejected: lock held
+	mutex.TryLockUpdate()
+	rec := httptest.NewRecorder()
+	handler.HandleUpdate(rec, httptest.NewRequest("POST", "/api/v1/self-update", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	mutex.UnlockUpdate()
+
+	// Failed: download error
+	rec = httptest.NewRecorder()
+	handler.HandleUpdate(rec, httptest.NewRequest("POST", "/api/v1/self-update", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	var response map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil || response["update_id"] == "" {
+		t.Fatalf("response = %v (%v), want an update_id", response, err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(ul.GetAll()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	logs := ul.GetAll()
+	if len(logs) != 2 {
+		t.Fatalf("Expected 2 recorded logs, got %d", len(logs))
+	}
+	if logs[0].Operation != updatelog.OperationSelfUpdate || logs[0].Status != updatelog.StatusRejected {
+		t.Errorf("first log = %+v, want a rejected self-update", logs[0])
+	}
+	if logs[1].ID != response["update_id"] || logs[1].Status != updatelog.StatusFailed ||
+		logs[1].Error != "download failed" || logs[1].TriggeredBy != "api-self-update" || logs[1].PreviousVersion != "dev" {
+		t.Errorf("second log = %+v, want the failed self-update with its error", logs[1])
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/HQGroup/nanobot-auto-updater/internal/approval"
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
 	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
 	"github.com/HQGroup/nanobot-auto-updater/internal/lifecycle"
@@ -21,6 +22,7 @@ import (
 type Server struct {
 	httpServer *http.Server
 	logger     *slog.Logger
+	approvals  *approval.Store
 }
 
 // NewServer creates a new HTTP API server
@@ -67,7 +69,7 @@ func NewServer(cfg *config.APIConfig, im *instance.InstanceManager, fullCfg *con
 	mux.HandleFunc("GET /api/v1/version", web.NewVersionHandler(version, logger))
 
 	// Instance restart API (Quick task 260325-ovr: Task 1)
-	mux.HandleFunc("POST /api/v1/instances/{name}/restart", web.NewInstanceRestartHandler(im, logger))
+	mux.HandleFunc("POST /api/v1/instances/{name}/restart", web.NewInstanceRestartHandler(im, updateLogger, logger))
 
 	// Home page endpoints (Quick task 260320-k8z: Task 2)
 	mux.HandleFunc("GET /", web.NewHomePageHandler(im, logger))
@@ -88,10 +90,75 @@ func NewServer(cfg *config.APIConfig, im *instance.InstanceManager, fullCfg *con
 	mux.Handle("POST /api/v1/trigger-update",
 		authMiddleware(http.HandlerFunc(triggerHandler.Handle)))
 
+	// Offline update from an uploaded wheel/sdist (staged in updater.upload_dir)
+	if fullCfg != nil {
+		triggerHandler.SetUploadDir(fullCfg.Updater.UploadDir)
+	}
+	mux.Handle("POST /api/v1/trigger-update/upload",
+		authMiddleware(http.HandlerFunc(triggerHandler.HandleUpload)))
+
+	// Background update jobs: progress polling and cancellation between phases
+	mux.Handle("GET /api/v1/updates/{id}",
+		authMiddleware(http.HandlerFunc(triggerHandler.HandleJob)))
+	mux.Handle("DELETE /api/v1/updates/{id}",
+		authMiddleware(http.HandlerFunc(triggerHandler.HandleCancel)))
+	mux.Handle("GET /api/v1/updates/{id}/events",
+		authMiddleware(http.HandlerFunc(triggerHandler.HandleJobEvents)))
+	mux.Handle("GET /api/v1/update/progress",
+		authMiddleware(http.HandlerFunc(triggerHandler.HandleProgress)))
+
+	// Update queue: requests waiting behind the running update, coalesced by target
+	updateQueueHandler := NewUpdateQueueHandler(im, logger)
+	mux.Handle("GET /api/v1/updates/queue",
+		authMiddleware(http.HandlerFunc(updateQueueHandler.Handle)))
+	mux.Handle("DELETE /api/v1/updates/queue/{id}",
+		authMiddleware(http.HandlerFunc(updateQueueHandler.HandleDrop)))
+
+	// Update approval (approval config): trigger-update and the scheduler create pending updates.
+	// Approve/reject accept the Bearer token or the link token of the Pushover notification,
+	// so they are not wrapped in authMiddleware; the link opens a confirmation page
+	var approvalCfg config.ApprovalConfig
+	if fullCfg != nil {
+		approvalCfg = fullCfg.Approval
+	}
+	var approvalRecorder approval.Recorder
+	if updateLogger != nil {
+		approvalRecorder = updateLogger
+	}
+	approvals := approval.NewStore(approvalCfg, approvalRecorder, notif, logger)
+	triggerHandler.SetApprovals(approvals)
+	approvalHandler := NewApprovalHandler(approvals, triggerHandler, getToken, logger)
+	mux.Handle("GET /api/v1/approvals",
+		authMiddleware(http.HandlerFunc(approvalHandler.HandleList)))
+	mux.HandleFunc("POST /api/v1/updates/{id}/approve", approvalHandler.HandleApprove)
+	mux.HandleFunc("POST /api/v1/updates/{id}/reject", approvalHandler.HandleReject)
+	mux.HandleFunc("GET /api/v1/updates/{id}/approve", approvalHandler.HandlePage)
+
+	// Update plan (dry run) endpoint with auth: what trigger-update would stop and install
+	mux.Handle("GET /api/v1/update/plan",
+		authMiddleware(http.HandlerFunc(triggerHandler.HandlePlan)))
+
+	// Nanobot update check endpoint with auth: compares installed nanobot with PyPI / git HEAD
+	nanobotCheckHandler := NewNanobotCheckHandler(im, logger)
+	mux.Handle("GET /api/v1/nanobot/check",
+		authMiddleware(http.HandlerFunc(nanobotCheckHandler.Handle)))
+
+	// Local nanobot source repo (updater.repo_path): branch, HEAD commit, dirty state
+	nanobotRepoHandler := NewNanobotRepoHandler(im, logger)
+	mux.Handle("GET /api/v1/nanobot/repo",
+		authMiddleware(http.HandlerFunc(nanobotRepoHandler.Handle)))
+
 	// Query update logs endpoint with auth (Phase 32: QUERY-01, QUERY-02)
 	queryHandler := NewQueryHandler(updateLogger, logger)
 	mux.Handle("GET /api/v1/update-logs",
 		authMiddleware(http.HandlerFunc(queryHandler.Handle)))
+	mux.Handle("GET /api/v1/update-logs/{id}/output",
+		authMiddleware(http.HandlerFunc(queryHandler.HandleOutput)))
+	if im != nil {
+		queryHandler.SetLogBuffers(im)
+	}
+	mux.Handle("GET /api/v1/update-logs/{id}/instances/{name}/logs",
+		authMiddleware(http.HandlerFunc(queryHandler.HandleInstanceLogs)))
 
 	// Web-config endpoint (Phase 44: API-02) -- localhost-only, no auth required
 	webConfigHandler := NewWebConfigHandler(cfg.BearerToken, logger)
@@ -100,6 +167,7 @@ func NewServer(cfg *config.APIConfig, im *instance.InstanceManager, fullCfg *con
 	// Self-update endpoints (Phase 39: API-01, API-02, API-03)
 	if selfUpdater != nil {
 		selfUpdateHandler := NewSelfUpdateHandler(selfUpdater, version, im, notif, logger)
+		selfUpdateHandler.SetUpdateLogger(updateLogger)
 		mux.Handle("GET /api/v1/self-update/check",
 			authMiddleware(http.HandlerFunc(selfUpdateHandler.HandleCheck)))
 		mux.Handle("POST /api/v1/self-update",
@@ -114,10 +182,8 @@ func NewServer(cfg *config.APIConfig, im *instance.InstanceManager, fullCfg *con
 			if err != nil {
 				return err
 			}
-			if !inst.IsRunning() {
-				return nil
-			}
-			return inst.StopForUpdate(ctx)
+			// Also cancels a pending crash restart of a stopped instance
+			return inst.StopForUpdate(instance.WithInitiator(ctx, instance.InitiatorAPI))
 		})
 	mux.Handle("GET /api/v1/instance-configs", authMiddleware(http.HandlerFunc(instanceConfigHandler.HandleList)))
 	mux.Handle("POST /api/v1/instance-configs", authMiddleware(http.HandlerFunc(instanceConfigHandler.HandleCreate)))
@@ -128,6 +194,7 @@ func NewServer(cfg *config.APIConfig, im *instance.InstanceManager, fullCfg *con
 
 	// Instance lifecycle control endpoints (Phase 51: LC-01, LC-02, LC-03)
 	lifecycleHandler := NewInstanceLifecycleHandler(im, logger)
+	lifecycleHandler.SetUpdateLogger(updateLogger)
 	mux.Handle("POST /api/v1/instances/{name}/start",
 		authMiddleware(http.HandlerFunc(lifecycleHandler.HandleStart)))
 	mux.Handle("POST /api/v1/instances/{name}/stop",
@@ -142,17 +209,17 @@ func NewServer(cfg *config.APIConfig, im *instance.InstanceManager, fullCfg *con
 		authMiddleware(http.HandlerFunc(nanobotConfigHandler.HandlePut)))
 
 	// Phase 52: Wire nanobot config creation into instance create/copy/delete flows (D-09)
-	instanceConfigHandler.SetOnCreateInstance(func(name string, port uint32, startCommand string) error {
-		return nanobotConfigManager.CreateDefaultConfig(name, port, startCommand)
+	instanceConfigHandler.SetOnCreateInstance(func(name string, port uint32, startCommand string, dirs nanobot.InstanceDirs) error {
+		return nanobotConfigManager.CreateDefaultConfig(name, port, startCommand, dirs)
 	})
-	instanceConfigHandler.SetOnCopyInstance(func(sourceName string, sourceStartCommand string, targetName string, targetPort uint32, targetStartCommand string) error {
-		return nanobotConfigManager.CloneConfig(sourceStartCommand, sourceName, targetName, targetPort, targetStartCommand)
+	instanceConfigHandler.SetOnCopyInstance(func(sourceName, sourceStartCommand string, sourceDirs nanobot.InstanceDirs, targetName string, targetPort uint32, targetStartCommand string, targetDirs nanobot.InstanceDirs) error {
+		return nanobotConfigManager.CloneConfig(sourceStartCommand, sourceName, sourceDirs, targetName, targetPort, targetStartCommand, targetDirs)
 	})
-	instanceConfigHandler.SetOnDeleteInstance(func(name string, startCommand string) error {
-		return nanobotConfigManager.CleanupConfig(startCommand, name)
+	instanceConfigHandler.SetOnDeleteInstance(func(name, startCommand string, dirs nanobot.InstanceDirs) error {
+		return nanobotConfigManager.CleanupConfig(startCommand, name, dirs)
 	})
-	instanceConfigHandler.SetOnUpdateInstance(func(name string, oldPort uint32, oldStartCommand string, newPort uint32, newStartCommand string) error {
-		return nanobotConfigManager.UpdateInstanceConfig(name, oldPort, oldStartCommand, newPort, newStartCommand)
+	instanceConfigHandler.SetOnUpdateInstance(func(name string, oldPort uint32, oldStartCommand string, oldDirs nanobot.InstanceDirs, newPort uint32, newStartCommand string, newDirs nanobot.InstanceDirs) error {
+		return nanobotConfigManager.UpdateInstanceConfig(name, oldPort, oldStartCommand, oldDirs, newPort, newStartCommand, newDirs)
 	})
 
 	// Create HTTP server
@@ -169,9 +236,16 @@ func NewServer(cfg *config.APIConfig, im *instance.InstanceManager, fullCfg *con
 	return &Server{
 		httpServer: httpServer,
 		logger:     logger,
+		approvals:  approvals,
 	}, nil
 }
 
+// Approvals returns the approval store, shared with the update scheduler so scheduled updates
+// wait for approval too (approval.enabled)
+func (s *Server) Approvals() *approval.Store {
+	return s.approvals
+}
+
 // Start starts the HTTP server with port binding retry (D-05).
 func (s *Server) Start() error {
 	s.logger.Info("HTTP server starting", "addr", s.httpServer.Addr)
@@ -5,23 +5,33 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/HQGroup/nanobot-auto-updater/internal/approval"
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
 	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
 	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
 )
 
 // TriggerUpdater is the interface for triggering instance updates.
 // Introduced to allow mock testing without real UV update calls.
+// Plan previews an update (dry run) without stopping or installing anything.
+// EnqueueUpdate admits an update request or rejects it with instance.ErrUpdateQueueFull; the returned
+// run waits in the update queue and then updates (see instance.InstanceManager.EnqueueUpdate).
+// QueueFull reports whether a new update request would currently be rejected.
 type TriggerUpdater interface {
-	TriggerUpdate(ctx context.Context) (*instance.UpdateResult, error)
+	QueueFull() bool
+	EnqueueUpdate(ctx context.Context, target updater.Target, force bool) (func(context.Context) (*instance.UpdateResult, error), error)
+	Plan(ctx context.Context, target updater.Target, force bool) (*instance.UpdateResult, error)
 }
 
 // Notifier is the interface for sending update notifications.
@@ -40,6 +50,9 @@ type TriggerHandler struct {
 	updateLogger    *updatelog.UpdateLogger // LOG-01, LOG-02: Update log recorder
 	notifier        Notifier                // UNOTIF-01, UNOTIF-02: Pushover notification sender
 	instanceCount   int                     // UNOTIF-01: instance count for start notification
+	jobs            *updateJobs             // Background update jobs by update_id
+	uploadDir       string                  // Staging directory of uploaded wheels (updater.upload_dir)
+	approvals       *approval.Store         // approval.enabled: updates wait for approval (nil = run directly)
 }
 
 // NewTriggerHandler creates a new trigger update handler
@@ -51,6 +64,16 @@ func NewTriggerHandler(im TriggerUpdater, cfg *config.APIConfig, logger *slog.Lo
 		updateLogger:    ul,
 		notifier:        notif,
 		instanceCount:   instanceCount,
+		jobs:            newUpdateJobs(),
+		uploadDir:       config.DefaultUploadDir,
+	}
+}
+
+// SetUploadDir sets the staging directory for POST /api/v1/trigger-update/upload.
+// An empty dir keeps the default (config.DefaultUploadDir).
+func (h *TriggerHandler) SetUploadDir(dir string) {
+	if dir != "" {
+		h.uploadDir = dir
 	}
 }
 
@@ -60,6 +83,15 @@ func NewTriggerHandler(im TriggerUpdater, cfg *config.APIConfig, logger *slog.Lo
 // API-04: Returns JSON formatted result
 // LOG-01: Records update log with timing and status
 // LOG-02: Generates UUID v4 and returns update_id
+// Optional JSON body selects the target version: {"ref":"v0.1.4"}, {"commit":"abc1234"}
+// or {"pypi_version":"0.1.3"}. An empty body installs the latest version.
+// Without {"force":true} (or ?force=true) the update is skipped when nanobot is already current.
+// With ?dry_run=true (or {"dry_run":true}) only the update plan is returned, see HandlePlan.
+// The update runs as a background job (see runJob) and 202 is returned immediately with the
+// update_id; progress is polled at GET /api/v1/updates/{id}. With ?wait=true the handler waits
+// up to api.timeout and returns the result like before (200/409/504/500); the job keeps running
+// when the wait times out or the client disconnects.
+// With approval.enabled a pending update is created instead (202, see requestApproval).
 func (h *TriggerHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	// 1. Validate method
 	if r.Method != http.MethodPost {
@@ -67,17 +99,148 @@ func (h *TriggerHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Generate UUID v4 and record start time (LOG-02)
+	// Parse optional update target before any notification or instance stop
+	req, err := parseUpdateRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if req.DryRun {
+		h.writePlan(w, r, req)
+		return
+	}
+	if h.approvals.Enabled() {
+		h.requestApproval(w, r, req)
+		return
+	}
+
+	// 2. Generate UUID v4 (LOG-02)
 	updateID := uuid.New().String()
+	// Also sent on error responses, so the output of a failed update can be fetched from
+	// GET /api/v1/update-logs/{id}/output and a rejection found in /api/v1/update-logs
+	w.Header().Set("X-Update-ID", updateID)
+
+	h.dispatch(w, r, updateID, req, nil)
+}
+
+// dispatch starts the background update job for req and writes the 202 response, or with
+// req.Wait the result. done (optional) runs once the job has finished or was rejected.
+func (h *TriggerHandler) dispatch(w http.ResponseWriter, r *http.Request, updateID string, req updateRequest, done func()) {
+	job := h.startJob(updateID, req, done)
+	if job == nil {
+		writeQueueFull(w)
+		return
+	}
+
+	if !req.Wait {
+		statusURL := "/api/v1/updates/" + updateID
+		w.Header().Set("Location", statusURL)
+		writeJSON(w, http.StatusAccepted, updateAcceptedResponse{
+			UpdateID:  updateID,
+			Phase:     instance.JobQueued,
+			StatusURL: statusURL,
+		}, h.logger)
+		return
+	}
+
+	// 4. ?wait=true: wait for the job like the synchronous API did
+	timer := time.NewTimer(h.config.Timeout)
+	defer timer.Stop()
+	select {
+	case <-job.Done():
+	case <-timer.C:
+		h.logger.Warn("Update still running after wait timeout", "timeout", h.config.Timeout, "update_id", updateID)
+		writeJSONError(w, http.StatusGatewayTimeout, "timeout",
+			fmt.Sprintf("Update operation timed out after %v, still running: poll /api/v1/updates/%s", h.config.Timeout, updateID))
+		return
+	case <-r.Context().Done():
+		h.logger.Warn("Client disconnected, update continues in background", "update_id", updateID)
+		return
+	}
+
+	snap := job.Snapshot()
+	if snap.Err != nil {
+		if errors.Is(snap.Err, instance.ErrUpdateInProgress) {
+			writeJSONError(w, http.StatusConflict, "conflict", "Update already in progress")
+			return
+		}
+		if errors.Is(snap.Err, context.DeadlineExceeded) {
+			writeJSONError(w, http.StatusGatewayTimeout, "timeout",
+				fmt.Sprintf("Update operation timed out after %v", updateJobTimeout))
+			return
+		}
+		// Other errors (e.g., UV update failed)
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", snap.Err.Error())
+		return
+	}
+
+	// 5. Return JSON result with update_id
+	writeJSON(w, http.StatusOK, buildAPIUpdateResult(updateID, snap.Result), h.logger)
+}
+
+// startJob starts the background update job for req. Returns nil when the update queue is full;
+// the rejection is recorded. done (optional) runs once the job has finished or was rejected.
+func (h *TriggerHandler) startJob(updateID string, req updateRequest, done func()) *instance.UpdateJob {
+	// Admit the request before the job is registered, so a full update queue never leaves a failed
+	// job behind. The queue decides under its own lock, concurrent requests cannot both take the
+	// last slot. During a running update the job stays queued until its turn
+	job := instance.NewUpdateJob(updateID)
+	run, err := h.instanceManager.EnqueueUpdate(instance.WithJob(context.Background(), job), req.Target, req.Force)
+	if err != nil {
+		if done != nil {
+			done()
+		}
+		h.recordBusy(updateID, req.source())
+		return nil
+	}
+	h.jobs.start(job)
+	h.logger.Info("Update triggered", "update_id", updateID, "target", req.Target.String(), "force", req.Force, "wait", req.Wait, "triggered_by", req.source())
+
+	// 3. Run the update on a background context: the HTTP request may end long before uv does
+	go func() {
+		h.runJob(job, req, run)
+		if done != nil {
+			done()
+		}
+	}()
+	return job
+}
+
+// runJob executes the update for job with the run admitted by EnqueueUpdate: start notification,
+// update, output and update log recording, completion notification. The job is finished last,
+// so a client that sees it completed can already query the update log.
+func (h *TriggerHandler) runJob(job *instance.UpdateJob, req updateRequest, run func(context.Context) (*instance.UpdateResult, error)) {
+	updateID := job.ID
+	target := req.Target
+	triggeredBy := req.source()
+	var (
+		result *instance.UpdateResult
+		err    error
+	)
 	startTime := time.Now().UTC()
-	h.logger.Info("Update triggered", "update_id", updateID)
+	defer func() {
+		if r := recover(); r != nil {
+			h.logger.Error("更新任务 goroutine panic",
+				"panic", r,
+				"stack", string(debug.Stack()),
+				"update_id", updateID)
+			err = fmt.Errorf("update panicked: %v", r)
+			h.record(updatelog.BuildFailedUpdateLog(updateID, startTime, time.Now().UTC(), triggeredBy, nil, err))
+		}
+		job.Finish(result, err)
+	}()
 
 	// Send start notification (UNOTIF-01, D-04)
 	// Per D-07: async, non-blocking. Per D-06: Notifier.Notify() handles IsEnabled() internally.
+	// startSent is closed once the start notification has been handed to the notifier,
+	// so the completion notification can never overtake it
+	var startSent chan struct{}
 	if h.notifier != nil {
+		startSent = make(chan struct{})
 		title := "Nanobot 更新开始"
-		message := fmt.Sprintf("触发来源: api-trigger\n待更新实例数: %d", h.instanceCount)
+		message := fmt.Sprintf("触发来源: %s\n目标版本: %s\n待更新实例数: %d", triggeredBy, target.String(), h.instanceCount)
 		go func() {
+			defer close(startSent)
 			defer func() {
 				if r := recover(); r != nil {
 					h.logger.Error("开始通知 goroutine panic",
@@ -93,55 +256,24 @@ func (h *TriggerHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
-	// 3. Create context with timeout from config
-	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeout)
-	defer cancel()
+	// updateJobTimeout starts once the job leaves the update queue, a long queue wait doesn't eat into it
+	ctx := instance.WithUpdateTimeout(instance.WithJob(context.Background(), job), updateJobTimeout)
 
-	// 4. Execute update
-	result, err := h.instanceManager.TriggerUpdate(ctx)
+	// Execute update
+	result, err = run(ctx)
 	endTime := time.Now().UTC() // Record end time immediately after
 
-	// 5. Handle specific errors
+	// Persist the uv/git output and the UpdateLog record (LOG-01, LOG-03, LOG-04);
+	// failed attempts are recorded too (status failed/timeout/rejected with the error text)
+	log := updatelog.RecordUpdate(h.recorder(), h.logger, updateID, triggeredBy, startTime, endTime, result, err)
 	if err != nil {
-		if errors.Is(err, instance.ErrUpdateInProgress) {
-			h.logger.Warn("Update request rejected: update already in progress", "update_id", updateID)
-			writeJSONError(w, http.StatusConflict, "conflict", "Update already in progress")
-			return
-		}
-		if errors.Is(err, context.DeadlineExceeded) {
-			h.logger.Error("Update operation timed out", "timeout", h.config.Timeout, "update_id", updateID)
-			writeJSONError(w, http.StatusGatewayTimeout, "timeout",
-				fmt.Sprintf("Update operation timed out after %v", h.config.Timeout))
-			return
-		}
-		// Other errors (e.g., UV update failed)
-		h.logger.Error("Update operation failed", "error", err, "update_id", updateID)
-		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
 		return
 	}
 
-	// 6. Build and record UpdateLog (LOG-01, LOG-03, LOG-04)
-	// Non-blocking: log recording failure does not affect response
-	if h.updateLogger != nil {
-		updateLog := updatelog.UpdateLog{
-			ID:          updateID,
-			StartTime:   startTime,
-			EndTime:     endTime,
-			Duration:    endTime.Sub(startTime).Milliseconds(),
-			Status:      updatelog.DetermineStatus(result),
-			Instances:   updatelog.BuildInstanceDetails(result),
-			TriggeredBy: "api-trigger",
-		}
-		if recordErr := h.updateLogger.Record(updateLog); recordErr != nil {
-			h.logger.Error("Failed to record update log", "error", recordErr, "update_id", updateID)
-			// Don't return error to client - update operation itself was successful
-		}
-	}
-
 	// Send completion notification (UNOTIF-02, D-05)
 	// Per D-07: async, non-blocking. Per D-06: Notifier.Notify() handles IsEnabled() internally.
 	if h.notifier != nil {
-		status := updatelog.DetermineStatus(result)
+		status := log.Status
 		elapsed := endTime.Sub(startTime).Seconds()
 		go func() {
 			defer func() {
@@ -151,6 +283,7 @@ func (h *TriggerHandler) Handle(w http.ResponseWriter, r *http.Request) {
 						"stack", string(debug.Stack()))
 				}
 			}()
+			<-startSent
 			title := statusToTitle(status)
 			msg := formatCompletionMessage(result, status, elapsed)
 			if err := h.notifier.Notify(title, msg); err != nil {
@@ -161,11 +294,48 @@ func (h *TriggerHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
-	// 7. Return JSON result with update_id
-	h.logger.Info("Update completed", "success", !result.HasErrors(), "update_id", updateID)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	h.logger.Info("Update completed", "success", !result.HasErrors(), "cancelled", result.Cancelled, "update_id", updateID)
+}
+
+// rejectBusy records and answers an update request refused because the update queue is full
+func (h *TriggerHandler) rejectBusy(w http.ResponseWriter, updateID, triggeredBy string) {
+	h.recordBusy(updateID, triggeredBy)
+	writeQueueFull(w)
+}
 
+// writeQueueFull answers an update request refused because the update queue is full (429)
+func writeQueueFull(w http.ResponseWriter) {
+	writeJSONError(w, http.StatusTooManyRequests, "too_many_requests", "Update queue is full")
+}
+
+// recordBusy records an update request refused because the update queue is full
+func (h *TriggerHandler) recordBusy(updateID, triggeredBy string) {
+	h.logger.Warn("Update request rejected: update queue is full", "update_id", updateID)
+	h.record(updatelog.BuildRejectedLog(updateID, updatelog.OperationNanobotUpdate, triggeredBy,
+		time.Now().UTC(), instance.ErrUpdateQueueFull.Error(), nil))
+}
+
+// recorder returns the update log recorder, nil when none is configured
+func (h *TriggerHandler) recorder() updatelog.Recorder {
+	if h.updateLogger == nil {
+		return nil
+	}
+	return h.updateLogger
+}
+
+// record persists an update log record.
+// Non-blocking: log recording failure does not affect the update result
+func (h *TriggerHandler) record(log updatelog.UpdateLog) {
+	if h.updateLogger == nil {
+		return
+	}
+	if err := h.updateLogger.Record(log); err != nil {
+		h.logger.Error("Failed to record update log", "error", err, "update_id", log.ID)
+	}
+}
+
+// buildAPIUpdateResult converts an UpdateResult to the JSON response of trigger-update
+func buildAPIUpdateResult(updateID string, result *instance.UpdateResult) APIUpdateResult {
 	// Convert InstanceErrors to APIInstanceErrors for JSON serialization
 	stopFailed := make([]*APIInstanceError, len(result.StopFailed))
 	for i, err := range result.StopFailed {
@@ -177,17 +347,35 @@ func (h *TriggerHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		startFailed[i] = convertToAPIError(err)
 	}
 
-	response := APIUpdateResult{
-		UpdateID:    updateID, // LOG-02: Return UUID v4 in response
-		Success:     !result.HasErrors(),
-		Stopped:     result.Stopped,
-		Started:     result.Started,
-		StopFailed:  stopFailed,
-		StartFailed: startFailed,
-	}
-
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.logger.Error("Failed to encode response", "error", err, "update_id", updateID)
+	return APIUpdateResult{
+		UpdateID:        updateID, // LOG-02: Return UUID v4 in response
+		Success:         !result.HasErrors(),
+		Stopped:         result.Stopped,
+		Started:         result.Started,
+		StopFailed:      stopFailed,
+		StartFailed:     startFailed,
+		Target:          result.Target,
+		InstallSpec:     result.InstallSpec,
+		Source:          result.Source,
+		SourceType:      result.SourceType,
+		Skipped:         result.Skipped,
+		SkipReason:      result.SkipReason,
+		RolledBack:      result.RolledBack,
+		PreviousVersion: result.PreviousVersion,
+		RollbackError:   result.RollbackError,
+		Aborted:         result.Aborted,
+		AbortStage:      result.AbortStage,
+		AbortReason:     result.AbortReason,
+		HeldBack:        result.HeldBack,
+		Cancelled:       result.Cancelled,
+		Coalesced:       result.Coalesced,
+		Channels:        result.Channels,
+		Hooks:           result.Hooks,
+		Strategy:        result.Strategy,
+		StageDuration:   result.StageDuration,
+		Downtime:        result.MaxDowntime(),
+		Repo:            result.Repo,
+		RepoSyncError:   result.RepoSyncError,
 	}
 }
 
@@ -195,12 +383,92 @@ func (h *TriggerHandler) Handle(w http.ResponseWriter, r *http.Request) {
 // API-04: JSON format response
 // LOG-02: Includes update_id field
 type APIUpdateResult struct {
-	UpdateID    string                    `json:"update_id"`               // LOG-02: UUID v4 identifier
-	Success     bool                      `json:"success"`
-	Stopped     []string                  `json:"stopped,omitempty"`
-	Started     []string                  `json:"started,omitempty"`
-	StopFailed  []*APIInstanceError       `json:"stop_failed,omitempty"`
-	StartFailed []*APIInstanceError       `json:"start_failed,omitempty"`
+	UpdateID        string                    `json:"update_id"` // LOG-02: UUID v4 identifier
+	Success         bool                      `json:"success"`
+	Stopped         []string                  `json:"stopped,omitempty"`
+	Started         []string                  `json:"started,omitempty"`
+	StopFailed      []*APIInstanceError       `json:"stop_failed,omitempty"`
+	StartFailed     []*APIInstanceError       `json:"start_failed,omitempty"`
+	Target          string                    `json:"target,omitempty"`            // Requested target (latest, ref:..., commit:..., pypi:...)
+	InstallSpec     string                    `json:"install_spec,omitempty"`      // uv spec that was actually installed
+	Source          string                    `json:"source,omitempty"`            // updater source that succeeded
+	SourceType      string                    `json:"source_type,omitempty"`       // git, pypi, local or upload
+	Skipped         bool                      `json:"skipped,omitempty"`           // Already up to date, nothing was stopped or installed
+	SkipReason      string                    `json:"skip_reason,omitempty"`       // Why the update was skipped
+	RolledBack      bool                      `json:"rolled_back,omitempty"`       // New version failed to start, previous version reinstalled
+	PreviousVersion string                    `json:"previous_version,omitempty"`  // Version installed before the update
+	RollbackError   string                    `json:"rollback_error,omitempty"`    // Rollback attempted but failed
+	Aborted         bool                      `json:"aborted,omitempty"`           // A canary or pre_update hook failed, the update was aborted
+	AbortStage      string                    `json:"abort_stage,omitempty"`       // Stage at which the update was aborted ("canary", "pre_update")
+	AbortReason     string                    `json:"abort_reason,omitempty"`      // Why the update was aborted
+	HeldBack        []string                  `json:"held_back,omitempty"`         // Instances left stopped after the abort
+	Cancelled       bool                      `json:"cancelled,omitempty"`         // Cancelled between phases, nothing was installed
+	Coalesced       []string                  `json:"coalesced,omitempty"`         // update_ids of queued requests served by this run
+	Channels        []*instance.ChannelResult `json:"channels,omitempty"`          // Per-install results when updater.channels is configured
+	Hooks           []*instance.HookResult    `json:"hooks,omitempty"`             // Hooks that ran and their exit codes
+	Strategy        string                    `json:"strategy,omitempty"`          // updater.strategy: stop_first or staged
+	StageDuration   int64                     `json:"stage_duration_ms,omitempty"` // staged: install time while instances kept running
+	Downtime        int64                     `json:"downtime_ms,omitempty"`       // Longest instance downtime
+	Repo            *updater.RepoStatus       `json:"repo,omitempty"`              // updater.repo_path HEAD after the post-install sync
+	RepoSyncError   string                    `json:"repo_sync_error,omitempty"`   // Why the repo sync failed
+}
+
+// updateRequest is the optional JSON body of POST /api/v1/trigger-update
+type updateRequest struct {
+	updater.Target
+	Force  bool `json:"force,omitempty"`   // Reinstall even when the installed version is current
+	DryRun bool `json:"dry_run,omitempty"` // Only report what the update would do
+	Wait   bool `json:"wait,omitempty"`    // Wait for the result instead of returning 202
+
+	triggeredBy string // Recorded in the update log and notifications, "" = api-trigger
+}
+
+// source returns who triggered the update
+func (req updateRequest) source() string {
+	if req.triggeredBy == "" {
+		return "api-trigger"
+	}
+	return req.triggeredBy
+}
+
+// parseUpdateRequest decodes the optional update target, force, dry_run and wait flags from the request.
+// An empty body means the latest version. force, dry_run and wait may also be given as query
+// parameters; without a body the target may be given as ?ref=, ?commit= or ?pypi_version=.
+func parseUpdateRequest(r *http.Request) (updateRequest, error) {
+	var req updateRequest
+	if r.Body != nil {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			return updateRequest{}, fmt.Errorf("Invalid JSON body: %w", err)
+		}
+	}
+
+	query := r.URL.Query()
+	if req.Target.IsLatest() {
+		req.Target = updater.Target{
+			Ref:         query.Get("ref"),
+			Commit:      query.Get("commit"),
+			PyPIVersion: query.Get("pypi_version"),
+		}
+	}
+
+	for name, flag := range map[string]*bool{"force": &req.Force, "dry_run": &req.DryRun, "wait": &req.Wait} {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return updateRequest{}, fmt.Errorf("Invalid %s parameter: %q", name, v)
+		}
+		*flag = *flag || b
+	}
+
+	if err := req.Target.Validate(); err != nil {
+		return updateRequest{}, err
+	}
+	return req, nil
 }
 
 // APIInstanceError is a JSON-serializable version of instance.InstanceError
@@ -233,6 +501,14 @@ func statusToTitle(status updatelog.UpdateStatus) string {
 		return "Nanobot 更新部分成功"
 	case updatelog.StatusFailed:
 		return "Nanobot 更新失败"
+	case updatelog.StatusRolledBack:
+		return "Nanobot 更新已回滚"
+	case updatelog.StatusSkipped:
+		return "Nanobot 已是最新版本"
+	case updatelog.StatusAborted:
+		return "Nanobot 更新已中止"
+	case updatelog.StatusCancelled:
+		return "Nanobot 更新已取消"
 	default:
 		return "Nanobot 更新完成"
 	}
@@ -242,8 +518,26 @@ func statusToTitle(status updatelog.UpdateStatus) string {
 func formatCompletionMessage(result *instance.UpdateResult, status updatelog.UpdateStatus, elapsed float64) string {
 	var msg strings.Builder
 	msg.WriteString(fmt.Sprintf("耗时: %.1fs\n", elapsed))
+	if result.Skipped {
+		msg.WriteString(fmt.Sprintf("未执行更新: %s\n", result.SkipReason))
+		return msg.String()
+	}
+	if result.Cancelled {
+		msg.WriteString("更新已在阶段之间取消, 未安装新版本\n")
+	}
 	msg.WriteString(fmt.Sprintf("总实例数: %d\n", len(result.Stopped)+len(result.StopFailed)))
 	msg.WriteString(fmt.Sprintf("成功: %d\n", len(result.Started)))
+	if result.Aborted {
+		msg.WriteString(fmt.Sprintf("更新在 %s 阶段中止: %s\n", result.AbortStage, result.AbortReason))
+		if len(result.HeldBack) > 0 {
+			msg.WriteString(fmt.Sprintf("保持停止的实例: %s\n", strings.Join(result.HeldBack, ", ")))
+		}
+	}
+	if result.RolledBack {
+		msg.WriteString(fmt.Sprintf("新版本启动失败, 已回滚到: %s\n", result.PreviousVersion))
+	} else if result.RollbackError != "" {
+		msg.WriteString(fmt.Sprintf("回滚失败: %s\n", result.RollbackError))
+	}
 	failedCount := len(result.StopFailed) + len(result.StartFailed)
 	if failedCount > 0 {
 		msg.WriteString(fmt.Sprintf("失败: %d\n", failedCount))
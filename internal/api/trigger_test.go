@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,18 +17,68 @@ import (
 	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
 	"github.com/HQGroup/nanobot-auto-updater/internal/notifier"
 	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
 )
 
 // mockTriggerUpdater is a mock implementation of TriggerUpdater for testing.
+// Async jobs call TriggerUpdate concurrently: the recorded calls are guarded by mu.
 type mockTriggerUpdater struct {
-	result *instance.UpdateResult
-	err    error
+	mu         sync.Mutex
+	result     *instance.UpdateResult
+	err        error
+	lastTarget updater.Target
+	lastForce  bool
+	calls      int
+	plan       *instance.UpdateResult
+	planCalls  int
+	queueFull  bool
+	block      chan struct{}               // if set, TriggerUpdate waits until it is closed
+	onUpdate   func(target updater.Target) // if set, called by TriggerUpdate
+	serialize  bool                        // if set, TriggerUpdate runs one update at a time like the update queue
+	serial     sync.Mutex
 }
 
-func (m *mockTriggerUpdater) TriggerUpdate(ctx context.Context) (*instance.UpdateResult, error) {
+func (m *mockTriggerUpdater) QueueFull() bool {
+	return m.queueFull
+}
+
+func (m *mockTriggerUpdater) EnqueueUpdate(ctx context.Context, target updater.Target, force bool) (func(context.Context) (*instance.UpdateResult, error), error) {
+	if m.queueFull {
+		return nil, instance.ErrUpdateQueueFull
+	}
+	return func(ctx context.Context) (*instance.UpdateResult, error) {
+		return m.TriggerUpdate(ctx, target, force)
+	}, nil
+}
+
+func (m *mockTriggerUpdater) TriggerUpdate(ctx context.Context, target updater.Target, force bool) (*instance.UpdateResult, error) {
+	if m.serialize {
+		m.serial.Lock()
+		defer m.serial.Unlock()
+	}
+	if m.block != nil {
+		<-m.block
+	}
+	if m.onUpdate != nil {
+		m.onUpdate(target)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastTarget = target
+	m.lastForce = force
+	m.calls++
 	return m.result, m.err
 }
 
+func (m *mockTriggerUpdater) Plan(ctx context.Context, target updater.Target, force bool) (*instance.UpdateResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastTarget = target
+	m.lastForce = force
+	m.planCalls++
+	return m.plan, m.err
+}
+
 // newTestHandler creates a TriggerHandler with mock InstanceManager for testing.
 func newTestHandler(logger *slog.Logger, ul *updatelog.UpdateLogger, mock *mockTriggerUpdater, notif Notifier) *TriggerHandler {
 	cfg := &config.APIConfig{
@@ -54,7 +105,7 @@ func TestTriggerHandler_UpdateIDInResponse(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -99,7 +150,7 @@ func TestTriggerHandler_RecordsUpdateLog(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -148,7 +199,7 @@ func TestTriggerHandler_LogRecordingFailureDoesNotAffectResponse(t *testing.T) {
 	// Use nil UpdateLogger to test non-blocking behavior
 	handler := newTestHandler(logger, nil, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -191,7 +242,7 @@ func TestTriggerHandler_StartTimeRecordedBeforeUpdate(t *testing.T) {
 
 	beforeCall := time.Now().UTC()
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -230,7 +281,7 @@ func TestTriggerHandler_EndTimeRecordedAfterUpdate(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -264,7 +315,7 @@ func TestTriggerHandler_DurationCalculatedInMilliseconds(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -339,7 +390,7 @@ func TestTriggerHandler_Success(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -374,7 +425,7 @@ func TestTriggerHandler_Conflict(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -395,6 +446,34 @@ func TestTriggerHandler_Conflict(t *testing.T) {
 	if response["message"] != "Update already in progress" {
 		t.Errorf("message = %q, want %q", response["message"], "Update already in progress")
 	}
+
+	logs := ul.GetAll()
+	if len(logs) != 1 || logs[0].Status != updatelog.StatusRejected || logs[0].RejectReason == "" {
+		t.Errorf("recorded logs = %+v, want one rejected log with a reason", logs)
+	}
+}
+
+// TestTriggerHandler_RejectedWhenQueueFullIsRecorded: a request refused before a job
+// is created still leaves a rejected nanobot-update record under its X-Update-ID
+func TestTriggerHandler_RejectedWhenQueueFullIsRecorded(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+	handler := newTestHandler(logger, ul, &mockTriggerUpdater{queueFull: true}, nil)
+
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("POST", "/api/v1/trigger-update", nil))
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	logs := ul.GetAll()
+	if len(logs) != 1 {
+		t.Fatalf("Expected 1 recorded log, got %d", len(logs))
+	}
+	if logs[0].ID != rec.Header().Get("X-Update-ID") || logs[0].Status != updatelog.StatusRejected ||
+		logs[0].Operation != updatelog.OperationNanobotUpdate || logs[0].TriggeredBy != "api-trigger" {
+		t.Errorf("recorded log = %+v, want a rejected api-trigger nanobot-update log keyed by X-Update-ID", logs[0])
+	}
 }
 
 // TestTriggerHandler_Timeout tests API-01:
@@ -414,7 +493,7 @@ func TestTriggerHandler_Timeout(t *testing.T) {
 	ul := updatelog.NewUpdateLogger(logger, "")
 	handler := NewTriggerHandler(mock, cfg, logger, ul, nil, 3)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -461,7 +540,7 @@ func TestTriggerHandler_JSONFormat(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -534,7 +613,7 @@ func TestTriggerHandler_WithAuth(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+			req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 			if tt.authHeader != "" {
 				req.Header.Set("Authorization", tt.authHeader)
 			}
@@ -574,6 +653,33 @@ func TestTriggerHandler_TimeoutScenario(t *testing.T) {
 }
 
 // TestTriggerHandler_UpdateFailed tests response with failed instances
+func TestTriggerHandler_RecordsOutputOfFailedInstall(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+
+	output := "2026-03-10T02:30:01.000Z [stderr] error: No solution found when resolving dependencies\n"
+	mock := &mockTriggerUpdater{
+		result: &instance.UpdateResult{Output: output},
+		err:    errors.New("UV update failed: update failed (all 2 sources)"),
+	}
+	handler := newTestHandler(logger, ul, mock, nil)
+
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	updateID := rec.Header().Get("X-Update-ID")
+	if updateID == "" {
+		t.Fatal("X-Update-ID header missing on error response")
+	}
+	got, err := ul.GetOutput(updateID)
+	if err != nil || got != output {
+		t.Errorf("GetOutput(%s) = %q, %v; want the full uv output", updateID, got, err)
+	}
+}
+
 func TestTriggerHandler_UpdateFailed(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	ul := updatelog.NewUpdateLogger(logger, "")
@@ -590,7 +696,7 @@ func TestTriggerHandler_UpdateFailed(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -634,7 +740,7 @@ func TestTriggerHandler_InternalError(t *testing.T) {
 	}
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -651,6 +757,11 @@ func TestTriggerHandler_InternalError(t *testing.T) {
 	if response["error"] != "internal_error" {
 		t.Errorf("error = %q, want %q", response["error"], "internal_error")
 	}
+
+	logs := ul.GetAll()
+	if len(logs) != 1 || logs[0].Status != updatelog.StatusFailed || logs[0].Error != mock.err.Error() {
+		t.Errorf("recorded logs = %+v, want one failed log with the error text", logs)
+	}
 }
 
 // TestTriggerHandler_NotifierNil_NilSafe verifies UNOTIF-03, UNOTIF-04:
@@ -670,7 +781,7 @@ func TestTriggerHandler_NotifierNil_NilSafe(t *testing.T) {
 	// Pass nil notifier -- should not panic
 	handler := newTestHandler(logger, ul, mock, nil)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -707,7 +818,7 @@ func TestTriggerHandler_DisabledNotifier_NilSafe(t *testing.T) {
 	disabledNotif := notifier.NewWithConfig(notifier.Config{}, logger)
 	handler := newTestHandler(logger, ul, mock, disabledNotif)
 
-	req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 	rec := httptest.NewRecorder()
 
 	handler.Handle(rec, req)
@@ -750,7 +861,7 @@ func TestTriggerHandler_NilNotifier_ErrorPaths(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			handler := newTestHandler(logger, ul, tt.mock, nil)
 
-			req := httptest.NewRequest("POST", "/api/v1/trigger-update", nil)
+			req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
 			rec := httptest.NewRecorder()
 
 			handler.Handle(rec, req)
@@ -761,3 +872,201 @@ func TestTriggerHandler_NilNotifier_ErrorPaths(t *testing.T) {
 		})
 	}
 }
+
+// TestTriggerHandler_TargetPassedAndRecorded verifies the JSON body target is passed to
+// TriggerUpdate and the resolved target is recorded in the UpdateLog and response
+func TestTriggerHandler_TargetPassedAndRecorded(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+
+	mock := &mockTriggerUpdater{
+		result: &instance.UpdateResult{
+			Stopped:     []string{"gateway"},
+			Started:     []string{"gateway"},
+			Target:      "ref:v0.1.4",
+			InstallSpec: "git+https://github.com/HKUDS/nanobot.git@v0.1.4",
+		},
+	}
+	handler := newTestHandler(logger, ul, mock, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", strings.NewReader(`{"ref":"v0.1.4"}`))
+	rec := httptest.NewRecorder()
+
+	handler.Handle(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if mock.lastTarget != (updater.Target{Ref: "v0.1.4"}) {
+		t.Errorf("TriggerUpdate target = %+v, want ref v0.1.4", mock.lastTarget)
+	}
+
+	var response APIUpdateResult
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	if response.Target != "ref:v0.1.4" {
+		t.Errorf("response target = %q, want %q", response.Target, "ref:v0.1.4")
+	}
+
+	logs := ul.GetAll()
+	if len(logs) != 1 {
+		t.Fatalf("Expected 1 recorded log, got %d", len(logs))
+	}
+	if logs[0].Target != "ref:v0.1.4" {
+		t.Errorf("UpdateLog.Target = %q, want %q", logs[0].Target, "ref:v0.1.4")
+	}
+	if logs[0].InstallSpec != "git+https://github.com/HKUDS/nanobot.git@v0.1.4" {
+		t.Errorf("UpdateLog.InstallSpec = %q", logs[0].InstallSpec)
+	}
+}
+
+// TestTriggerHandler_InvalidTarget verifies malformed targets are rejected with 400
+// before TriggerUpdate is called
+func TestTriggerHandler_InvalidTarget(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	bodies := []string{
+		`{"ref":"v0.1.4","commit":"abc1234"}`,
+		`{"commit":"not-a-sha"}`,
+		`{"ref":"main --index-url http://example.com"}`,
+		`{"version":"0.1.3"}`,
+		`not json`,
+	}
+
+	for _, body := range bodies {
+		t.Run(body, func(t *testing.T) {
+			mock := &mockTriggerUpdater{result: &instance.UpdateResult{}}
+			handler := newTestHandler(logger, nil, mock, nil)
+
+			req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Status code = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if mock.calls != 0 {
+				t.Errorf("TriggerUpdate called %d times, want 0", mock.calls)
+			}
+		})
+	}
+}
+
+// TestTriggerHandler_ForceFlag verifies force is read from the JSON body or the query string
+func TestTriggerHandler_ForceFlag(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name      string
+		url       string
+		body      string
+		wantForce bool
+		wantCode  int
+	}{
+		{"default", "/api/v1/trigger-update?wait=true", "", false, http.StatusOK},
+		{"body", "/api/v1/trigger-update?wait=true", `{"force":true}`, true, http.StatusOK},
+		{"body with target", "/api/v1/trigger-update?wait=true", `{"pypi_version":"0.1.3","force":true}`, true, http.StatusOK},
+		{"query", "/api/v1/trigger-update?force=true&wait=true", "", true, http.StatusOK},
+		{"invalid query", "/api/v1/trigger-update?force=maybe", "", false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockTriggerUpdater{result: &instance.UpdateResult{}}
+			handler := newTestHandler(logger, nil, mock, nil)
+
+			req := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.Handle(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if mock.lastForce != tt.wantForce {
+				t.Errorf("TriggerUpdate force = %v, want %v", mock.lastForce, tt.wantForce)
+			}
+		})
+	}
+}
+
+// TestTriggerHandler_Skipped verifies an up-to-date result is reported and recorded as skipped
+func TestTriggerHandler_Skipped(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+
+	mock := &mockTriggerUpdater{
+		result: &instance.UpdateResult{
+			Target:     "latest",
+			Skipped:    true,
+			SkipReason: "installed version 0.1.4 is current",
+		},
+	}
+	handler := newTestHandler(logger, ul, mock, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil)
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var response APIUpdateResult
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	if !response.Success || !response.Skipped || response.SkipReason == "" {
+		t.Errorf("response = %+v, want successful skipped result with reason", response)
+	}
+
+	logs := ul.GetAll()
+	if len(logs) != 1 || logs[0].Status != updatelog.StatusSkipped {
+		t.Fatalf("recorded logs = %+v, want one skipped log", logs)
+	}
+	if logs[0].SkipReason != "installed version 0.1.4 is current" {
+		t.Errorf("UpdateLog.SkipReason = %q", logs[0].SkipReason)
+	}
+	if title := statusToTitle(updatelog.StatusSkipped); title != "Nanobot 已是最新版本" {
+		t.Errorf("statusToTitle(skipped) = %q", title)
+	}
+}
+
+func TestTriggerHandler_CanaryAborted(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+
+	mock := &mockTriggerUpdater{
+		result: &instance.UpdateResult{
+			Target:      "latest",
+			Stopped:     []string{"canary", "gateway"},
+			StartFailed: []*instance.InstanceError{{InstanceName: "canary", Operation: "canary", Port: 18790, Err: errors.New("process exited")}},
+			Aborted:     true,
+			AbortStage:  instance.AbortStageCanary,
+			AbortReason: "canary canary unhealthy during soak period: process exited",
+			HeldBack:    []string{"gateway"},
+		},
+	}
+	handler := newTestHandler(logger, ul, mock, nil)
+
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("POST", "/api/v1/trigger-update?wait=true", nil))
+
+	var response APIUpdateResult
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	if response.Success || !response.Aborted || response.AbortStage != "canary" || len(response.HeldBack) != 1 {
+		t.Errorf("response = %+v, want failed canary abort with one held back instance", response)
+	}
+
+	logs := ul.GetAll()
+	if len(logs) != 1 || logs[0].Status != updatelog.StatusAborted || logs[0].AbortStage != "canary" {
+		t.Fatalf("recorded logs = %+v, want one aborted log", logs)
+	}
+	msg := formatCompletionMessage(mock.result, updatelog.StatusAborted, 1)
+	if !strings.Contains(msg, "canary 阶段中止") || !strings.Contains(msg, "gateway") {
+		t.Errorf("completion message = %q, want abort stage and held back instance", msg)
+	}
+}
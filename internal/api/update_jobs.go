@@ -0,0 +1,256 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+)
+
+const (
+	// updateJobTimeout bounds a background update job (stop, uv install with retries, start, rollback).
+	// Independent of api.timeout, which only bounds how long ?wait=true waits for the result
+	updateJobTimeout = 30 * time.Minute
+
+	// maxFinishedUpdateJobs is how many finished jobs stay queryable at GET /api/v1/updates/{id};
+	// older results remain available in /api/v1/update-logs
+	maxFinishedUpdateJobs = 50
+
+	// jobEventsPingInterval keeps idle /api/v1/updates/{id}/events connections alive (same as log SSE)
+	jobEventsPingInterval = 30 * time.Second
+)
+
+// updateJobs is the registry of background update jobs started by POST /api/v1/trigger-update.
+// Several jobs may be unfinished at once: one running, the others queued behind it.
+type updateJobs struct {
+	mu    sync.Mutex
+	jobs  map[string]*instance.UpdateJob
+	order []string // update_ids, oldest first
+}
+
+func newUpdateJobs() *updateJobs {
+	return &updateJobs{jobs: make(map[string]*instance.UpdateJob)}
+}
+
+// start registers job.
+func (j *updateJobs) start(job *instance.UpdateJob) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jobs[job.ID] = job
+	j.order = append(j.order, job.ID)
+	j.prune()
+}
+
+// prune drops the oldest finished jobs beyond maxFinishedUpdateJobs. Unfinished jobs are kept.
+// Caller must hold mu.
+func (j *updateJobs) prune() {
+	finished := 0
+	for _, id := range j.order {
+		if isDone(j.jobs[id]) {
+			finished++
+		}
+	}
+	kept := j.order[:0]
+	for _, id := range j.order {
+		if finished > maxFinishedUpdateJobs && isDone(j.jobs[id]) {
+			delete(j.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	j.order = kept
+}
+
+// latest returns the running job (the oldest unfinished job that has left the queue),
+// or the most recently started one. Nil if no job was started.
+func (j *updateJobs) latest() *instance.UpdateJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, id := range j.order {
+		job := j.jobs[id]
+		if !isDone(job) && job.Snapshot().Phase != instance.JobQueued {
+			return job
+		}
+	}
+	if len(j.order) == 0 {
+		return nil
+	}
+	return j.jobs[j.order[len(j.order)-1]]
+}
+
+// isDone reports whether job has finished
+func isDone(job *instance.UpdateJob) bool {
+	select {
+	case <-job.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// get returns the job with the given update_id, or nil.
+func (j *updateJobs) get(id string) *instance.UpdateJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jobs[id]
+}
+
+// updateAcceptedResponse is the 202 response of POST /api/v1/trigger-update
+type updateAcceptedResponse struct {
+	UpdateID  string            `json:"update_id"`
+	Phase     instance.JobPhase `json:"phase"`
+	StatusURL string            `json:"status_url"` // GET for progress, DELETE to cancel
+}
+
+// UpdateJobResponse is the JSON response of GET/DELETE /api/v1/updates/{id}
+type UpdateJobResponse struct {
+	UpdateID        string                      `json:"update_id"`
+	Phase           instance.JobPhase           `json:"phase"` // queued/checking/staging/stopping/installing/swapping/starting/verifying/rolling_back/completed/failed/cancelled
+	Done            bool                        `json:"done"`
+	CancelRequested bool                        `json:"cancel_requested,omitempty"`
+	Progress        instance.UpdateProgress     `json:"progress"`
+	Instances       []instance.InstanceProgress `json:"instances"`
+	StartTime       time.Time                   `json:"start_time"`
+	EndTime         *time.Time                  `json:"end_time,omitempty"`
+	Error           string                      `json:"error,omitempty"`  // Update error (e.g. uv install failed), phase=failed
+	Result          *APIUpdateResult            `json:"result,omitempty"` // Set once the job has finished with a result
+}
+
+// buildJobResponse converts a job snapshot to its JSON response
+func buildJobResponse(snap instance.JobSnapshot) UpdateJobResponse {
+	resp := UpdateJobResponse{
+		UpdateID:        snap.ID,
+		Phase:           snap.Phase,
+		Done:            !snap.EndTime.IsZero(),
+		CancelRequested: snap.CancelRequested,
+		Progress:        snap.Progress,
+		Instances:       snap.Instances,
+		StartTime:       snap.StartTime,
+	}
+	if resp.Done {
+		end := snap.EndTime
+		resp.EndTime = &end
+	}
+	if snap.Err != nil {
+		resp.Error = snap.Err.Error()
+	}
+	if snap.Result != nil {
+		result := buildAPIUpdateResult(snap.ID, snap.Result)
+		resp.Result = &result
+	}
+	return resp
+}
+
+// HandleJob handles GET /api/v1/updates/{id}: phase, per-instance progress and,
+// once finished, the result of a background update job
+func (h *TriggerHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	job := h.jobs.get(r.PathValue("id"))
+	if job == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Update job not found: "+r.PathValue("id"))
+		return
+	}
+	writeJSON(w, http.StatusOK, buildJobResponse(job.Snapshot()), h.logger)
+}
+
+// HandleProgress handles GET /api/v1/update/progress: progress of the running update job,
+// or of the last one when none is running. {"phase":"idle"} before the first API update.
+// Jobs waiting behind the running update are listed at GET /api/v1/updates/queue.
+func (h *TriggerHandler) HandleProgress(w http.ResponseWriter, r *http.Request) {
+	job := h.jobs.latest()
+	if job == nil {
+		writeJSON(w, http.StatusOK, UpdateJobResponse{Phase: "idle", Instances: []instance.InstanceProgress{}}, h.logger)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildJobResponse(job.Snapshot()), h.logger)
+}
+
+// HandleJobEvents handles GET /api/v1/updates/{id}/events: streams the job status as
+// Server-Sent Events. A "progress" event is sent on connect and on every change (phase,
+// instance progress, install heartbeat); the final "done" event carries the result.
+func (h *TriggerHandler) HandleJobEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	job := h.jobs.get(id)
+	if job == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Update job not found: "+id)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", "Streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	changed, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	ping := time.NewTicker(jobEventsPingInterval)
+	defer ping.Stop()
+
+	h.writeJobEvent(w, flusher, "progress", job)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-job.Done():
+			h.writeJobEvent(w, flusher, "done", job)
+			return
+		case <-changed:
+			h.writeJobEvent(w, flusher, "progress", job)
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeJobEvent writes the current job status as an SSE event
+func (h *TriggerHandler) writeJobEvent(w http.ResponseWriter, flusher http.Flusher, event string, job *instance.UpdateJob) {
+	data, err := json.Marshal(buildJobResponse(job.Snapshot()))
+	if err != nil {
+		h.logger.Error("Failed to encode update job event", "error", err, "update_id", job.ID)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	flusher.Flush()
+}
+
+// HandleCancel handles DELETE /api/v1/updates/{id}: cancels the job before its next phase.
+// Cancelling is only possible until the installation starts; afterwards 409 is returned.
+// A queued job leaves the update queue and ends cancelled.
+func (h *TriggerHandler) HandleCancel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	job := h.jobs.get(id)
+	if job == nil {
+		writeJSONError(w, http.StatusNotFound, "not_found", "Update job not found: "+id)
+		return
+	}
+	if err := job.RequestCancel(); err != nil {
+		if errors.Is(err, instance.ErrJobFinished) || errors.Is(err, instance.ErrJobNotCancellable) {
+			writeJSONError(w, http.StatusConflict, "conflict", err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	h.logger.Info("Update cancel requested", "update_id", id)
+	writeJSON(w, http.StatusAccepted, buildJobResponse(job.Snapshot()), h.logger)
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v any, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("Failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// startAsyncUpdate posts trigger-update without ?wait and returns the 202 response
+func startAsyncUpdate(t *testing.T, handler *TriggerHandler) updateAcceptedResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("POST", "/api/v1/trigger-update", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Status code = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	var accepted updateAcceptedResponse
+	if err := json.NewDecoder(rec.Body).Decode(&accepted); err != nil {
+		t.Fatalf("Failed to decode 202 response: %v", err)
+	}
+	if accepted.UpdateID == "" || accepted.StatusURL != "/api/v1/updates/"+accepted.UpdateID {
+		t.Fatalf("202 response = %+v, want update_id and status_url", accepted)
+	}
+	if rec.Header().Get("Location") != accepted.StatusURL {
+		t.Errorf("Location = %q, want %q", rec.Header().Get("Location"), accepted.StatusURL)
+	}
+	return accepted
+}
+
+// getJob calls GET /api/v1/updates/{id}
+func getJob(handler *TriggerHandler, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/v1/updates/"+id, nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	handler.HandleJob(rec, req)
+	return rec
+}
+
+// cancelJob calls DELETE /api/v1/updates/{id}
+func cancelJob(handler *TriggerHandler, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("DELETE", "/api/v1/updates/"+id, nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	handler.HandleCancel(rec, req)
+	return rec
+}
+
+func waitJobDone(t *testing.T, handler *TriggerHandler, id string) {
+	t.Helper()
+	select {
+	case <-handler.jobs.get(id).Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("update job did not finish")
+	}
+}
+
+func TestTriggerHandler_AsyncJobStatus(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+	mock := &mockTriggerUpdater{
+		result: &instance.UpdateResult{Target: "latest", Stopped: []string{"gateway"}, Started: []string{"gateway"}},
+		block:  make(chan struct{}),
+	}
+	handler := newTestHandler(logger, ul, mock, nil)
+
+	accepted := startAsyncUpdate(t, handler)
+
+	// Still running: no result yet
+	rec := getJob(handler, accepted.UpdateID)
+	var running UpdateJobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&running); err != nil {
+		t.Fatalf("Failed to decode job status: %v", err)
+	}
+	if rec.Code != http.StatusOK || running.Done || running.Result != nil {
+		t.Errorf("running job = %d %+v, want 200 without result", rec.Code, running)
+	}
+
+	close(mock.block)
+	waitJobDone(t, handler, accepted.UpdateID)
+
+	rec = getJob(handler, accepted.UpdateID)
+	var done UpdateJobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&done); err != nil {
+		t.Fatalf("Failed to decode job status: %v", err)
+	}
+	if done.Phase != instance.JobCompleted || !done.Done || done.EndTime == nil {
+		t.Errorf("finished job phase=%s done=%v end=%v, want completed", done.Phase, done.Done, done.EndTime)
+	}
+	if done.Result == nil || !done.Result.Success || done.Result.UpdateID != accepted.UpdateID {
+		t.Errorf("finished job result = %+v, want successful result with the update_id", done.Result)
+	}
+	if logs := ul.GetAll(); len(logs) != 1 || logs[0].ID != accepted.UpdateID {
+		t.Errorf("update logs = %+v, want one log recorded under the update_id", logs)
+	}
+
+	if rec := getJob(handler, "unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown job status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestTriggerHandler_QueuedWhileJobRunning(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	release := make(chan struct{})
+	var entered atomic.Int32
+	mock := &mockTriggerUpdater{result: &instance.UpdateResult{}, serialize: true}
+	mock.onUpdate = func(updater.Target) {
+		// The first update runs until released
+		if entered.Add(1) == 1 {
+			<-release
+		}
+	}
+	handler := newTestHandler(logger, nil, mock, nil)
+
+	first := startAsyncUpdate(t, handler)
+	deadline := time.Now().Add(5 * time.Second)
+	for entered.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("first update did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The second request is accepted too; it waits in the update queue instead of 409
+	second := startAsyncUpdate(t, handler)
+	if first.UpdateID == second.UpdateID {
+		t.Fatalf("both requests got update_id %s", first.UpdateID)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := entered.Load(); n != 1 {
+		t.Fatalf("%d updates running, want the second one to wait for the first", n)
+	}
+	if snap := handler.jobs.get(second.UpdateID).Snapshot(); snap.Phase != instance.JobQueued || !snap.EndTime.IsZero() {
+		t.Fatalf("second job phase = %s (ended %v) while the first runs, want queued", snap.Phase, !snap.EndTime.IsZero())
+	}
+
+	close(release)
+	waitJobDone(t, handler, first.UpdateID)
+	waitJobDone(t, handler, second.UpdateID)
+
+	rec := getJob(handler, second.UpdateID)
+	var done UpdateJobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&done); err != nil {
+		t.Fatalf("Failed to decode job status: %v", err)
+	}
+	if done.Phase != instance.JobCompleted || entered.Load() != 2 {
+		t.Errorf("queued job phase = %s after %d updates, want completed after the first", done.Phase, entered.Load())
+	}
+}
+
+func TestTriggerHandler_CancelJob(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mock := &mockTriggerUpdater{result: &instance.UpdateResult{Cancelled: true}, block: make(chan struct{})}
+	handler := newTestHandler(logger, nil, mock, nil)
+
+	accepted := startAsyncUpdate(t, handler)
+
+	rec := cancelJob(handler, accepted.UpdateID)
+	var resp UpdateJobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode cancel response: %v", err)
+	}
+	if rec.Code != http.StatusAccepted || !resp.CancelRequested {
+		t.Errorf("cancel = %d %+v, want 202 with cancel_requested", rec.Code, resp)
+	}
+
+	close(mock.block)
+	waitJobDone(t, handler, accepted.UpdateID)
+
+	if got := handler.jobs.get(accepted.UpdateID).Snapshot().Phase; got != instance.JobCancelled {
+		t.Errorf("phase = %s, want %s", got, instance.JobCancelled)
+	}
+	if rec := cancelJob(handler, accepted.UpdateID); rec.Code != http.StatusConflict {
+		t.Errorf("cancel of a finished job = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := cancelJob(handler, "unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("cancel of an unknown job = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestTriggerHandler_JobEventsStreamUntilDone(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mock := &mockTriggerUpdater{result: &instance.UpdateResult{Started: []string{"gateway"}}, block: make(chan struct{})}
+	handler := newTestHandler(logger, nil, mock, nil)
+
+	accepted := startAsyncUpdate(t, handler)
+
+	req := httptest.NewRequest("GET", "/api/v1/updates/"+accepted.UpdateID+"/events", nil)
+	req.SetPathValue("id", accepted.UpdateID)
+	rec := httptest.NewRecorder()
+	streamDone := make(chan struct{})
+	go func() {
+		defer close(streamDone)
+		handler.HandleJobEvents(rec, req)
+	}()
+
+	close(mock.block)
+	select {
+	case <-streamDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event stream did not end when the job finished")
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	if len(events) < 2 || !strings.HasPrefix(events[0], "event: progress\n") {
+		t.Fatalf("events = %q, want a progress event first", events)
+	}
+	last := events[len(events)-1]
+	if !strings.HasPrefix(last, "event: done\ndata: ") {
+		t.Fatalf("last event = %q, want done", last)
+	}
+	var final UpdateJobResponse
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(last, "event: done\ndata: ")), &final); err != nil {
+		t.Fatalf("Failed to decode done event: %v", err)
+	}
+	if !final.Done || final.Result == nil || !final.Result.Success {
+		t.Errorf("done event = %+v, want the finished job with its result", final)
+	}
+
+	if rec := getJobEvents(handler, "unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("events of an unknown job = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func getJobEvents(handler *TriggerHandler, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/v1/updates/"+id+"/events", nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	handler.HandleJobEvents(rec, req)
+	return rec
+}
+
+func TestTriggerHandler_Progress(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mock := &mockTriggerUpdater{result: &instance.UpdateResult{}}
+	handler := newTestHandler(logger, nil, mock, nil)
+
+	progress := func() UpdateJobResponse {
+		rec := httptest.NewRecorder()
+		handler.HandleProgress(rec, httptest.NewRequest("GET", "/api/v1/update/progress", nil))
+		var resp UpdateJobResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode progress: %v", err)
+		}
+		return resp
+	}
+
+	if got := progress(); got.Phase != "idle" {
+		t.Errorf("progress before any update = %+v, want idle", got)
+	}
+
+	accepted := startAsyncUpdate(t, handler)
+	waitJobDone(t, handler, accepted.UpdateID)
+
+	if got := progress(); got.UpdateID != accepted.UpdateID || got.Phase != instance.JobCompleted {
+		t.Errorf("progress = %+v, want the last job completed", got)
+	}
+}
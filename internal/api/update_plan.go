@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// APIUpdatePlan is the JSON response of GET /api/v1/update/plan and
+// POST /api/v1/trigger-update?dry_run=true. Field names follow APIUpdateResult and the
+// instances use the update log InstanceUpdateDetail shape, so the UI can render both alike.
+type APIUpdatePlan struct {
+	DryRun           bool                             `json:"dry_run"`           // Always true
+	Ready            bool                             `json:"ready"`             // No preflight problems
+	Target           string                           `json:"target"`            // Requested target (latest, ref:..., commit:..., pypi:...)
+	InstallSpec      string                           `json:"install_spec"`      // uv spec that would be installed first
+	Source           string                           `json:"source"`            // updater source that would be tried first
+	InstalledVersion string                           `json:"installed_version"` // Currently installed nanobot version
+	UpdateAvailable  *bool                            `json:"update_available"`  // null when the check failed
+	Skipped          bool                             `json:"skipped"`           // The update would be skipped (already current, force=false)
+	SkipReason       string                           `json:"skip_reason,omitempty"`
+	Stopped          []string                         `json:"stopped"`   // Instances stopAll would stop (have a PID)
+	Started          []string                         `json:"started"`   // Instances startAll would start
+	Instances        []updatelog.InstanceUpdateDetail `json:"instances"` // Per-instance status and current PID
+	Check            *updater.CheckResult             `json:"check,omitempty"`
+	Preflight        []string                         `json:"preflight"`          // Problems that would make the update fail (e.g. uv missing)
+	Channels         []*instance.ChannelResult        `json:"channels,omitempty"` // Per-install plan when updater.channels is configured
+}
+
+// HandlePlan handles GET /api/v1/update/plan
+// Reports what POST /api/v1/trigger-update would do for the same ?ref=, ?commit=, ?pypi_version=
+// and ?force= parameters. Nothing is stopped or installed, no notification is sent and no update
+// log is recorded.
+func (h *TriggerHandler) HandlePlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET method is supported")
+		return
+	}
+
+	req, err := parseUpdateRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	h.writePlan(w, r, req)
+}
+
+// writePlan builds the update plan for req and writes it as APIUpdatePlan
+func (h *TriggerHandler) writePlan(w http.ResponseWriter, r *http.Request, req updateRequest) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeout)
+	defer cancel()
+
+	result, err := h.instanceManager.Plan(ctx, req.Target, req.Force)
+	if err != nil {
+		if errors.Is(err, updater.ErrInvalidTarget) {
+			writeJSONError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		h.logger.Error("Failed to build update plan", "error", err, "target", req.Target.String())
+		writeJSONError(w, http.StatusInternalServerError, "internal_error",
+			fmt.Sprintf("Failed to build update plan: %v", err))
+		return
+	}
+
+	h.logger.Info("Update plan requested",
+		"target", result.Target,
+		"force", req.Force,
+		"skipped", result.Skipped,
+		"preflight", result.Preflight)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(buildPlanResponse(result)); err != nil {
+		h.logger.Error("Failed to encode response", "error", err)
+	}
+}
+
+// buildPlanResponse converts a dry-run UpdateResult into APIUpdatePlan
+func buildPlanResponse(result *instance.UpdateResult) APIUpdatePlan {
+	plan := APIUpdatePlan{
+		DryRun:           true,
+		Ready:            len(result.Preflight) == 0,
+		Target:           result.Target,
+		InstallSpec:      result.InstallSpec,
+		Source:           result.Source,
+		InstalledVersion: result.PreviousVersion,
+		Skipped:          result.Skipped,
+		SkipReason:       result.SkipReason,
+		Stopped:          result.Stopped,
+		Started:          result.Started,
+		Instances:        updatelog.BuildInstanceDetails(result),
+		Check:            result.Check,
+		Preflight:        result.Preflight,
+		Channels:         result.Channels,
+	}
+	if result.Check != nil {
+		available := result.Check.UpdateAvailable
+		plan.UpdateAvailable = &available
+	}
+	if plan.Stopped == nil {
+		plan.Stopped = []string{}
+	}
+	if plan.Started == nil {
+		plan.Started = []string{}
+	}
+	if plan.Preflight == nil {
+		plan.Preflight = []string{}
+	}
+	return plan
+}
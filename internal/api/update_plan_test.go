@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+func newTestPlan() *instance.UpdateResult {
+	return &instance.UpdateResult{
+		DryRun:          true,
+		Target:          "latest",
+		InstallSpec:     "git+https://github.com/HKUDS/nanobot.git",
+		Source:          "github",
+		PreviousVersion: "0.1.3",
+		Stopped:         []string{"gateway"},
+		Started:         []string{"gateway", "worker"},
+		Check:           &updater.CheckResult{InstalledVersion: "0.1.3", LatestVersion: "0.1.4", UpdateAvailable: true},
+		Instances: []instance.InstanceStatusInfo{
+			{Name: "gateway", Port: 18790, Running: true, PID: 4242},
+			{Name: "worker", Port: 18791},
+		},
+	}
+}
+
+// TestTriggerHandler_HandlePlan verifies the plan endpoint reports the dry run without
+// triggering an update, recording a log or sending notifications
+func TestTriggerHandler_HandlePlan(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+	notif := &recordingNotifier{}
+
+	mock := &mockTriggerUpdater{plan: newTestPlan()}
+	handler := newTestHandler(logger, ul, mock, notif)
+
+	req := httptest.NewRequest("GET", "/api/v1/update/plan?ref=v0.1.4&force=true", nil)
+	rec := httptest.NewRecorder()
+	handler.HandlePlan(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if mock.calls != 0 || mock.planCalls != 1 {
+		t.Fatalf("TriggerUpdate calls = %d, Plan calls = %d, want 0 and 1", mock.calls, mock.planCalls)
+	}
+	if mock.lastTarget.Ref != "v0.1.4" || !mock.lastForce {
+		t.Errorf("Plan called with target %+v force %v, want ref v0.1.4 and force", mock.lastTarget, mock.lastForce)
+	}
+
+	var plan APIUpdatePlan
+	if err := json.NewDecoder(rec.Body).Decode(&plan); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	if !plan.DryRun || !plan.Ready || plan.Source != "github" || plan.InstalledVersion != "0.1.3" {
+		t.Errorf("plan = %+v, want ready dry run from github with installed version 0.1.3", plan)
+	}
+	if plan.UpdateAvailable == nil || !*plan.UpdateAvailable {
+		t.Errorf("plan.UpdateAvailable = %v, want true", plan.UpdateAvailable)
+	}
+	if len(plan.Instances) != 2 || plan.Instances[0].PID != 4242 || plan.Instances[0].Status != "would_restart" {
+		t.Errorf("plan.Instances = %+v, want gateway would_restart with PID 4242", plan.Instances)
+	}
+
+	if logs := ul.GetAll(); len(logs) != 0 {
+		t.Errorf("recorded logs = %+v, want none for a dry run", logs)
+	}
+	if n := notif.CallCount(); n != 0 {
+		t.Errorf("notifications = %d, want none for a dry run", n)
+	}
+}
+
+// TestTriggerHandler_DryRun verifies POST /api/v1/trigger-update?dry_run=true returns the plan
+func TestTriggerHandler_DryRun(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	tests := []struct {
+		name string
+		url  string
+		body string
+	}{
+		{"query", "/api/v1/trigger-update?dry_run=true", ""},
+		{"body", "/api/v1/trigger-update", `{"pypi_version":"0.1.4","dry_run":true}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := newTestPlan()
+			plan.Preflight = []string{"uv is not installed or not in PATH"}
+			mock := &mockTriggerUpdater{plan: plan}
+			handler := newTestHandler(logger, nil, mock, nil)
+
+			req := httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.Handle(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if mock.calls != 0 || mock.planCalls != 1 {
+				t.Fatalf("TriggerUpdate calls = %d, Plan calls = %d, want 0 and 1", mock.calls, mock.planCalls)
+			}
+
+			var resp APIUpdatePlan
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response JSON: %v", err)
+			}
+			if resp.Ready || len(resp.Preflight) != 1 {
+				t.Errorf("plan = %+v, want not ready with one preflight problem", resp)
+			}
+		})
+	}
+}
+
+// TestTriggerHandler_HandlePlan_InvalidTarget verifies conflicting targets are rejected
+func TestTriggerHandler_HandlePlan_InvalidTarget(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mock := &mockTriggerUpdater{plan: newTestPlan()}
+	handler := newTestHandler(logger, nil, mock, nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/update/plan?ref=main&pypi_version=0.1.4", nil)
+	rec := httptest.NewRecorder()
+	handler.HandlePlan(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if mock.planCalls != 0 {
+		t.Errorf("Plan calls = %d, want 0", mock.planCalls)
+	}
+}
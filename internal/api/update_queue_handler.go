@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+)
+
+// UpdateQueue lists and drops updates waiting behind the running update.
+// Satisfied by *instance.InstanceManager via duck typing.
+type UpdateQueue interface {
+	QueuedUpdates() []instance.QueuedUpdate
+	DropQueuedUpdate(id string) (instance.QueuedUpdate, error)
+}
+
+// UpdateQueueHandler handles GET /api/v1/updates/queue and DELETE /api/v1/updates/queue/{id}
+type UpdateQueueHandler struct {
+	queue  UpdateQueue
+	logger *slog.Logger
+}
+
+// NewUpdateQueueHandler creates a new UpdateQueueHandler
+func NewUpdateQueueHandler(queue UpdateQueue, logger *slog.Logger) *UpdateQueueHandler {
+	return &UpdateQueueHandler{
+		queue:  queue,
+		logger: logger.With("source", "api-update-queue"),
+	}
+}
+
+// UpdateQueueResponse is the JSON response of GET /api/v1/updates/queue
+type UpdateQueueResponse struct {
+	Pending []instance.QueuedUpdate `json:"pending"` // In execution order; the running update is not included
+}
+
+// Handle handles GET /api/v1/updates/queue
+// Requests arriving during an update wait here: requests for the same target are coalesced
+// into one follow-up run (update_ids lists them), other targets are queued in order.
+func (h *UpdateQueueHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	pending := h.queue.QueuedUpdates()
+	if pending == nil {
+		pending = []instance.QueuedUpdate{}
+	}
+	writeJSON(w, http.StatusOK, UpdateQueueResponse{Pending: pending}, h.logger)
+}
+
+// HandleDrop handles DELETE /api/v1/updates/queue/{id}
+// Drops a pending update; every request coalesced into it ends cancelled.
+// 404 when the update is not queued (already running, finished or unknown).
+func (h *UpdateQueueHandler) HandleDrop(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	dropped, err := h.queue.DropQueuedUpdate(id)
+	if err != nil {
+		if errors.Is(err, instance.ErrQueuedUpdateNotFound) {
+			writeJSONError(w, http.StatusNotFound, "not_found", err.Error())
+			return
+		}
+		h.logger.Error("Failed to drop queued update", "error", err, "queue_id", id)
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	h.logger.Info("Queued update dropped", "queue_id", id, "update_ids", dropped.UpdateIDs)
+	writeJSON(w, http.StatusOK, dropped, h.logger)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+)
+
+type mockUpdateQueue struct {
+	pending []instance.QueuedUpdate
+	dropped []string
+}
+
+func (m *mockUpdateQueue) QueuedUpdates() []instance.QueuedUpdate {
+	return m.pending
+}
+
+func (m *mockUpdateQueue) DropQueuedUpdate(id string) (instance.QueuedUpdate, error) {
+	for i, item := range m.pending {
+		if item.ID == id {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			m.dropped = append(m.dropped, id)
+			return item, nil
+		}
+	}
+	return instance.QueuedUpdate{}, fmt.Errorf("%w: %s", instance.ErrQueuedUpdateNotFound, id)
+}
+
+func dropQueued(handler *UpdateQueueHandler, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("DELETE", "/api/v1/updates/queue/"+id, nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	handler.HandleDrop(rec, req)
+	return rec
+}
+
+func TestUpdateQueueHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	queue := &mockUpdateQueue{pending: []instance.QueuedUpdate{
+		{ID: "a1", Target: "latest", UpdateIDs: []string{"a1", "b2"}, QueuedAt: time.Now().UTC()},
+		{ID: "c3", Target: "ref:v0.1.4", Force: true, UpdateIDs: []string{"c3"}, QueuedAt: time.Now().UTC()},
+	}}
+	handler := NewUpdateQueueHandler(queue, logger)
+
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("GET", "/api/v1/updates/queue", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp UpdateQueueResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	if len(resp.Pending) != 2 || len(resp.Pending[0].UpdateIDs) != 2 || resp.Pending[1].Target != "ref:v0.1.4" {
+		t.Errorf("pending = %+v, want the coalesced latest run and then ref:v0.1.4", resp.Pending)
+	}
+
+	if rec := dropQueued(handler, "c3"); rec.Code != http.StatusOK {
+		t.Errorf("drop status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := dropQueued(handler, "c3"); rec.Code != http.StatusNotFound {
+		t.Errorf("second drop status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if len(queue.dropped) != 1 || len(queue.pending) != 1 {
+		t.Errorf("dropped = %v, pending = %+v; want only c3 dropped", queue.dropped, queue.pending)
+	}
+}
+
+func TestUpdateQueueHandler_Empty(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := NewUpdateQueueHandler(&mockUpdateQueue{}, logger)
+
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, httptest.NewRequest("GET", "/api/v1/updates/queue", nil))
+	if body := rec.Body.String(); body != "{\"pending\":[]}\n" {
+		t.Errorf("body = %q, want an empty pending list", body)
+	}
+}
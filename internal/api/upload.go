@@ -0,0 +1,158 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+const (
+	// maxUploadSize bounds the multipart request of POST /api/v1/trigger-update/upload
+	maxUploadSize = 256 << 20
+	// uploadMemory is how much of the upload is kept in memory before spilling to a temp file
+	uploadMemory = 32 << 20
+	// uploadReadTimeout replaces the server ReadTimeout (10s) for upload requests
+	uploadReadTimeout = 5 * time.Minute
+)
+
+var (
+	// uploadNamePattern accepts wheel and sdist file names; uv derives the package from the name
+	uploadNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*\.(whl|tar\.gz|zip)$`)
+	// sha256Pattern accepts a hex encoded SHA-256 digest
+	sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// HandleUpload handles POST /api/v1/trigger-update/upload: offline update from an uploaded
+// nanobot wheel or sdist. The multipart form carries the file in "file" and its expected
+// SHA-256 in "sha256". The file is staged in <upload_dir>/<update_id>/, verified and installed
+// with `uv tool install --force <file>` through the normal stop -> install -> start flow,
+// ignoring the configured sources. Like trigger-update the update runs as a background job
+// (202 with update_id, ?wait=true for the result); the staged file is removed afterwards.
+// A checksum mismatch is rejected with 400 and recorded in the update log.
+func (h *TriggerHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	updateID := uuid.New().String()
+	w.Header().Set("X-Update-ID", updateID)
+
+	// Uploads may take longer than the server-wide ReadTimeout
+	if err := http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadReadTimeout)); err != nil {
+		h.logger.Debug("Cannot extend read deadline for upload", "error", err)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "too_large",
+				fmt.Sprintf("Upload exceeds %d MiB", maxUploadSize>>20))
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, "bad_request", "Invalid multipart form: "+err.Error())
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	expected := strings.ToLower(strings.TrimSpace(r.FormValue("sha256")))
+	if !sha256Pattern.MatchString(expected) {
+		writeJSONError(w, http.StatusBadRequest, "bad_request", "sha256 field must be the 64 hex character SHA-256 of the file")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "bad_request", "file field is required")
+		return
+	}
+	defer file.Close()
+	if !uploadNamePattern.MatchString(header.Filename) {
+		writeJSONError(w, http.StatusBadRequest, "bad_request",
+			fmt.Sprintf("file name %q is not a wheel (.whl) or sdist (.tar.gz, .zip)", header.Filename))
+		return
+	}
+
+	wait := false
+	for _, v := range []string{r.URL.Query().Get("wait"), r.FormValue("wait")} {
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("Invalid wait parameter: %q", v))
+			return
+		}
+		wait = wait || b
+	}
+
+	// Don't stage a file that cannot be queued for installation. Only an early answer: the request
+	// is admitted by startJob, a full queue then still rejects it and removes the staged file
+	if h.instanceManager.QueueFull() {
+		h.rejectBusy(w, updateID, "api-trigger")
+		return
+	}
+
+	path, sum, err := h.stageUpload(updateID, header.Filename, file)
+	if err != nil {
+		h.logger.Error("Failed to stage uploaded file", "error", err, "update_id", updateID)
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", "Failed to store upload: "+err.Error())
+		return
+	}
+	stageDir := filepath.Dir(path)
+	if sum != expected {
+		os.RemoveAll(stageDir)
+		reason := fmt.Sprintf("sha256 mismatch for %s: expected %s, got %s", header.Filename, expected, sum)
+		h.logger.Warn("Upload rejected", "reason", reason, "update_id", updateID)
+		h.record(updatelog.BuildRejectedLog(updateID, updatelog.OperationNanobotUpdate, "api-trigger",
+			time.Now().UTC(), reason, nil))
+		writeJSONError(w, http.StatusBadRequest, "checksum_mismatch", reason)
+		return
+	}
+	h.logger.Info("Upload staged", "file", header.Filename, "size", header.Size, "sha256", sum, "update_id", updateID)
+
+	// Uploaded files are always installed, there is no upstream to compare against
+	req := updateRequest{Target: updater.Target{File: path}, Force: true, Wait: wait}
+	h.dispatch(w, r, updateID, req, func() {
+		if err := os.RemoveAll(stageDir); err != nil {
+			h.logger.Warn("Failed to remove staged upload", "error", err, "path", stageDir)
+		}
+	})
+}
+
+// stageUpload copies the uploaded file to <upload_dir>/<update_id>/<name> and returns its
+// absolute path and SHA-256
+func (h *TriggerHandler) stageUpload(updateID, name string, src io.Reader) (string, string, error) {
+	dir, err := filepath.Abs(filepath.Join(h.uploadDir, updateID))
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+	path := filepath.Join(dir, name)
+	dst, err := os.Create(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(dst, hash), src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	return path, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// newUploadRequest builds a multipart POST /api/v1/trigger-update/upload request
+func newUploadRequest(t *testing.T, target, filename string, content []byte, sum string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if sum != "" {
+		if err := mw.WriteField("sha256", sum); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if filename != "" {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest("POST", target, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTriggerHandler_UploadInstallsStagedFile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+	wheel := []byte("fake wheel content")
+	const name = "nanobot_ai-0.2.0-py3-none-any.whl"
+
+	var staged []byte
+	mock := &mockTriggerUpdater{
+		result: &instance.UpdateResult{
+			Stopped: []string{"gateway"}, Started: []string{"gateway"},
+			Target: "upload:" + name, Source: "upload:" + name, SourceType: string(updater.SourceUpload),
+		},
+		onUpdate: func(target updater.Target) {
+			staged, _ = os.ReadFile(target.File)
+		},
+	}
+	handler := newTestHandler(logger, ul, mock, nil)
+	uploadDir := t.TempDir()
+	handler.SetUploadDir(uploadDir)
+
+	rec := httptest.NewRecorder()
+	handler.HandleUpload(rec, newUploadRequest(t, "/api/v1/trigger-update/upload?wait=true", name, wheel, strings.ToUpper(sha256Hex(wheel))))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status code = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if filepath.Base(mock.lastTarget.File) != name || !mock.lastForce {
+		t.Errorf("TriggerUpdate target = %+v force = %v, want the staged %s with force", mock.lastTarget, mock.lastForce, name)
+	}
+	if !bytes.Equal(staged, wheel) {
+		t.Errorf("staged file content = %q, want the uploaded wheel", staged)
+	}
+
+	var response APIUpdateResult
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response JSON: %v", err)
+	}
+	if response.SourceType != "upload" {
+		t.Errorf("response source_type = %q, want upload", response.SourceType)
+	}
+	logs := ul.GetAll()
+	if len(logs) != 1 || logs[0].SourceType != "upload" || logs[0].ID != rec.Header().Get("X-Update-ID") {
+		t.Errorf("recorded logs = %+v, want one upload log keyed by X-Update-ID", logs)
+	}
+
+	// The staging directory is removed once the job has finished
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries, _ := os.ReadDir(uploadDir)
+		if len(entries) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("staged upload not removed: %v", entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTriggerHandler_UploadChecksumMismatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ul := updatelog.NewUpdateLogger(logger, "")
+	mock := &mockTriggerUpdater{}
+	handler := newTestHandler(logger, ul, mock, nil)
+	uploadDir := t.TempDir()
+	handler.SetUploadDir(uploadDir)
+
+	rec := httptest.NewRecorder()
+	handler.HandleUpload(rec, newUploadRequest(t, "/api/v1/trigger-update/upload", "nanobot_ai-0.2.0-py3-none-any.whl",
+		[]byte("tampered"), sha256Hex([]byte("original"))))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Status code = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if mock.calls != 0 {
+		t.Error("TriggerUpdate called although the checksum did not match")
+	}
+	if entries, _ := os.ReadDir(uploadDir); len(entries) != 0 {
+		t.Errorf("rejected upload left staged files: %v", entries)
+	}
+	logs := ul.GetAll()
+	if len(logs) != 1 || logs[0].Status != updatelog.StatusRejected || !strings.Contains(logs[0].RejectReason, "sha256 mismatch") {
+		t.Errorf("recorded logs = %+v, want a rejected log with the checksum mismatch", logs)
+	}
+}
+
+func TestTriggerHandler_UploadInvalidRequests(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	wheel := []byte("wheel")
+
+	tests := []struct {
+		name      string
+		filename  string
+		sum       string
+		queueFull bool
+		want      int
+	}{
+		{"missing sha256", "nanobot_ai-0.2.0-py3-none-any.whl", "", false, http.StatusBadRequest},
+		{"malformed sha256", "nanobot_ai-0.2.0-py3-none-any.whl", "abc", false, http.StatusBadRequest},
+		{"missing file", "", sha256Hex(wheel), false, http.StatusBadRequest},
+		{"not a wheel", "install.sh", sha256Hex(wheel), false, http.StatusBadRequest},
+		{"update queue full", "nanobot_ai-0.2.0-py3-none-any.whl", sha256Hex(wheel), true, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockTriggerUpdater{queueFull: tt.queueFull}
+			handler := newTestHandler(logger, updatelog.NewUpdateLogger(logger, ""), mock, nil)
+			uploadDir := t.TempDir()
+			handler.SetUploadDir(uploadDir)
+
+			rec := httptest.NewRecorder()
+			handler.HandleUpload(rec, newUploadRequest(t, "/api/v1/trigger-update/upload", tt.filename, wheel, tt.sum))
+
+			if rec.Code != tt.want {
+				t.Errorf("Status code = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if mock.calls != 0 {
+				t.Error("TriggerUpdate called for an invalid upload")
+			}
+			if entries, _ := os.ReadDir(uploadDir); len(entries) != 0 {
+				t.Errorf("invalid upload left staged files: %v", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,415 @@
+// Package approval implements two-step nanobot updates (approval config): the scheduler and
+// POST /api/v1/trigger-update create a pending update, which only runs once it is approved via
+// POST /api/v1/updates/{id}/approve or the link in the Pushover notification. Pending updates
+// expire after approval.timeout. Every state change is recorded in the update log as an
+// update-approval record with the update id the approved nanobot-update run uses.
+package approval
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// maxDecidedUpdates is how many approved, rejected or expired updates stay listed at
+// GET /api/v1/approvals; older ones remain in /api/v1/update-logs
+const maxDecidedUpdates = 50
+
+// State is the approval state of an update
+type State string
+
+const (
+	StatePending  State = "pending"
+	StateApproved State = "approved"
+	StateRejected State = "rejected"
+	StateExpired  State = "expired"
+)
+
+var (
+	// ErrNotFound is returned for an unknown update id
+	ErrNotFound = errors.New("pending update not found")
+	// ErrNotPending is returned when the update was already approved, rejected or has expired
+	ErrNotPending = errors.New("update is no longer pending")
+)
+
+// Recorder records update-approval records.
+// Satisfied by *updatelog.UpdateLogger via duck typing.
+type Recorder interface {
+	Record(log updatelog.UpdateLog) error
+}
+
+// Notifier sends notifications.
+// Satisfied by *notifier.Notifier via duck typing.
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// urlNotifier is implemented by notifiers that can attach a link (*notifier.Notifier)
+type urlNotifier interface {
+	NotifyWithURL(title, message, url, urlTitle string) error
+}
+
+// Request describes the update that waits for approval
+type Request struct {
+	Target      updater.Target
+	Force       bool
+	TriggeredBy string               // "scheduler" or "api-trigger", recorded on the nanobot-update run
+	Check       *updater.CheckResult // Update check at creation time (nil if the check failed)
+}
+
+// Update is a pending or decided update
+type Update struct {
+	ID           string               `json:"id"` // Also the update_id of the nanobot-update run once approved
+	State        State                `json:"state"`
+	Target       updater.Target       `json:"-"`
+	TargetName   string               `json:"target"` // latest, ref:..., commit:..., pypi:...
+	Force        bool                 `json:"force"`
+	TriggeredBy  string               `json:"triggered_by"`
+	NewVersion   string               `json:"new_version,omitempty"`   // Version or commit the update would install
+	ChangelogURL string               `json:"changelog_url,omitempty"` // What the update changes
+	Check        *updater.CheckResult `json:"check,omitempty"`
+	CreatedAt    time.Time            `json:"created_at"`
+	ExpiresAt    time.Time            `json:"expires_at"`
+	DecidedAt    *time.Time           `json:"decided_at,omitempty"`
+	DecidedBy    string               `json:"decided_by,omitempty"`    // "api" (Bearer token) or "link" (notification link)
+	RejectReason string               `json:"reject_reason,omitempty"` // state=rejected
+}
+
+// entry is a stored update with its link token and expiry timer
+type entry struct {
+	Update
+	token string
+	timer *time.Timer
+}
+
+// Store holds pending updates and the recently decided ones
+type Store struct {
+	mu       sync.Mutex
+	cfg      config.ApprovalConfig
+	updates  map[string]*entry
+	order    []string // ids, oldest first
+	recorder Recorder
+	notifier Notifier
+	logger   *slog.Logger
+	now      func() time.Time // overrideable for tests
+}
+
+// NewStore creates the approval store. recorder and notifier may be nil.
+func NewStore(cfg config.ApprovalConfig, recorder Recorder, notifier Notifier, logger *slog.Logger) *Store {
+	return &Store{
+		cfg:      cfg,
+		updates:  make(map[string]*entry),
+		recorder: recorder,
+		notifier: notifier,
+		logger:   logger.With("source", "update-approval"),
+		now:      time.Now,
+	}
+}
+
+// Enabled reports whether updates need approval. A nil store never requires approval.
+func (s *Store) Enabled() bool {
+	return s != nil && s.cfg.Enabled
+}
+
+// Create stores a pending update for req, records it and sends the approval notification.
+// id "" generates one. While an update for the same target is still pending, that update is
+// returned instead and created is false, so a repeated schedule does not pile up approvals.
+// A request with force upgrades that pending update to force, like coalesced queued updates.
+func (s *Store) Create(id string, req Request) (update Update, created bool) {
+	s.mu.Lock()
+	target := req.Target.String()
+	for _, existing := range s.order {
+		if e := s.updates[existing]; e.State == StatePending && e.TargetName == target {
+			upgraded := req.Force && !e.Force
+			e.Force = e.Force || req.Force
+			update = e.snapshot()
+			s.mu.Unlock()
+			s.logger.Info("Update for this target already pending approval", "update_id", e.ID, "target", target, "force", update.Force, "force_upgraded", upgraded, "triggered_by", req.TriggeredBy)
+			return update, false
+		}
+	}
+
+	if id == "" {
+		id = uuid.New().String()
+	}
+	now := s.now().UTC()
+	e := &entry{
+		Update: Update{
+			ID:          id,
+			State:       StatePending,
+			Target:      req.Target,
+			TargetName:  target,
+			Force:       req.Force,
+			TriggeredBy: req.TriggeredBy,
+			NewVersion:  newVersion(req.Check),
+			Check:       req.Check,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.cfg.EffectiveTimeout()),
+		},
+		token: newToken(),
+	}
+	if req.Check != nil {
+		e.ChangelogURL = req.Check.ChangelogURL
+	}
+	e.timer = time.AfterFunc(s.cfg.EffectiveTimeout(), func() { s.expire(id) })
+	s.updates[id] = e
+	s.order = append(s.order, id)
+	s.pruneLocked()
+	update = e.snapshot()
+	link := s.cfg.ApprovalURL(id, e.token)
+	s.mu.Unlock()
+
+	s.logger.Info("Update waiting for approval", "update_id", id, "target", target, "new_version", update.NewVersion, "expires_at", update.ExpiresAt, "triggered_by", req.TriggeredBy)
+	s.record(update, updatelog.StatusPendingApproval)
+	s.notifyPending(update, link)
+	return update, true
+}
+
+// Approve marks the pending update id as approved and returns it; the caller starts the update.
+// by is recorded as the decider ("api", "link").
+func (s *Store) Approve(id, by string) (Update, error) {
+	update, err := s.decide(id, StateApproved, by, "")
+	if err != nil {
+		return update, err
+	}
+	s.logger.Info("Update approved", "update_id", id, "decided_by", by, "target", update.TargetName)
+	s.record(update, updatelog.StatusApproved)
+	return update, nil
+}
+
+// Reject marks the pending update id as rejected. reason is optional.
+func (s *Store) Reject(id, by, reason string) (Update, error) {
+	update, err := s.decide(id, StateRejected, by, reason)
+	if err != nil {
+		return update, err
+	}
+	s.logger.Info("Update rejected", "update_id", id, "decided_by", by, "reason", reason)
+	s.record(update, updatelog.StatusRejected)
+	return update, nil
+}
+
+// decide moves the pending update id to state. A pending update past its expiry is expired
+// first and ErrNotPending is returned.
+func (s *Store) decide(id string, state State, by, reason string) (Update, error) {
+	s.mu.Lock()
+	e, ok := s.updates[id]
+	if !ok {
+		s.mu.Unlock()
+		return Update{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if e.State == StatePending && !s.now().Before(e.ExpiresAt) {
+		s.mu.Unlock()
+		s.expire(id)
+		s.mu.Lock()
+	}
+	if e.State != StatePending {
+		update := e.snapshot()
+		s.mu.Unlock()
+		return update, fmt.Errorf("%w: %s is %s", ErrNotPending, id, update.State)
+	}
+	s.closeLocked(e, state, by)
+	e.RejectReason = reason
+	update := e.snapshot()
+	s.mu.Unlock()
+	return update, nil
+}
+
+// expire marks the update id as expired if it is still pending, records it and notifies
+func (s *Store) expire(id string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Approval expiry panic", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	s.mu.Lock()
+	e, ok := s.updates[id]
+	if !ok || e.State != StatePending {
+		s.mu.Unlock()
+		return
+	}
+	s.closeLocked(e, StateExpired, "")
+	update := e.snapshot()
+	s.mu.Unlock()
+
+	s.logger.Warn("Pending update expired without approval", "update_id", id, "target", update.TargetName)
+	s.record(update, updatelog.StatusExpired)
+	s.notify("Nanobot 更新审批已过期", fmt.Sprintf("更新 %s 未在 %s 前审批, 已取消\n目标版本: %s",
+		id, update.ExpiresAt.Local().Format("2006-01-02 15:04"), versionText(update)))
+}
+
+// closeLocked ends the pending state of e. Caller holds mu.
+func (s *Store) closeLocked(e *entry, state State, by string) {
+	now := s.now().UTC()
+	e.State = state
+	e.DecidedAt = &now
+	e.DecidedBy = by
+	e.token = ""
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	s.pruneLocked()
+}
+
+// pruneLocked drops the oldest decided updates beyond maxDecidedUpdates. Caller holds mu.
+func (s *Store) pruneLocked() {
+	decided := 0
+	for _, id := range s.order {
+		if s.updates[id].State != StatePending {
+			decided++
+		}
+	}
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if decided > maxDecidedUpdates && s.updates[id].State != StatePending {
+			delete(s.updates, id)
+			decided--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+// ValidToken reports whether token is the notification link token of the pending update id
+func (s *Store) ValidToken(id, token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.updates[id]
+	if !ok || e.token == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(e.token), []byte(token)) == 1
+}
+
+// Get returns the update with the given id
+func (s *Store) Get(id string) (Update, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.updates[id]
+	if !ok {
+		return Update{}, false
+	}
+	return e.snapshot(), true
+}
+
+// List returns pending and recently decided updates, newest first
+func (s *Store) List() []Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	updates := make([]Update, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		updates = append(updates, s.updates[s.order[i]].snapshot())
+	}
+	return updates
+}
+
+// snapshot returns a copy of the update safe to use without the store lock
+func (e *entry) snapshot() Update {
+	update := e.Update
+	if e.DecidedAt != nil {
+		decidedAt := *e.DecidedAt
+		update.DecidedAt = &decidedAt
+	}
+	return update
+}
+
+// record writes the update-approval record for update
+func (s *Store) record(update Update, status updatelog.UpdateStatus) {
+	if s.recorder == nil {
+		return
+	}
+	at := update.CreatedAt
+	if update.DecidedAt != nil {
+		at = *update.DecidedAt
+	}
+	log := updatelog.BuildApprovalLog(update.ID, update.TriggeredBy, update.TargetName, status, at, update.RejectReason,
+		updatelog.ApprovalInfo{
+			NewVersion:   update.NewVersion,
+			ChangelogURL: update.ChangelogURL,
+			ExpiresAt:    update.ExpiresAt,
+			DecidedBy:    update.DecidedBy,
+		})
+	if err := s.recorder.Record(log); err != nil {
+		s.logger.Error("Failed to record update log", "error", err, "update_id", update.ID)
+	}
+}
+
+// notifyPending sends the approval request, with the approval link when approval.public_url is set
+func (s *Store) notifyPending(update Update, link string) {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("触发来源: %s\n", update.TriggeredBy))
+	msg.WriteString(fmt.Sprintf("目标版本: %s\n", versionText(update)))
+	if update.Check != nil && update.Check.InstalledVersion != "" {
+		msg.WriteString(fmt.Sprintf("已安装版本: %s\n", update.Check.InstalledVersion))
+	}
+	if update.ChangelogURL != "" {
+		msg.WriteString(fmt.Sprintf("变更: %s\n", update.ChangelogURL))
+	}
+	msg.WriteString(fmt.Sprintf("请在 %s 前审批\n", update.ExpiresAt.Local().Format("2006-01-02 15:04")))
+	msg.WriteString(fmt.Sprintf("POST /api/v1/updates/%s/approve", update.ID))
+
+	title := "Nanobot 更新待审批"
+	if un, ok := s.notifier.(urlNotifier); ok && link != "" {
+		if err := un.NotifyWithURL(title, msg.String(), link, "审批更新"); err != nil {
+			s.logger.Error("Failed to send approval notification", "error", err, "update_id", update.ID)
+		}
+		return
+	}
+	s.notify(title, msg.String())
+}
+
+// notify sends a notification if a notifier is configured
+func (s *Store) notify(title, message string) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.Notify(title, message); err != nil {
+		s.logger.Error("Failed to send approval notification", "error", err)
+	}
+}
+
+// newVersion returns what the update would install: the PyPI version, or the short commit of git sources
+func newVersion(check *updater.CheckResult) string {
+	if check == nil {
+		return ""
+	}
+	if (check.SourceType == updater.SourceGit && check.LatestCommit != "") || check.LatestVersion == "" {
+		if len(check.LatestCommit) > 7 {
+			return check.LatestCommit[:7]
+		}
+		return check.LatestCommit
+	}
+	return check.LatestVersion
+}
+
+// versionText describes the target of update for notifications
+func versionText(update Update) string {
+	if update.NewVersion != "" {
+		return fmt.Sprintf("%s (%s)", update.NewVersion, update.TargetName)
+	}
+	return update.TargetName
+}
+
+// newToken returns a random token for the approval link
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms; fall back to a random UUID
+		return strings.ReplaceAll(uuid.New().String(), "-", "")
+	}
+	return hex.EncodeToString(b)
+}
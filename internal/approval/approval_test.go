@@ -0,0 +1,221 @@
+package approval
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+type recordedLogs struct {
+	mu   sync.Mutex
+	logs []updatelog.UpdateLog
+}
+
+func (r *recordedLogs) Record(log updatelog.UpdateLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *recordedLogs) statuses() []updatelog.UpdateStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var statuses []updatelog.UpdateStatus
+	for _, log := range r.logs {
+		statuses = append(statuses, log.Status)
+	}
+	return statuses
+}
+
+type sentNotification struct {
+	title, message, url string
+}
+
+type fakeNotifier struct {
+	mu   sync.Mutex
+	sent []sentNotification
+}
+
+func (n *fakeNotifier) Notify(title, message string) error {
+	return n.NotifyWithURL(title, message, "", "")
+}
+
+func (n *fakeNotifier) NotifyWithURL(title, message, url, urlTitle string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, sentNotification{title, message, url})
+	return nil
+}
+
+func (n *fakeNotifier) last() sentNotification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.sent) == 0 {
+		return sentNotification{}
+	}
+	return n.sent[len(n.sent)-1]
+}
+
+func newTestStore(cfg config.ApprovalConfig) (*Store, *recordedLogs, *fakeNotifier) {
+	recorder, notifier := &recordedLogs{}, &fakeNotifier{}
+	return NewStore(cfg, recorder, notifier, slog.New(slog.NewTextHandler(io.Discard, nil))), recorder, notifier
+}
+
+func testCheck() *updater.CheckResult {
+	return &updater.CheckResult{
+		SourceType:       updater.SourcePyPI,
+		InstalledVersion: "0.1.4",
+		LatestVersion:    "0.2.0",
+		UpdateAvailable:  true,
+		ChangelogURL:     "https://pypi.org/project/nanobot-ai/0.2.0/",
+	}
+}
+
+func TestStore_ApproveWithLinkToken(t *testing.T) {
+	store, recorder, notifier := newTestStore(config.ApprovalConfig{Enabled: true, Timeout: time.Hour, PublicURL: "https://bot.example.com/"})
+
+	update, created := store.Create("upd-1", Request{TriggeredBy: "scheduler", Check: testCheck()})
+	if !created || update.State != StatePending || update.NewVersion != "0.2.0" || update.TargetName != "latest" {
+		t.Fatalf("Create() = %+v, %v; want a pending update for 0.2.0", update, created)
+	}
+
+	// 通知包含版本、变更链接和带令牌的审批链接
+	sent := notifier.last()
+	if !strings.Contains(sent.message, "0.2.0") || !strings.Contains(sent.message, update.ChangelogURL) {
+		t.Errorf("notification message = %q, want the new version and changelog link", sent.message)
+	}
+	if !strings.HasPrefix(sent.url, "https://bot.example.com/api/v1/updates/upd-1/approve?token=") {
+		t.Fatalf("notification url = %q, want the approval link", sent.url)
+	}
+	token := sent.url[strings.Index(sent.url, "token=")+len("token="):]
+	if !store.ValidToken("upd-1", token) || store.ValidToken("upd-1", "wrong") || store.ValidToken("other", token) {
+		t.Error("ValidToken() should accept only the link token of upd-1")
+	}
+
+	// 同一目标仍在等待审批时不创建新的更新
+	if again, created := store.Create("upd-2", Request{TriggeredBy: "scheduler"}); created || again.ID != "upd-1" || again.Force {
+		t.Errorf("second Create() = %s, %v; want the pending upd-1", again.ID, created)
+	}
+	// 带 force 的请求把等待中的更新升级为强制重新安装, 之后不带 force 的请求不会取消它
+	if again, created := store.Create("upd-3", Request{Force: true, TriggeredBy: "api-trigger"}); created || again.ID != "upd-1" || !again.Force {
+		t.Errorf("Create() with force = %+v, %v; want the pending upd-1 upgraded to force", again, created)
+	}
+	if again, _ := store.Create("upd-4", Request{TriggeredBy: "scheduler"}); !again.Force {
+		t.Error("Create() without force downgraded the pending update")
+	}
+
+	approved, err := store.Approve("upd-1", "link")
+	if err != nil || approved.State != StateApproved || approved.DecidedBy != "link" || approved.DecidedAt == nil || !approved.Force {
+		t.Fatalf("Approve() = %+v, %v; want approved by link", approved, err)
+	}
+	if store.ValidToken("upd-1", token) {
+		t.Error("link token still valid after the update was approved")
+	}
+	if _, err := store.Approve("upd-1", "api"); !errors.Is(err, ErrNotPending) {
+		t.Errorf("second Approve() = %v, want ErrNotPending", err)
+	}
+	if _, err := store.Reject("missing", "api", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Reject(missing) = %v, want ErrNotFound", err)
+	}
+
+	if got := recorder.statuses(); len(got) != 2 || got[0] != updatelog.StatusPendingApproval || got[1] != updatelog.StatusApproved {
+		t.Errorf("recorded statuses = %v, want [pending_approval approved]", got)
+	}
+	if log := recorder.logs[1]; log.ID != "upd-1" || log.Operation != updatelog.OperationUpdateApproval || log.Approval.DecidedBy != "link" {
+		t.Errorf("approved record = %+v", log)
+	}
+}
+
+func TestStore_Reject(t *testing.T) {
+	store, recorder, notifier := newTestStore(config.ApprovalConfig{Enabled: true, Timeout: time.Hour})
+
+	update, _ := store.Create("", Request{Target: updater.Target{Ref: "v0.2.0"}, TriggeredBy: "api-trigger"})
+	if update.ID == "" || update.TargetName != "ref:v0.2.0" {
+		t.Fatalf("Create() = %+v, want a generated id for ref:v0.2.0", update)
+	}
+	if sent := notifier.last(); sent.url != "" {
+		t.Errorf("notification url = %q, want none without approval.public_url", sent.url)
+	}
+
+	rejected, err := store.Reject(update.ID, "api", "freeze week")
+	if err != nil || rejected.State != StateRejected || rejected.RejectReason != "freeze week" {
+		t.Fatalf("Reject() = %+v, %v", rejected, err)
+	}
+	if got := recorder.statuses(); len(got) != 2 || got[1] != updatelog.StatusRejected || recorder.logs[1].RejectReason != "freeze week" {
+		t.Errorf("recorded statuses = %v, want a rejected record with its reason", got)
+	}
+	if list := store.List(); len(list) != 1 || list[0].State != StateRejected {
+		t.Errorf("List() = %+v, want the rejected update", list)
+	}
+}
+
+func TestStore_Expires(t *testing.T) {
+	store, recorder, notifier := newTestStore(config.ApprovalConfig{Enabled: true, Timeout: 50 * time.Millisecond})
+
+	update, _ := store.Create("upd-1", Request{TriggeredBy: "scheduler"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if got, _ := store.Get(update.ID); got.State == StateExpired {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pending update did not expire")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := store.Approve(update.ID, "api"); !errors.Is(err, ErrNotPending) {
+		t.Errorf("Approve() after expiry = %v, want ErrNotPending", err)
+	}
+	if got := recorder.statuses(); len(got) != 2 || got[1] != updatelog.StatusExpired {
+		t.Errorf("recorded statuses = %v, want [pending_approval expired]", got)
+	}
+	if sent := notifier.last(); !strings.Contains(sent.title, "过期") {
+		t.Errorf("last notification = %+v, want the expiry notice", sent)
+	}
+
+	// 过期后同一目标可以重新创建
+	if _, created := store.Create("upd-2", Request{TriggeredBy: "scheduler"}); !created {
+		t.Error("Create() after expiry should create a new pending update")
+	}
+}
+
+func TestStore_ApproveAfterDeadlineExpires(t *testing.T) {
+	store, recorder, _ := newTestStore(config.ApprovalConfig{Enabled: true, Timeout: time.Hour})
+	update, _ := store.Create("upd-1", Request{TriggeredBy: "scheduler"})
+
+	// 计时器尚未触发, 但已超过过期时间
+	store.now = func() time.Time { return update.ExpiresAt.Add(time.Second) }
+	if _, err := store.Approve("upd-1", "api"); !errors.Is(err, ErrNotPending) {
+		t.Errorf("Approve() past expires_at = %v, want ErrNotPending", err)
+	}
+	if got := recorder.statuses(); len(got) != 2 || got[1] != updatelog.StatusExpired {
+		t.Errorf("recorded statuses = %v, want [pending_approval expired]", got)
+	}
+}
+
+func TestNewVersion(t *testing.T) {
+	tests := []struct {
+		check    *updater.CheckResult
+		expected string
+	}{
+		{nil, ""},
+		{&updater.CheckResult{SourceType: updater.SourcePyPI, LatestVersion: "0.2.0", LatestCommit: "1111111111"}, "0.2.0"},
+		{&updater.CheckResult{SourceType: updater.SourceGit, LatestVersion: "0.2.0", LatestCommit: "1111111111"}, "1111111"},
+		{&updater.CheckResult{SourceType: updater.SourceLocal}, ""},
+	}
+	for _, tt := range tests {
+		if got := newVersion(tt.check); got != tt.expected {
+			t.Errorf("newVersion(%+v) = %q, want %q", tt.check, got, tt.expected)
+		}
+	}
+}
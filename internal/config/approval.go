@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultApprovalTimeout is how long a pending update waits for approval before it expires.
+const DefaultApprovalTimeout = 24 * time.Hour
+
+// ApprovalConfig makes nanobot updates two-step: scheduled updates and trigger-update requests
+// create a pending update that only runs once it is approved (API or the link in the Pushover
+// notification). Pending updates expire after Timeout.
+type ApprovalConfig struct {
+	Enabled   bool          `yaml:"enabled" mapstructure:"enabled"`       // 是否需要审批 (默认 false)
+	Timeout   time.Duration `yaml:"timeout" mapstructure:"timeout"`       // 未审批的更新在该时间后过期 (默认 24h)
+	PublicURL string        `yaml:"public_url" mapstructure:"public_url"` // 通知中审批链接的地址前缀, 如 https://nanobot.example.com:8080; 为空时通知不带链接
+}
+
+// Validate validates the ApprovalConfig values.
+func (a *ApprovalConfig) Validate() error {
+	if a.Timeout < 0 || (a.Timeout > 0 && a.Timeout < time.Minute) {
+		return fmt.Errorf("approval.timeout 至少为 1m，当前值: %v", a.Timeout)
+	}
+	if a.PublicURL != "" {
+		u, err := url.Parse(a.PublicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("approval.public_url 必须是 http(s) 地址，当前值: %q", a.PublicURL)
+		}
+	}
+	return nil
+}
+
+// EffectiveTimeout returns the configured timeout, defaulting to DefaultApprovalTimeout.
+func (a *ApprovalConfig) EffectiveTimeout() time.Duration {
+	if a.Timeout <= 0 {
+		return DefaultApprovalTimeout
+	}
+	return a.Timeout
+}
+
+// ApprovalURL returns the approval link for update id with its one-time token,
+// or "" when no public_url is configured.
+func (a *ApprovalConfig) ApprovalURL(id, token string) string {
+	if a.PublicURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/api/v1/updates/%s/approve?token=%s",
+		strings.TrimSuffix(a.PublicURL, "/"), url.PathEscape(id), url.QueryEscape(token))
+}
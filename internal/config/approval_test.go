@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&ApprovalConfig{}).Validate())
+	assert.NoError(t, (&ApprovalConfig{Enabled: true, Timeout: 2 * time.Hour, PublicURL: "https://bot.example.com:8080"}).Validate())
+
+	err := (&ApprovalConfig{Timeout: 10 * time.Second}).Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "approval.timeout")
+	}
+	err = (&ApprovalConfig{PublicURL: "bot.example.com"}).Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "approval.public_url")
+	}
+}
+
+func TestApprovalConfig_ApprovalURL(t *testing.T) {
+	assert.Equal(t, "", (&ApprovalConfig{}).ApprovalURL("id-1", "tok"))
+	assert.Equal(t, "https://bot.example.com/api/v1/updates/id-1/approve?token=tok",
+		(&ApprovalConfig{PublicURL: "https://bot.example.com/"}).ApprovalURL("id-1", "tok"))
+	assert.Equal(t, DefaultApprovalTimeout, (&ApprovalConfig{}).EffectiveTimeout())
+}
+
+func TestLoad_Approval(t *testing.T) {
+	yaml := `api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "inst"
+    port: 18790
+    start_command: "nanobot gateway"
+`
+	cfg, err := Load(writeTempConfig(t, yaml))
+	require.NoError(t, err)
+	assert.False(t, cfg.Approval.Enabled)
+	assert.Equal(t, DefaultApprovalTimeout, cfg.Approval.Timeout)
+
+	cfg, err = Load(writeTempConfig(t, yaml+"approval:\n  enabled: true\n  timeout: 2h\n  public_url: \"https://bot.example.com\"\n"))
+	require.NoError(t, err)
+	assert.True(t, cfg.Approval.Enabled)
+	assert.Equal(t, 2*time.Hour, cfg.Approval.Timeout)
+	assert.Equal(t, "https://bot.example.com", cfg.Approval.PublicURL)
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,13 +20,17 @@ type PushoverConfig struct {
 
 // Config holds the main application configuration.
 type Config struct {
-	Instances  []InstanceConfig  `yaml:"instances" mapstructure:"instances"`
-	Pushover   PushoverConfig    `yaml:"pushover" mapstructure:"pushover"`
-	API        APIConfig         `yaml:"api" mapstructure:"api"`                    // HTTP API server config (CONF-02, CONF-03)
-	Monitor    MonitorConfig     `yaml:"monitor" mapstructure:"monitor"`            // Monitoring service config (CONF-04, CONF-05)
-	HealthCheck HealthCheckConfig `yaml:"health_check" mapstructure:"health_check"` // Instance health monitoring config (HEALTH-01)
-	SelfUpdate SelfUpdateConfig  `yaml:"self_update" mapstructure:"self_update"`   // Self-update config (UPDATE-07)
-	Service    ServiceConfig    `yaml:"service" mapstructure:"service"`           // Service mode config (MGR-01)
+	Instances      []InstanceConfig     `yaml:"instances" mapstructure:"instances"`
+	Pushover       PushoverConfig       `yaml:"pushover" mapstructure:"pushover"`
+	API            APIConfig            `yaml:"api" mapstructure:"api"`                         // HTTP API server config (CONF-02, CONF-03)
+	Monitor        MonitorConfig        `yaml:"monitor" mapstructure:"monitor"`                 // Monitoring service config (CONF-04, CONF-05)
+	HealthCheck    HealthCheckConfig    `yaml:"health_check" mapstructure:"health_check"`       // Instance health monitoring config (HEALTH-01)
+	SelfUpdate     SelfUpdateConfig     `yaml:"self_update" mapstructure:"self_update"`         // Self-update config (UPDATE-07)
+	Service        ServiceConfig        `yaml:"service" mapstructure:"service"`                 // Service mode config (MGR-01)
+	Updater        UpdaterConfig        `yaml:"updater" mapstructure:"updater"`                 // Nanobot update process config (rollback)
+	UpdateSchedule UpdateScheduleConfig `yaml:"update_schedule" mapstructure:"update_schedule"` // Cron-scheduled nanobot updates
+	Hooks          HooksConfig          `yaml:"hooks" mapstructure:"hooks"`                     // Commands run before/after nanobot updates
+	Approval       ApprovalConfig       `yaml:"approval" mapstructure:"approval"`               // Updates wait for approval before they run
 }
 
 // defaults sets the default values for the configuration.
@@ -52,6 +59,23 @@ func (c *Config) defaults() {
 	c.Service.AutoStart = nil // nil = false, unconfigured behaves same as current (D-02)
 	c.Service.ServiceName = "NanobotAutoUpdater"
 	c.Service.DisplayName = "Nanobot Auto Updater"
+
+	// Updater defaults: roll back only when every instance fails to start
+	c.Updater.Rollback.Enabled = true
+	c.Updater.Rollback.FailureThreshold = 1.0
+	c.Updater.Canary.SoakPeriod = DefaultCanarySoakPeriod
+	c.Updater.Canary.OnFailure = CanaryOnFailureRollback
+	c.Updater.UploadDir = DefaultUploadDir
+	c.Updater.Strategy = StrategyStopFirst
+	c.Updater.StagingDir = DefaultStagingDir
+
+	// UpdateSchedule defaults: disabled, only update when a new version is available
+	c.UpdateSchedule.Enabled = false
+	c.UpdateSchedule.Mode = ScheduleModeOnlyIfNewVersion
+
+	// Approval defaults: updates run without approval
+	c.Approval.Enabled = false
+	c.Approval.Timeout = DefaultApprovalTimeout
 }
 
 // validateUniqueNames checks for duplicate instance names.
@@ -129,6 +153,41 @@ func (c *Config) Validate() error {
 		errs = append(errs, err)
 	}
 
+	// Validate Updater config
+	if err := c.Updater.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, inst := range c.Instances {
+		if inst.Channel == "" {
+			continue
+		}
+		if _, ok := c.Updater.Channel(inst.Channel); !ok {
+			errs = append(errs, fmt.Errorf("实例 %q 引用的 channel %q 未在 updater.channels 中配置", inst.Name, inst.Channel))
+		}
+	}
+	if c.Updater.IsStaged() {
+		for i := range c.Instances {
+			if err := c.Instances[i].ValidateStaged(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// Validate UpdateSchedule config
+	if err := c.UpdateSchedule.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Validate Hooks config
+	if err := c.Hooks.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Validate Approval config
+	if err := c.Approval.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
 	return errors.Join(errs...)
 }
 
@@ -169,6 +228,9 @@ func ReloadConfig(old *Config) (*Config, error) {
 	if err := viperInstance.Unmarshal(newCfg); err != nil {
 		return old, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if err := restoreEnvCase(newCfg, viperInstance.ConfigFileUsed()); err != nil {
+		return old, fmt.Errorf("failed to read instance env: %w", err)
+	}
 	if err := newCfg.Validate(); err != nil {
 		return old, fmt.Errorf("config validation failed: %w", err)
 	}
@@ -184,14 +246,78 @@ var updateMu sync.Mutex
 // the YAML keys that mapstructure uses for reading.
 func instanceConfigToMap(ic InstanceConfig) map[string]interface{} {
 	m := map[string]interface{}{
-		"name":           ic.Name,
-		"port":           ic.Port,
-		"start_command":  ic.StartCommand,
+		"name":            ic.Name,
+		"port":            ic.Port,
+		"start_command":   ic.StartCommand,
 		"startup_timeout": ic.StartupTimeout,
 	}
 	if ic.AutoStart != nil {
 		m["auto_start"] = *ic.AutoStart
 	}
+	if ic.Channel != "" {
+		m["channel"] = ic.Channel
+	}
+	if ic.Canary {
+		m["canary"] = true
+	}
+	if ic.Restart != (RestartConfig{}) {
+		m["restart"] = restartConfigToMap(ic.Restart)
+	}
+	if ic.Readiness != (ReadinessConfig{}) {
+		m["readiness"] = readinessConfigToMap(ic.Readiness)
+	}
+	if len(ic.Env) > 0 {
+		m["env"] = ic.Env
+	}
+	if ic.EnvFile != "" {
+		m["env_file"] = ic.EnvFile
+	}
+	if ic.WorkingDir != "" {
+		m["working_dir"] = ic.WorkingDir
+	}
+	if ic.OwnHome {
+		m["own_home"] = true
+	}
+	return m
+}
+
+// restartConfigToMap converts a RestartConfig to a map, omitting unset (default) values.
+// Durations are written as strings ("30s") so the file stays readable.
+func restartConfigToMap(rc RestartConfig) map[string]interface{} {
+	m := map[string]interface{}{}
+	if rc.Policy != "" {
+		m["policy"] = rc.Policy
+	}
+	if rc.Backoff != 0 {
+		m["backoff"] = rc.Backoff.String()
+	}
+	if rc.MaxBackoff != 0 {
+		m["max_backoff"] = rc.MaxBackoff.String()
+	}
+	if rc.CrashLoopCount != 0 {
+		m["crash_loop_count"] = rc.CrashLoopCount
+	}
+	if rc.CrashLoopWindow != 0 {
+		m["crash_loop_window"] = rc.CrashLoopWindow.String()
+	}
+	return m
+}
+
+// readinessConfigToMap converts a ReadinessConfig to a map, omitting unset (default) values.
+func readinessConfigToMap(rc ReadinessConfig) map[string]interface{} {
+	m := map[string]interface{}{}
+	if rc.TCP {
+		m["tcp"] = true
+	}
+	if rc.HTTP != "" {
+		m["http"] = rc.HTTP
+	}
+	if rc.LogPattern != "" {
+		m["log_pattern"] = rc.LogPattern
+	}
+	if rc.Interval != 0 {
+		m["interval"] = rc.Interval.String()
+	}
 	return m
 }
 
@@ -222,7 +348,7 @@ func deepCopyConfig(cfg *Config) *Config {
 //
 // This function serializes all callers via updateMu, so concurrent API requests are safe.
 //
-// D-08: Uses viper.WriteConfig() to persist to the same file viper loaded from.
+// D-08: Persists to the same file viper loaded from (atomic temp file + rename).
 // D-09: After writing, hot-reload watcher detects the file change (500ms debounce).
 func UpdateConfig(fn func(*Config) error) error {
 	updateMu.Lock()
@@ -278,7 +404,7 @@ func UpdateConfig(fn func(*Config) error) error {
 		instanceMaps[i] = instanceConfigToMap(ic)
 	}
 	v.Set("instances", instanceMaps)
-	if err := v.WriteConfig(); err != nil {
+	if err := writeConfigAtomic(v); err != nil {
 		if globalHotReload != nil {
 			globalHotReload.skipReload = false
 		}
@@ -299,6 +425,40 @@ func UpdateConfig(fn func(*Config) error) error {
 	return nil
 }
 
+// writeConfigAtomic writes viper's config to a temp file next to the config file and renames
+// it into place. viper's own file watcher calls ReadInConfig() on every write event; with an
+// in-place WriteConfig() it can observe a truncated file and drop keys such as api.bearer_token
+// from viper's state, which the next write would then persist.
+func writeConfigAtomic(v *viper.Viper) error {
+	path := v.ConfigFileUsed()
+	if path == "" {
+		return fmt.Errorf("config file path unknown")
+	}
+	// A symlinked config is replaced at its target, the link itself stays in place
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	ext := filepath.Ext(path)
+	tmpPath := filepath.Join(filepath.Dir(path), "."+strings.TrimSuffix(filepath.Base(path), ext)+".tmp"+ext)
+
+	if err := v.WriteConfigAs(tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// The renamed file keeps the mode of the original (e.g. 0600 for a config with tokens)
+	if info, err := os.Stat(path); err == nil {
+		if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // Load reads configuration from the specified YAML file.
 // Returns Config with defaults applied, then file values, then validation.
 func Load(configPath string) (*Config, error) {
@@ -334,6 +494,23 @@ func Load(configPath string) (*Config, error) {
 	viperInstance.SetDefault("service.service_name", cfg.Service.ServiceName)
 	viperInstance.SetDefault("service.display_name", cfg.Service.DisplayName)
 
+	// Set defaults for Updater config
+	viperInstance.SetDefault("updater.rollback.enabled", cfg.Updater.Rollback.Enabled)
+	viperInstance.SetDefault("updater.rollback.failure_threshold", cfg.Updater.Rollback.FailureThreshold)
+	viperInstance.SetDefault("updater.canary.soak_period", cfg.Updater.Canary.SoakPeriod)
+	viperInstance.SetDefault("updater.canary.on_failure", cfg.Updater.Canary.OnFailure)
+	viperInstance.SetDefault("updater.upload_dir", cfg.Updater.UploadDir)
+	viperInstance.SetDefault("updater.strategy", cfg.Updater.Strategy)
+	viperInstance.SetDefault("updater.staging_dir", cfg.Updater.StagingDir)
+
+	// Set defaults for UpdateSchedule config
+	viperInstance.SetDefault("update_schedule.enabled", cfg.UpdateSchedule.Enabled)
+	viperInstance.SetDefault("update_schedule.mode", cfg.UpdateSchedule.Mode)
+
+	// Set defaults for Approval config
+	viperInstance.SetDefault("approval.enabled", cfg.Approval.Enabled)
+	viperInstance.SetDefault("approval.timeout", cfg.Approval.Timeout)
+
 	// Read config file (optional - use defaults if missing)
 	if err := viperInstance.ReadInConfig(); err != nil {
 		// If file doesn't exist, use defaults
@@ -347,6 +524,10 @@ func Load(configPath string) (*Config, error) {
 	if err := viperInstance.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	// viper 会把 env 的变量名转为小写, 从原始 YAML 恢复
+	if err := restoreEnvCase(cfg, configPath); err != nil {
+		return nil, fmt.Errorf("failed to read instance env: %w", err)
+	}
 
 	// Validate
 	if err := cfg.Validate(); err != nil {
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultInstanceHomeRoot is the parent directory of the own HOME of instances with own_home.
+const DefaultInstanceHomeRoot = "./homes"
+
+// envVarRegex matches ${VAR} references in env and env_file values
+var envVarRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// envNameRegex matches valid environment variable names
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnv validates the variable names of env.
+// env_file and working_dir are read when the instance starts, their errors fail the start.
+func (ic *InstanceConfig) validateEnv() error {
+	for name := range ic.Env {
+		if !envNameRegex.MatchString(name) {
+			return fmt.Errorf("实例 %q env 中的变量名无效: %q", ic.Name, name)
+		}
+		if strings.EqualFold(name, "HOME") && ic.OwnHome {
+			return fmt.Errorf("实例 %q 启用了 own_home，不能在 env 中设置 HOME", ic.Name)
+		}
+	}
+	return nil
+}
+
+// HomeDir returns the own HOME directory of the instance (<DefaultInstanceHomeRoot>/<name>) when
+// own_home is enabled, or "" when the instance uses the HOME of the service.
+func (ic *InstanceConfig) HomeDir() string {
+	if !ic.OwnHome {
+		return ""
+	}
+	dir := filepath.Join(DefaultInstanceHomeRoot, ic.Name)
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// Environ returns the extra environment variables of the instance process as sorted
+// KEY=VALUE pairs: the variables of env_file, overridden by env. ${VAR} in the values is
+// expanded from the earlier env_file variables, HOME of the instance (own_home) and the
+// environment of the service; unknown variables expand to "".
+func (ic *InstanceConfig) Environ() ([]string, error) {
+	vars := map[string]string{}
+	lookup := func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if name == "HOME" && ic.OwnHome {
+			return ic.HomeDir()
+		}
+		return os.Getenv(name)
+	}
+
+	if ic.EnvFile != "" {
+		fileVars, err := readEnvFile(ic.EnvFile, lookup)
+		if err != nil {
+			return nil, fmt.Errorf("instance %q env_file: %w", ic.Name, err)
+		}
+		for name, value := range fileVars {
+			vars[name] = value
+		}
+	}
+
+	// env 的值只引用 env_file 和服务环境, 不引用 env 中的其他变量 (顺序不确定)
+	expanded := make(map[string]string, len(ic.Env))
+	for name, value := range ic.Env {
+		expanded[name] = expandVars(value, lookup)
+	}
+	for name, value := range expanded {
+		vars[name] = value
+	}
+
+	env := make([]string, 0, len(vars))
+	for name, value := range vars {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// expandVars replaces ${VAR} in s with lookup(VAR)
+func expandVars(s string, lookup func(string) string) string {
+	return envVarRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		return lookup(ref[2 : len(ref)-1])
+	})
+}
+
+// readEnvFile reads KEY=VALUE lines of an env file. Blank lines and # comments are skipped,
+// an "export " prefix and quotes around the value are removed. Values are expanded with
+// lookup, which sees the variables of the earlier lines.
+func readEnvFile(path string, lookup func(string) string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := map[string]string{}
+	fileLookup := func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return lookup(name)
+	}
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !envNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			vars[name] = value[1 : len(value)-1] // 单引号内不展开
+			continue
+		}
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		vars[name] = expandVars(value, fileLookup)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// restoreEnvCase restores the case of the instances[].env variable names, which viper
+// lowercases like all keys, from the raw YAML of the config file.
+func restoreEnvCase(cfg *Config, configPath string) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var raw struct {
+		Instances []struct {
+			Env map[string]any `yaml:"env"`
+		} `yaml:"instances"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw.Instances) != len(cfg.Instances) {
+		return nil
+	}
+	for i, rawInst := range raw.Instances {
+		ic := &cfg.Instances[i]
+		if len(ic.Env) == 0 {
+			continue
+		}
+		env := make(map[string]string, len(ic.Env))
+		for name := range rawInst.Env {
+			if value, ok := ic.Env[strings.ToLower(name)]; ok {
+				env[name] = value
+			}
+		}
+		if len(env) == len(ic.Env) {
+			ic.Env = env
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceConfig_Environ(t *testing.T) {
+	t.Setenv("ENV_TEST_BASE", "/srv")
+	envFile := filepath.Join(t.TempDir(), "inst.env")
+	require.NoError(t, os.WriteFile(envFile, []byte(`# comment
+export API_KEY="secret"
+DATA_DIR=${ENV_TEST_BASE}/data
+CACHE_DIR=${DATA_DIR}/cache
+LITERAL='${ENV_TEST_BASE}'
+LOG_LEVEL=info
+`), 0644))
+
+	ic := InstanceConfig{
+		Name:    "inst",
+		EnvFile: envFile,
+		Env: map[string]string{
+			"LOG_LEVEL":   "debug",
+			"OUTPUT_DIR":  "${DATA_DIR}/out",
+			"UNSET_VALUE": "${ENV_TEST_UNSET}",
+		},
+	}
+	env, err := ic.Environ()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"API_KEY=secret",
+		"CACHE_DIR=/srv/data/cache",
+		"DATA_DIR=/srv/data",
+		"LITERAL=${ENV_TEST_BASE}",
+		"LOG_LEVEL=debug",
+		"OUTPUT_DIR=/srv/data/out",
+		"UNSET_VALUE=",
+	}, env)
+
+	// own_home: ${HOME} 指向实例自己的 HOME
+	ic = InstanceConfig{Name: "inst", OwnHome: true, Env: map[string]string{"NANOBOT_DATA": "${HOME}/data"}}
+	env, err = ic.Environ()
+	require.NoError(t, err)
+	home, err := filepath.Abs(filepath.Join(DefaultInstanceHomeRoot, "inst"))
+	require.NoError(t, err)
+	assert.Equal(t, home, ic.HomeDir())
+	assert.Equal(t, []string{"NANOBOT_DATA=" + filepath.Join(home, "data")}, env)
+
+	ic = InstanceConfig{Name: "inst", EnvFile: filepath.Join(t.TempDir(), "missing.env")}
+	_, err = ic.Environ()
+	assert.Error(t, err)
+}
+
+func TestInstanceConfig_ValidateEnv(t *testing.T) {
+	base := InstanceConfig{Name: "inst", Port: 18790, StartCommand: "nanobot gateway"}
+
+	ic := base
+	ic.Env = map[string]string{"GOOD_NAME": "x"}
+	assert.NoError(t, ic.Validate())
+
+	ic.Env = map[string]string{"1BAD": "x"}
+	assert.ErrorContains(t, ic.Validate(), "env")
+
+	ic.Env = map[string]string{"HOME": "/tmp"}
+	ic.OwnHome = true
+	assert.ErrorContains(t, ic.Validate(), "own_home")
+}
+
+func TestUpdateConfig_PreservesEnvCase(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "inst"
+    port: 18790
+    start_command: "nanobot gateway"
+    env:
+      OPENAI_API_KEY: "${OPENAI_KEY}"
+      Mixed_Case: "1"
+`), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"OPENAI_API_KEY": "${OPENAI_KEY}", "Mixed_Case": "1"}, cfg.Instances[0].Env)
+
+	WatchConfig(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), &HotReloadCallbacks{})
+	err = UpdateConfig(func(c *Config) error {
+		c.Instances[0].Env["HTTPS_PROXY"] = "http://proxy:3128"
+		c.Instances[0].WorkingDir = "/srv/inst"
+		c.Instances[0].OwnHome = true
+		return nil
+	})
+	require.NoError(t, err)
+	StopWatch()
+	viperInstance = nil
+
+	newCfg, err := Load(configPath)
+	require.NoError(t, err)
+	ic := newCfg.Instances[0]
+	assert.Equal(t, map[string]string{
+		"OPENAI_API_KEY": "${OPENAI_KEY}",
+		"Mixed_Case":     "1",
+		"HTTPS_PROXY":    "http://proxy:3128",
+	}, ic.Env)
+	assert.Equal(t, "/srv/inst", ic.WorkingDir)
+	assert.True(t, ic.OwnHome)
+}
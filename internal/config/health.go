@@ -2,12 +2,36 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
+// Defaults of LivenessConfig
+const (
+	DefaultLivenessTimeout          = 5 * time.Second
+	DefaultLivenessFailureThreshold = 3
+	DefaultLivenessSuccessThreshold = 1
+)
+
 // HealthCheckConfig holds configuration for instance health monitoring.
 type HealthCheckConfig struct {
-	Interval time.Duration `yaml:"interval" mapstructure:"interval"` // 健康检查间隔
+	Interval time.Duration  `yaml:"interval" mapstructure:"interval"` // 健康检查间隔
+	Liveness LivenessConfig `yaml:"liveness" mapstructure:"liveness"` // 运行中实例的存活检查, 未配置时只检查进程是否存在
+}
+
+// LivenessConfig are the liveness probes run on every running instance at each health check.
+// An instance becomes unhealthy after FailureThreshold consecutive failed checks and healthy
+// again after SuccessThreshold consecutive passed checks. With Restart, an instance that
+// becomes unhealthy is restarted.
+type LivenessConfig struct {
+	TCP              bool          `yaml:"tcp" mapstructure:"tcp"`                             // 连接实例端口
+	HTTP             string        `yaml:"http" mapstructure:"http"`                           // GET http://127.0.0.1:<port><http>, 如 "/health"
+	HTTPStatus       int           `yaml:"http_status" mapstructure:"http_status"`             // 期望的状态码, 0 表示任意 2xx/3xx
+	LogSilence       time.Duration `yaml:"log_silence" mapstructure:"log_silence"`             // 实例超过该时间没有输出视为失败, 0 表示不检查
+	Timeout          time.Duration `yaml:"timeout" mapstructure:"timeout"`                     // TCP/HTTP 检查超时 (默认 5s)
+	FailureThreshold int           `yaml:"failure_threshold" mapstructure:"failure_threshold"` // 连续失败该次数后视为不健康 (默认 3)
+	SuccessThreshold int           `yaml:"success_threshold" mapstructure:"success_threshold"` // 不健康后连续成功该次数恢复健康 (默认 1)
+	Restart          bool          `yaml:"restart" mapstructure:"restart"`                     // 不健康时重启实例
 }
 
 // Validate validates the HealthCheckConfig values.
@@ -21,5 +45,51 @@ func (h *HealthCheckConfig) Validate() error {
 		return fmt.Errorf("health_check.interval 不能超过 10 分钟，当前值: %v", h.Interval)
 	}
 
+	return h.Liveness.Validate()
+}
+
+// Validate validates the LivenessConfig values.
+func (l *LivenessConfig) Validate() error {
+	if l.HTTP != "" && !strings.HasPrefix(l.HTTP, "/") {
+		return fmt.Errorf("health_check.liveness.http 必须是以 / 开头的路径，当前值: %q", l.HTTP)
+	}
+	if l.HTTPStatus != 0 && (l.HTTPStatus < 100 || l.HTTPStatus > 599) {
+		return fmt.Errorf("health_check.liveness.http_status 必须是有效的 HTTP 状态码，当前值: %d", l.HTTPStatus)
+	}
+	if l.LogSilence < 0 || l.Timeout < 0 {
+		return fmt.Errorf("health_check.liveness 的 log_silence 和 timeout 不能为负数")
+	}
+	if l.FailureThreshold < 0 || l.SuccessThreshold < 0 {
+		return fmt.Errorf("health_check.liveness 的 failure_threshold 和 success_threshold 不能为负数")
+	}
 	return nil
 }
+
+// Enabled reports whether any liveness probe is configured.
+func (l *LivenessConfig) Enabled() bool {
+	return l.TCP || l.HTTP != "" || l.LogSilence > 0
+}
+
+// EffectiveTimeout returns the configured timeout, defaulting to DefaultLivenessTimeout.
+func (l *LivenessConfig) EffectiveTimeout() time.Duration {
+	if l.Timeout <= 0 {
+		return DefaultLivenessTimeout
+	}
+	return l.Timeout
+}
+
+// EffectiveFailureThreshold returns the configured failure_threshold, defaulting to DefaultLivenessFailureThreshold.
+func (l *LivenessConfig) EffectiveFailureThreshold() int {
+	if l.FailureThreshold <= 0 {
+		return DefaultLivenessFailureThreshold
+	}
+	return l.FailureThreshold
+}
+
+// EffectiveSuccessThreshold returns the configured success_threshold, defaulting to DefaultLivenessSuccessThreshold.
+func (l *LivenessConfig) EffectiveSuccessThreshold() int {
+	if l.SuccessThreshold <= 0 {
+		return DefaultLivenessSuccessThreshold
+	}
+	return l.SuccessThreshold
+}
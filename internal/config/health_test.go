@@ -32,3 +32,35 @@ func TestConfig_HealthCheck_DefaultsAndValidation(t *testing.T) {
 	cfg.defaults()
 	assert.Equal(t, 1*time.Minute, cfg.HealthCheck.Interval)
 }
+
+func TestLivenessConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		liveness LivenessConfig
+		wantErr  string
+	}{
+		{"none", LivenessConfig{}, ""},
+		{"all probes", LivenessConfig{TCP: true, HTTP: "/health", HTTPStatus: 204, LogSilence: 30 * time.Minute, FailureThreshold: 2, Restart: true}, ""},
+		{"http url", LivenessConfig{HTTP: "http://127.0.0.1:18790/health"}, "liveness.http"},
+		{"invalid status", LivenessConfig{HTTP: "/health", HTTPStatus: 42}, "http_status"},
+		{"negative silence", LivenessConfig{LogSilence: -time.Minute}, "不能为负数"},
+		{"negative threshold", LivenessConfig{TCP: true, SuccessThreshold: -1}, "success_threshold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HealthCheckConfig{Interval: time.Minute, Liveness: tt.liveness}
+			err := h.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	var defaults LivenessConfig
+	assert.False(t, defaults.Enabled())
+	assert.Equal(t, DefaultLivenessTimeout, defaults.EffectiveTimeout())
+	assert.Equal(t, DefaultLivenessFailureThreshold, defaults.EffectiveFailureThreshold())
+	assert.Equal(t, DefaultLivenessSuccessThreshold, defaults.EffectiveSuccessThreshold())
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout is used when a hook has no timeout configured.
+const DefaultHookTimeout = 60 * time.Second
+
+// Hook stages (hooks.<stage>)
+const (
+	HookPreUpdate   = "pre_update"   // 停止实例之前; 失败时中止更新
+	HookPostInstall = "post_install" // 新版本安装成功后, 启动实例之前
+	HookPostStart   = "post_start"   // 新版本下所有实例启动成功后
+	HookOnFailure   = "on_failure"   // 更新失败、中止或回滚后
+)
+
+// HooksConfig holds the commands run around nanobot updates, e.g. to back up the workspace
+// before stopping instances or patch a config key after installing a new version.
+// Hooks of a stage run in order; each gets the update ID, stage, target and instances as
+// NANOBOT_UPDATE_* environment variables and the same data (plus the result) as JSON on stdin.
+type HooksConfig struct {
+	PreUpdate   []HookConfig `yaml:"pre_update" mapstructure:"pre_update"`     // 停止实例之前, 任一失败则中止更新
+	PostInstall []HookConfig `yaml:"post_install" mapstructure:"post_install"` // 安装成功后, 启动实例之前
+	PostStart   []HookConfig `yaml:"post_start" mapstructure:"post_start"`     // 所有实例在新版本下启动成功后
+	OnFailure   []HookConfig `yaml:"on_failure" mapstructure:"on_failure"`     // 更新失败、中止或回滚后
+}
+
+// HookConfig is a single hook command, run through the system shell (sh -c / cmd /C).
+type HookConfig struct {
+	Name    string        `yaml:"name" mapstructure:"name"`       // 可选名称, 用于日志和更新记录
+	Command string        `yaml:"command" mapstructure:"command"` // 要执行的命令
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"` // 超时时间 (0 表示默认 60 秒)
+}
+
+// Label returns the name of the hook, or its command when no name is set.
+func (h HookConfig) Label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.Command
+}
+
+// EffectiveTimeout returns the configured timeout, defaulting to DefaultHookTimeout.
+func (h HookConfig) EffectiveTimeout() time.Duration {
+	if h.Timeout <= 0 {
+		return DefaultHookTimeout
+	}
+	return h.Timeout
+}
+
+// Stage returns the hooks configured for stage (one of the Hook* constants).
+func (h *HooksConfig) Stage(stage string) []HookConfig {
+	switch stage {
+	case HookPreUpdate:
+		return h.PreUpdate
+	case HookPostInstall:
+		return h.PostInstall
+	case HookPostStart:
+		return h.PostStart
+	case HookOnFailure:
+		return h.OnFailure
+	}
+	return nil
+}
+
+// Validate validates the HooksConfig values.
+func (h *HooksConfig) Validate() error {
+	var errs []error
+	for _, stage := range []string{HookPreUpdate, HookPostInstall, HookPostStart, HookOnFailure} {
+		for i, hook := range h.Stage(stage) {
+			if strings.TrimSpace(hook.Command) == "" {
+				errs = append(errs, fmt.Errorf("hooks.%s[%d].command 不能为空", stage, i))
+			}
+			if hook.Timeout < 0 {
+				errs = append(errs, fmt.Errorf("hooks.%s[%d].timeout 不能为负数，当前值: %v", stage, i, hook.Timeout))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooksConfig_Validate(t *testing.T) {
+	valid := HooksConfig{
+		PreUpdate:   []HookConfig{{Name: "backup", Command: "backup.sh", Timeout: 2 * time.Minute}},
+		PostInstall: []HookConfig{{Command: "nanobot --version"}},
+	}
+	assert.NoError(t, valid.Validate())
+	assert.NoError(t, (&HooksConfig{}).Validate())
+
+	err := (&HooksConfig{PostStart: []HookConfig{{Name: "empty", Command: "  "}}}).Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "hooks.post_start[0].command")
+	}
+	err = (&HooksConfig{OnFailure: []HookConfig{{Command: "alert.sh", Timeout: -time.Second}}}).Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "hooks.on_failure[0].timeout")
+	}
+}
+
+func TestHookConfig_LabelAndTimeout(t *testing.T) {
+	assert.Equal(t, "backup", HookConfig{Name: "backup", Command: "backup.sh"}.Label())
+	assert.Equal(t, "backup.sh", HookConfig{Command: "backup.sh"}.Label())
+
+	assert.Equal(t, DefaultHookTimeout, HookConfig{Command: "backup.sh"}.EffectiveTimeout())
+	assert.Equal(t, 5*time.Second, HookConfig{Command: "backup.sh", Timeout: 5 * time.Second}.EffectiveTimeout())
+}
+
+func TestLoad_Hooks(t *testing.T) {
+	yaml := `api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "inst"
+    port: 18790
+    start_command: "nanobot gateway"
+hooks:
+  pre_update:
+    - name: "backup"
+      command: "backup.sh"
+      timeout: 2m
+  on_failure:
+    - command: "alert.sh"
+`
+	cfg, err := Load(writeTempConfig(t, yaml))
+	require.NoError(t, err)
+	require.Len(t, cfg.Hooks.PreUpdate, 1)
+	assert.Equal(t, "backup", cfg.Hooks.PreUpdate[0].Name)
+	assert.Equal(t, 2*time.Minute, cfg.Hooks.PreUpdate[0].Timeout)
+	require.Len(t, cfg.Hooks.OnFailure, 1)
+	assert.Equal(t, "alert.sh", cfg.Hooks.OnFailure[0].Command)
+	assert.Empty(t, cfg.Hooks.Stage(HookPostStart))
+
+	_, err = Load(writeTempConfig(t, yaml+"  post_start:\n    - name: \"no-command\"\n"))
+	assert.Error(t, err)
+}
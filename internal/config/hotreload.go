@@ -34,6 +34,9 @@ type HotReloadCallbacks struct {
 
 	// OnInstancesChange handles instance config changes (full replace: stop all -> recreate -> start all).
 	OnInstancesChange func(newCfg *Config)
+
+	// OnUpdateScheduleChange reschedules the update cron jobs when update_schedule config changes.
+	OnUpdateScheduleChange func(newCfg *Config)
 }
 
 // hotReloadState manages the config file watcher lifecycle.
@@ -216,6 +219,17 @@ func (s *hotReloadState) handleConfigChange(oldCfg, newCfg *Config) {
 			s.callbacks.OnInstancesChange(newCfg)
 		}
 	}
+
+	// UpdateSchedule (cron/window/mode/blackout) -> reschedule update jobs
+	if !reflect.DeepEqual(oldCfg.UpdateSchedule, newCfg.UpdateSchedule) {
+		s.logger.Info("update_schedule config changed, rescheduling",
+			"enabled", newCfg.UpdateSchedule.Enabled,
+			"cron", newCfg.UpdateSchedule.Cron,
+		)
+		if s.callbacks.OnUpdateScheduleChange != nil {
+			s.callbacks.OnUpdateScheduleChange(newCfg)
+		}
+	}
 }
 
 // StopWatch stops the config file watcher.
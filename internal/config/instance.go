@@ -2,16 +2,28 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
+// DefaultStartupTimeout is the startup timeout of an instance without startup_timeout
+const DefaultStartupTimeout = 30 * time.Second
+
 // InstanceConfig holds configuration for a single nanobot instance.
 type InstanceConfig struct {
-	Name           string        `mapstructure:"name"`
-	Port           uint32        `mapstructure:"port"`
-	StartCommand   string        `mapstructure:"start_command"`
-	StartupTimeout time.Duration `mapstructure:"startup_timeout"`
-	AutoStart      *bool         `mapstructure:"auto_start"` // nil = default true
+	Name           string            `mapstructure:"name"`
+	Port           uint32            `mapstructure:"port"`
+	StartCommand   string            `mapstructure:"start_command"`
+	StartupTimeout time.Duration     `mapstructure:"startup_timeout"`
+	AutoStart      *bool             `mapstructure:"auto_start"`  // nil = default true
+	Channel        string            `mapstructure:"channel"`     // updater.channels 中的独立安装, 空表示全局安装
+	Canary         bool              `mapstructure:"canary"`      // 更新后先启动并观察 updater.canary.soak_period
+	Restart        RestartConfig     `mapstructure:"restart"`     // 进程意外退出后的自动重启策略
+	Readiness      ReadinessConfig   `mapstructure:"readiness"`   // 启动后的就绪检查, 未配置时只检查进程 2 秒后仍存活
+	Env            map[string]string `mapstructure:"env"`         // 额外的环境变量, 值中的 ${VAR} 会被展开, 覆盖 env_file
+	EnvFile        string            `mapstructure:"env_file"`    // KEY=VALUE 格式的环境变量文件, 启动时读取
+	WorkingDir     string            `mapstructure:"working_dir"` // 进程的工作目录, 空表示服务的工作目录
+	OwnHome        bool              `mapstructure:"own_home"`    // 使用独立的 HOME (./homes/<name>), 不与其他实例共享 ~/.nanobot
 }
 
 // Validate validates the InstanceConfig values.
@@ -36,9 +48,58 @@ func (ic *InstanceConfig) Validate() error {
 		return fmt.Errorf("实例 %q startup_timeout 必须至少 5 秒,当前值: %v", ic.Name, ic.StartupTimeout)
 	}
 
+	// Validate restart
+	if err := ic.Restart.Validate(ic.Name); err != nil {
+		return err
+	}
+
+	// Validate readiness
+	if err := ic.Readiness.Validate(ic.Name); err != nil {
+		return err
+	}
+
+	// Validate env
+	if err := ic.validateEnv(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateStaged checks that start_command can run the nanobot of a staging slot (updater.strategy: staged):
+// only a bare executable name is looked up in the slot's bin directory (and PATH), an executable
+// given as a path would keep running the old install after the swap.
+func (ic *InstanceConfig) ValidateStaged() error {
+	exe := startExecutable(ic.StartCommand)
+	if exe == "" || !isPlainName(exe) || strings.Contains(exe, `"`) {
+		return fmt.Errorf("实例 %q 的 start_command 可执行文件 %q 必须是不带路径的名称 (如 nanobot), staged 策略从槽位的 bin 目录查找", ic.Name, exe)
+	}
 	return nil
 }
 
+// startExecutable returns the executable of command: the first word, or the quoted string it starts with
+func startExecutable(command string) string {
+	command = strings.TrimSpace(command)
+	if strings.HasPrefix(command, `"`) {
+		if end := strings.Index(command[1:], `"`); end >= 0 {
+			return command[1 : end+1]
+		}
+		return command
+	}
+	if i := strings.IndexAny(command, " \t"); i >= 0 {
+		return command[:i]
+	}
+	return command
+}
+
+// EffectiveStartupTimeout returns the configured startup_timeout, defaulting to DefaultStartupTimeout.
+func (ic *InstanceConfig) EffectiveStartupTimeout() time.Duration {
+	if ic.StartupTimeout == 0 {
+		return DefaultStartupTimeout
+	}
+	return ic.StartupTimeout
+}
+
 // ShouldAutoStart returns whether the instance should be automatically started.
 // nil AutoStart defaults to true, explicit values are honored.
 func (ic *InstanceConfig) ShouldAutoStart() bool {
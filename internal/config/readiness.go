@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultReadinessInterval is the default poll interval of the readiness probes
+const DefaultReadinessInterval = 500 * time.Millisecond
+
+// ReadinessConfig are the readiness probes of an instance: after launching the process, the start
+// waits up to startup_timeout until every configured probe passes. Without probes the start only
+// checks that the process is still alive after 2 seconds.
+type ReadinessConfig struct {
+	TCP        bool          `mapstructure:"tcp"`         // 端口在监听, 且监听的是启动的进程 (或其子进程)
+	HTTP       string        `mapstructure:"http"`        // GET 返回 2xx/3xx; 路径 ("/health") 访问 http://127.0.0.1:<port>, 也可以是完整 URL
+	LogPattern string        `mapstructure:"log_pattern"` // 进程输出中出现匹配该正则的行
+	Interval   time.Duration `mapstructure:"interval"`    // 检查间隔 (默认 500ms)
+}
+
+// Enabled reports whether any readiness probe is configured.
+func (r *ReadinessConfig) Enabled() bool {
+	return r.TCP || r.HTTP != "" || r.LogPattern != ""
+}
+
+// Validate validates the ReadinessConfig values of instance name.
+func (r *ReadinessConfig) Validate(name string) error {
+	if r.HTTP != "" && !strings.HasPrefix(r.HTTP, "/") {
+		u, err := url.Parse(r.HTTP)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("实例 %q readiness.http 必须是以 / 开头的路径或 http(s) URL，当前值: %q", name, r.HTTP)
+		}
+	}
+	if r.LogPattern != "" {
+		if _, err := regexp.Compile(r.LogPattern); err != nil {
+			return fmt.Errorf("实例 %q readiness.log_pattern 不是有效的正则表达式: %w", name, err)
+		}
+	}
+	if r.Interval < 0 {
+		return fmt.Errorf("实例 %q readiness.interval 不能为负数，当前值: %v", name, r.Interval)
+	}
+	return nil
+}
+
+// HTTPURL returns the URL of the HTTP probe for an instance on port, or "" without an HTTP probe.
+func (r *ReadinessConfig) HTTPURL(port uint32) string {
+	if r.HTTP == "" || !strings.HasPrefix(r.HTTP, "/") {
+		return r.HTTP
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d%s", port, r.HTTP)
+}
+
+// EffectiveInterval returns the configured interval, defaulting to DefaultReadinessInterval.
+func (r *ReadinessConfig) EffectiveInterval() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultReadinessInterval
+	}
+	return r.Interval
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		readiness ReadinessConfig
+		wantErr   string
+	}{
+		{"none", ReadinessConfig{}, ""},
+		{"all probes", ReadinessConfig{TCP: true, HTTP: "/health", LogPattern: `Telegram bot commands registered`, Interval: time.Second}, ""},
+		{"http url", ReadinessConfig{HTTP: "http://127.0.0.1:18790/"}, ""},
+		{"http without slash", ReadinessConfig{HTTP: "health"}, "readiness.http"},
+		{"http other scheme", ReadinessConfig{HTTP: "ftp://host/"}, "readiness.http"},
+		{"invalid pattern", ReadinessConfig{LogPattern: "(unclosed"}, "readiness.log_pattern"},
+		{"negative interval", ReadinessConfig{TCP: true, Interval: -time.Second}, "readiness.interval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.readiness.Validate("inst")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestReadinessConfig_HTTPURL(t *testing.T) {
+	assert.Equal(t, "http://127.0.0.1:18790/health", (&ReadinessConfig{HTTP: "/health"}).HTTPURL(18790))
+	assert.Equal(t, "http://gateway:8080/", (&ReadinessConfig{HTTP: "http://gateway:8080/"}).HTTPURL(18790))
+	assert.Equal(t, "", (&ReadinessConfig{TCP: true}).HTTPURL(18790))
+
+	var defaults ReadinessConfig
+	assert.False(t, defaults.Enabled())
+	assert.Equal(t, DefaultReadinessInterval, defaults.EffectiveInterval())
+}
+
+func TestUpdateConfig_WritesReadinessConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "inst"
+    port: 18790
+    start_command: "nanobot gateway"
+    readiness:
+      tcp: true
+      log_pattern: "Telegram bot commands registered"
+`), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	require.Equal(t, ReadinessConfig{TCP: true, LogPattern: "Telegram bot commands registered"}, cfg.Instances[0].Readiness)
+
+	WatchConfig(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), &HotReloadCallbacks{})
+	err = UpdateConfig(func(c *Config) error {
+		c.Instances[0].Readiness.HTTP = "/health"
+		c.Instances[0].Readiness.Interval = 250 * time.Millisecond
+		return nil
+	})
+	require.NoError(t, err)
+	StopWatch()
+	viperInstance = nil
+
+	newCfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, ReadinessConfig{
+		TCP:        true,
+		HTTP:       "/health",
+		LogPattern: "Telegram bot commands registered",
+		Interval:   250 * time.Millisecond,
+	}, newCfg.Instances[0].Readiness)
+}
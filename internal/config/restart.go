@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Restart policies of an instance (instances[].restart.policy)
+const (
+	RestartPolicyNever     = "never"      // 进程退出后不重启 (默认)
+	RestartPolicyOnFailure = "on-failure" // 进程以非 0 退出码或被信号终止时重启
+	RestartPolicyAlways    = "always"     // 进程退出后总是重启
+)
+
+// Defaults of RestartConfig
+const (
+	DefaultRestartBackoff         = time.Second
+	DefaultRestartMaxBackoff      = time.Minute
+	DefaultRestartCrashLoopCount  = 5
+	DefaultRestartCrashLoopWindow = 10 * time.Minute
+)
+
+// RestartConfig is the crash supervisor of an instance: when the process exits without being
+// stopped (API, update, config reload), it is restarted after Backoff, doubled for every further
+// crash within CrashLoopWindow up to MaxBackoff. CrashLoopCount crashes within CrashLoopWindow
+// are a crash loop: the instance is no longer restarted and a notification is sent.
+type RestartConfig struct {
+	Policy          string        `mapstructure:"policy"`            // never (默认) / on-failure / always
+	Backoff         time.Duration `mapstructure:"backoff"`           // 第一次重启前的等待时间 (默认 1s)
+	MaxBackoff      time.Duration `mapstructure:"max_backoff"`       // 等待时间上限 (默认 1m)
+	CrashLoopCount  int           `mapstructure:"crash_loop_count"`  // crash_loop_window 内崩溃该次数后放弃重启 (默认 5)
+	CrashLoopWindow time.Duration `mapstructure:"crash_loop_window"` // 崩溃循环的统计窗口 (默认 10m)
+}
+
+// Validate validates the RestartConfig values of instance name.
+func (r *RestartConfig) Validate(name string) error {
+	switch r.Policy {
+	case "", RestartPolicyNever, RestartPolicyOnFailure, RestartPolicyAlways:
+	default:
+		return fmt.Errorf("实例 %q restart.policy 必须是 never、on-failure 或 always，当前值: %q", name, r.Policy)
+	}
+	if r.Backoff < 0 || r.MaxBackoff < 0 || r.CrashLoopWindow < 0 {
+		return fmt.Errorf("实例 %q restart 的 backoff、max_backoff 和 crash_loop_window 不能为负数", name)
+	}
+	if r.MaxBackoff > 0 && r.MaxBackoff < r.EffectiveBackoff() {
+		return fmt.Errorf("实例 %q restart.max_backoff (%v) 不能小于 backoff (%v)", name, r.MaxBackoff, r.EffectiveBackoff())
+	}
+	if r.CrashLoopCount < 0 {
+		return fmt.Errorf("实例 %q restart.crash_loop_count 不能为负数，当前值: %d", name, r.CrashLoopCount)
+	}
+	return nil
+}
+
+// EffectivePolicy returns the configured policy, defaulting to RestartPolicyNever.
+func (r *RestartConfig) EffectivePolicy() string {
+	if r.Policy == "" {
+		return RestartPolicyNever
+	}
+	return r.Policy
+}
+
+// EffectiveBackoff returns the configured backoff, defaulting to DefaultRestartBackoff.
+func (r *RestartConfig) EffectiveBackoff() time.Duration {
+	if r.Backoff <= 0 {
+		return DefaultRestartBackoff
+	}
+	return r.Backoff
+}
+
+// EffectiveMaxBackoff returns the configured max_backoff, defaulting to DefaultRestartMaxBackoff
+// (or backoff when that is larger).
+func (r *RestartConfig) EffectiveMaxBackoff() time.Duration {
+	if r.MaxBackoff > 0 {
+		return r.MaxBackoff
+	}
+	return max(DefaultRestartMaxBackoff, r.EffectiveBackoff())
+}
+
+// EffectiveCrashLoopCount returns the configured crash_loop_count, defaulting to DefaultRestartCrashLoopCount.
+func (r *RestartConfig) EffectiveCrashLoopCount() int {
+	if r.CrashLoopCount <= 0 {
+		return DefaultRestartCrashLoopCount
+	}
+	return r.CrashLoopCount
+}
+
+// EffectiveCrashLoopWindow returns the configured crash_loop_window, defaulting to DefaultRestartCrashLoopWindow.
+func (r *RestartConfig) EffectiveCrashLoopWindow() time.Duration {
+	if r.CrashLoopWindow <= 0 {
+		return DefaultRestartCrashLoopWindow
+	}
+	return r.CrashLoopWindow
+}
+
+// BackoffFor returns the delay before the restart after the n-th crash (n >= 1) within
+// the crash loop window: backoff * 2^(n-1), capped at max_backoff.
+func (r *RestartConfig) BackoffFor(n int) time.Duration {
+	delay, limit := r.EffectiveBackoff(), r.EffectiveMaxBackoff()
+	for i := 1; i < n && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
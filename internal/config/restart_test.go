@@ -0,0 +1,89 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		restart RestartConfig
+		wantErr string
+	}{
+		{"defaults", RestartConfig{}, ""},
+		{"on-failure", RestartConfig{Policy: RestartPolicyOnFailure, Backoff: 2 * time.Second, MaxBackoff: time.Minute}, ""},
+		{"unknown policy", RestartConfig{Policy: "sometimes"}, "restart.policy"},
+		{"negative backoff", RestartConfig{Policy: RestartPolicyAlways, Backoff: -time.Second}, "不能为负数"},
+		{"max below backoff", RestartConfig{Backoff: 10 * time.Second, MaxBackoff: 5 * time.Second}, "max_backoff"},
+		{"negative crash loop count", RestartConfig{CrashLoopCount: -1}, "crash_loop_count"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.restart.Validate("inst")
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestRestartConfig_BackoffFor(t *testing.T) {
+	rc := RestartConfig{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expected := range want {
+		assert.Equal(t, expected, rc.BackoffFor(i+1), "crash %d", i+1)
+	}
+
+	var defaults RestartConfig
+	assert.Equal(t, RestartPolicyNever, defaults.EffectivePolicy())
+	assert.Equal(t, DefaultRestartBackoff, defaults.BackoffFor(1))
+	assert.Equal(t, DefaultRestartMaxBackoff, defaults.BackoffFor(100))
+}
+
+func TestUpdateConfig_WritesRestartConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "inst"
+    port: 18790
+    start_command: "nanobot gateway"
+    restart:
+      policy: on-failure
+      max_backoff: 30s
+`), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	require.Equal(t, RestartConfig{Policy: RestartPolicyOnFailure, MaxBackoff: 30 * time.Second}, cfg.Instances[0].Restart)
+
+	WatchConfig(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), &HotReloadCallbacks{})
+	err = UpdateConfig(func(c *Config) error {
+		c.Instances[0].Restart.CrashLoopCount = 3
+		c.Instances[0].Restart.CrashLoopWindow = 5 * time.Minute
+		return nil
+	})
+	require.NoError(t, err)
+	StopWatch()
+	viperInstance = nil
+
+	newCfg, err := Load(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, RestartConfig{
+		Policy:          RestartPolicyOnFailure,
+		MaxBackoff:      30 * time.Second,
+		CrashLoopCount:  3,
+		CrashLoopWindow: 5 * time.Minute,
+	}, newCfg.Instances[0].Restart)
+}
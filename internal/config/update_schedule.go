@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Update schedule modes
+const (
+	ScheduleModeAlways           = "always"              // 每次触发都强制重新安装
+	ScheduleModeOnlyIfNewVersion = "only-if-new-version" // 仅在有新版本时更新 (默认)
+	ScheduleModeNotifyOnly       = "notify-only"         // 只发送"有可用更新"通知, 不安装
+)
+
+// blackoutDateLayout is the format of update_schedule.blackout_dates entries
+const blackoutDateLayout = "2006-01-02"
+
+// UpdateScheduleConfig holds configuration for cron-scheduled nanobot updates.
+type UpdateScheduleConfig struct {
+	Enabled           bool     `yaml:"enabled" mapstructure:"enabled"`                       // 是否启用定时更新
+	Cron              []string `yaml:"cron" mapstructure:"cron"`                             // 标准 5 段 cron 表达式 (本地时间), 如 "30 2 * * *"
+	MaintenanceWindow string   `yaml:"maintenance_window" mapstructure:"maintenance_window"` // 维护窗口 "HH:MM-HH:MM" (本地时间, 可跨午夜), 为空表示不限制
+	Mode              string   `yaml:"mode" mapstructure:"mode"`                             // always / only-if-new-version / notify-only
+	BlackoutDates     []string `yaml:"blackout_dates" mapstructure:"blackout_dates"`         // 禁止更新的日期 "YYYY-MM-DD"
+}
+
+// Validate validates the UpdateScheduleConfig values.
+func (s *UpdateScheduleConfig) Validate() error {
+	switch s.Mode {
+	case "", ScheduleModeAlways, ScheduleModeOnlyIfNewVersion, ScheduleModeNotifyOnly:
+	default:
+		return fmt.Errorf("update_schedule.mode 必须是 always、only-if-new-version 或 notify-only，当前值: %q", s.Mode)
+	}
+
+	if s.Enabled && len(s.Cron) == 0 {
+		return fmt.Errorf("update_schedule.cron 启用定时更新时至少需要一个 cron 表达式")
+	}
+	for i, expr := range s.Cron {
+		if _, err := cron.ParseStandard(expr); err != nil {
+			return fmt.Errorf("update_schedule.cron[%d] 无效的 cron 表达式 %q: %w", i, expr, err)
+		}
+	}
+
+	if s.MaintenanceWindow != "" {
+		if _, _, err := parseWindow(s.MaintenanceWindow); err != nil {
+			return fmt.Errorf("update_schedule.maintenance_window 格式必须是 HH:MM-HH:MM，当前值: %q", s.MaintenanceWindow)
+		}
+	}
+
+	for i, d := range s.BlackoutDates {
+		if _, err := time.Parse(blackoutDateLayout, d); err != nil {
+			return fmt.Errorf("update_schedule.blackout_dates[%d] 格式必须是 YYYY-MM-DD，当前值: %q", i, d)
+		}
+	}
+	return nil
+}
+
+// EffectiveMode returns the configured mode, defaulting to only-if-new-version.
+func (s *UpdateScheduleConfig) EffectiveMode() string {
+	if s.Mode == "" {
+		return ScheduleModeOnlyIfNewVersion
+	}
+	return s.Mode
+}
+
+// InMaintenanceWindow reports whether t (converted to local time) falls inside the maintenance
+// window. An empty or invalid window allows any time. Windows may wrap midnight (e.g. 23:00-02:00).
+func (s *UpdateScheduleConfig) InMaintenanceWindow(t time.Time) bool {
+	if s.MaintenanceWindow == "" {
+		return true
+	}
+	start, end, err := parseWindow(s.MaintenanceWindow)
+	if err != nil {
+		return true
+	}
+
+	t = t.Local()
+	minute := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// IsBlackout reports whether t (converted to local time) is on a blackout date.
+func (s *UpdateScheduleConfig) IsBlackout(t time.Time) bool {
+	day := t.Local().Format(blackoutDateLayout)
+	for _, d := range s.BlackoutDates {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWindow parses "HH:MM-HH:MM" into start/end minutes of the day.
+func parseWindow(window string) (int, int, error) {
+	var sh, sm, eh, em int
+	if _, err := fmt.Sscanf(window, "%d:%d-%d:%d", &sh, &sm, &eh, &em); err != nil {
+		return 0, 0, err
+	}
+	for _, v := range []struct{ h, m int }{{sh, sm}, {eh, em}} {
+		if v.h < 0 || v.h > 24 || v.m < 0 || v.m > 59 || (v.h == 24 && v.m != 0) {
+			return 0, 0, fmt.Errorf("time out of range")
+		}
+	}
+	start, end := sh*60+sm, eh*60+em
+	if start == end {
+		return 0, 0, fmt.Errorf("empty window")
+	}
+	return start, end, nil
+}
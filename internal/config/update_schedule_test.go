@@ -0,0 +1,65 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateScheduleConfig_Validate(t *testing.T) {
+	valid := []UpdateScheduleConfig{
+		{},
+		{Enabled: true, Cron: []string{"30 2 * * *"}, Mode: "always"},
+		{Enabled: true, Cron: []string{"0 3 * * 1-5", "@daily"}, MaintenanceWindow: "23:00-02:00", Mode: "notify-only"},
+		{Enabled: true, Cron: []string{"0 3 * * *"}, MaintenanceWindow: "02:00-24:00", BlackoutDates: []string{"2026-12-24", "2026-12-25"}},
+	}
+	for _, c := range valid {
+		assert.NoError(t, c.Validate(), "%+v", c)
+	}
+
+	invalid := []struct {
+		cfg    UpdateScheduleConfig
+		errMsg string
+	}{
+		{UpdateScheduleConfig{Enabled: true}, "cron"},
+		{UpdateScheduleConfig{Cron: []string{"every night"}}, "cron[0]"},
+		{UpdateScheduleConfig{Mode: "sometimes"}, "mode"},
+		{UpdateScheduleConfig{MaintenanceWindow: "2am-5am"}, "maintenance_window"},
+		{UpdateScheduleConfig{MaintenanceWindow: "02:00-25:00"}, "maintenance_window"},
+		{UpdateScheduleConfig{MaintenanceWindow: "02:00-02:00"}, "maintenance_window"},
+		{UpdateScheduleConfig{BlackoutDates: []string{"12/25"}}, "blackout_dates[0]"},
+	}
+	for _, tt := range invalid {
+		err := tt.cfg.Validate()
+		if assert.Error(t, err, "%+v", tt.cfg) {
+			assert.Contains(t, err.Error(), tt.errMsg)
+		}
+	}
+}
+
+func TestUpdateScheduleConfig_InMaintenanceWindow(t *testing.T) {
+	day := func(hh, mm int) time.Time { return time.Date(2026, 3, 10, hh, mm, 0, 0, time.Local) }
+
+	c := UpdateScheduleConfig{MaintenanceWindow: "02:00-05:00"}
+	assert.True(t, c.InMaintenanceWindow(day(2, 0)))
+	assert.True(t, c.InMaintenanceWindow(day(4, 59)))
+	assert.False(t, c.InMaintenanceWindow(day(5, 0)))
+	assert.False(t, c.InMaintenanceWindow(day(14, 0)))
+
+	overnight := UpdateScheduleConfig{MaintenanceWindow: "23:00-02:00"}
+	assert.True(t, overnight.InMaintenanceWindow(day(23, 30)))
+	assert.True(t, overnight.InMaintenanceWindow(day(1, 0)))
+	assert.False(t, overnight.InMaintenanceWindow(day(12, 0)))
+
+	assert.True(t, (&UpdateScheduleConfig{}).InMaintenanceWindow(day(12, 0)), "no window allows any time")
+}
+
+func TestUpdateScheduleConfig_IsBlackoutAndMode(t *testing.T) {
+	c := UpdateScheduleConfig{BlackoutDates: []string{"2026-12-25"}}
+	assert.True(t, c.IsBlackout(time.Date(2026, 12, 25, 3, 0, 0, 0, time.Local)))
+	assert.False(t, c.IsBlackout(time.Date(2026, 12, 26, 3, 0, 0, 0, time.Local)))
+
+	assert.Equal(t, ScheduleModeOnlyIfNewVersion, (&UpdateScheduleConfig{}).EffectiveMode())
+	assert.Equal(t, ScheduleModeAlways, (&UpdateScheduleConfig{Mode: "always"}).EffectiveMode())
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -269,3 +270,45 @@ instances:
 	assert.Equal(t, uint32(9999), newCfg.API.Port)
 	assert.Equal(t, "test-token-123456789012345678901", newCfg.API.BearerToken)
 }
+
+func TestUpdateConfig_KeepsModeAndSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetPath := filepath.Join(tmpDir, "real", "config.yaml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(targetPath), 0755))
+	require.NoError(t, os.WriteFile(targetPath, []byte(`api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "existing"
+    port: 18790
+    start_command: "nanobot gateway"
+`), 0600))
+	require.NoError(t, os.Chmod(targetPath, 0600)) // 不受 umask 影响
+	linkPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.Symlink(targetPath, linkPath); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	cfg, err := Load(linkPath)
+	require.NoError(t, err)
+	WatchConfig(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), &HotReloadCallbacks{})
+	err = UpdateConfig(func(c *Config) error {
+		c.Instances[0].Port = 18795
+		return nil
+	})
+	require.NoError(t, err)
+	StopWatch()
+	viperInstance = nil
+
+	linkInfo, err := os.Lstat(linkPath)
+	require.NoError(t, err)
+	assert.NotZero(t, linkInfo.Mode()&os.ModeSymlink, "config symlink is kept")
+	info, err := os.Stat(targetPath)
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "config mode is kept")
+	}
+
+	newCfg, err := Load(targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(18795), newCfg.Instances[0].Port)
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UpdaterConfig holds configuration for the nanobot update process (uv tool install).
+type UpdaterConfig struct {
+	Sources  []UpdateSourceConfig `yaml:"sources" mapstructure:"sources"`   // 按顺序尝试的安装源, 为空时使用 GitHub → PyPI
+	Rollback RollbackConfig       `yaml:"rollback" mapstructure:"rollback"` // 更新后启动失败时自动回滚
+	Channels []ChannelConfig      `yaml:"channels" mapstructure:"channels"` // 独立安装目录, 实例通过 channel 字段引用
+	Canary   CanaryConfig         `yaml:"canary" mapstructure:"canary"`     // canary: true 的实例先启动并观察
+
+	// UploadDir 是 POST /api/v1/trigger-update/upload 上传的 wheel/sdist 暂存目录 (默认 ./uploads)
+	UploadDir string `yaml:"upload_dir" mapstructure:"upload_dir"`
+
+	// RepoPath 是本地 nanobot 源码仓库 (可选): 每次安装成功后 git pull (不存在时 clone), 并记录 HEAD
+	RepoPath string `yaml:"repo_path" mapstructure:"repo_path"`
+
+	// Strategy 是安装方式: stop_first (默认, 停止实例后安装) / staged (实例运行时安装到暂存槽位, 停止后切换)
+	Strategy string `yaml:"strategy" mapstructure:"strategy"`
+	// StagingDir 是 staged 策略的安装槽位根目录 (默认 ./staging)
+	StagingDir string `yaml:"staging_dir" mapstructure:"staging_dir"`
+}
+
+// DefaultUploadDir is used when updater.upload_dir is not set.
+const DefaultUploadDir = "./uploads"
+
+// Update strategies (updater.strategy)
+const (
+	// StrategyStopFirst stops the instances, installs with uv and starts them again.
+	// Instances are down for the whole install.
+	StrategyStopFirst = "stop_first"
+	// StrategyStaged installs into an alternate slot under staging_dir while the instances keep
+	// running, then stops them, switches them to the new slot and starts them again.
+	StrategyStaged = "staged"
+)
+
+// DefaultStagingDir is used when updater.staging_dir is not set.
+const DefaultStagingDir = "./staging"
+
+// Canary failure handling (updater.canary.on_failure)
+const (
+	CanaryOnFailureHold     = "hold"     // 其余实例保持停止, 等待人工处理
+	CanaryOnFailureRollback = "rollback" // 重新安装更新前的版本并启动所有实例
+)
+
+// DefaultCanarySoakPeriod is used when updater.canary.soak_period is not set.
+const DefaultCanarySoakPeriod = 60 * time.Second
+
+// CanaryConfig controls the canary-first start phase. After an install, instances marked
+// canary: true start first and must stay healthy (process alive, port listening, no Telegram
+// failure) for SoakPeriod before the remaining instances are started.
+type CanaryConfig struct {
+	SoakPeriod time.Duration `yaml:"soak_period" mapstructure:"soak_period"` // canary 观察时间 (0 表示默认 60 秒)
+	OnFailure  string        `yaml:"on_failure" mapstructure:"on_failure"`   // hold / rollback (默认 rollback)
+}
+
+// ChannelConfig is one entry of updater.channels: an isolated nanobot installation
+// (UV_TOOL_DIR=<install_dir>/tools, UV_TOOL_BIN_DIR=<install_dir>/bin) shared by the instances
+// whose channel field names it. A channel may pin a version with ref, commit or pypi_version;
+// pinned channels ignore the target of trigger-update requests.
+type ChannelConfig struct {
+	Name        string `yaml:"name" mapstructure:"name"`                 // channel 名称, 实例 channel 字段引用
+	InstallDir  string `yaml:"install_dir" mapstructure:"install_dir"`   // 独立安装根目录
+	Ref         string `yaml:"ref" mapstructure:"ref"`                   // 固定 git 分支/标签 (可选)
+	Commit      string `yaml:"commit" mapstructure:"commit"`             // 固定 git 提交 (可选)
+	PyPIVersion string `yaml:"pypi_version" mapstructure:"pypi_version"` // 固定 PyPI 版本 (可选)
+}
+
+// Update source types
+const (
+	SourceTypeGit   = "git"
+	SourceTypePyPI  = "pypi"
+	SourceTypeLocal = "local"
+)
+
+// UpdateSourceConfig is one entry of updater.sources.
+// type=git uses url (+ optional ref), type=pypi uses package (+ optional index_url mirror),
+// type=local uses path to a wheel or sdist.
+type UpdateSourceConfig struct {
+	Name     string        `yaml:"name" mapstructure:"name"`           // 可选名称, 用于日志和更新记录
+	Type     string        `yaml:"type" mapstructure:"type"`           // git / pypi / local
+	URL      string        `yaml:"url" mapstructure:"url"`             // git 仓库地址
+	Ref      string        `yaml:"ref" mapstructure:"ref"`             // git 分支/标签/提交 (可选)
+	Package  string        `yaml:"package" mapstructure:"package"`     // PyPI 包名 (默认 nanobot-ai)
+	IndexURL string        `yaml:"index_url" mapstructure:"index_url"` // PyPI 镜像地址 (可选, 如清华/阿里云镜像)
+	Path     string        `yaml:"path" mapstructure:"path"`           // 本地 wheel/sdist 路径
+	Timeout  time.Duration `yaml:"timeout" mapstructure:"timeout"`     // 单次尝试超时 (0 表示默认 5 分钟)
+	Retries  int           `yaml:"retries" mapstructure:"retries"`     // 失败后的重试次数
+}
+
+// RollbackConfig controls automatic rollback when instances fail to start after an update.
+// Rollback is triggered when all instances fail to start, or when the failed fraction
+// exceeds FailureThreshold.
+type RollbackConfig struct {
+	Enabled          bool    `yaml:"enabled" mapstructure:"enabled"`                     // 是否启用自动回滚
+	FailureThreshold float64 `yaml:"failure_threshold" mapstructure:"failure_threshold"` // 启动失败比例阈值 (0, 1], 1 表示仅在全部失败时回滚; 0 视为 1
+}
+
+// Validate validates the UpdaterConfig values.
+func (u *UpdaterConfig) Validate() error {
+	var errs []error
+	for i := range u.Sources {
+		if err := u.Sources[i].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("updater.sources[%d]: %w", i, err))
+		}
+	}
+	if err := u.Rollback.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := u.Canary.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	switch u.Strategy {
+	case "", StrategyStopFirst, StrategyStaged:
+	default:
+		errs = append(errs, fmt.Errorf("updater.strategy 必须是 stop_first 或 staged，当前值: %q", u.Strategy))
+	}
+
+	names := make(map[string]bool)
+	dirs := make(map[string]string)
+	for i := range u.Channels {
+		ch := &u.Channels[i]
+		if err := ch.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("updater.channels[%d]: %w", i, err))
+			continue
+		}
+		if names[ch.Name] {
+			errs = append(errs, fmt.Errorf("updater.channels[%d]: channel 名称 %q 重复", i, ch.Name))
+		}
+		names[ch.Name] = true
+		if u.IsStaged() && !isPlainName(ch.Name) {
+			// staged 策略的槽位目录为 <staging_dir>/channels/<name>
+			errs = append(errs, fmt.Errorf("updater.channels[%d]: staged 策略下 channel 名称 %q 不能包含路径分隔符", i, ch.Name))
+		}
+		dir := filepath.Clean(ch.InstallDir)
+		if other, ok := dirs[dir]; ok {
+			errs = append(errs, fmt.Errorf("updater.channels[%d]: install_dir %q 已被 channel %q 使用", i, ch.InstallDir, other))
+		}
+		dirs[dir] = ch.Name
+	}
+	return errors.Join(errs...)
+}
+
+// IsStaged reports whether new versions are installed with StrategyStaged.
+func (u *UpdaterConfig) IsStaged() bool {
+	return u.Strategy == StrategyStaged
+}
+
+// EffectiveStrategy returns the update strategy, defaulting to StrategyStopFirst.
+func (u *UpdaterConfig) EffectiveStrategy() string {
+	if u.Strategy == "" {
+		return StrategyStopFirst
+	}
+	return u.Strategy
+}
+
+// EffectiveStagingDir returns the staging directory, defaulting to DefaultStagingDir.
+func (u *UpdaterConfig) EffectiveStagingDir() string {
+	if u.StagingDir == "" {
+		return DefaultStagingDir
+	}
+	return u.StagingDir
+}
+
+// isPlainName reports whether name can be used as a single path element.
+func isPlainName(name string) bool {
+	return name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// Channel returns the updater.channels entry named name.
+func (u *UpdaterConfig) Channel(name string) (ChannelConfig, bool) {
+	for _, ch := range u.Channels {
+		if ch.Name == name {
+			return ch, true
+		}
+	}
+	return ChannelConfig{}, false
+}
+
+// Validate validates the ChannelConfig values.
+func (c *ChannelConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("channel 缺少必填字段 \"name\"")
+	}
+	if c.InstallDir == "" {
+		return fmt.Errorf("channel %q 缺少必填字段 \"install_dir\"", c.Name)
+	}
+	pinned := 0
+	for _, v := range []string{c.Ref, c.Commit, c.PyPIVersion} {
+		if v != "" {
+			pinned++
+		}
+	}
+	if pinned > 1 {
+		return fmt.Errorf("channel %q 的 ref、commit 和 pypi_version 只能设置一个", c.Name)
+	}
+	return nil
+}
+
+// IsPinned reports whether the channel pins a specific version.
+func (c *ChannelConfig) IsPinned() bool {
+	return c.Ref != "" || c.Commit != "" || c.PyPIVersion != ""
+}
+
+// Validate validates the UpdateSourceConfig values.
+func (s *UpdateSourceConfig) Validate() error {
+	switch s.Type {
+	case SourceTypeGit:
+		if s.URL == "" {
+			return fmt.Errorf("git 源必须配置 url")
+		}
+	case SourceTypePyPI:
+		// package 默认 nanobot-ai, index_url 可选
+	case SourceTypeLocal:
+		if s.Path == "" {
+			return fmt.Errorf("local 源必须配置 path")
+		}
+	default:
+		return fmt.Errorf("type 必须是 git、pypi 或 local，当前值: %q", s.Type)
+	}
+
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout 不能为负数，当前值: %v", s.Timeout)
+	}
+	if s.Retries < 0 || s.Retries > 10 {
+		return fmt.Errorf("retries 必须在 0-10 之间，当前值: %d", s.Retries)
+	}
+	return nil
+}
+
+// Validate validates the RollbackConfig values.
+func (r *RollbackConfig) Validate() error {
+	if r.FailureThreshold < 0 || r.FailureThreshold > 1 {
+		return fmt.Errorf("updater.rollback.failure_threshold 必须在 (0, 1] 范围内，当前值: %v", r.FailureThreshold)
+	}
+	return nil
+}
+
+// Validate validates the CanaryConfig values.
+func (c *CanaryConfig) Validate() error {
+	if c.SoakPeriod < 0 {
+		return fmt.Errorf("updater.canary.soak_period 不能为负数，当前值: %v", c.SoakPeriod)
+	}
+	switch c.OnFailure {
+	case "", CanaryOnFailureHold, CanaryOnFailureRollback:
+	default:
+		return fmt.Errorf("updater.canary.on_failure 必须是 hold 或 rollback，当前值: %q", c.OnFailure)
+	}
+	return nil
+}
+
+// EffectiveSoakPeriod returns the soak period, defaulting to DefaultCanarySoakPeriod.
+func (c *CanaryConfig) EffectiveSoakPeriod() time.Duration {
+	if c.SoakPeriod == 0 {
+		return DefaultCanarySoakPeriod
+	}
+	return c.SoakPeriod
+}
+
+// EffectiveOnFailure returns the canary failure handling, defaulting to rollback.
+func (c *CanaryConfig) EffectiveOnFailure() string {
+	if c.OnFailure == "" {
+		return CanaryOnFailureRollback
+	}
+	return c.OnFailure
+}
+
+// ShouldRollback reports whether failed out of total instance starts warrants a rollback.
+func (r *RollbackConfig) ShouldRollback(failed, total int) bool {
+	if !r.Enabled || failed == 0 || total == 0 {
+		return false
+	}
+	if failed >= total {
+		return true
+	}
+	threshold := r.FailureThreshold
+	if threshold == 0 {
+		threshold = 1.0 // Unset: only roll back when all instances fail
+	}
+	return float64(failed)/float64(total) > threshold
+}
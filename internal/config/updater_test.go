@@ -0,0 +1,290 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&RollbackConfig{FailureThreshold: 0.5}).Validate())
+	assert.NoError(t, (&RollbackConfig{FailureThreshold: 1}).Validate())
+	assert.NoError(t, (&RollbackConfig{}).Validate(), "unset threshold falls back to 1")
+
+	err := (&RollbackConfig{FailureThreshold: 1.5}).Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failure_threshold")
+
+	assert.Error(t, (&RollbackConfig{FailureThreshold: -0.1}).Validate())
+}
+
+func TestRollbackConfig_ShouldRollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       RollbackConfig
+		failed    int
+		total     int
+		wantValue bool
+	}{
+		{"disabled", RollbackConfig{Enabled: false, FailureThreshold: 0.1}, 3, 3, false},
+		{"no failures", RollbackConfig{Enabled: true, FailureThreshold: 0.1}, 0, 3, false},
+		{"all failed with default threshold", RollbackConfig{Enabled: true, FailureThreshold: 1}, 3, 3, true},
+		{"partial failure with default threshold", RollbackConfig{Enabled: true, FailureThreshold: 1}, 2, 3, false},
+		{"above fraction", RollbackConfig{Enabled: true, FailureThreshold: 0.5}, 2, 3, true},
+		{"equal to fraction", RollbackConfig{Enabled: true, FailureThreshold: 0.5}, 1, 2, false},
+		{"unset threshold only on all failed", RollbackConfig{Enabled: true}, 2, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantValue, tt.cfg.ShouldRollback(tt.failed, tt.total))
+		})
+	}
+}
+
+func TestConfig_Updater_Defaults(t *testing.T) {
+	cfg := &Config{}
+	cfg.defaults()
+	assert.True(t, cfg.Updater.Rollback.Enabled)
+	assert.Equal(t, 1.0, cfg.Updater.Rollback.FailureThreshold)
+}
+
+func TestUpdateSourceConfig_Validate(t *testing.T) {
+	valid := []UpdateSourceConfig{
+		{Type: "git", URL: "https://github.com/HKUDS/nanobot.git", Ref: "main"},
+		{Type: "pypi"},
+		{Type: "pypi", Package: "nanobot-ai", IndexURL: "https://pypi.tuna.tsinghua.edu.cn/simple", Retries: 2, Timeout: time.Minute},
+		{Type: "local", Path: "/opt/wheels/nanobot_ai-0.1.4-py3-none-any.whl"},
+	}
+	for _, s := range valid {
+		assert.NoError(t, s.Validate(), "%+v", s)
+	}
+
+	invalid := []struct {
+		source UpdateSourceConfig
+		errMsg string
+	}{
+		{UpdateSourceConfig{Type: "svn"}, "type"},
+		{UpdateSourceConfig{Type: "git"}, "url"},
+		{UpdateSourceConfig{Type: "local"}, "path"},
+		{UpdateSourceConfig{Type: "pypi", Retries: 11}, "retries"},
+		{UpdateSourceConfig{Type: "pypi", Timeout: -time.Second}, "timeout"},
+	}
+	for _, tt := range invalid {
+		err := tt.source.Validate()
+		assert.Error(t, err, "%+v", tt.source)
+		if err != nil {
+			assert.Contains(t, err.Error(), tt.errMsg)
+		}
+	}
+}
+
+func TestLoad_UpdaterSources(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "inst"
+    port: 18790
+    start_command: "nanobot gateway"
+updater:
+  sources:
+    - name: "tsinghua"
+      type: "pypi"
+      index_url: "https://pypi.tuna.tsinghua.edu.cn/simple"
+      timeout: 2m
+      retries: 1
+    - type: "git"
+      url: "https://github.com/HKUDS/nanobot.git"
+      ref: "main"
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	cfg, err := Load(configPath)
+	require.NoError(t, err)
+	require.Len(t, cfg.Updater.Sources, 2)
+	assert.Equal(t, "tsinghua", cfg.Updater.Sources[0].Name)
+	assert.Equal(t, 2*time.Minute, cfg.Updater.Sources[0].Timeout)
+	assert.Equal(t, 1, cfg.Updater.Sources[0].Retries)
+	assert.Equal(t, "git", cfg.Updater.Sources[1].Type)
+	assert.True(t, cfg.Updater.Rollback.Enabled, "rollback default should survive a partial updater section")
+
+	_, err = Load(writeTempConfig(t, yaml+"    - type: \"ftp\"\n"))
+	assert.Error(t, err)
+}
+
+func TestUpdaterConfig_Channels(t *testing.T) {
+	valid := UpdaterConfig{Channels: []ChannelConfig{
+		{Name: "stable", InstallDir: "/opt/nanobot/stable", PyPIVersion: "0.1.4"},
+		{Name: "canary", InstallDir: "/opt/nanobot/canary", Ref: "main"},
+	}}
+	assert.NoError(t, valid.Validate())
+
+	ch, ok := valid.Channel("canary")
+	assert.True(t, ok)
+	assert.True(t, ch.IsPinned())
+	_, ok = valid.Channel("missing")
+	assert.False(t, ok)
+
+	invalid := []ChannelConfig{
+		{InstallDir: "/opt/nanobot/a"},
+		{Name: "a"},
+		{Name: "a", InstallDir: "/opt/nanobot/a", Ref: "main", PyPIVersion: "0.1.4"},
+	}
+	for _, ch := range invalid {
+		assert.Error(t, (&UpdaterConfig{Channels: []ChannelConfig{ch}}).Validate(), "channel %+v", ch)
+	}
+
+	dupName := UpdaterConfig{Channels: []ChannelConfig{{Name: "a", InstallDir: "/x"}, {Name: "a", InstallDir: "/y"}}}
+	assert.Error(t, dupName.Validate())
+	dupDir := UpdaterConfig{Channels: []ChannelConfig{{Name: "a", InstallDir: "/x"}, {Name: "b", InstallDir: "/x/"}}}
+	assert.Error(t, dupDir.Validate())
+}
+
+func TestLoad_InstanceChannel(t *testing.T) {
+	yaml := `api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "stable-bot"
+    port: 18790
+    start_command: "nanobot gateway"
+    channel: "stable"
+  - name: "global-bot"
+    port: 18791
+    start_command: "nanobot gateway"
+updater:
+  channels:
+    - name: "stable"
+      install_dir: "/opt/nanobot/stable"
+      pypi_version: "0.1.4"
+`
+	cfg, err := Load(writeTempConfig(t, yaml))
+	require.NoError(t, err)
+	assert.Equal(t, "stable", cfg.Instances[0].Channel)
+	assert.Empty(t, cfg.Instances[1].Channel)
+	require.Len(t, cfg.Updater.Channels, 1)
+	assert.Equal(t, "0.1.4", cfg.Updater.Channels[0].PyPIVersion)
+
+	_, err = Load(writeTempConfig(t, strings.Replace(yaml, `channel: "stable"`, `channel: "canary"`, 1)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "canary")
+}
+
+func TestLoad_Canary(t *testing.T) {
+	yaml := `api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "canary-bot"
+    port: 18790
+    start_command: "nanobot gateway"
+    canary: true
+  - name: "main-bot"
+    port: 18791
+    start_command: "nanobot gateway"
+`
+	cfg, err := Load(writeTempConfig(t, yaml))
+	require.NoError(t, err)
+	assert.True(t, cfg.Instances[0].Canary)
+	assert.False(t, cfg.Instances[1].Canary)
+	assert.Equal(t, DefaultCanarySoakPeriod, cfg.Updater.Canary.SoakPeriod)
+	assert.Equal(t, CanaryOnFailureRollback, cfg.Updater.Canary.OnFailure)
+
+	cfg, err = Load(writeTempConfig(t, yaml+`updater:
+  canary:
+    soak_period: 2m
+    on_failure: hold
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, cfg.Updater.Canary.EffectiveSoakPeriod())
+	assert.Equal(t, CanaryOnFailureHold, cfg.Updater.Canary.EffectiveOnFailure())
+
+	_, err = Load(writeTempConfig(t, yaml+`updater:
+  canary:
+    on_failure: ignore
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "on_failure")
+
+	var zero CanaryConfig
+	assert.Equal(t, DefaultCanarySoakPeriod, zero.EffectiveSoakPeriod())
+	assert.Equal(t, CanaryOnFailureRollback, zero.EffectiveOnFailure())
+	assert.Error(t, (&CanaryConfig{SoakPeriod: -time.Second}).Validate())
+}
+
+func TestLoad_Strategy(t *testing.T) {
+	yaml := `api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "bot"
+    port: 18790
+    start_command: "nanobot gateway"
+`
+	cfg, err := Load(writeTempConfig(t, yaml))
+	require.NoError(t, err)
+	assert.Equal(t, StrategyStopFirst, cfg.Updater.Strategy)
+	assert.False(t, cfg.Updater.IsStaged())
+	assert.Equal(t, DefaultStagingDir, cfg.Updater.StagingDir)
+
+	cfg, err = Load(writeTempConfig(t, yaml+`updater:
+  strategy: staged
+  staging_dir: "/opt/nanobot/staging"
+`))
+	require.NoError(t, err)
+	assert.True(t, cfg.Updater.IsStaged())
+	assert.Equal(t, "/opt/nanobot/staging", cfg.Updater.EffectiveStagingDir())
+
+	_, err = Load(writeTempConfig(t, yaml+`updater:
+  strategy: blue_green
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "strategy")
+
+	var zero UpdaterConfig
+	assert.Equal(t, StrategyStopFirst, zero.EffectiveStrategy())
+	assert.Equal(t, DefaultStagingDir, zero.EffectiveStagingDir())
+
+	// staged 槽位目录使用 channel 名称
+	staged := UpdaterConfig{Strategy: StrategyStaged, Channels: []ChannelConfig{{Name: "../stable", InstallDir: "/opt/nanobot/stable"}}}
+	assert.Error(t, staged.Validate())
+	staged.Strategy = StrategyStopFirst
+	assert.NoError(t, staged.Validate())
+}
+
+func TestLoad_StagedStartCommand(t *testing.T) {
+	config := func(startCommand string) string {
+		return `api:
+  bearer_token: "test-token-123456789012345678901"
+instances:
+  - name: "bot"
+    port: 18790
+    start_command: '` + startCommand + `'
+updater:
+  strategy: staged
+`
+	}
+
+	// 不带路径的可执行文件从槽位的 bin 目录查找
+	for _, cmd := range []string{"nanobot gateway", `"nanobot" gateway`, `sh -c "nanobot gateway"`} {
+		_, err := Load(writeTempConfig(t, config(cmd)))
+		assert.NoError(t, err, cmd)
+	}
+
+	// 带路径的可执行文件切换后仍运行旧安装
+	for _, cmd := range []string{"/root/.local/bin/nanobot gateway", `"C:\Users\bot\.local\bin\nanobot.exe" gateway`, "./nanobot gateway"} {
+		_, err := Load(writeTempConfig(t, config(cmd)))
+		require.Error(t, err, cmd)
+		assert.Contains(t, err.Error(), "start_command", cmd)
+	}
+}
+
+func writeTempConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
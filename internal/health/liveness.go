@@ -0,0 +1,110 @@
+package health
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+)
+
+// Names of the liveness probes (ProbeResult.Name)
+const (
+	ProbeTCP        = "tcp"
+	ProbeHTTP       = "http"
+	ProbeLogSilence = "log_silence"
+)
+
+// LivenessStatus is the liveness state of a running instance (health_check.liveness),
+// included in GET /api/v1/instances/status
+type LivenessStatus struct {
+	Healthy              bool          `json:"healthy"`                // 连续失败 failure_threshold 次后为 false, 连续成功 success_threshold 次后恢复
+	ConsecutiveFailures  int           `json:"consecutive_failures"`   // 连续失败的检查次数
+	ConsecutiveSuccesses int           `json:"consecutive_successes"`  // 连续成功的检查次数
+	LastCheck            time.Time     `json:"last_check"`             // 最近一次检查的时间
+	LastFailure          string        `json:"last_failure,omitempty"` // 最近一次失败的原因
+	Restarts             int           `json:"restarts"`               // 存活检查触发的重启次数
+	Probes               []ProbeResult `json:"probes"`                 // 最近一次检查各探针的结果
+}
+
+// ProbeResult is the result of a single liveness probe
+type ProbeResult struct {
+	Name      string `json:"name"` // tcp / http / log_silence
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// runProbes runs the configured liveness probes against a running instance
+func runProbes(cfg config.LivenessConfig, client *http.Client, status InstanceStatus, now time.Time) []ProbeResult {
+	var results []ProbeResult
+	probe := func(name string, check func() error) {
+		start := time.Now()
+		err := check()
+		result := ProbeResult{Name: name, OK: err == nil, LatencyMs: time.Since(start).Milliseconds()}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.FormatUint(uint64(status.Port), 10))
+	if cfg.TCP {
+		probe(ProbeTCP, func() error {
+			conn, err := net.DialTimeout("tcp", addr, cfg.EffectiveTimeout())
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		})
+	}
+	if cfg.HTTP != "" {
+		probe(ProbeHTTP, func() error { return checkHTTP(client, "http://"+addr+cfg.HTTP, cfg.HTTPStatus) })
+	}
+	if cfg.LogSilence > 0 {
+		probe(ProbeLogSilence, func() error { return checkLogSilence(status, cfg.LogSilence, now) })
+	}
+	return results
+}
+
+// checkHTTP GETs url and checks the status: want, or any 2xx/3xx when want is 0
+func checkHTTP(client *http.Client, url string, want int) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if want != 0 && resp.StatusCode != want {
+		return fmt.Errorf("GET %s returned %s, want %d", url, resp.Status, want)
+	}
+	if want == 0 && resp.StatusCode >= 400 {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// checkLogSilence fails when the instance has not written any output for longer than silence.
+// The process start counts as output, so a freshly (re)started instance is not reported silent.
+func checkLogSilence(status InstanceStatus, silence time.Duration, now time.Time) error {
+	last := status.LastOutputAt
+	if status.StartedAt.After(last) {
+		last = status.StartedAt
+	}
+	if last.IsZero() {
+		return nil // 无法判断
+	}
+	if quiet := now.Sub(last); quiet > silence {
+		return fmt.Errorf("no output for %v (log_silence %v)", quiet.Truncate(time.Second), silence)
+	}
+	return nil
+}
+
+// newProbeClient returns the HTTP client of the HTTP probe: 3xx responses are not followed
+func newProbeClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:       timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
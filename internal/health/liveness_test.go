@@ -0,0 +1,217 @@
+package health
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+)
+
+// listenerPort returns the port of a listener address like 127.0.0.1:12345
+func listenerPort(t *testing.T, addr string) uint32 {
+	t.Helper()
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	require.NoError(t, err)
+	return uint32(tcpAddr.Port)
+}
+
+// closedPort returns a port nothing listens on
+func closedPort(t *testing.T) uint32 {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listenerPort(t, ln.Addr().String())
+	ln.Close()
+	return port
+}
+
+func TestRunProbes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	port := listenerPort(t, server.Listener.Addr().String())
+
+	now := time.Now()
+	client := newProbeClient(time.Second)
+	status := InstanceStatus{Name: "inst", Port: port, Running: true, StartedAt: now.Add(-time.Hour), LastOutputAt: now.Add(-time.Minute)}
+
+	results := runProbes(config.LivenessConfig{TCP: true, HTTP: "/health", HTTPStatus: 204, LogSilence: 5 * time.Minute}, client, status, now)
+	require.Len(t, results, 3)
+	for _, r := range results {
+		assert.True(t, r.OK, "%s: %s", r.Name, r.Error)
+	}
+
+	results = runProbes(config.LivenessConfig{HTTP: "/health", HTTPStatus: 200}, client, status, now)
+	assert.False(t, results[0].OK)
+	assert.Contains(t, results[0].Error, "want 200")
+
+	results = runProbes(config.LivenessConfig{HTTP: "/missing"}, client, status, now)
+	assert.False(t, results[0].OK)
+	assert.Contains(t, results[0].Error, "404")
+
+	results = runProbes(config.LivenessConfig{LogSilence: 30 * time.Second}, client, status, now)
+	assert.Equal(t, ProbeLogSilence, results[0].Name)
+	assert.False(t, results[0].OK)
+	assert.Contains(t, results[0].Error, "no output for 1m0s")
+
+	status.Port = closedPort(t)
+	results = runProbes(config.LivenessConfig{TCP: true}, client, status, now)
+	assert.Equal(t, ProbeTCP, results[0].Name)
+	assert.False(t, results[0].OK)
+}
+
+func TestCheckLogSilence_CountsProcessStart(t *testing.T) {
+	now := time.Now()
+	// 刚重启的实例还没有输出, 启动时间算作最近一次输出
+	status := InstanceStatus{StartedAt: now.Add(-time.Minute), LastOutputAt: now.Add(-time.Hour)}
+	assert.NoError(t, checkLogSilence(status, 10*time.Minute, now))
+	assert.NoError(t, checkLogSilence(InstanceStatus{}, time.Minute, now))
+}
+
+func TestMonitor_LivenessThresholdsAndRestart(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	openPort := listenerPort(t, ln.Addr().String())
+
+	var mu sync.Mutex
+	status := InstanceStatus{Name: "inst", Port: closedPort(t), Running: true, PID: 42}
+	hm := NewHealthMonitor(func() []InstanceStatus {
+		mu.Lock()
+		defer mu.Unlock()
+		return []InstanceStatus{status}
+	}, 30*time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	hm.SetLiveness(config.LivenessConfig{TCP: true, Timeout: time.Second, FailureThreshold: 2, SuccessThreshold: 2, Restart: true})
+
+	var reported []*LivenessStatus
+	hm.SetOnLiveness(func(name string, s *LivenessStatus) { reported = append(reported, s) })
+	var restarted []string
+	hm.SetRestart(func(ctx context.Context, name string) error {
+		restarted = append(restarted, name)
+		mu.Lock()
+		status.Port = openPort // 重启后的进程正常监听
+		mu.Unlock()
+		return nil
+	})
+
+	// 第一次失败: 未达到 failure_threshold
+	hm.checkAllInstances()
+	live := hm.Liveness("inst")
+	require.NotNil(t, live)
+	assert.True(t, live.Healthy)
+	assert.Equal(t, 1, live.ConsecutiveFailures)
+	assert.Empty(t, restarted)
+
+	// 第二次失败: 不健康, 触发重启, 新进程重新计数
+	hm.checkAllInstances()
+	hm.restarts.Wait()
+	assert.Equal(t, []string{"inst"}, restarted)
+	require.Len(t, reported, 3)
+	assert.False(t, reported[1].Healthy)
+	assert.Equal(t, 2, reported[1].ConsecutiveFailures)
+	assert.Contains(t, reported[1].LastFailure, "tcp:")
+	live = hm.Liveness("inst")
+	assert.True(t, live.Healthy)
+	assert.Equal(t, 0, live.ConsecutiveFailures)
+	assert.Equal(t, 1, live.Restarts)
+
+	hm.checkAllInstances()
+	live = hm.Liveness("inst")
+	assert.Equal(t, 1, live.ConsecutiveSuccesses)
+	require.Len(t, live.Probes, 1)
+	assert.True(t, live.Probes[0].OK)
+
+	// 实例停止后清除存活状态
+	mu.Lock()
+	status.Running = false
+	mu.Unlock()
+	hm.checkAllInstances()
+	assert.Nil(t, hm.Liveness("inst"))
+	assert.Nil(t, reported[len(reported)-1])
+	assert.Len(t, restarted, 1)
+}
+
+func TestMonitor_LivenessRecoversAfterSuccessThreshold(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	status := InstanceStatus{Name: "inst", Port: closedPort(t), Running: true}
+	hm := NewHealthMonitor(func() []InstanceStatus { return []InstanceStatus{status} },
+		30*time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	hm.SetLiveness(config.LivenessConfig{TCP: true, Timeout: time.Second, FailureThreshold: 1, SuccessThreshold: 2})
+
+	hm.checkAllInstances()
+	assert.False(t, hm.Liveness("inst").Healthy, "unhealthy after failure_threshold failures")
+
+	status.Port = listenerPort(t, ln.Addr().String())
+	hm.checkAllInstances()
+	assert.False(t, hm.Liveness("inst").Healthy, "still unhealthy before success_threshold successes")
+	hm.checkAllInstances()
+	live := hm.Liveness("inst")
+	assert.True(t, live.Healthy)
+	assert.Equal(t, 0, live.Restarts, "no restart without liveness.restart")
+}
+
+func TestMonitor_RestartRunsInBackground(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	down := InstanceStatus{Name: "down", Port: closedPort(t), Running: true}
+	up := InstanceStatus{Name: "up", Port: listenerPort(t, ln.Addr().String()), Running: true}
+	hm := NewHealthMonitor(func() []InstanceStatus { return []InstanceStatus{down, up} },
+		30*time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	hm.SetLiveness(config.LivenessConfig{TCP: true, Timeout: time.Second, FailureThreshold: 1, Restart: true})
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var restarted []string
+	hm.SetRestart(func(ctx context.Context, name string) error {
+		mu.Lock()
+		restarted = append(restarted, name)
+		mu.Unlock()
+		<-release
+		return nil
+	})
+
+	// 重启阻塞时检查循环不等待, 其他实例照常检查
+	done := make(chan struct{})
+	go func() {
+		hm.checkAllInstances()
+		hm.checkAllInstances()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("checkAllInstances() blocked on the restart")
+	}
+	assert.Equal(t, 2, hm.Liveness("up").ConsecutiveSuccesses)
+
+	// 重启进行中的实例不再检查, 也不会重复重启
+	live := hm.Liveness("down")
+	assert.False(t, live.Healthy)
+	assert.Equal(t, 1, live.ConsecutiveFailures)
+	assert.Equal(t, 1, live.Restarts)
+
+	close(release)
+	hm.restarts.Wait()
+	assert.Equal(t, []string{"down"}, restarted)
+	assert.True(t, hm.Liveness("down").Healthy)
+}
@@ -3,10 +3,17 @@ package health
 import (
 	"context"
 	"log/slog"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
 )
 
+// restartTimeout bounds a restart triggered by a failed liveness probe
+const restartTimeout = 5 * time.Minute
+
 // InstanceStatus holds the running status of a single instance.
 // Returned by the status check function provided to HealthMonitor.
 type InstanceStatus struct {
@@ -14,12 +21,18 @@ type InstanceStatus struct {
 	Port    uint32
 	Running bool
 	PID     int32
+
+	StartedAt    time.Time // 当前进程的启动时间 (log_silence 检查)
+	LastOutputAt time.Time // 实例最近一次输出的时间 (LogBuffer), 没有输出时为零值
 }
 
 // InstanceHealthState tracks instance health
 type InstanceHealthState struct {
 	IsRunning bool
 	LastCheck time.Time
+	Liveness  *LivenessStatus // 运行中且配置了 liveness 时的存活状态
+
+	restarting bool // 存活检查触发的重启进行中, 期间不再检查该实例
 }
 
 // HealthMonitor manages health check loop
@@ -31,6 +44,12 @@ type HealthMonitor struct {
 	mu            sync.RWMutex
 	ctx           context.Context
 	cancel        context.CancelFunc
+
+	liveness   config.LivenessConfig
+	client     *http.Client
+	onLiveness func(name string, status *LivenessStatus)
+	restart    func(ctx context.Context, name string) error
+	restarts   sync.WaitGroup // 进行中的重启 goroutine
 }
 
 // NewHealthMonitor creates a new health monitor.
@@ -52,6 +71,37 @@ func NewHealthMonitor(
 	}
 }
 
+// SetLiveness enables the liveness probes of health_check.liveness for running instances.
+func (hm *HealthMonitor) SetLiveness(cfg config.LivenessConfig) {
+	hm.liveness = cfg
+	hm.client = newProbeClient(cfg.EffectiveTimeout())
+}
+
+// SetOnLiveness registers a callback receiving the liveness status of an instance after each
+// check, or nil when the instance is not running (its status is reset).
+func (hm *HealthMonitor) SetOnLiveness(fn func(name string, status *LivenessStatus)) {
+	hm.onLiveness = fn
+}
+
+// SetRestart registers the function restarting an instance that became unhealthy.
+// It is only called when health_check.liveness.restart is enabled.
+func (hm *HealthMonitor) SetRestart(fn func(ctx context.Context, name string) error) {
+	hm.restart = fn
+}
+
+// Liveness returns the liveness status of an instance, nil when none is known.
+func (hm *HealthMonitor) Liveness(name string) *LivenessStatus {
+	hm.mu.RLock()
+	defer hm.mu.RUnlock()
+	state, ok := hm.states[name]
+	if !ok || state.Liveness == nil {
+		return nil
+	}
+	status := *state.Liveness
+	status.Probes = append([]ProbeResult(nil), status.Probes...)
+	return &status
+}
+
 // Start begins the health check loop (runs in goroutine)
 func (hm *HealthMonitor) Start() {
 	hm.logger.Info("Health monitor started", "interval", hm.interval)
@@ -78,6 +128,9 @@ func (hm *HealthMonitor) checkAllInstances() {
 	statuses := hm.checkStatuses()
 	for _, status := range statuses {
 		hm.checkInstance(status)
+		if hm.liveness.Enabled() {
+			hm.checkLiveness(status)
+		}
 	}
 }
 
@@ -126,6 +179,124 @@ func (hm *HealthMonitor) checkInstance(status InstanceStatus) {
 	}
 }
 
+// checkLiveness runs the liveness probes of a running instance, applies the failure and
+// success thresholds and restarts the instance when it becomes unhealthy (liveness.restart).
+// The restart runs in its own goroutine so it does not delay the checks of other instances;
+// the instance is not checked again until its restart has finished.
+func (hm *HealthMonitor) checkLiveness(status InstanceStatus) {
+	hm.mu.RLock()
+	restarting := hm.states[status.Name].restarting
+	hm.mu.RUnlock()
+	if restarting {
+		return
+	}
+
+	if !status.Running {
+		hm.mu.Lock()
+		state := hm.states[status.Name]
+		reset := state != nil && state.Liveness != nil
+		if reset {
+			state.Liveness = nil
+		}
+		hm.mu.Unlock()
+		if reset && hm.onLiveness != nil {
+			hm.onLiveness(status.Name, nil)
+		}
+		return
+	}
+
+	// 探针在锁外执行 (网络 IO)
+	now := time.Now()
+	probes := runProbes(hm.liveness, hm.client, status, now)
+	var failures []string
+	for _, p := range probes {
+		if !p.OK {
+			failures = append(failures, p.Name+": "+p.Error)
+		}
+	}
+
+	hm.mu.Lock()
+	state := hm.states[status.Name]
+	if state.Liveness == nil {
+		state.Liveness = &LivenessStatus{Healthy: true}
+	}
+	live := state.Liveness
+	live.LastCheck = now
+	live.Probes = probes
+
+	restart := false
+	if len(failures) == 0 {
+		live.ConsecutiveSuccesses++
+		live.ConsecutiveFailures = 0
+		if !live.Healthy && live.ConsecutiveSuccesses >= hm.liveness.EffectiveSuccessThreshold() {
+			live.Healthy = true
+			hm.logger.Info("Instance liveness recovered", "instance", status.Name)
+		}
+	} else {
+		live.ConsecutiveFailures++
+		live.ConsecutiveSuccesses = 0
+		live.LastFailure = strings.Join(failures, "; ")
+		hm.logger.Warn("Instance liveness probe failed",
+			"instance", status.Name,
+			"consecutive_failures", live.ConsecutiveFailures,
+			"reason", live.LastFailure)
+		if live.Healthy && live.ConsecutiveFailures >= hm.liveness.EffectiveFailureThreshold() {
+			live.Healthy = false
+			hm.logger.Error("Instance is unhealthy",
+				"instance", status.Name,
+				"pid", status.PID,
+				"consecutive_failures", live.ConsecutiveFailures,
+				"reason", live.LastFailure)
+			restart = hm.liveness.Restart && hm.restart != nil
+		}
+	}
+	if restart {
+		live.Restarts++
+		state.restarting = true
+		hm.restarts.Add(1)
+	}
+	result := *live
+	hm.mu.Unlock()
+
+	if hm.onLiveness != nil {
+		hm.onLiveness(status.Name, &result)
+	}
+	if restart {
+		go hm.restartUnhealthy(status.Name)
+	}
+}
+
+// restartUnhealthy restarts an unhealthy instance; the new process starts out healthy
+// and becomes unhealthy again only after failure_threshold failed checks
+func (hm *HealthMonitor) restartUnhealthy(name string) {
+	defer hm.restarts.Done()
+
+	hm.logger.Warn("Restarting unhealthy instance", "instance", name)
+	ctx, cancel := context.WithTimeout(hm.ctx, restartTimeout)
+	defer cancel()
+	if err := hm.restart(ctx, name); err != nil {
+		hm.logger.Error("Failed to restart unhealthy instance", "instance", name, "error", err)
+	}
+
+	hm.mu.Lock()
+	state := hm.states[name]
+	state.restarting = false
+	live := state.Liveness
+	if live == nil {
+		hm.mu.Unlock()
+		return
+	}
+	live.Healthy = true
+	live.ConsecutiveFailures = 0
+	live.ConsecutiveSuccesses = 0
+	result := *live
+	hm.mu.Unlock()
+
+	if hm.onLiveness != nil {
+		hm.onLiveness(name, &result)
+	}
+}
+
 // Stop gracefully stops the monitor
 func (hm *HealthMonitor) Stop() {
 	if hm.cancel != nil {
@@ -0,0 +1,192 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/lifecycle"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// AbortStageCanary is recorded in UpdateResult.AbortStage when a canary instance failed
+const AbortStageCanary = "canary"
+
+// canaryPollInterval 是 canary 观察期间检查进程和 Telegram 状态的间隔
+const canaryPollInterval = time.Second
+
+// IsCanary reports whether the instance is started first after an update (canary: true)
+func (il *InstanceLifecycle) IsCanary() bool {
+	return il.config.Canary
+}
+
+// checkHealth 检查实例进程是否存活, Telegram 是否连接失败; checkPort 为 true 时还检查端口是否在监听
+// 观察期内进程崩溃后已被 restart 策略重启, 同样视为不健康
+func (il *InstanceLifecycle) checkHealth(checkPort bool) error {
+	if state := il.State(); state.State != StateRunning {
+		if state.LastExitReason != "" {
+			return fmt.Errorf("instance is %s, process exited: %s", state.State, state.LastExitReason)
+		}
+		return fmt.Errorf("instance is %s", state.State)
+	}
+	il.mu.Lock()
+	crashes, lastExit := il.sup.status.CrashCount-il.sup.startCrashes, il.sup.status.LastExitError
+	monitor := il.telegramMonitor
+	il.mu.Unlock()
+	if crashes > 0 {
+		return fmt.Errorf("process crashed %d time(s) since start: %s", crashes, lastExit)
+	}
+	if monitor != nil {
+		if failure := monitor.Failure(); failure != "" {
+			return fmt.Errorf("%s", failure)
+		}
+	}
+	if checkPort {
+		pid, err := lifecycle.FindPIDByPort(il.config.Port, il.logger)
+		if err != nil {
+			return fmt.Errorf("failed to check port %d: %w", il.config.Port, err)
+		}
+		if pid == 0 {
+			return fmt.Errorf("port %d is not listening", il.config.Port)
+		}
+	}
+	return nil
+}
+
+// splitCanaries 将实例分为 canary 实例和其余实例, 保持配置顺序
+func splitCanaries(instances []*InstanceLifecycle) (canaries, rest []*InstanceLifecycle) {
+	for _, inst := range instances {
+		if inst.IsCanary() {
+			canaries = append(canaries, inst)
+		} else {
+			rest = append(rest, inst)
+		}
+	}
+	return canaries, rest
+}
+
+// startWithCanaries 先启动 canary 实例并观察 updater.canary.soak_period, 全部健康后才启动其余实例.
+// canary 启动失败或观察期内不健康时中止更新: on_failure=hold 时其余实例保持停止,
+// on_failure=rollback 时重新安装更新前的版本并启动该分组的所有实例
+func (m *InstanceManager) startWithCanaries(ctx context.Context, u *updater.Updater, instances, canaries, rest []*InstanceLifecycle, result *UpdateResult) {
+	canaryCfg := m.updaterCfg.Canary
+	m.logger.Info("Starting canary phase",
+		"canaries", instanceNames(canaries),
+		"soak_period", canaryCfg.EffectiveSoakPeriod(),
+		"on_failure", canaryCfg.EffectiveOnFailure())
+
+	failedBefore := len(result.StartFailed)
+	m.startInstances(ctx, canaries, result)
+	if len(result.StartFailed) > failedBefore {
+		failed := result.StartFailed[failedBefore]
+		m.abortCanary(ctx, u, instances, rest, fmt.Sprintf("canary %s failed to start: %v", failed.InstanceName, failed.Err), result)
+		return
+	}
+
+	jobFrom(ctx).enter(JobVerifying)
+	if inst, err := m.soakCanaries(ctx, canaries, canaryCfg.EffectiveSoakPeriod()); err != nil {
+		// 观察期内失败的 canary 记为启动失败
+		result.Started = removeName(result.Started, inst.Name())
+		result.StartFailed = append(result.StartFailed, &InstanceError{
+			InstanceName: inst.Name(),
+			Operation:    "canary",
+			Port:         inst.Port(),
+			Err:          err,
+		})
+		jobFrom(ctx).setInstance(inst.Name(), ProgressStartFailed, err)
+		m.abortCanary(ctx, u, instances, rest, fmt.Sprintf("canary %s unhealthy during soak period: %v", inst.Name(), err), result)
+		return
+	}
+
+	m.logger.Info("Canary phase passed, starting remaining instances", "instance_count", len(rest))
+	jobFrom(ctx).enter(JobStarting)
+	m.startInstances(ctx, rest, result)
+}
+
+// soakCanaries 在 soakPeriod 内定期检查 canary 实例, 结束时检查端口.
+// 返回第一个不健康的实例及原因; ctx 取消视为失败
+func (m *InstanceManager) soakCanaries(ctx context.Context, canaries []*InstanceLifecycle, soakPeriod time.Duration) (*InstanceLifecycle, error) {
+	deadline := time.NewTimer(soakPeriod)
+	defer deadline.Stop()
+	ticker := time.NewTicker(canaryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return canaries[0], fmt.Errorf("soak period interrupted: %w", ctx.Err())
+		case <-ticker.C:
+			for _, inst := range canaries {
+				if err := inst.checkHealth(false); err != nil {
+					return inst, err
+				}
+			}
+		case <-deadline.C:
+			for _, inst := range canaries {
+				if err := inst.checkHealth(true); err != nil {
+					return inst, err
+				}
+			}
+			return nil, nil
+		}
+	}
+}
+
+// abortCanary 记录 canary 阶段中止, 并按 on_failure 回滚或保持其余实例停止
+func (m *InstanceManager) abortCanary(ctx context.Context, u *updater.Updater, instances, rest []*InstanceLifecycle, reason string, result *UpdateResult) {
+	result.Aborted = true
+	result.AbortStage = AbortStageCanary
+	result.AbortReason = reason
+	m.logger.Error("Canary failed, aborting update", "reason", reason, "on_failure", m.updaterCfg.Canary.EffectiveOnFailure())
+
+	if m.updaterCfg.Canary.EffectiveOnFailure() == config.CanaryOnFailureRollback {
+		m.rollback(ctx, u, instances, result)
+	}
+
+	// 未启动 (也未尝试启动) 的非 canary 实例保持停止
+	for _, inst := range rest {
+		if !containsName(result.Started, inst.Name()) && !containsError(result.StartFailed, inst.Name()) {
+			result.HeldBack = append(result.HeldBack, inst.Name())
+		}
+	}
+	if len(result.HeldBack) > 0 {
+		m.logger.Warn("Instances held back after canary failure", "instances", result.HeldBack)
+	}
+}
+
+func instanceNames(instances []*InstanceLifecycle) []string {
+	names := make([]string, len(instances))
+	for i, inst := range instances {
+		names[i] = inst.Name()
+	}
+	return names
+}
+
+func removeName(names []string, name string) []string {
+	out := names[:0]
+	for _, n := range names {
+		if n != name {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsError(errs []*InstanceError, name string) bool {
+	for _, err := range errs {
+		if err.InstanceName == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// listenPorts makes the test process listen on ports so the canary port check passes
+func listenPorts(t *testing.T, ports ...uint32) {
+	t.Helper()
+	for _, port := range ports {
+		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Fatalf("failed to listen on port %d: %v", port, err)
+		}
+		t.Cleanup(func() { ln.Close() })
+	}
+}
+
+// sleepCommand returns a start command that stays alive for seconds (--port keeps the port flag out of sleep's arguments)
+func sleepCommand(seconds int, port uint32) string {
+	return fmt.Sprintf(`sh -c "exec sleep %d # --port %d"`, seconds, port)
+}
+
+func newCanaryTestManager(t *testing.T, instances []config.InstanceConfig, canary config.CanaryConfig) *InstanceManager {
+	t.Helper()
+
+	return newUpdateTestManager(t, &config.Config{
+		Instances: instances,
+		Updater: config.UpdaterConfig{
+			Rollback: config.RollbackConfig{Enabled: true, FailureThreshold: 1},
+			Canary:   canary,
+		},
+	})
+}
+
+func TestUpdateAll_CanaryHealthyStartsRemaining(t *testing.T) {
+	setupFakeUv(t, "0.1.4", "0.2.0")
+	listenPorts(t, 18921)
+
+	m := newCanaryTestManager(t, []config.InstanceConfig{
+		{Name: "main", Port: 18922, StartCommand: sleepCommand(30, 18922), StartupTimeout: 5 * time.Second},
+		{Name: "canary", Port: 18921, StartCommand: sleepCommand(30, 18921), StartupTimeout: 5 * time.Second, Canary: true},
+	}, config.CanaryConfig{SoakPeriod: 2 * time.Second, OnFailure: config.CanaryOnFailureHold})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+	if result.Aborted || result.HasErrors() {
+		t.Fatalf("expected healthy canary, got aborted=%v reason=%q start_failed=%v",
+			result.Aborted, result.AbortReason, extractNames(result.StartFailed))
+	}
+	// canary 先启动, 其余实例在观察期之后启动
+	if len(result.Started) != 2 || result.Started[0] != "canary" || result.Started[1] != "main" {
+		t.Errorf("Started = %v, want [canary main]", result.Started)
+	}
+}
+
+func TestUpdateAll_CanaryFailureHoldsBackRemaining(t *testing.T) {
+	setupFakeUv(t, "0.1.4", "0.2.0")
+	listenPorts(t, 18931)
+
+	m := newCanaryTestManager(t, []config.InstanceConfig{
+		// canary 通过 2 秒启动检查后在观察期内退出
+		{Name: "canary", Port: 18931, StartCommand: sleepCommand(3, 18931), StartupTimeout: 5 * time.Second, Canary: true},
+		{Name: "main1", Port: 18932, StartCommand: sleepCommand(30, 18932), StartupTimeout: 5 * time.Second},
+		{Name: "main2", Port: 18933, StartCommand: sleepCommand(30, 18933), StartupTimeout: 5 * time.Second},
+	}, config.CanaryConfig{SoakPeriod: 4 * time.Second, OnFailure: config.CanaryOnFailureHold})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+	if !result.Aborted || result.AbortStage != AbortStageCanary {
+		t.Fatalf("expected abort at canary stage, got aborted=%v stage=%q", result.Aborted, result.AbortStage)
+	}
+	if len(result.StartFailed) != 1 || result.StartFailed[0].InstanceName != "canary" || result.StartFailed[0].Operation != "canary" {
+		t.Errorf("StartFailed = %v, want the canary", extractNames(result.StartFailed))
+	}
+	if len(result.Started) != 0 {
+		t.Errorf("Started = %v, want none", result.Started)
+	}
+	if len(result.HeldBack) != 2 {
+		t.Errorf("HeldBack = %v, want [main1 main2]", result.HeldBack)
+	}
+	if result.RolledBack {
+		t.Error("on_failure=hold must not roll back")
+	}
+	for _, inst := range m.instances[1:] {
+		if inst.IsRunning() {
+			t.Errorf("held back instance %s is running", inst.Name())
+		}
+	}
+}
+
+func TestUpdateAll_CanaryFailureRollsBack(t *testing.T) {
+	statePath, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newCanaryTestManager(t, []config.InstanceConfig{
+		{Name: "canary", Port: 18941, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18941), StartupTimeout: 5 * time.Second, Canary: true},
+		{Name: "main", Port: 18942, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18942), StartupTimeout: 5 * time.Second},
+	}, config.CanaryConfig{SoakPeriod: time.Second})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+	if !result.Aborted || !result.RolledBack {
+		t.Fatalf("expected abort and rollback, got aborted=%v rolled_back=%v (rollback_error=%q)",
+			result.Aborted, result.RolledBack, result.RollbackError)
+	}
+	if len(result.FailedAfterUpdate) != 1 || result.FailedAfterUpdate[0].InstanceName != "canary" {
+		t.Errorf("FailedAfterUpdate = %v, want [canary]", extractNames(result.FailedAfterUpdate))
+	}
+	// main 从未在新版本下启动, 回滚后两个实例都在旧版本下启动
+	if len(result.Started) != 2 || len(result.HeldBack) != 0 {
+		t.Errorf("Started = %v, HeldBack = %v, want both started after rollback", result.Started, result.HeldBack)
+	}
+	if got := readInstallLog(t, logPath); len(got) != 2 || got[1] != "nanobot-ai==0.1.4" {
+		t.Errorf("install log = %v, want new version then nanobot-ai==0.1.4", got)
+	}
+}
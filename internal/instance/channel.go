@@ -0,0 +1,98 @@
+package instance
+
+import (
+	"fmt"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// installGroup 是共享同一 nanobot 安装的实例: 全局安装 (channel 为 nil) 或 updater.channels 中的一个 channel
+type installGroup struct {
+	channel   *config.ChannelConfig
+	instances []*InstanceLifecycle
+}
+
+// String 返回用于日志和错误信息的分组名称
+func (g installGroup) String() string {
+	if g.channel == nil {
+		return "global install"
+	}
+	return fmt.Sprintf("channel %q", g.channel.Name)
+}
+
+// installDir 返回独立安装目录, 全局安装为空
+func (g installGroup) installDir() string {
+	if g.channel == nil {
+		return ""
+	}
+	return g.channel.InstallDir
+}
+
+// target 返回该分组要安装的版本: 固定版本的 channel 忽略请求的目标
+func (g installGroup) target(requested updater.Target) updater.Target {
+	if g.channel == nil || !g.channel.IsPinned() {
+		return requested
+	}
+	return updater.Target{
+		Ref:         g.channel.Ref,
+		Commit:      g.channel.Commit,
+		PyPIVersion: g.channel.PyPIVersion,
+	}
+}
+
+// result 将该分组的更新结果转换为 ChannelResult
+func (g installGroup) result(r *UpdateResult) *ChannelResult {
+	cr := &ChannelResult{
+		InstallDir:      g.installDir(),
+		Target:          r.Target,
+		InstallSpec:     r.InstallSpec,
+		Source:          r.Source,
+		PreviousVersion: r.PreviousVersion,
+		Skipped:         r.Skipped,
+		SkipReason:      r.SkipReason,
+		RolledBack:      r.RolledBack,
+		RollbackError:   r.RollbackError,
+		Aborted:         r.Aborted,
+		AbortReason:     r.AbortReason,
+		Cancelled:       r.Cancelled,
+		StagedDir:       r.StagedDir,
+		Check:           r.Check,
+	}
+	if g.channel != nil {
+		cr.Channel = g.channel.Name
+	}
+	for _, inst := range g.instances {
+		cr.Instances = append(cr.Instances, inst.Name())
+	}
+	return cr
+}
+
+// installGroups 按安装位置对实例分组: 全局安装在前, 然后按 updater.channels 的顺序.
+// 没有实例的 channel 不更新
+func (m *InstanceManager) installGroups() []installGroup {
+	var groups []installGroup
+
+	global := installGroup{}
+	for _, inst := range m.instances {
+		if _, ok := m.updaterCfg.Channel(inst.config.Channel); !ok {
+			global.instances = append(global.instances, inst)
+		}
+	}
+	if len(global.instances) > 0 {
+		groups = append(groups, global)
+	}
+
+	for i := range m.updaterCfg.Channels {
+		g := installGroup{channel: &m.updaterCfg.Channels[i]}
+		for _, inst := range m.instances {
+			if inst.config.Channel == g.channel.Name {
+				g.instances = append(g.instances, inst)
+			}
+		}
+		if len(g.instances) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
@@ -0,0 +1,187 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// fakeChannelUvScript emulates uv with UV_TOOL_DIR / UV_TOOL_BIN_DIR support: every tool
+// directory keeps its own installed version, and an install links a fake nanobot into the bin
+// directory that records its version in $FAKE_NANOBOT_MARKERS/<port> before sleeping.
+// Installs take $FAKE_UV_INSTALL_DELAY seconds; the fake nanobot of version $FAKE_NANOBOT_BROKEN exits at once.
+const fakeChannelUvScript = `#!/bin/sh
+dir="${UV_TOOL_DIR:-$FAKE_UV_GLOBAL/tools}"
+bin="${UV_TOOL_BIN_DIR:-$FAKE_UV_GLOBAL/bin}"
+case "$1 $2" in
+"tool list")
+	if [ -s "$dir/state" ]; then
+		echo "nanobot-ai v$(cat "$dir/state")"
+		echo "- nanobot"
+	fi
+	;;
+"tool dir")
+	echo "$dir"
+	;;
+"tool install")
+	spec="$4"
+	echo "$dir $spec" >> "$FAKE_UV_LOG"
+	sleep "${FAKE_UV_INSTALL_DELAY:-0}"
+	case "$spec" in
+	nanobot-ai==*) version="${spec#nanobot-ai==}" ;;
+	*) version="$FAKE_UV_NEW_VERSION" ;;
+	esac
+	mkdir -p "$dir" "$bin"
+	echo "$version" > "$dir/state"
+	printf '#!/bin/sh\n[ %s = "$FAKE_NANOBOT_BROKEN" ] && exit 1\necho %s > "$FAKE_NANOBOT_MARKERS/$3"\nexec sleep 30\n' "$version" "$version" > "$bin/nanobot"
+	chmod +x "$bin/nanobot"
+	;;
+esac
+`
+
+// fakeChannelInstall pre-installs version into the tool directories under root
+func fakeChannelInstall(t *testing.T, root, version string) {
+	t.Helper()
+	for _, dir := range []string{filepath.Join(root, "tools"), filepath.Join(root, "bin")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "tools", "state"), []byte(version+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := fmt.Sprintf("#!/bin/sh\necho %s > \"$FAKE_NANOBOT_MARKERS/$3\"\nexec sleep 30\n", version)
+	if err := os.WriteFile(filepath.Join(root, "bin", "nanobot"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readMarker returns the nanobot version the instance on port was started with
+func readMarker(t *testing.T, markers string, port uint32) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(markers, fmt.Sprint(port)))
+	if err != nil {
+		t.Fatalf("instance on port %d did not start a fake nanobot: %v", port, err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestUpdateAll_Channels(t *testing.T) {
+	root := t.TempDir()
+	uvDir := filepath.Join(root, "uv")
+	global := filepath.Join(root, "global")
+	markers := filepath.Join(root, "markers")
+	logPath := filepath.Join(root, "install.log")
+	for _, dir := range []string{uvDir, markers} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(uvDir, "uv"), []byte(fakeChannelUvScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	stableDir := filepath.Join(root, "stable")
+	canaryDir := filepath.Join(root, "canary")
+	fakeChannelInstall(t, global, "0.1.0")
+	fakeChannelInstall(t, stableDir, "0.1.4")
+	fakeChannelInstall(t, canaryDir, "0.1.0")
+
+	t.Setenv("PATH", uvDir+string(os.PathListSeparator)+filepath.Join(global, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("UV_TOOL_DIR", "")
+	t.Setenv("UV_TOOL_BIN_DIR", "")
+	t.Setenv("FAKE_UV_GLOBAL", global)
+	t.Setenv("FAKE_UV_LOG", logPath)
+	t.Setenv("FAKE_UV_NEW_VERSION", "0.2.0")
+	t.Setenv("FAKE_NANOBOT_MARKERS", markers)
+
+	pypi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"info":{"version":"0.2.0"}}`)
+	}))
+	t.Cleanup(pypi.Close)
+
+	cfg := &config.Config{
+		Instances: []config.InstanceConfig{
+			{Name: "global-bot", Port: 18921, StartCommand: "nanobot gateway", StartupTimeout: 5 * time.Second},
+			{Name: "stable-bot", Port: 18922, StartCommand: "nanobot gateway", StartupTimeout: 5 * time.Second, Channel: "stable"},
+			{Name: "canary-bot", Port: 18923, StartCommand: "nanobot gateway", StartupTimeout: 5 * time.Second, Channel: "canary"},
+		},
+		Updater: config.UpdaterConfig{
+			Sources:  []config.UpdateSourceConfig{{Name: "pypi", Type: "pypi"}},
+			Rollback: config.RollbackConfig{Enabled: true},
+			Channels: []config.ChannelConfig{
+				{Name: "stable", InstallDir: stableDir, PyPIVersion: "0.1.4"},
+				{Name: "canary", InstallDir: canaryDir},
+			},
+		},
+	}
+	m := newUpdateTestManager(t, cfg)
+	m.pypiJSONURL = pypi.URL + "/pypi"
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, false)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if len(result.Channels) != 3 {
+		t.Fatalf("Channels = %d, want global, stable and canary", len(result.Channels))
+	}
+	global0, stable, canary := result.Channels[0], result.Channels[1], result.Channels[2]
+	if global0.Channel != "" || stable.Channel != "stable" || canary.Channel != "canary" {
+		t.Errorf("channel order = %q, %q, %q", global0.Channel, stable.Channel, canary.Channel)
+	}
+	if !stable.Skipped || stable.Target != "pypi:0.1.4" {
+		t.Errorf("stable = %+v, want pinned 0.1.4 skipped as current", stable)
+	}
+	if canary.Skipped || canary.PreviousVersion != "0.1.0" || canary.InstallSpec != "nanobot-ai" {
+		t.Errorf("canary = %+v, want update from 0.1.0 to latest", canary)
+	}
+	if result.Skipped {
+		t.Error("result must not be skipped when some channels were updated")
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read install log: %v", err)
+	}
+	installs := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{
+		filepath.Join(global, "tools") + " nanobot-ai",
+		filepath.Join(canaryDir, "tools") + " nanobot-ai",
+	}
+	if strings.Join(installs, "|") != strings.Join(want, "|") {
+		t.Errorf("uv installs = %q, want %q", installs, want)
+	}
+
+	if v := readMarker(t, markers, 18921); v != "0.2.0" {
+		t.Errorf("global-bot runs %s, want 0.2.0", v)
+	}
+	if v := readMarker(t, markers, 18923); v != "0.2.0" {
+		t.Errorf("canary-bot runs %s, want its own install 0.2.0", v)
+	}
+	if _, err := os.Stat(filepath.Join(markers, "18922")); !os.IsNotExist(err) {
+		t.Error("stable-bot must not be restarted when its pinned version is current")
+	}
+	if len(result.Started) != 2 {
+		t.Errorf("Started = %v, want global-bot and canary-bot", result.Started)
+	}
+
+	// A stable instance started by hand uses the stable install, not the global one
+	if err := m.instances[1].StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("failed to start stable-bot: %v", err)
+	}
+	if v := readMarker(t, markers, 18922); v != "0.1.4" {
+		t.Errorf("stable-bot runs %s, want its pinned install 0.1.4", v)
+	}
+}
@@ -0,0 +1,167 @@
+package instance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// AbortStagePreUpdate 是 pre_update hook 失败时 UpdateResult.AbortStage 的值
+const AbortStagePreUpdate = "pre_update"
+
+// HookResult 是一次 hook 执行的结果, 记录在 UpdateResult.Hooks 和更新日志中.
+// hook 的 stdout/stderr 与 uv/git 输出一起写入 _updater 日志流和 UpdateResult.Output
+type HookResult struct {
+	Hook     string `json:"hook"`              // pre_update/post_install/post_start/on_failure
+	Name     string `json:"name"`              // hooks 配置中的名称, 未配置时为命令
+	Command  string `json:"command"`           // 执行的命令
+	Channel  string `json:"channel,omitempty"` // 所属安装分组 (updater.channels), 空表示全局安装
+	ExitCode int    `json:"exit_code"`         // 退出码, 未能启动或超时被终止时为 -1
+	Duration int64  `json:"duration_ms"`       // 执行耗时 (毫秒)
+	Error    string `json:"error,omitempty"`   // 失败原因 (非零退出码、超时、无法启动)
+}
+
+// hookInstance 是传给 hook 的实例信息
+type hookInstance struct {
+	Name    string `json:"name"`
+	Port    uint32 `json:"port"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// hookPayload 是通过 stdin 传给 hook 的 JSON
+type hookPayload struct {
+	UpdateID  string         `json:"update_id"`
+	Hook      string         `json:"hook"`
+	Target    string         `json:"target"`
+	Channel   string         `json:"channel,omitempty"`
+	Instances []hookInstance `json:"instances"`
+	Result    *UpdateResult  `json:"result,omitempty"` // 分组到目前为止的更新结果, pre_update 时为空
+	Error     string         `json:"error,omitempty"`  // on_failure: 失败原因
+}
+
+// runHooks 依次执行 stage 配置的 hooks, 结果追加到 result.Hooks, 返回第一个失败的 hook 的错误.
+// pre_update 在第一个失败后停止, 其他阶段继续执行其余 hook. failure 是传给 on_failure 的失败原因
+func (m *InstanceManager) runHooks(ctx context.Context, stage string, g installGroup, target updater.Target, result *UpdateResult, failure string) error {
+	hooks := m.hooks.Stage(stage)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	payload := hookPayload{
+		UpdateID: updateIDFrom(ctx),
+		Hook:     stage,
+		Target:   target.String(),
+		Error:    failure,
+	}
+	if g.channel != nil {
+		payload.Channel = g.channel.Name
+	}
+	for _, inst := range g.instances {
+		payload.Instances = append(payload.Instances, hookInstance{Name: inst.Name(), Port: inst.Port(), Channel: inst.config.Channel})
+	}
+	if stage != config.HookPreUpdate {
+		payload.Result = result
+	}
+	stdin, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s hook input: %w", stage, err)
+	}
+
+	env := []string{
+		"NANOBOT_UPDATE_ID=" + payload.UpdateID,
+		"NANOBOT_UPDATE_HOOK=" + stage,
+		"NANOBOT_UPDATE_TARGET=" + payload.Target,
+		"NANOBOT_UPDATE_CHANNEL=" + payload.Channel,
+		"NANOBOT_UPDATE_INSTANCES=" + strings.Join(instanceNames(g.instances), ","),
+		"NANOBOT_UPDATE_INSTALL_SPEC=" + result.InstallSpec,
+		"NANOBOT_UPDATE_PREVIOUS_VERSION=" + result.PreviousVersion,
+		"NANOBOT_UPDATE_ERROR=" + failure,
+	}
+
+	var firstErr error
+	for _, hook := range hooks {
+		hr := m.runHook(ctx, stage, hook, payload.Channel, stdin, env)
+		result.Hooks = append(result.Hooks, hr)
+		if hr.Error == "" {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s hook %q failed: %s", stage, hr.Name, hr.Error)
+		}
+		if stage == config.HookPreUpdate {
+			break
+		}
+	}
+	return firstErr
+}
+
+// runHook 执行单个 hook, 输出逐行写入 _updater 日志流
+func (m *InstanceManager) runHook(ctx context.Context, stage string, hook config.HookConfig, channel string, stdin []byte, env []string) *HookResult {
+	timeout := hook.EffectiveTimeout()
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	hr := &HookResult{Hook: stage, Name: hook.Label(), Command: hook.Command, Channel: channel, ExitCode: -1}
+	m.logger.Info("Running update hook", "hook", stage, "name", hr.Name, "channel", channel, "timeout", timeout)
+	output := m.outputFor(ctx)
+	_ = output.Write(logbuffer.LogEntry{Timestamp: time.Now(), Source: "stdout", Content: fmt.Sprintf("$ [%s hook] %s", stage, hook.Command)})
+
+	stdout := updater.NewLineWriter(output, "stdout")
+	stderr := updater.NewLineWriter(output, "stderr")
+	cmd := hookCommand(hookCtx, hook.Command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// 命令被终止后, 仍持有输出管道的子进程最多再等待 5 秒
+	cmd.WaitDelay = 5 * time.Second
+
+	begin := time.Now()
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	hr.Duration = time.Since(begin).Milliseconds()
+	if cmd.ProcessState != nil {
+		hr.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	if err != nil {
+		if errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		hr.Error = err.Error()
+		_ = output.Write(logbuffer.LogEntry{Timestamp: time.Now(), Source: "stderr", Content: fmt.Sprintf("%s hook %q failed: %v", stage, hr.Name, err)})
+		m.logger.Error("Update hook failed", "hook", stage, "name", hr.Name, "exit_code", hr.ExitCode, "error", err)
+		return hr
+	}
+	m.logger.Info("Update hook completed", "hook", stage, "name", hr.Name, "duration_ms", hr.Duration)
+	return hr
+}
+
+// groupFailure 返回分组更新失败的原因, 成功时返回空: 安装失败、canary 中止、回滚或实例启停失败
+func groupFailure(result *UpdateResult, err error) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case result.Aborted:
+		return fmt.Sprintf("aborted at %s: %s", result.AbortStage, result.AbortReason)
+	case result.RolledBack:
+		return fmt.Sprintf("new version failed to start, rolled back to %s", result.PreviousVersion)
+	case result.RollbackError != "":
+		return "new version failed to start, rollback failed: " + result.RollbackError
+	case len(result.StopFailed) > 0:
+		return fmt.Sprintf("failed to stop: %s", strings.Join(extractNames(result.StopFailed), ", "))
+	case len(result.StartFailed) > 0:
+		return fmt.Sprintf("failed to start: %s", strings.Join(extractNames(result.StartFailed), ", "))
+	}
+	return ""
+}
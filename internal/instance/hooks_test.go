@@ -0,0 +1,147 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+func newHooksTestManager(t *testing.T, inst config.InstanceConfig, hooks config.HooksConfig) *InstanceManager {
+	t.Helper()
+	m := newRollbackTestManager(t, []config.InstanceConfig{inst}, config.RollbackConfig{})
+	m.hooks = hooks
+	return m
+}
+
+func TestUpdateAll_PreUpdateHookFailureAborts(t *testing.T) {
+	statePath, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+	failureFile := filepath.Join(t.TempDir(), "failure")
+
+	m := newHooksTestManager(t,
+		config.InstanceConfig{Name: "inst1", Port: 18981, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18981), StartupTimeout: 5 * time.Second},
+		config.HooksConfig{
+			PreUpdate: []config.HookConfig{
+				{Name: "backup", Command: "echo backup failed >&2; exit 3"},
+				{Name: "never", Command: "true"},
+			},
+			OnFailure: []config.HookConfig{{Command: fmt.Sprintf(`echo "$NANOBOT_UPDATE_HOOK $NANOBOT_UPDATE_ERROR" > %s`, failureFile)}},
+		})
+	if err := m.instances[0].StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("failed to start instance: %v", err)
+	}
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if !result.Aborted || result.AbortStage != AbortStagePreUpdate {
+		t.Errorf("result aborted=%v stage=%q, want aborted at %q", result.Aborted, result.AbortStage, AbortStagePreUpdate)
+	}
+	if !strings.Contains(result.AbortReason, `"backup"`) {
+		t.Errorf("AbortReason = %q, want the failing hook name", result.AbortReason)
+	}
+	if len(result.Stopped) != 0 || !m.instances[0].IsRunning() {
+		t.Errorf("stopped=%v running=%v, want nothing stopped", result.Stopped, m.instances[0].IsRunning())
+	}
+	if _, statErr := os.Stat(logPath); statErr == nil {
+		t.Error("uv tool install ran although pre_update failed")
+	}
+
+	// 第一个失败后不再执行其余 pre_update hook, 随后执行 on_failure
+	if len(result.Hooks) != 2 {
+		t.Fatalf("hooks = %+v, want backup and on_failure", result.Hooks)
+	}
+	if h := result.Hooks[0]; h.Hook != config.HookPreUpdate || h.Name != "backup" || h.ExitCode != 3 || h.Error == "" {
+		t.Errorf("pre_update hook = %+v, want backup with exit code 3", h)
+	}
+	if h := result.Hooks[1]; h.Hook != config.HookOnFailure || h.Error != "" {
+		t.Errorf("on_failure hook = %+v, want a successful on_failure hook", h)
+	}
+	data, err := os.ReadFile(failureFile)
+	if err != nil {
+		t.Fatalf("on_failure hook did not run: %v", err)
+	}
+	if got := string(data); !strings.HasPrefix(got, "on_failure aborted at pre_update") {
+		t.Errorf("on_failure saw %q, want the abort reason", got)
+	}
+	if !strings.Contains(result.Output, "[stderr] backup failed") {
+		t.Errorf("result.Output = %q, want the hook stderr", result.Output)
+	}
+}
+
+func TestUpdateAll_RunsHooksWithUpdateContext(t *testing.T) {
+	statePath, _ := setupFakeUv(t, "0.1.4", "0.2.0")
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env")
+	stdinFile := filepath.Join(dir, "stdin")
+	startedFile := filepath.Join(dir, "started")
+
+	m := newHooksTestManager(t,
+		config.InstanceConfig{Name: "inst1", Port: 18982, StartCommand: startOnVersionCommand(statePath, "0.2.0", 18982), StartupTimeout: 5 * time.Second},
+		config.HooksConfig{
+			PreUpdate: []config.HookConfig{{Name: "context", Command: fmt.Sprintf(
+				`echo "$NANOBOT_UPDATE_ID $NANOBOT_UPDATE_HOOK $NANOBOT_UPDATE_INSTANCES $NANOBOT_UPDATE_PREVIOUS_VERSION" > %s; cat > %s`, envFile, stdinFile)}},
+			PostInstall: []config.HookConfig{
+				{Name: "slow", Command: "sleep 5", Timeout: 200 * time.Millisecond},
+				{Name: "version", Command: fmt.Sprintf("cat %s", statePath)},
+			},
+			PostStart: []config.HookConfig{{Command: fmt.Sprintf("echo $NANOBOT_UPDATE_HOOK > %s", startedFile)}},
+			OnFailure: []config.HookConfig{{Name: "not-run", Command: "true"}},
+		})
+
+	result, err := m.UpdateAll(WithUpdateID(context.Background(), "update-42"), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+	if result.HasErrors() || len(result.Started) != 1 {
+		t.Fatalf("result started=%v errors=%v, want a successful update", result.Started, result.HasErrors())
+	}
+
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("pre_update hook did not run: %v", err)
+	}
+	if got, want := strings.TrimSpace(string(data)), "update-42 pre_update inst1"; got != want {
+		t.Errorf("pre_update env = %q, want %q", got, want)
+	}
+	var payload hookPayload
+	data, err = os.ReadFile(stdinFile)
+	if err != nil {
+		t.Fatalf("failed to read hook stdin: %v", err)
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("hook stdin is not JSON: %v (%q)", err, data)
+	}
+	if payload.UpdateID != "update-42" || len(payload.Instances) != 1 || payload.Instances[0].Port != 18982 {
+		t.Errorf("hook stdin = %+v, want update-42 with inst1", payload)
+	}
+
+	// 超时的 post_install hook 只记录, 不影响更新
+	var names []string
+	for _, h := range result.Hooks {
+		names = append(names, h.Name)
+	}
+	if got := strings.Join(names, ","); !strings.HasPrefix(got, "context,slow,version,") || strings.Contains(got, "not-run") {
+		t.Errorf("hooks = %s, want context,slow,version,<post_start> without on_failure", got)
+	}
+	if slow := result.Hooks[1]; !strings.Contains(slow.Error, "timed out") || slow.Duration >= 5000 {
+		t.Errorf("slow hook = %+v, want a timeout after 200ms", slow)
+	}
+	if !strings.Contains(result.Output, "[stdout] 0.2.0") {
+		t.Errorf("result.Output = %q, want the post_install hook output", result.Output)
+	}
+	if data, err := os.ReadFile(startedFile); err != nil || strings.TrimSpace(string(data)) != "post_start" {
+		t.Errorf("post_start hook output = %q (err=%v), want post_start", data, err)
+	}
+}
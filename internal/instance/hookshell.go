@@ -0,0 +1,20 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// hookCommand runs a hook command line through sh in its own process group,
+// so that a timeout also kills the commands started by the shell
+func hookCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return cmd
+}
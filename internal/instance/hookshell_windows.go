@@ -0,0 +1,32 @@
+//go:build windows
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// hookCommand runs a hook command line through cmd.exe without a console window.
+// The command line is passed verbatim (/S /C "<command>"), so quotes in the hook keep their meaning.
+// On timeout the whole process tree is killed (taskkill /T /F), not only cmd.exe
+func hookCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd.exe")
+	cmd.SysProcAttr = &windows.SysProcAttr{
+		CmdLine:       `cmd.exe /S /C "` + command + `"`,
+		HideWindow:    true,
+		CreationFlags: windows.CREATE_NO_WINDOW,
+	}
+	cmd.Cancel = func() error {
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", fmt.Sprintf("%d", cmd.Process.Pid))
+		kill.SysProcAttr = &windows.SysProcAttr{HideWindow: true, CreationFlags: windows.CREATE_NO_WINDOW}
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	return cmd
+}
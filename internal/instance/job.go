@@ -0,0 +1,392 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// JobPhase 是异步更新任务当前所处的阶段
+type JobPhase string
+
+const (
+	JobQueued      JobPhase = "queued"       // 已创建, 尚未开始
+	JobChecking    JobPhase = "checking"     // 比较已安装版本与目标版本
+	JobStaging     JobPhase = "staging"      // staged 策略: 实例运行期间把新版本安装到备用槽位
+	JobStopping    JobPhase = "stopping"     // 停止实例
+	JobInstalling  JobPhase = "installing"   // uv 安装新版本
+	JobSwapping    JobPhase = "swapping"     // staged 策略: 实例停止后切换到新版本的槽位
+	JobStarting    JobPhase = "starting"     // 启动实例
+	JobVerifying   JobPhase = "verifying"    // canary 观察期: 确认新版本健康后才启动其余实例
+	JobRollingBack JobPhase = "rolling_back" // 新版本启动失败, 回滚到更新前的版本
+	JobCompleted   JobPhase = "completed"    // 已结束 (成功与否见结果)
+	JobFailed      JobPhase = "failed"       // 更新返回错误 (如 uv 安装失败)
+	JobCancelled   JobPhase = "cancelled"    // 已在阶段之间取消
+)
+
+// 实例进度状态
+const (
+	ProgressPending     = "pending"
+	ProgressStopping    = "stopping"
+	ProgressStopped     = "stopped"
+	ProgressStopFailed  = "stop_failed"
+	ProgressStarting    = "starting"
+	ProgressRunning     = "running"
+	ProgressStartFailed = "start_failed"
+)
+
+var (
+	// ErrJobFinished 表示更新任务已结束, 无法再取消
+	ErrJobFinished = errors.New("update job already finished")
+	// ErrJobNotCancellable 表示更新任务已进入安装阶段, 为避免实例停留在半安装状态不再接受取消
+	ErrJobNotCancellable = errors.New("update job can no longer be cancelled: installation already started")
+)
+
+// InstanceProgress 是更新任务中单个实例的进度
+type InstanceProgress struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`          // pending/stopping/stopped/stop_failed/starting/running/start_failed
+	Error  string `json:"error,omitempty"` // 停止或启动失败的原因
+}
+
+// UpdateProgress 是更新任务的实时进度, 供轮询和 SSE 使用
+type UpdateProgress struct {
+	Phase           JobPhase           `json:"phase"`
+	CurrentInstance string             `json:"current_instance,omitempty"` // 正在停止或启动的实例
+	Done            int                `json:"done"`                       // 当前阶段已处理的实例数
+	Remaining       int                `json:"remaining"`                  // 当前阶段剩余的实例数
+	ElapsedMs       int64              `json:"elapsed_ms"`                 // 任务开始以来的时间
+	Heartbeat       *updater.Heartbeat `json:"heartbeat,omitempty"`        // 安装过程中最近一次心跳 (来源、尝试次数、安装耗时)
+}
+
+// UpdateJob 跟踪一次在后台运行的更新: 当前阶段、各实例进度、结果, 并支持在阶段之间取消.
+// 通过 WithJob 放入 context 传给 TriggerUpdate, UpdateAll 在阶段切换和实例启停时更新它.
+// 所有方法对 nil 接收者安全, 未关联任务的更新 (如定时更新) 不受影响.
+type UpdateJob struct {
+	ID string
+
+	mu              sync.Mutex
+	phase           JobPhase
+	instances       []*InstanceProgress
+	cancelRequested bool
+	following       bool   // 合并到的排队更新已由其他请求开始执行, 不能再取消
+	current         string // 正在停止或启动的实例
+	phaseTotal      int    // 当前阶段要处理的实例数
+	phaseDone       int    // 当前阶段已处理的实例数
+	heartbeat       *updater.Heartbeat
+	subscribers     map[chan struct{}]struct{}
+	startTime       time.Time
+	endTime         time.Time
+	result          *UpdateResult
+	err             error
+	done            chan struct{}
+}
+
+// JobSnapshot 是 UpdateJob 某一时刻的只读副本
+type JobSnapshot struct {
+	ID              string
+	Phase           JobPhase
+	Instances       []InstanceProgress
+	CancelRequested bool
+	Progress        UpdateProgress
+	StartTime       time.Time
+	EndTime         time.Time // 未结束时为零值
+	Result          *UpdateResult
+	Err             error
+}
+
+// NewUpdateJob 创建处于 queued 阶段的更新任务
+func NewUpdateJob(id string) *UpdateJob {
+	return &UpdateJob{
+		ID:          id,
+		phase:       JobQueued,
+		startTime:   time.Now().UTC(),
+		done:        make(chan struct{}),
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+}
+
+type jobContextKey struct{}
+
+// WithJob 返回携带 job 的 context, UpdateAll 通过它报告进度和检查取消请求
+func WithJob(ctx context.Context, job *UpdateJob) context.Context {
+	return context.WithValue(ctx, jobContextKey{}, job)
+}
+
+// jobFrom 返回 ctx 携带的更新任务, 没有时返回 nil
+func jobFrom(ctx context.Context) *UpdateJob {
+	job, _ := ctx.Value(jobContextKey{}).(*UpdateJob)
+	return job
+}
+
+type updateIDContextKey struct{}
+
+// WithUpdateID 返回携带更新 ID 的 context, 用于没有关联 UpdateJob 的更新 (如定时更新).
+// 更新 ID 传给 hooks, 与更新日志的 update_id 对应
+func WithUpdateID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, updateIDContextKey{}, id)
+}
+
+// updateIDFrom 返回 ctx 中更新任务的 ID 或 WithUpdateID 设置的 ID, 都没有时返回空
+func updateIDFrom(ctx context.Context) string {
+	if job := jobFrom(ctx); job != nil {
+		return job.ID
+	}
+	id, _ := ctx.Value(updateIDContextKey{}).(string)
+	return id
+}
+
+type updateTimeoutContextKey struct{}
+
+// WithUpdateTimeout 返回限制更新执行时间的 context. 超时从更新离开更新队列开始执行时计算,
+// 排队等待前一个更新的时间不计入 (ctx 自身的截止时间仍然包含排队)
+func WithUpdateTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, updateTimeoutContextKey{}, timeout)
+}
+
+// updateTimeoutFrom 返回 WithUpdateTimeout 设置的超时, 未设置时返回 0
+func updateTimeoutFrom(ctx context.Context) time.Duration {
+	timeout, _ := ctx.Value(updateTimeoutContextKey{}).(time.Duration)
+	return timeout
+}
+
+// cancellable 报告当前阶段是否仍可取消: 安装开始之前 (含停止阶段, 取消在停止完成后生效).
+// staged 策略的暂存阶段实例仍在运行, 取消在暂存完成后生效, 暂存的新版本不会被使用
+func (j *UpdateJob) cancellable() bool {
+	if j.following {
+		return false
+	}
+	switch j.phase {
+	case JobQueued, JobChecking, JobStaging, JobStopping:
+		return true
+	}
+	return false
+}
+
+// RequestCancel 请求取消更新. 取消在下一个阶段开始前生效: 尚未停止实例时直接结束,
+// 已停止实例时跳过安装并重新启动它们. 安装开始后返回 ErrJobNotCancellable, 结束后返回 ErrJobFinished.
+func (j *UpdateJob) RequestCancel() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.endTime.IsZero() {
+		return ErrJobFinished
+	}
+	if !j.cancellable() {
+		return ErrJobNotCancellable
+	}
+	j.cancelRequested = true
+	j.notify()
+	return nil
+}
+
+// cancelPending 报告是否已请求取消, 供排队等待中的请求检查
+func (j *UpdateJob) cancelPending() bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.cancelRequested
+}
+
+// follow 标记任务合并到的排队更新已由其他请求开始执行: 之后的取消请求被拒绝,
+// 尚未生效的取消请求被撤销, 任务以该更新的实际结果结束
+func (j *UpdateJob) follow() {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.following {
+		return
+	}
+	j.following = true
+	j.cancelRequested = false
+	j.notify()
+}
+
+// enter 切换到 phase. 已请求取消且 phase 是暂存、停止、安装或切换时不切换, 返回 false.
+// 检查和切换在同一把锁内完成, 取消请求要么生效, 要么被 RequestCancel 拒绝
+func (j *UpdateJob) enter(phase JobPhase) bool {
+	if j == nil {
+		return true
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.cancelRequested && (phase == JobStaging || phase == JobStopping || phase == JobInstalling || phase == JobSwapping) {
+		return false
+	}
+	if phase != j.phase {
+		j.phase = phase
+		j.current = ""
+		j.phaseTotal = 0
+		j.phaseDone = 0
+		j.notify()
+	}
+	return true
+}
+
+// expect 将 n 个实例计入当前阶段要处理的实例数 (同一阶段可多次调用, 如 canary 与其余实例)
+func (j *UpdateJob) expect(n int) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.phaseTotal += n
+	j.notify()
+}
+
+// setHeartbeat 记录安装心跳, 作为 updater.Updater 的 heartbeat 回调
+func (j *UpdateJob) setHeartbeat(hb updater.Heartbeat) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.heartbeat = &hb
+	j.notify()
+}
+
+// Subscribe 返回任务状态变化时收到信号的 channel (合并连续的变化), 以及取消订阅的函数.
+// 任务结束时 Done() 关闭, 订阅方应同时监听它
+func (j *UpdateJob) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	j.mu.Lock()
+	j.subscribers[ch] = struct{}{}
+	j.mu.Unlock()
+	return ch, func() {
+		j.mu.Lock()
+		delete(j.subscribers, ch)
+		j.mu.Unlock()
+	}
+}
+
+// notify 通知订阅方状态已变化, 不阻塞. 调用方需持有 mu
+func (j *UpdateJob) notify() {
+	for ch := range j.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// addInstances 将实例加入进度列表 (pending), 已存在的忽略
+func (j *UpdateJob) addInstances(names []string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, name := range names {
+		if j.progress(name) == nil {
+			j.instances = append(j.instances, &InstanceProgress{Name: name, Status: ProgressPending})
+		}
+	}
+}
+
+// setInstance 更新实例进度, err 非空时记录失败原因
+func (j *UpdateJob) setInstance(name, status string, err error) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	p := j.progress(name)
+	if p == nil {
+		p = &InstanceProgress{Name: name}
+		j.instances = append(j.instances, p)
+	}
+	p.Status = status
+	p.Error = ""
+	if err != nil {
+		p.Error = err.Error()
+	}
+	switch status {
+	case ProgressStopping, ProgressStarting:
+		j.current = name
+	case ProgressPending:
+	default:
+		j.phaseDone++
+		if j.current == name {
+			j.current = ""
+		}
+	}
+	j.notify()
+}
+
+// progress 返回实例的进度条目, 调用方需持有 mu
+func (j *UpdateJob) progress(name string) *InstanceProgress {
+	for _, p := range j.instances {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Finish 记录更新结果并结束任务, 由运行任务的调用方在 TriggerUpdate 返回后调用
+func (j *UpdateJob) Finish(result *UpdateResult, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.endTime.IsZero() {
+		return
+	}
+	j.result = result
+	j.err = err
+	j.endTime = time.Now().UTC()
+	switch {
+	case result != nil && result.Cancelled:
+		j.phase = JobCancelled
+	case err != nil:
+		j.phase = JobFailed
+	default:
+		j.phase = JobCompleted
+	}
+	close(j.done)
+}
+
+// Done 返回任务结束时关闭的 channel
+func (j *UpdateJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Snapshot 返回任务当前状态的副本
+func (j *UpdateJob) Snapshot() JobSnapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	instances := make([]InstanceProgress, len(j.instances))
+	for i, p := range j.instances {
+		instances[i] = *p
+	}
+	end := j.endTime
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	progress := UpdateProgress{
+		Phase:           j.phase,
+		CurrentInstance: j.current,
+		Done:            j.phaseDone,
+		Remaining:       max(j.phaseTotal-j.phaseDone, 0),
+		ElapsedMs:       end.Sub(j.startTime).Milliseconds(),
+	}
+	if j.heartbeat != nil {
+		hb := *j.heartbeat
+		progress.Heartbeat = &hb
+	}
+	return JobSnapshot{
+		ID:              j.ID,
+		Phase:           j.phase,
+		Instances:       instances,
+		CancelRequested: j.cancelRequested,
+		Progress:        progress,
+		StartTime:       j.startTime,
+		EndTime:         j.endTime,
+		Result:          j.result,
+		Err:             j.err,
+	}
+}
@@ -0,0 +1,120 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+func TestUpdateJob_CancelBetweenPhases(t *testing.T) {
+	job := NewUpdateJob("job-1")
+
+	if !job.enter(JobChecking) {
+		t.Fatal("enter(checking) refused without a cancel request")
+	}
+	if err := job.RequestCancel(); err != nil {
+		t.Fatalf("RequestCancel() during checking: %v", err)
+	}
+	// 取消在下一个停止或安装阶段开始前生效
+	if job.enter(JobStopping) {
+		t.Error("enter(stopping) accepted after a cancel request")
+	}
+	if got := job.Snapshot(); got.Phase != JobChecking || !got.CancelRequested {
+		t.Errorf("snapshot = phase %s cancel_requested %v, want checking with cancel requested", got.Phase, got.CancelRequested)
+	}
+
+	job.Finish(&UpdateResult{Cancelled: true}, nil)
+	if got := job.Snapshot(); got.Phase != JobCancelled || got.EndTime.IsZero() {
+		t.Errorf("finished snapshot = phase %s end %v, want cancelled with end time", got.Phase, got.EndTime)
+	}
+	if err := job.RequestCancel(); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("RequestCancel() after finish = %v, want ErrJobFinished", err)
+	}
+}
+
+func TestUpdateJob_CancelDuringStaging(t *testing.T) {
+	job := NewUpdateJob("job-3")
+	job.enter(JobStaging)
+
+	// staged 策略: 暂存安装时实例仍在运行, 可以取消; 在停止之前生效
+	if err := job.RequestCancel(); err != nil {
+		t.Fatalf("RequestCancel() during staging: %v", err)
+	}
+	if job.enter(JobStopping) || job.enter(JobSwapping) {
+		t.Error("enter(stopping/swapping) accepted after a cancel request")
+	}
+}
+
+func TestUpdateJob_NotCancellableOnceInstalling(t *testing.T) {
+	job := NewUpdateJob("job-2")
+	job.enter(JobStopping)
+	job.enter(JobInstalling)
+
+	if err := job.RequestCancel(); !errors.Is(err, ErrJobNotCancellable) {
+		t.Errorf("RequestCancel() during install = %v, want ErrJobNotCancellable", err)
+	}
+
+	job.Finish(nil, errors.New("uv failed"))
+	select {
+	case <-job.Done():
+	default:
+		t.Error("Done() not closed after Finish")
+	}
+	if got := job.Snapshot(); got.Phase != JobFailed || got.Err == nil {
+		t.Errorf("snapshot = phase %s err %v, want failed with error", got.Phase, got.Err)
+	}
+}
+
+func TestUpdateJob_InstanceProgress(t *testing.T) {
+	job := NewUpdateJob("job-3")
+	ctx := WithJob(context.Background(), job)
+
+	jobFrom(ctx).addInstances([]string{"a", "b"})
+	jobFrom(ctx).setInstance("a", ProgressStopFailed, errors.New("timeout"))
+
+	got := job.Snapshot().Instances
+	if len(got) != 2 || got[0].Status != ProgressStopFailed || got[0].Error != "timeout" || got[1].Status != ProgressPending {
+		t.Errorf("instances = %+v, want a stop_failed with error and b pending", got)
+	}
+
+	// 未关联任务的更新 (定时更新) 不受影响
+	jobFrom(context.Background()).setInstance("a", ProgressRunning, nil)
+	if !jobFrom(context.Background()).enter(JobStopping) {
+		t.Error("nil job must never refuse a phase")
+	}
+}
+
+func TestUpdateJob_ProgressAndSubscribe(t *testing.T) {
+	job := NewUpdateJob("job-4")
+	changed, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	job.enter(JobStopping)
+	job.expect(3)
+	job.setInstance("a", ProgressStopping, nil)
+	job.setInstance("a", ProgressStopped, nil)
+	job.setInstance("b", ProgressStopping, nil)
+	job.setHeartbeat(updater.Heartbeat{Source: "pypi", Attempt: 2})
+
+	select {
+	case <-changed:
+	default:
+		t.Error("subscriber not notified of progress changes")
+	}
+
+	got := job.Snapshot().Progress
+	if got.Phase != JobStopping || got.CurrentInstance != "b" || got.Done != 1 || got.Remaining != 2 {
+		t.Errorf("progress = %+v, want stopping b with 1 done and 2 remaining", got)
+	}
+	if got.Heartbeat == nil || got.Heartbeat.Source != "pypi" || got.Heartbeat.Attempt != 2 {
+		t.Errorf("heartbeat = %+v, want the latest install heartbeat", got.Heartbeat)
+	}
+
+	// 新阶段重新计数
+	job.enter(JobInstalling)
+	if got := job.Snapshot().Progress; got.Done != 0 || got.Remaining != 0 || got.CurrentInstance != "" {
+		t.Errorf("progress after phase change = %+v, want counts reset", got)
+	}
+}
@@ -2,16 +2,23 @@ package instance
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/health"
 	"github.com/HQGroup/nanobot-auto-updater/internal/lifecycle"
 	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
 	"github.com/HQGroup/nanobot-auto-updater/internal/telegram"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
 )
 
+// startupMargin is added to the startup timeout in StartContext
+const startupMargin = 30 * time.Second
+
 // Notifier interface for dependency injection (duck typing, per D-03)
 // Satisfied by *notifier.Notifier without direct import.
 type Notifier interface {
@@ -27,10 +34,15 @@ type InstanceLifecycle struct {
 	config           config.InstanceConfig
 	logger           *slog.Logger
 	logBuffer        *logbuffer.LogBuffer           // INST-01: LogBuffer for this instance
+	mu               sync.Mutex                     // Guards pid, st, sup, liveness and the Telegram monitor fields
 	pid              int32                          // Process ID of the running instance (0 if not running)
+	st               stateMachine                   // State machine (stopped/starting/running/...), driven by the process exit event
+	sup              supervisor                     // Crash supervisor state (restart policy, counters)
+	liveness         *health.LivenessStatus         // Liveness probe state reported by the health monitor (nil if not probed)
 	notifier         Notifier                       // D-03: injected via constructor, immutable (D-04)
 	telegramMonitor  *telegram.TelegramMonitor       // D-01: per-instance monitor
 	monitorCancel    context.CancelFunc              // cancel monitor goroutine's context
+	installDir       string                          // updater.channels 独立安装目录 (空表示全局安装)
 }
 
 // NewInstanceLifecycle creates an instance lifecycle manager with context-aware logging.
@@ -49,6 +61,7 @@ func NewInstanceLifecycle(cfg config.InstanceConfig, baseLogger *slog.Logger, no
 		logger:    instanceLogger,
 		logBuffer: logBuffer,
 		notifier:  notifier,
+		st:        stateMachine{info: StateInfo{State: StateStopped, Since: time.Now()}},
 	}
 }
 
@@ -56,34 +69,60 @@ func NewInstanceLifecycle(cfg config.InstanceConfig, baseLogger *slog.Logger, no
 // Returns nil if instance is not running (not an error).
 // Returns InstanceError if stop operation fails.
 // Uses PID-based process management: if pid > 0, stops the process directly by PID.
+// An intentional stop is never restarted by the crash supervisor, a pending restart is cancelled.
+// The state goes stopping → stopped; the initiator is taken from ctx (WithInitiator).
 func (il *InstanceLifecycle) StopForUpdate(ctx context.Context) error {
 	il.logger.Info("Starting stop-before-update process")
 
 	// D-01: Stop monitor before stopping process
 	il.stopTelegramMonitor()
 
-	// If we don't have a PID, the instance was never started
-	if il.pid == 0 {
+	initiator := initiatorFrom(ctx)
+	il.mu.Lock()
+	il.releaseLocked()
+	pid := il.pid
+	if pid == 0 {
+		// 未运行 (从未启动、已崩溃或启动失败): 取消待执行的重启即可
+		if il.st.info.State != StateStopped {
+			il.st.started = time.Time{}
+			il.setStateLocked(StateStopped, initiator)
+		}
+		il.mu.Unlock()
 		il.logger.Info("Instance never started, nothing to stop")
 		return nil
 	}
+	il.setStateLocked(StateStopping, initiator)
+	il.mu.Unlock()
 
-	il.logger.Info("Stopping instance by PID", "pid", il.pid)
+	il.logger.Info("Stopping instance by PID", "pid", pid)
 
 	// Stop the instance using the saved PID
 	stopTimeout := 5 * time.Second // Locked decision: 5 second timeout
-	if err := lifecycle.StopNanobot(ctx, il.pid, stopTimeout, il.logger); err != nil {
-		il.logger.Error("Failed to stop instance", "pid", il.pid, "error", err)
+	if err := lifecycle.StopNanobot(ctx, pid, stopTimeout, il.logger); err != nil {
+		il.logger.Error("Failed to stop instance", "pid", pid, "error", err)
+		il.mu.Lock()
+		if il.st.info.State == StateStopping {
+			il.setStateLocked(StateRunning, initiator)
+		}
+		il.mu.Unlock()
 		return &InstanceError{
 			InstanceName: il.config.Name,
 			Operation:    "stop",
 			Port:         il.config.Port,
-			Err:          fmt.Errorf("failed to stop instance (PID %d): %w", il.pid, err),
+			Err:          fmt.Errorf("failed to stop instance (PID %d): %w", pid, err),
 		}
 	}
 
-	// Clear the PID after successful stop
-	il.pid = 0
+	// Clear the PID after successful stop (unless the exit event already did)
+	il.mu.Lock()
+	if il.pid == pid {
+		il.pid = 0
+		il.st.started = time.Time{}
+	}
+	if il.st.info.State == StateStopping {
+		il.setStateLocked(StateStopped, initiator)
+	}
+	il.mu.Unlock()
 	il.logger.Info("Instance stopped successfully")
 	return nil
 }
@@ -94,24 +133,75 @@ func (il *InstanceLifecycle) StopForUpdate(ctx context.Context) error {
 // INST-05: Clears LogBuffer before starting
 // INST-03: Uses StartNanobotWithCapture with instance's LogBuffer
 // Saves the PID for future process management.
+// A manual start also resets the crash loop state of the crash supervisor.
+// The state goes starting → running (or failed); the initiator is taken from ctx (WithInitiator).
 func (il *InstanceLifecycle) StartAfterUpdate(ctx context.Context) error {
 	il.logger.Info("Starting instance after update")
 
 	// INST-05: Clear LogBuffer on restart (fresh start)
 	il.logBuffer.Clear()
 
-	// Handle default startup timeout
-	startupTimeout := il.config.StartupTimeout
-	if startupTimeout == 0 {
-		startupTimeout = 30 * time.Second // Default: 30 seconds
-		il.logger.Debug("Using default startup timeout", "timeout", startupTimeout)
+	il.mu.Lock()
+	generation := il.superviseLocked()
+	il.mu.Unlock()
+
+	if err := il.start(ctx, generation, initiatorFrom(ctx)); err != nil {
+		return err
 	}
 
+	// D-01: Start Telegram monitor after successful process start
+	il.startTelegramMonitor()
+
+	return nil
+}
+
+// start launches the process for supervisor generation and saves its PID.
+// The exit of the process is reported to handleExit. When the instance was stopped or started
+// again while launching (generation is stale), the new process is stopped again.
+func (il *InstanceLifecycle) start(ctx context.Context, generation uint64, initiator string) error {
+	il.mu.Lock()
+	if generation != il.sup.generation {
+		il.mu.Unlock()
+		return il.staleStartError()
+	}
+	il.st.procGen = generation
+	il.setStateLocked(StateStarting, initiator)
+	il.mu.Unlock()
+
+	startupTimeout := il.config.EffectiveStartupTimeout()
+
 	// Start the instance using lifecycle package with instance-specific command and port
 	// INST-03: Use StartNanobotWithCapture with instance's LogBuffer
-	pid, err := lifecycle.StartNanobotWithCapture(ctx, il.config.StartCommand, il.config.Port, startupTimeout, il.logger, il.logBuffer)
-	if err != nil {
+	// start_command 中的 nanobot 优先使用 channel 独立安装的可执行文件
+	opts := lifecycle.StartOptions{
+		Env:    updater.ToolEnv(il.installDir),
+		Dir:    il.config.WorkingDir,
+		Home:   il.config.HomeDir(),
+		OnExit: func(err error) { il.handleExit(generation, err) },
+	}
+	if il.installDir != "" {
+		opts.BinDir = updater.ToolBinDir(il.installDir)
+	}
+	var pid int
+	launchedAt := time.Now()
+	// 实例的 env / env_file 在每次启动时重新读取, 覆盖服务的环境变量
+	instanceEnv, err := il.config.Environ()
+	if err == nil {
+		opts.Env = append(opts.Env, instanceEnv...)
+		// readiness 已配置时等待就绪检查 (最长 startup_timeout), 否则只检查进程 2 秒后仍存活
+		opts.Readiness, err = lifecycle.NewReadinessProbe(il.config.Readiness, il.config.Port)
+	}
+	if err == nil {
+		pid, err = lifecycle.StartNanobotWithOptions(ctx, il.config.StartCommand, il.config.Port, startupTimeout, il.logger, il.logBuffer, opts)
+	}
+	if err != nil && !errors.Is(err, lifecycle.ErrReadinessCancelled) {
 		il.logger.Error("Failed to start instance", "error", err)
+		il.mu.Lock()
+		if il.st.procGen == generation && il.st.info.State == StateStarting {
+			il.setStateLocked(StateFailed, initiator)
+			il.st.info.Error = err.Error()
+		}
+		il.mu.Unlock()
 		return &InstanceError{
 			InstanceName: il.config.Name,
 			Operation:    "start",
@@ -121,13 +211,50 @@ func (il *InstanceLifecycle) StartAfterUpdate(ctx context.Context) error {
 	}
 
 	// Save the PID for future process management
-	il.pid = int32(pid)
+	il.mu.Lock()
+	current := il.sup.generation == generation
+	if current && il.st.info.State == StateStarting {
+		il.pid = int32(pid)
+		il.st.started = launchedAt
+		il.setStateLocked(StateRunning, initiator)
+	}
+	il.mu.Unlock()
+	if !current {
+		il.logger.Warn("Instance was stopped while starting, stopping the new process", "pid", pid)
+		if stopErr := lifecycle.StopNanobot(context.Background(), int32(pid), 5*time.Second, il.logger); stopErr != nil {
+			il.logger.Error("Failed to stop the new process", "pid", pid, "error", stopErr)
+		}
+		return il.staleStartError()
+	}
+	if err != nil {
+		// 调用方取消了等待, 进程仍在运行并按已启动的进程管理, 但就绪未确认
+		il.logger.Warn("Start cancelled before the instance was ready, the process keeps running", "pid", pid, "error", err)
+		return &InstanceError{
+			InstanceName: il.config.Name,
+			Operation:    "start",
+			Port:         il.config.Port,
+			Err:          err,
+		}
+	}
 	il.logger.Info("Instance started successfully with log capture", "pid", pid)
+	return nil
+}
 
-	// D-01: Start Telegram monitor after successful process start
-	il.startTelegramMonitor()
+// StartContext returns the context for StartAfterUpdate on behalf of ctx: it keeps the values of
+// ctx (initiator, job) but not its cancellation, so a disconnected HTTP client does not abort the
+// start, and expires startupMargin after the startup timeout, so the readiness deadline fires first.
+func (il *InstanceLifecycle) StartContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), il.config.EffectiveStartupTimeout()+startupMargin)
+}
 
-	return nil
+// staleStartError is returned by start when the instance was stopped or started again meanwhile
+func (il *InstanceLifecycle) staleStartError() error {
+	return &InstanceError{
+		InstanceName: il.config.Name,
+		Operation:    "start",
+		Port:         il.config.Port,
+		Err:          fmt.Errorf("instance was stopped while starting"),
+	}
 }
 
 // GetLogBuffer returns the instance's LogBuffer.
@@ -154,28 +281,35 @@ func (il *InstanceLifecycle) ShouldAutoStart() bool {
 	return il.config.ShouldAutoStart()
 }
 
-// IsRunning checks if the instance is currently running by checking if the process exists.
-// Uses PID-based process management: returns true if pid > 0 and process exists.
+// IsRunning reports whether the instance is in the running state.
+// The state is driven by the process exit event (cmd.Wait), the process is not polled.
 func (il *InstanceLifecycle) IsRunning() bool {
-	if il.pid == 0 {
-		return false // Never started
-	}
-
-	// Check if process exists using gopsutil
-	proc, err := lifecycle.FindProcessByPID(il.pid, il.logger)
-	if err != nil || proc == nil {
-		return false // Process doesn't exist
-	}
-
-	// Process exists and is running
-	return true
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	return il.st.info.State == StateRunning
 }
 
 // GetPID returns the process ID of the instance (0 if not running).
 func (il *InstanceLifecycle) GetPID() int32 {
+	il.mu.Lock()
+	defer il.mu.Unlock()
 	return il.pid
 }
 
+// SetLiveness records the liveness status reported by the health monitor (nil clears it).
+func (il *InstanceLifecycle) SetLiveness(status *health.LivenessStatus) {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	il.liveness = status
+}
+
+// Liveness returns the last liveness status reported by the health monitor, nil if none.
+func (il *InstanceLifecycle) Liveness() *health.LivenessStatus {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	return il.liveness
+}
+
 // startTelegramMonitor creates and starts the Telegram monitor for this instance.
 // Called after successful process start in StartAfterUpdate (D-01).
 func (il *InstanceLifecycle) startTelegramMonitor() {
@@ -187,8 +321,10 @@ func (il *InstanceLifecycle) startTelegramMonitor() {
 		il.logger,
 	)
 	monitorCtx, cancel := context.WithCancel(context.Background())
+	il.mu.Lock()
 	il.telegramMonitor = monitor
 	il.monitorCancel = cancel
+	il.mu.Unlock()
 
 	go func() {
 		defer func() {
@@ -207,11 +343,15 @@ func (il *InstanceLifecycle) startTelegramMonitor() {
 // stopTelegramMonitor cancels the Telegram monitor for this instance.
 // Called before stopping the process in StopForUpdate (D-01).
 func (il *InstanceLifecycle) stopTelegramMonitor() {
-	if il.telegramMonitor != nil {
-		il.telegramMonitor.Stop()   // cancels internal timer + context
-		il.monitorCancel()          // cancel caller's context (unblocks Start channel read)
-		il.telegramMonitor = nil
-		il.monitorCancel = nil
+	il.mu.Lock()
+	monitor, cancel := il.telegramMonitor, il.monitorCancel
+	il.telegramMonitor = nil
+	il.monitorCancel = nil
+	il.mu.Unlock()
+
+	if monitor != nil {
+		monitor.Stop() // cancels internal timer + context
+		cancel()       // cancel caller's context (unblocks Start channel read)
 		il.logger.Info("Telegram monitor stopped")
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/health"
 	"github.com/HQGroup/nanobot-auto-updater/internal/lifecycle"
 	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
 	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
@@ -22,7 +23,14 @@ var ErrUpdateInProgress = errors.New("update already in progress")
 type InstanceManager struct {
 	instances  []*InstanceLifecycle
 	logger     *slog.Logger
-	isUpdating atomic.Bool // API-06: 并发控制标志
+	isUpdating atomic.Bool          // API-06: 并发控制标志
+	queue      *updateQueue         // 更新进行中到达的请求
+	updaterCfg config.UpdaterConfig // 更新流程配置 (更新源, 自动回滚)
+	hooks      config.HooksConfig   // 更新前后执行的 hook 命令
+	output     *updateOutput        // uv/git 命令输出 (_updater 日志流)
+
+	// pypiJSONURL 覆盖 PyPI JSON API 地址 (仅测试使用, 为空时使用 pypi.org)
+	pypiJSONURL string
 }
 
 // NewInstanceManager 创建实例管理器
@@ -34,56 +42,243 @@ func NewInstanceManager(cfg *config.Config, baseLogger *slog.Logger, notifier No
 	instances := make([]*InstanceLifecycle, 0, len(cfg.Instances))
 	for _, instCfg := range cfg.Instances {
 		lifecycle := NewInstanceLifecycle(instCfg, baseLogger, notifier)
+		if ch, ok := cfg.Updater.Channel(instCfg.Channel); ok {
+			lifecycle.installDir = ch.InstallDir
+		}
 		instances = append(instances, lifecycle)
 	}
 
-	return &InstanceManager{
-		instances: instances,
-		logger:    logger,
+	m := &InstanceManager{
+		instances:  instances,
+		logger:     logger,
+		updaterCfg: cfg.Updater,
+		hooks:      cfg.Hooks,
+		queue:      newUpdateQueue(),
+		output:     newUpdateOutput(logbuffer.NewLogBuffer(logger.With("instance", UpdaterLogName))),
 	}
+	// staged 策略: 实例使用上次切换到的槽位
+	m.applyStagingSlots()
+	return m
 }
 
 // UpdateAll 执行完整更新流程: 停止所有 → UV 更新 → 启动所有
-func (m *InstanceManager) UpdateAll(ctx context.Context) (*UpdateResult, error) {
-	m.logger.Info("Starting full update process", "instance_count", len(m.instances))
+// target 指定要安装的版本, 零值表示最新版本 (GitHub main, PyPI 兜底)
+// force 为 false 时, 已安装版本与目标一致则跳过整个停止→安装→启动流程
+// 配置了 updater.channels 时按安装分组依次更新 (全局安装在前), 每组只停止、更新、启动自己的实例;
+// 固定版本的 channel 忽略 target. 某组安装失败时返回错误, 后续分组不再更新
+func (m *InstanceManager) UpdateAll(ctx context.Context, target updater.Target, force bool) (*UpdateResult, error) {
+	m.logger.Info("Starting full update process", "instance_count", len(m.instances), "target", target.String(), "force", force)
+	ctx = withDefaultInitiator(ctx, InitiatorUpdate)
+
+	result := &UpdateResult{Target: target.String(), Strategy: m.updaterCfg.EffectiveStrategy()}
+
+	// 收集本次更新的完整 uv/git 输出, 由调用方与更新日志一起保存
+	run := &updateRun{log: m.output}
+	ctx = withUpdateRun(ctx, run)
+	defer func() { result.Output = run.String() }()
+
+	// 在停止实例之前校验目标, 避免无效目标导致实例停机
+	if err := target.Validate(); err != nil {
+		return result, err
+	}
+	jobFrom(ctx).addInstances(instanceNames(m.instances))
+
+	if len(m.updaterCfg.Channels) == 0 {
+		// 所有实例共享全局安装
+		if err := m.updateGroup(ctx, installGroup{instances: m.instances}, target, force, result); err != nil {
+			return result, err
+		}
+	} else {
+		for _, g := range m.installGroups() {
+			groupTarget := g.target(target)
+			groupResult := &UpdateResult{Target: groupTarget.String()}
+			err := m.updateGroup(ctx, g, groupTarget, force, groupResult)
+			result.addChannel(g.result(groupResult), groupResult)
+			if err != nil {
+				return result, fmt.Errorf("%s: %w", g, err)
+			}
+			if result.Cancelled || groupResult.AbortStage == AbortStagePreUpdate {
+				// 已取消或 pre_update hook 失败: 后续分组不再更新
+				break
+			}
+		}
+		result.Skipped, result.SkipReason = result.channelsSkipped()
+	}
+
+	if result.installed && m.updaterCfg.RepoPath != "" {
+		m.syncRepo(ctx, result)
+	}
+
+	// Log final result
+	m.logger.Info("Update process completed",
+		"stopped_success", len(result.Stopped),
+		"stopped_failed", len(result.StopFailed),
+		"started_success", len(result.Started),
+		"started_failed", len(result.StartFailed))
 
-	result := &UpdateResult{}
+	return result, nil
+}
 
-	// Phase 1: Stop all instances (graceful degradation)
-	m.stopAll(ctx, result)
+// updateGroup 对共享同一安装的实例执行 检查 → 停止 → UV 更新 → 启动 (canary 优先) → 回滚, 结果写入 result.
+// staged 策略 (updater.strategy) 改为 检查 → 暂存安装 (实例仍在运行) → 停止 → 切换槽位 → 启动 → 回滚 (切回之前的安装),
+// 停机时间只包含停止和启动.
+// hooks: pre_update 在停止之前 (失败时中止), post_install 在安装之后, post_start 在全部启动成功之后,
+// on_failure 在分组更新失败、中止或回滚之后
+func (m *InstanceManager) updateGroup(ctx context.Context, g installGroup, target updater.Target, force bool, result *UpdateResult) (err error) {
+	job := jobFrom(ctx)
+	u := m.newUpdater(ctx, m.activeInstallDir(g))
+	u.SetHeartbeat(job.setHeartbeat)
+	staged := m.updaterCfg.IsStaged()
+	if g.channel != nil {
+		m.logger.Info("Updating channel", "channel", g.channel.Name, "install_dir", g.channel.InstallDir,
+			"target", target.String(), "instance_count", len(g.instances))
+	}
+
+	// Phase 0: 已是目标版本时不停机
+	job.enter(JobChecking)
+	if !force {
+		if check := m.checkCurrent(ctx, u, target); check != nil && !check.UpdateAvailable {
+			result.Skipped = true
+			result.SkipReason = check.Reason
+			m.logger.Info("Nanobot already up to date, skipping update", "reason", check.Reason, "install_dir", m.activeInstallDir(g))
+			return nil
+		}
+	}
+
+	// Phase 1: Stop all instances (graceful degradation); staged 策略先在实例运行时暂存安装
+	// 取消请求在阶段之间生效: 尚未停止任何实例时直接结束
+	firstPhase := JobStopping
+	if staged {
+		firstPhase = JobStaging
+	}
+	if !job.enter(firstPhase) {
+		m.cancelUpdate(ctx, nil, result)
+		return nil
+	}
+
+	// 从这里开始失败时执行 on_failure hooks (取消不算失败)
+	defer func() {
+		if failure := groupFailure(result, err); failure != "" && !result.Cancelled {
+			_ = m.runHooks(ctx, config.HookOnFailure, g, target, result, failure)
+		}
+	}()
+
+	// pre_update hooks: 任一失败则在停止任何实例之前中止更新
+	if hookErr := m.runHooks(ctx, config.HookPreUpdate, g, target, result, ""); hookErr != nil {
+		result.Aborted = true
+		result.AbortStage = AbortStagePreUpdate
+		result.AbortReason = hookErr.Error()
+		m.logger.Error("pre_update hook failed, update aborted before stopping instances", "error", hookErr)
+		return nil
+	}
+
+	if staged {
+		// 记录更新前的版本作为回滚点 (staged 回滚切回之前的安装, 不依赖该版本)
+		m.recordPreviousInstall(ctx, u, result)
+		install, stageErr := m.stageInstall(ctx, g, target, result)
+		if stageErr != nil {
+			// 实例未停止, 继续运行旧版本
+			m.logger.Error("Staging install failed, instances keep running the current version", "error", stageErr)
+			return fmt.Errorf("UV update failed: %w", stageErr)
+		}
+		result.staged = install
+
+		// post_install 失败只记录, 不影响后续切换
+		_ = m.runHooks(ctx, config.HookPostInstall, g, target, result, "")
+
+		// 暂存期间请求取消: 实例仍在运行, 暂存的新版本不使用
+		if !job.enter(JobStopping) {
+			result.discardStaged()
+			m.cancelUpdate(ctx, nil, result)
+			return nil
+		}
+	}
+
+	m.stopInstances(ctx, g.instances, result)
 
 	// Phase 2: UV update (skip if any instance failed to stop)
+	uvUpdated := false
 	if len(result.StopFailed) > 0 {
 		m.logger.Warn("Skipping UV update due to stop failures",
 			"failed_count", len(result.StopFailed),
 			"failed_instances", extractNames(result.StopFailed))
+		result.discardStaged()
+	} else if staged {
+		// 停止阶段中请求取消: 不切换, 用之前的安装重新启动已停止的实例
+		if !job.enter(JobSwapping) {
+			result.discardStaged()
+			m.cancelUpdate(ctx, g.instances, result)
+			return nil
+		}
+		if swapErr := result.staged.swap(); swapErr != nil {
+			// 切换失败: 实例继续使用之前的安装
+			m.logger.Error("Failed to switch to the staged install, restarting the current version", "error", swapErr)
+			result.discardStaged()
+			job.enter(JobStarting)
+			m.startInstances(ctx, g.instances, result)
+			return fmt.Errorf("UV update failed: %w", swapErr)
+		}
+		m.logger.Info("Switched instances to the staged install", "install_dir", result.StagedDir)
+		uvUpdated = true
 	} else {
-		if err := m.performUpdate(ctx); err != nil {
+		// 停止阶段中请求取消: 跳过安装, 重新启动已停止的实例
+		if !job.enter(JobInstalling) {
+			m.cancelUpdate(ctx, g.instances, result)
+			return nil
+		}
+
+		// 记录更新前的安装作为回滚点
+		m.recordPreviousInstall(ctx, u, result)
+
+		if err := m.performUpdate(ctx, u, target, result); err != nil {
 			// Critical failure: UV update failed
 			m.logger.Error("UV update failed, cannot start instances", "error", err)
-			return result, fmt.Errorf("UV update failed: %w", err)
+			return fmt.Errorf("UV update failed: %w", err)
 		}
+		uvUpdated = true
+
+		// post_install 失败只记录, 不影响启动
+		_ = m.runHooks(ctx, config.HookPostInstall, g, target, result, "")
 	}
 
 	// Phase 3: Start all instances (graceful degradation)
-	m.startAll(ctx, result)
+	// 安装了新版本且配置了 canary 实例时, canary 先启动并观察, 健康后才启动其余实例
+	job.enter(JobStarting)
+	if canaries, rest := splitCanaries(g.instances); uvUpdated && len(canaries) > 0 {
+		m.startWithCanaries(ctx, u, g.instances, canaries, rest, result)
+	} else {
+		m.startInstances(ctx, g.instances, result)
+	}
 
-	// Log final result
-	m.logger.Info("Update process completed",
-		"stopped_success", len(result.Stopped),
-		"stopped_failed", len(result.StopFailed),
-		"started_success", len(result.Started),
-		"started_failed", len(result.StartFailed))
+	// Phase 4: 新版本启动失败过多时自动回滚到更新前的版本
+	if uvUpdated && !result.Aborted && m.updaterCfg.Rollback.ShouldRollback(len(result.StartFailed), len(g.instances)) {
+		m.rollback(ctx, u, g.instances, result)
+	}
 
-	return result, nil
+	if uvUpdated && groupFailure(result, nil) == "" {
+		_ = m.runHooks(ctx, config.HookPostStart, g, target, result, "")
+	}
+	return nil
 }
 
 // stopAll 停止所有实例(串行执行,优雅降级)
 func (m *InstanceManager) stopAll(ctx context.Context, result *UpdateResult) {
-	m.logger.Info("Starting stop phase", "instance_count", len(m.instances))
+	m.stopInstances(ctx, m.instances, result)
+}
 
-	for _, inst := range m.instances {
-		if err := inst.StopForUpdate(ctx); err != nil {
+// stopInstances 停止给定实例(串行执行,优雅降级)
+func (m *InstanceManager) stopInstances(ctx context.Context, instances []*InstanceLifecycle, result *UpdateResult) {
+	m.logger.Info("Starting stop phase", "instance_count", len(instances))
+
+	job := jobFrom(ctx)
+	job.expect(len(instances))
+	for _, inst := range instances {
+		job.setInstance(inst.config.Name, ProgressStopping, nil)
+		wasRunning := inst.IsRunning()
+		begin := time.Now()
+		err := inst.StopForUpdate(ctx)
+		result.timing(inst).stopped(begin, wasRunning && err == nil)
+		if err != nil {
 			m.logger.Error("Failed to stop instance",
 				"error", err,
 				"port", inst.config.Port)
@@ -91,8 +286,10 @@ func (m *InstanceManager) stopAll(ctx context.Context, result *UpdateResult) {
 			// 记录失败但不返回,继续停止其他实例
 			// Type assertion: StopForUpdate always returns *InstanceError on error
 			result.StopFailed = append(result.StopFailed, err.(*InstanceError))
+			job.setInstance(inst.config.Name, ProgressStopFailed, err)
 		} else {
 			result.Stopped = append(result.Stopped, inst.config.Name)
+			job.setInstance(inst.config.Name, ProgressStopped, nil)
 		}
 	}
 
@@ -103,10 +300,23 @@ func (m *InstanceManager) stopAll(ctx context.Context, result *UpdateResult) {
 
 // startAll 启动所有实例(串行执行,优雅降级)
 func (m *InstanceManager) startAll(ctx context.Context, result *UpdateResult) {
-	m.logger.Info("Starting start phase", "instance_count", len(m.instances))
+	m.startInstances(ctx, m.instances, result)
+}
 
-	for _, inst := range m.instances {
-		if err := inst.StartAfterUpdate(ctx); err != nil {
+// startInstances 启动给定实例(串行执行,优雅降级)
+func (m *InstanceManager) startInstances(ctx context.Context, instances []*InstanceLifecycle, result *UpdateResult) {
+	m.logger.Info("Starting start phase", "instance_count", len(instances))
+
+	job := jobFrom(ctx)
+	job.expect(len(instances))
+	for _, inst := range instances {
+		job.setInstance(inst.config.Name, ProgressStarting, nil)
+		timing := result.timing(inst)
+		timing.starting(inst.logBuffer)
+		begin := time.Now()
+		err := inst.StartAfterUpdate(ctx)
+		timing.started(begin, inst.logBuffer, err == nil)
+		if err != nil {
 			m.logger.Error("Failed to start instance",
 				"error", err,
 				"port", inst.config.Port)
@@ -114,8 +324,10 @@ func (m *InstanceManager) startAll(ctx context.Context, result *UpdateResult) {
 			// 记录失败但不返回,继续启动其他实例
 			// Type assertion: StartAfterUpdate always returns *InstanceError on error
 			result.StartFailed = append(result.StartFailed, err.(*InstanceError))
+			job.setInstance(inst.config.Name, ProgressStartFailed, err)
 		} else {
 			result.Started = append(result.Started, inst.config.Name)
+			job.setInstance(inst.config.Name, ProgressRunning, nil)
 		}
 	}
 
@@ -124,23 +336,289 @@ func (m *InstanceManager) startAll(ctx context.Context, result *UpdateResult) {
 		"failed", len(result.StartFailed))
 }
 
-// performUpdate 执行 UV 更新
-func (m *InstanceManager) performUpdate(ctx context.Context) error {
-	m.logger.Info("Starting UV update")
-
-	// 复用 Phase 2 的 Updater 结构
-	uvUpdater := updater.NewUpdater(m.logger)
+// performUpdate 执行 UV 更新, 并将实际安装的 spec 写入 result
+func (m *InstanceManager) performUpdate(ctx context.Context, u *updater.Updater, target updater.Target, result *UpdateResult) error {
+	m.logger.Info("Starting UV update", "target", target.String())
 
-	updateResult, err := uvUpdater.Update(ctx)
+	updateResult, err := u.UpdateTo(ctx, target)
 	if err != nil {
-		m.logger.Error("UV update failed", "error", err)
+		m.logger.Error("UV update failed", "error", err, "target", target.String())
 		return err
 	}
 
-	m.logger.Info("UV update completed successfully", "result", updateResult)
+	result.InstallSpec = updateResult.Spec
+	result.Source = updateResult.Source
+	result.SourceType = string(updateResult.SourceType)
+	result.installed = true
+	m.logger.Info("UV update completed successfully",
+		"source", updateResult.Source,
+		"attempts", updateResult.Attempts,
+		"target", target.String(),
+		"install_spec", result.InstallSpec)
 	return nil
 }
 
+// CheckUpdate 比较已安装的 nanobot 与 target 对应的上游版本 (PyPI 最新版本, git 源的 HEAD 提交)
+// 检查的是全局安装 (staged 策略下为其当前槽位); updater.channels 的独立安装见 Plan
+func (m *InstanceManager) CheckUpdate(ctx context.Context, target updater.Target) (*updater.CheckResult, error) {
+	return m.newUpdater(ctx, m.activeInstallDir(installGroup{})).Check(ctx, target)
+}
+
+// Plan 预览 UpdateAll(target, force) 将执行的操作 (dry run): 不获取更新锁, 不停止实例, 不安装
+// Stopped 为 stopAll 将停止的实例 (有 PID), Started 为 startAll 将启动的实例,
+// InstallSpec/Source 为将首先尝试的更新源, PreviousVersion 为当前安装的版本.
+// 实际更新可能失败的问题 (uv 未安装、正在更新等) 记录在 Preflight 中, 不作为错误返回.
+// 配置了 updater.channels 时每个安装的预览记录在 Channels 中
+func (m *InstanceManager) Plan(ctx context.Context, target updater.Target, force bool) (*UpdateResult, error) {
+	result := &UpdateResult{Target: target.String(), DryRun: true}
+
+	if err := target.Validate(); err != nil {
+		return result, err
+	}
+
+	result.Instances = m.GetInstanceStatuses()
+
+	if m.isUpdating.Load() {
+		result.Preflight = append(result.Preflight, ErrUpdateInProgress.Error())
+	}
+	uvErr := updater.CheckUvInstalled()
+	if uvErr != nil {
+		result.Preflight = append(result.Preflight, uvErr.Error())
+	}
+
+	if len(m.updaterCfg.Channels) == 0 {
+		problems := m.planGroup(ctx, installGroup{instances: m.instances}, target, force, uvErr == nil, result)
+		result.Preflight = append(result.Preflight, problems...)
+	} else {
+		for _, g := range m.installGroups() {
+			groupTarget := g.target(target)
+			groupResult := &UpdateResult{Target: groupTarget.String()}
+			for _, problem := range m.planGroup(ctx, g, groupTarget, force, uvErr == nil, groupResult) {
+				result.Preflight = append(result.Preflight, fmt.Sprintf("%s: %s", g, problem))
+			}
+			result.addChannel(g.result(groupResult), groupResult)
+		}
+		result.Skipped, result.SkipReason = result.channelsSkipped()
+	}
+
+	m.logger.Info("Update plan built",
+		"target", result.Target,
+		"source", result.Source,
+		"skipped", result.Skipped,
+		"would_stop", len(result.Stopped),
+		"preflight_problems", len(result.Preflight))
+	return result, nil
+}
+
+// planGroup 预览一个安装分组的更新, 返回预检问题. uvInstalled 为 false 时不检查已安装版本
+func (m *InstanceManager) planGroup(ctx context.Context, g installGroup, target updater.Target, force, uvInstalled bool, result *UpdateResult) []string {
+	var problems []string
+	u := m.newUpdater(ctx, m.activeInstallDir(g))
+
+	if planned, err := u.PlanInstall(target); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		result.InstallSpec = planned.Spec
+		result.Source = planned.Source
+		result.SourceType = string(planned.SourceType)
+	}
+
+	if uvInstalled {
+		if check, err := u.Check(ctx, target); err != nil {
+			problems = append(problems, fmt.Sprintf("无法检查已安装版本: %v", err))
+		} else {
+			result.Check = check
+			result.PreviousVersion = check.InstalledVersion
+			if !force && !check.UpdateAvailable {
+				result.Skipped = true
+				result.SkipReason = check.Reason
+			}
+		}
+	}
+
+	if !result.Skipped {
+		for _, inst := range g.instances {
+			if inst.GetPID() != 0 {
+				result.Stopped = append(result.Stopped, inst.Name())
+			}
+			result.Started = append(result.Started, inst.Name())
+		}
+	}
+	return problems
+}
+
+// checkCurrent 在更新前检查是否有新版本, 检查失败返回 nil (继续更新, 不因网络问题跳过)
+func (m *InstanceManager) checkCurrent(ctx context.Context, u *updater.Updater, target updater.Target) *updater.CheckResult {
+	check, err := u.Check(ctx, target)
+	if err != nil {
+		m.logger.Warn("无法检查 nanobot 是否为最新版本, 继续更新", "error", err)
+		return nil
+	}
+	return check
+}
+
+// syncRepo 在安装成功后同步本地 nanobot 源码仓库 (updater.repo_path, 不存在时 clone),
+// 并记录同步后的 HEAD. 失败只记录在 RepoSyncError 中, 不影响更新结果
+func (m *InstanceManager) syncRepo(ctx context.Context, result *UpdateResult) {
+	u := m.newUpdater(ctx, "")
+	if err := u.SyncRepo(ctx); err != nil {
+		m.logger.Warn("同步本地 nanobot 源码仓库失败", "repo_path", m.updaterCfg.RepoPath, "error", err)
+		result.RepoSyncError = err.Error()
+	}
+	status, err := u.RepoStatus(ctx)
+	if err != nil {
+		m.logger.Warn("读取本地 nanobot 源码仓库状态失败", "repo_path", m.updaterCfg.RepoPath, "error", err)
+		if result.RepoSyncError == "" {
+			result.RepoSyncError = err.Error()
+		}
+		return
+	}
+	result.Repo = status
+	m.logger.Info("本地 nanobot 源码仓库已同步", "repo_path", status.Path, "branch", status.Branch,
+		"commit", status.Commit, "subject", status.Subject)
+}
+
+// RepoStatus 返回本地 nanobot 源码仓库 (updater.repo_path) 的分支、HEAD 提交和是否有未提交修改.
+// 未配置时返回 updater.ErrRepoNotConfigured
+func (m *InstanceManager) RepoStatus(ctx context.Context) (*updater.RepoStatus, error) {
+	return m.newUpdater(ctx, "").RepoStatus(ctx)
+}
+
+// newUpdater 创建使用 updater.sources 配置的 Updater (未配置时为 GitHub → PyPI)
+// installDir 非空时操作 updater.channels 的独立安装. 命令输出写入 outputFor(ctx)
+func (m *InstanceManager) newUpdater(ctx context.Context, installDir string) *updater.Updater {
+	u := updater.NewUpdater(m.logger)
+	u.SetSources(convertSources(m.updaterCfg.Sources))
+	u.SetInstallDir(installDir)
+	u.SetRepoPath(m.updaterCfg.RepoPath)
+	u.SetOutput(m.outputFor(ctx))
+	if m.pypiJSONURL != "" {
+		u.SetPyPIJSONURL(m.pypiJSONURL)
+	}
+	return u
+}
+
+// convertSources 将配置中的更新源转换为 updater.Source
+func convertSources(cfgSources []config.UpdateSourceConfig) []updater.Source {
+	if len(cfgSources) == 0 {
+		return nil
+	}
+	sources := make([]updater.Source, len(cfgSources))
+	for i, s := range cfgSources {
+		sources[i] = updater.Source{
+			Name:     s.Name,
+			Type:     updater.SourceType(s.Type),
+			URL:      s.URL,
+			Ref:      s.Ref,
+			Package:  s.Package,
+			IndexURL: s.IndexURL,
+			Path:     s.Path,
+			Timeout:  s.Timeout,
+			Retries:  s.Retries,
+		}
+	}
+	return sources
+}
+
+// recordPreviousInstall 在 UV 更新前记录已安装的 nanobot 版本及其安装 spec 和源 (回滚点)
+// 获取失败不阻塞更新, 只是无法回滚
+func (m *InstanceManager) recordPreviousInstall(ctx context.Context, u *updater.Updater, result *UpdateResult) {
+	if !m.updaterCfg.Rollback.Enabled {
+		return
+	}
+
+	previous, err := u.CurrentInstall(ctx)
+	result.PreviousVersion = previous.Version
+	result.previous = previous
+	if err != nil {
+		m.logger.Warn("无法获取当前安装的 nanobot, 更新失败时将无法回滚", "error", err)
+		return
+	}
+	m.logger.Info("已记录更新前版本",
+		"previous_version", previous.Version,
+		"previous_spec", previous.Spec,
+		"previous_source", previous.Source)
+}
+
+// cancelUpdate 在阶段之间取消更新: 重新启动 instances 中已停止的实例, 未安装任何版本
+func (m *InstanceManager) cancelUpdate(ctx context.Context, instances []*InstanceLifecycle, result *UpdateResult) {
+	result.Cancelled = true
+	m.logger.Warn("Update cancelled between phases", "stopped", result.Stopped)
+
+	var stopped []*InstanceLifecycle
+	for _, inst := range instances {
+		if containsName(result.Stopped, inst.Name()) {
+			stopped = append(stopped, inst)
+		}
+	}
+	if len(stopped) > 0 {
+		jobFrom(ctx).enter(JobStarting)
+		m.startInstances(ctx, stopped, result)
+	}
+}
+
+// rollback 在新版本启动失败后重新安装更新前的版本并再次启动 instances
+// (staged 策略切回切换前的安装, 不重新安装)
+// 新版本下的启动失败记录在 result.FailedAfterUpdate, Started/StartFailed 反映回滚后的启动结果
+func (m *InstanceManager) rollback(ctx context.Context, u *updater.Updater, instances []*InstanceLifecycle, result *UpdateResult) {
+	m.logger.Warn("新版本启动失败, 开始自动回滚",
+		"start_failed", len(result.StartFailed),
+		"instance_count", len(instances),
+		"failed_instances", extractNames(result.StartFailed),
+		"previous_version", result.PreviousVersion)
+	jobFrom(ctx).enter(JobRollingBack)
+
+	if result.PreviousVersion == "" && result.staged == nil {
+		result.RollbackError = "previous version unknown, rollback skipped"
+		m.logger.Error("未记录更新前版本, 跳过回滚")
+		return
+	}
+
+	// 停止已在新版本下启动成功的实例, 以便重新安装
+	for _, inst := range instances {
+		wasRunning := inst.IsRunning()
+		begin := time.Now()
+		err := inst.StopForUpdate(ctx)
+		result.timing(inst).stopped(begin, wasRunning && err == nil)
+		if err != nil {
+			result.RollbackError = fmt.Sprintf("failed to stop instance before rollback: %v", err)
+			m.logger.Error("回滚前停止实例失败, 放弃回滚", "instance", inst.Name(), "error", err)
+			return
+		}
+	}
+
+	result.FailedAfterUpdate = result.StartFailed
+	result.Started = nil
+	result.StartFailed = nil
+
+	if result.staged != nil {
+		// staged: 之前的安装仍在, 切回即可, 不需要重新安装
+		if err := result.staged.revert(); err != nil {
+			result.RollbackError = err.Error()
+			m.logger.Error("切回之前的安装失败, 使用新版本重新启动实例", "error", err)
+		} else {
+			result.RolledBack = true
+		}
+	} else if rollbackResult, err := u.Rollback(ctx, result.previous); err != nil {
+		// 重新安装失败: 仍然尝试启动实例 (新版本仍在), 避免实例保持停止状态
+		result.RollbackError = err.Error()
+		m.logger.Error("回滚安装失败, 使用新版本重新启动实例", "error", err)
+	} else {
+		result.RolledBack = true
+		result.InstallSpec = rollbackResult.Spec
+		result.Source = rollbackResult.Source
+		result.SourceType = string(rollbackResult.SourceType)
+	}
+
+	m.startInstances(ctx, instances, result)
+
+	m.logger.Warn("自动回滚完成",
+		"rolled_back", result.RolledBack,
+		"previous_version", result.PreviousVersion,
+		"started", len(result.Started),
+		"start_failed", len(result.StartFailed))
+}
+
 // extractNames 辅助函数,从 InstanceError 中提取实例名称
 func extractNames(errs []*InstanceError) []string {
 	names := make([]string, len(errs))
@@ -152,12 +630,16 @@ func extractNames(errs []*InstanceError) []string {
 
 // GetLogBuffer returns the LogBuffer for the specified instance.
 // INST-02: Used by HTTP API to access instance buffers for SSE streaming.
+// UpdaterLogName returns the uv/git command output of nanobot updates.
 func (m *InstanceManager) GetLogBuffer(instanceName string) (*logbuffer.LogBuffer, error) {
 	for _, inst := range m.instances {
 		if inst.config.Name == instanceName {
 			return inst.GetLogBuffer(), nil
 		}
 	}
+	if instanceName == UpdaterLogName {
+		return m.output.buffer, nil
+	}
 	return nil, &InstanceError{
 		InstanceName: instanceName,
 		Operation:    "get_log_buffer",
@@ -203,6 +685,7 @@ func (m *InstanceManager) StartAllInstances(ctx context.Context) *AutoStartResul
 
 	result := &AutoStartResult{}
 	startTime := time.Now()
+	ctx = withDefaultInitiator(ctx, InitiatorAutoStart)
 
 	// Step 1: 停止所有 nanobot.exe 进程，确保干净的启动环境
 	// 这样可以避免多实例场景下的进程混淆问题
@@ -273,27 +756,72 @@ func (m *InstanceManager) StartAllInstances(ctx context.Context) *AutoStartResul
 	return result
 }
 
-// TriggerUpdate 触发 API 更新，返回是否被拒绝
+// TriggerUpdate 触发 API 或定时更新
 // API-06: 使用 atomic.Bool 实现并发控制
 // API-03: 调用 UpdateAll 执行完整的停止→更新→启动流程
-func (m *InstanceManager) TriggerUpdate(ctx context.Context) (*UpdateResult, error) {
-	// 尝试设置更新标志 (原子操作)
-	if !m.isUpdating.CompareAndSwap(false, true) {
-		// 已经在更新中
-		m.logger.Warn("更新请求被拒绝: 更新正在进行中")
-		return nil, ErrUpdateInProgress
-	}
-	// 确保更新完成后重置标志 (无论成功或失败)
-	defer m.isUpdating.Store(false)
-
-	m.logger.Info("开始 API 触发的更新")
-	result, err := m.UpdateAll(ctx)
+// target 为零值时安装最新版本, 否则安装指定的 ref/commit/pypi_version
+// force 为 true 时即使已是目标版本也重新安装
+// 更新进行中到达的请求进入更新队列并等待: 与队尾目标相同的请求合并为一次后续运行 (共享结果),
+// 目标不同的请求按顺序排队. 队列已满时返回 ErrUpdateQueueFull (包装 ErrUpdateInProgress)
+func (m *InstanceManager) TriggerUpdate(ctx context.Context, target updater.Target, force bool) (*UpdateResult, error) {
+	run, err := m.EnqueueUpdate(ctx, target, force)
+	if err != nil {
+		return nil, err
+	}
+	return run(ctx)
+}
+
+// EnqueueUpdate 是 TriggerUpdate 的第一步: 获得更新锁或加入更新队列, 队列已满时立即返回 ErrUpdateQueueFull.
+// 接受时返回的 run 等待轮到执行后执行更新 (见 TriggerUpdate), 调用方必须调用 run, 否则更新锁或队列条目不会释放.
+// ctx 提供请求的更新 ID (WithJob/WithUpdateID)
+func (m *InstanceManager) EnqueueUpdate(ctx context.Context, target updater.Target, force bool) (run func(context.Context) (*UpdateResult, error), err error) {
+	item, err := m.queue.enqueue(updateIDFrom(ctx), target, force, &m.isUpdating)
+	if err != nil {
+		m.logger.Warn("更新请求被拒绝: 更新队列已满", "target", target.String())
+		return nil, err
+	}
+	if item != nil {
+		m.logger.Info("更新正在进行中, 请求已加入更新队列", "queue_id", item.ID, "target", target.String(), "force", force)
+	}
+	return func(ctx context.Context) (*UpdateResult, error) {
+		return m.runUpdate(ctx, item, target, force)
+	}, nil
+}
+
+// runUpdate 执行 EnqueueUpdate 接受的请求. item 为 nil 时调用方已持有更新锁, 否则先等待 item 轮到执行.
+// WithUpdateTimeout 设置的超时从轮到执行时开始计算, 不包含排队等待的时间
+func (m *InstanceManager) runUpdate(ctx context.Context, item *queuedUpdate, target updater.Target, force bool) (result *UpdateResult, err error) {
+	var coalesced []string
+	if item != nil {
+		run, queuedResult, queuedErr := m.waitQueued(ctx, item)
+		if !run {
+			return queuedResult, queuedErr
+		}
+		force, coalesced = m.queue.snapshot(item)
+		// 合并的请求共享本次结果
+		defer func() { m.queue.finish(item, result, err) }()
+	}
+	// 确保更新完成后释放锁并唤醒排队的请求 (无论成功或失败)
+	defer m.UnlockUpdate()
+
+	if timeout := updateTimeoutFrom(ctx); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	m.logger.Info("开始 API 触发的更新", "target", target.String(), "force", force)
+	result, err = m.UpdateAll(ctx, target, force)
+	if result != nil && len(coalesced) > 1 {
+		result.Coalesced = coalesced
+	}
 	if err != nil {
 		m.logger.Error("API 触发的更新失败", "error", err)
 		return result, err
 	}
 
 	m.logger.Info("API 触发的更新完成",
+		"skipped", result.Skipped,
 		"stopped", len(result.Stopped),
 		"started", len(result.Started),
 		"stop_failed", len(result.StopFailed),
@@ -313,36 +841,86 @@ func (m *InstanceManager) TryLockUpdate() bool {
 	return m.isUpdating.CompareAndSwap(false, true)
 }
 
-// UnlockUpdate releases the update lock.
+// UnlockUpdate releases the update lock and wakes queued update requests.
 // Must be called when update completes (success or failure) to prevent deadlock.
 func (m *InstanceManager) UnlockUpdate() {
 	m.isUpdating.Store(false)
+	m.queue.wake()
 }
 
 // InstanceStatusInfo holds the status information for a single instance.
-// Used by status API and health monitor to get PID-based running state.
+// Used by status API and health monitor; the state comes from the instance state machine.
 type InstanceStatusInfo struct {
-	Name    string
-	Port    uint32
-	Running bool
-	PID     int32
+	Name         string                 `json:"name"`
+	Port         uint32                 `json:"port"`
+	Running      bool                   `json:"running"` // state 为 running
+	PID          int32                  `json:"pid"`
+	Restart      RestartStatus          `json:"restart"`                  // 崩溃监督状态 (restart 策略, 重启/崩溃次数)
+	LastOutputAt *time.Time             `json:"last_output_at,omitempty"` // 最近一次输出的时间
+	Liveness     *health.LivenessStatus `json:"liveness,omitempty"`       // 存活检查状态 (health_check.liveness), 运行中时才有
+	StateInfo                           // 状态机: state, since, initiator, uptime_ms, last_exit_*
 }
 
-// GetInstanceStatuses returns the running status of all instances using PID-based detection.
-// This is the accurate method for multi-instance scenarios where all instances share the same binary.
+// GetInstanceStatuses returns the state of all instances. The state is driven by the process
+// exit event of each instance's own process, so instances sharing the same binary are not confused.
 func (m *InstanceManager) GetInstanceStatuses() []InstanceStatusInfo {
 	statuses := make([]InstanceStatusInfo, 0, len(m.instances))
 	for _, inst := range m.instances {
-		statuses = append(statuses, InstanceStatusInfo{
-			Name:    inst.Name(),
-			Port:    inst.Port(),
-			Running: inst.IsRunning(),
-			PID:     inst.GetPID(),
-		})
+		state := inst.State()
+		info := InstanceStatusInfo{
+			Name:      inst.Name(),
+			Port:      inst.Port(),
+			Running:   state.State == StateRunning,
+			PID:       inst.GetPID(),
+			Restart:   inst.RestartStatus(),
+			Liveness:  inst.Liveness(),
+			StateInfo: state,
+		}
+		if last := inst.logBuffer.LastWrite(); !last.IsZero() {
+			info.LastOutputAt = &last
+		}
+		statuses = append(statuses, info)
 	}
 	return statuses
 }
 
+// SetLiveness records the liveness status reported by the health monitor for an instance.
+// nil clears it (the instance is not running).
+func (m *InstanceManager) SetLiveness(name string, status *health.LivenessStatus) {
+	if inst, err := m.GetLifecycle(name); err == nil {
+		inst.SetLiveness(status)
+	}
+}
+
+// RestartInstance stops and starts an instance, recording initiator for both transitions.
+// Used by the health monitor to restart instances that failed their liveness probes.
+// Returns ErrUpdateInProgress without touching the instance while an update is running;
+// the restart holds the update lock, so updates requested meanwhile wait in the update queue.
+func (m *InstanceManager) RestartInstance(ctx context.Context, name, initiator string) error {
+	inst, err := m.GetLifecycle(name)
+	if err != nil {
+		return err
+	}
+	if !m.TryLockUpdate() {
+		return ErrUpdateInProgress
+	}
+	defer m.UnlockUpdate()
+
+	ctx = WithInitiator(ctx, initiator)
+	if err := inst.StopForUpdate(ctx); err != nil {
+		return err
+	}
+	return inst.StartAfterUpdate(ctx)
+}
+
+// DisableRestarts stops the crash supervisor of all instances without stopping their processes.
+// Called before the instances are stopped outside of InstanceLifecycle (config reload replace).
+func (m *InstanceManager) DisableRestarts() {
+	for _, inst := range m.instances {
+		inst.DisableRestart()
+	}
+}
+
 // GetLifecycle returns the InstanceLifecycle for a specific instance by name.
 // Returns error if instance not found.
 func (m *InstanceManager) GetLifecycle(name string) (*InstanceLifecycle, error) {
@@ -9,10 +9,11 @@ import (
 	"time"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
 )
 
 // TestTriggerUpdate_Concurrent tests API-06:
-// TriggerUpdate returns ErrUpdateInProgress when called during ongoing update
+// TriggerUpdate called during an ongoing update waits in the update queue instead of failing
 func TestTriggerUpdate_Concurrent(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
@@ -27,12 +28,16 @@ func TestTriggerUpdate_Concurrent(t *testing.T) {
 	// Manually set updating flag to simulate ongoing update
 	manager.isUpdating.Store(true)
 
-	// Try to start update - should get ErrUpdateInProgress
-	ctx := context.Background()
-	_, err := manager.TriggerUpdate(ctx)
+	// Try to start update - should wait in the queue until its context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := manager.TriggerUpdate(ctx, updater.Target{}, true)
 
-	if !errors.Is(err, ErrUpdateInProgress) {
-		t.Errorf("Expected ErrUpdateInProgress, got %v", err)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the queued request to wait until context.DeadlineExceeded, got %v", err)
+	}
+	if queued := manager.QueuedUpdates(); len(queued) != 0 {
+		t.Errorf("Expected the timed out request to leave the queue, got %+v", queued)
 	}
 
 	// Reset flag
@@ -59,7 +64,7 @@ func TestTriggerUpdate_ResetsFlag(t *testing.T) {
 	ctx := context.Background()
 
 	// Call with empty instances (will complete immediately)
-	_, _ = manager.TriggerUpdate(ctx)
+	_, _ = manager.TriggerUpdate(ctx, updater.Target{}, true)
 
 	// Verify flag is reset
 	if manager.IsUpdating() {
@@ -67,7 +72,7 @@ func TestTriggerUpdate_ResetsFlag(t *testing.T) {
 	}
 
 	// Second call should not return ErrUpdateInProgress
-	_, err := manager.TriggerUpdate(ctx)
+	_, err := manager.TriggerUpdate(ctx, updater.Target{}, true)
 	if errors.Is(err, ErrUpdateInProgress) {
 		t.Error("Second TriggerUpdate should not return ErrUpdateInProgress")
 	}
@@ -88,7 +93,7 @@ func TestTriggerUpdate_ResetsFlagOnError(t *testing.T) {
 	ctx := context.Background()
 
 	// TriggerUpdate with empty instances won't error (no instances to manage)
-	_, _ = manager.TriggerUpdate(ctx)
+	_, _ = manager.TriggerUpdate(ctx, updater.Target{}, true)
 
 	// Verify flag is reset
 	if manager.IsUpdating() {
@@ -112,7 +117,7 @@ func TestTriggerUpdate_ContextCancellation(t *testing.T) {
 	cancel() // Cancel immediately
 
 	// Call TriggerUpdate with cancelled context
-	_, _ = manager.TriggerUpdate(ctx)
+	_, _ = manager.TriggerUpdate(ctx, updater.Target{}, true)
 
 	// Verify flag is reset even after cancellation
 	if manager.IsUpdating() {
@@ -169,7 +174,7 @@ func TestTriggerUpdate_CallsUpdateAll(t *testing.T) {
 	ctx := context.Background()
 
 	// TriggerUpdate should call UpdateAll and return UpdateResult
-	result, _ := manager.TriggerUpdate(ctx)
+	result, _ := manager.TriggerUpdate(ctx, updater.Target{}, true)
 
 	// Result should not be nil (UpdateAll was called)
 	if result == nil {
@@ -693,3 +698,25 @@ func TestInstanceLifecycleHelpers(t *testing.T) {
 		})
 	}
 }
+
+// TestUpdateAll_InvalidTarget verifies an invalid target is rejected before any instance is stopped
+func TestUpdateAll_InvalidTarget(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	cfg := &config.Config{
+		Instances: []config.InstanceConfig{
+			{Name: "inst1", Port: 9999, StartCommand: "nonexistent"},
+		},
+	}
+
+	manager := NewInstanceManager(cfg, logger, newTestNotifier())
+
+	result, err := manager.UpdateAll(context.Background(), updater.Target{Commit: "zzz"}, true)
+	if !errors.Is(err, updater.ErrInvalidTarget) {
+		t.Fatalf("UpdateAll error = %v, want ErrInvalidTarget", err)
+	}
+	if len(result.Stopped) != 0 || len(result.StopFailed) != 0 {
+		t.Errorf("no instance should be stopped for an invalid target, got stopped=%v stop_failed=%d",
+			result.Stopped, len(result.StopFailed))
+	}
+}
@@ -0,0 +1,278 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// maxQueuedUpdates 是等待执行的更新 (合并后) 的上限, 超出时拒绝新的请求
+const maxQueuedUpdates = 10
+
+var (
+	// ErrUpdateQueueFull 表示更新队列已满. 包装 ErrUpdateInProgress, 调用方按拒绝处理 (429, 更新日志 rejected)
+	ErrUpdateQueueFull = fmt.Errorf("%w: update queue is full", ErrUpdateInProgress)
+	// ErrQueuedUpdateNotFound 表示队列中没有该更新 (已开始执行、已结束或 ID 不存在)
+	ErrQueuedUpdateNotFound = errors.New("queued update not found")
+)
+
+// QueuedUpdate 是更新队列中等待执行的一次更新. 同一目标的连续请求合并为一次后续运行
+type QueuedUpdate struct {
+	ID        string    `json:"id"`         // 第一个请求的更新 ID, 用于移除
+	Target    string    `json:"target"`     // 目标版本 (latest, ref:v0.1.4, ...)
+	Force     bool      `json:"force"`      // 任一合并的请求要求强制重新安装
+	UpdateIDs []string  `json:"update_ids"` // 合并到本次运行的所有请求
+	QueuedAt  time.Time `json:"queued_at"`
+}
+
+// queuedUpdate 是队列条目及等待它的请求的共享状态
+type queuedUpdate struct {
+	QueuedUpdate
+	target  updater.Target
+	waiters int  // 仍在等待的请求数, 全部离开时条目移出队列
+	started bool // 已由某个等待者开始执行, 不再在队列中
+	dropped bool // 已通过 DropQueuedUpdate 移除
+	done    chan struct{}
+	result  *UpdateResult
+	err     error
+}
+
+// updateQueue 保存更新进行中到达的请求. 正在执行的更新不在队列中:
+// 与队尾目标相同的请求合并到队尾条目, 目标不同的请求按顺序排队
+type updateQueue struct {
+	mu      sync.Mutex
+	items   []*queuedUpdate
+	changed chan struct{} // 队列或更新锁变化时关闭并替换, 唤醒所有等待者
+	seq     int
+}
+
+func newUpdateQueue() *updateQueue {
+	return &updateQueue{changed: make(chan struct{})}
+}
+
+// notify 唤醒等待者, 调用方需持有 mu
+func (q *updateQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// wake 在更新锁释放后唤醒等待者
+func (q *updateQueue) wake() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.notify()
+}
+
+// enqueue 在队列为空且获得更新锁时返回 nil (调用方立即执行, 持有锁);
+// 否则把请求合并到队尾条目或追加新条目并返回该条目
+func (q *updateQueue) enqueue(id string, target updater.Target, force bool, lock *atomic.Bool) (*queuedUpdate, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 && lock.CompareAndSwap(false, true) {
+		return nil, nil
+	}
+	if id == "" {
+		q.seq++
+		id = fmt.Sprintf("queued-%d", q.seq)
+	}
+	if n := len(q.items); n > 0 && q.items[n-1].target == target {
+		item := q.items[n-1]
+		item.Force = item.Force || force
+		item.UpdateIDs = append(item.UpdateIDs, id)
+		item.waiters++
+		return item, nil
+	}
+	if len(q.items) >= maxQueuedUpdates {
+		return nil, ErrUpdateQueueFull
+	}
+	item := &queuedUpdate{
+		QueuedUpdate: QueuedUpdate{
+			ID:        id,
+			Target:    target.String(),
+			Force:     force,
+			UpdateIDs: []string{id},
+			QueuedAt:  time.Now().UTC(),
+		},
+		target:  target,
+		waiters: 1,
+		done:    make(chan struct{}),
+	}
+	q.items = append(q.items, item)
+	q.notify()
+	return item, nil
+}
+
+// take 在 item 位于队首、尚未开始且获得更新锁时把它移出队列并返回 true, 调用方负责执行.
+// 同时返回当前的唤醒 channel
+func (q *updateQueue) take(item *queuedUpdate, lock *atomic.Bool) (bool, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !item.started && !item.dropped && len(q.items) > 0 && q.items[0] == item && lock.CompareAndSwap(false, true) {
+		item.started = true
+		q.items = q.items[1:]
+		q.notify()
+		return true, q.changed
+	}
+	return false, q.changed
+}
+
+// leave 让请求 id 离开 item (取消或超时); 最后一个等待者离开且尚未开始时移除条目.
+// item 已由其他请求开始执行时不离开, 返回 false
+func (q *updateQueue) leave(item *queuedUpdate, id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if item.started {
+		return false
+	}
+	item.waiters--
+	if item.dropped {
+		return true
+	}
+	if i := slices.Index(item.UpdateIDs, id); i >= 0 {
+		item.UpdateIDs = slices.Delete(item.UpdateIDs, i, i+1)
+	}
+	if item.waiters == 0 {
+		q.remove(item)
+	}
+	return true
+}
+
+// remove 从队列中删除 item, 调用方需持有 mu
+func (q *updateQueue) remove(item *queuedUpdate) {
+	if i := slices.Index(q.items, item); i >= 0 {
+		q.items = slices.Delete(q.items, i, i+1)
+		q.notify()
+	}
+}
+
+// finish 记录 item 的执行结果, 唤醒合并到它的其他请求
+func (q *updateQueue) finish(item *queuedUpdate, result *UpdateResult, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item.result = result
+	item.err = err
+	close(item.done)
+}
+
+// list 返回等待中的更新, 按执行顺序
+func (q *updateQueue) list() []QueuedUpdate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := make([]QueuedUpdate, len(q.items))
+	for i, item := range q.items {
+		items[i] = item.QueuedUpdate
+		items[i].UpdateIDs = slices.Clone(item.UpdateIDs)
+	}
+	return items
+}
+
+// drop 移除 ID 为 id 的等待中的更新, 其等待者以取消结束
+func (q *updateQueue) drop(id string) (QueuedUpdate, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, item := range q.items {
+		if item.ID == id {
+			item.dropped = true
+			q.remove(item)
+			return item.QueuedUpdate, nil
+		}
+	}
+	return QueuedUpdate{}, fmt.Errorf("%w: %s", ErrQueuedUpdateNotFound, id)
+}
+
+// snapshot 返回 item 的 force 和合并的请求, 用于开始执行时
+func (q *updateQueue) snapshot(item *queuedUpdate) (bool, []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return item.Force, slices.Clone(item.UpdateIDs)
+}
+
+// state 返回 item 是否已开始或已被移除
+func (q *updateQueue) state(item *queuedUpdate) (started, dropped bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return item.started, item.dropped
+}
+
+// waitQueued 等待 item 轮到执行. 轮到时返回 run=true, 调用方持有更新锁并执行;
+// 其他请求已开始执行 item 时等待其结果, 此后不能再取消. 请求的任务在 item 开始前被取消或 item 被移除时
+// 返回取消的结果, ctx 结束时返回 ctx.Err()
+func (m *InstanceManager) waitQueued(ctx context.Context, item *queuedUpdate) (run bool, result *UpdateResult, err error) {
+	id := updateIDFrom(ctx)
+	if id == "" {
+		id = item.ID
+	}
+	job := jobFrom(ctx)
+	var jobChanged <-chan struct{}
+	if job != nil {
+		ch, unsubscribe := job.Subscribe()
+		defer unsubscribe()
+		jobChanged = ch
+	}
+	cancelled := func() *UpdateResult {
+		return &UpdateResult{Target: item.Target, Cancelled: true}
+	}
+
+	for {
+		ok, changed := m.queue.take(item, &m.isUpdating)
+		if ok {
+			return true, nil, nil
+		}
+		started, dropped := m.queue.state(item)
+		if dropped {
+			m.logger.Info("排队的更新已被移除", "queue_id", item.ID, "update_id", id)
+			return false, cancelled(), nil
+		}
+		if started {
+			// 合并的更新已在执行, 本请求以它的结果结束
+			job.follow()
+		}
+		select {
+		case <-item.done:
+			return false, item.result, item.err
+		case <-changed:
+		case <-jobChanged:
+			if job.cancelPending() {
+				if !m.queue.leave(item, id) {
+					m.logger.Info("合并的更新已开始执行, 忽略取消请求", "queue_id", item.ID, "update_id", id)
+					job.follow()
+					continue
+				}
+				m.logger.Info("排队的更新请求已取消", "queue_id", item.ID, "update_id", id)
+				return false, cancelled(), nil
+			}
+		case <-ctx.Done():
+			m.queue.leave(item, id)
+			return false, nil, ctx.Err()
+		}
+	}
+}
+
+// QueuedUpdates 返回等待执行的更新 (不含正在执行的更新), 按执行顺序
+func (m *InstanceManager) QueuedUpdates() []QueuedUpdate {
+	return m.queue.list()
+}
+
+// DropQueuedUpdate 从队列中移除等待中的更新, 合并到它的请求以取消结束.
+// 已开始执行或不存在时返回 ErrQueuedUpdateNotFound
+func (m *InstanceManager) DropQueuedUpdate(id string) (QueuedUpdate, error) {
+	dropped, err := m.queue.drop(id)
+	if err != nil {
+		return QueuedUpdate{}, err
+	}
+	m.logger.Info("已从更新队列移除", "queue_id", id, "target", dropped.Target, "update_ids", dropped.UpdateIDs)
+	return dropped, nil
+}
+
+// QueueFull 报告更新队列是否已满, 此时新的更新请求 (与队尾目标不同) 会被拒绝
+func (m *InstanceManager) QueueFull() bool {
+	m.queue.mu.Lock()
+	defer m.queue.mu.Unlock()
+	return len(m.queue.items) >= maxQueuedUpdates
+}
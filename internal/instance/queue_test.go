@@ -0,0 +1,228 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// queuedCall 是在后台调用 TriggerUpdate 的结果
+type queuedCall struct {
+	result *UpdateResult
+	err    error
+}
+
+// triggerInBackground 在 goroutine 中调用 TriggerUpdate, 并等待请求进入队列
+func triggerInBackground(t *testing.T, m *InstanceManager, ctx context.Context, target updater.Target, queued int) <-chan queuedCall {
+	t.Helper()
+	ch := make(chan queuedCall, 1)
+	go func() {
+		result, err := m.TriggerUpdate(ctx, target, false)
+		ch <- queuedCall{result, err}
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for countQueuedRequests(m) < queued {
+		if time.Now().After(deadline) {
+			t.Fatalf("request for %s was not queued", target.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return ch
+}
+
+func countQueuedRequests(m *InstanceManager) int {
+	n := 0
+	for _, item := range m.QueuedUpdates() {
+		n += len(item.UpdateIDs)
+	}
+	return n
+}
+
+func waitQueuedCall(t *testing.T, ch <-chan queuedCall) queuedCall {
+	t.Helper()
+	select {
+	case call := <-ch:
+		return call
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued TriggerUpdate did not return")
+		return queuedCall{}
+	}
+}
+
+func newQueueTestManager() *InstanceManager {
+	return NewInstanceManager(&config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)), newTestNotifier())
+}
+
+func TestTriggerUpdate_QueuesAndCoalescesWhileUpdating(t *testing.T) {
+	m := newQueueTestManager()
+	// 模拟正在进行的更新
+	if !m.TryLockUpdate() {
+		t.Fatal("TryLockUpdate failed on an idle manager")
+	}
+	defer m.UnlockUpdate()
+
+	v014 := updater.Target{Ref: "v0.1.4"}
+	latest1 := triggerInBackground(t, m, WithUpdateID(context.Background(), "req-1"), updater.Target{}, 1)
+	latest2 := triggerInBackground(t, m, WithUpdateID(context.Background(), "req-2"), updater.Target{}, 2)
+	pinned := triggerInBackground(t, m, WithUpdateID(context.Background(), "req-3"), v014, 3)
+
+	// 同一目标合并为一次后续运行, 不同目标按顺序排队
+	queued := m.QueuedUpdates()
+	if len(queued) != 2 {
+		t.Fatalf("queue = %+v, want 2 items", queued)
+	}
+	if queued[0].ID != "req-1" || queued[0].Target != "latest" || len(queued[0].UpdateIDs) != 2 || queued[0].UpdateIDs[1] != "req-2" {
+		t.Errorf("first item = %+v, want latest coalescing req-1 and req-2", queued[0])
+	}
+	if queued[1].ID != "req-3" || queued[1].Target != v014.String() {
+		t.Errorf("second item = %+v, want %s for req-3", queued[1], v014.String())
+	}
+
+	// 移除排队的更新: 合并到它的请求都以取消结束
+	if _, err := m.DropQueuedUpdate("req-1"); err != nil {
+		t.Fatalf("DropQueuedUpdate: %v", err)
+	}
+	for _, ch := range []<-chan queuedCall{latest1, latest2} {
+		call := waitQueuedCall(t, ch)
+		if call.err != nil || call.result == nil || !call.result.Cancelled {
+			t.Errorf("dropped request returned %+v, %v; want a cancelled result", call.result, call.err)
+		}
+	}
+	if _, err := m.DropQueuedUpdate("req-1"); !errors.Is(err, ErrQueuedUpdateNotFound) {
+		t.Errorf("second DropQueuedUpdate = %v, want ErrQueuedUpdateNotFound", err)
+	}
+
+	// 排队请求的任务被取消时离开队列
+	job := NewUpdateJob("job-4")
+	jobCall := triggerInBackground(t, m, WithJob(context.Background(), job), updater.Target{}, 2)
+	if err := job.RequestCancel(); err != nil {
+		t.Fatalf("RequestCancel of a queued job: %v", err)
+	}
+	if call := waitQueuedCall(t, jobCall); call.err != nil || call.result == nil || !call.result.Cancelled {
+		t.Errorf("cancelled queued job returned %+v, %v; want a cancelled result", call.result, call.err)
+	}
+
+	// ctx 结束时离开队列
+	m.DropQueuedUpdate("req-3")
+	waitQueuedCall(t, pinned)
+	ctx, cancel := context.WithCancel(context.Background())
+	timedOut := triggerInBackground(t, m, ctx, updater.Target{}, 1)
+	cancel()
+	if call := waitQueuedCall(t, timedOut); !errors.Is(call.err, context.Canceled) {
+		t.Errorf("request with a cancelled context returned %v, want context.Canceled", call.err)
+	}
+	if queued := m.QueuedUpdates(); len(queued) != 0 {
+		t.Errorf("queue = %+v, want empty after every request left", queued)
+	}
+}
+
+func TestTriggerUpdate_CoalescedRequestsShareOneRun(t *testing.T) {
+	m := newQueueTestManager()
+	if !m.TryLockUpdate() {
+		t.Fatal("TryLockUpdate failed on an idle manager")
+	}
+
+	// 无效目标在停止实例之前失败, 不需要 uv; 两个请求合并后只运行一次并得到同一结果
+	invalid := updater.Target{Ref: "v0.1.4", Commit: "abc1234"}
+	first := triggerInBackground(t, m, WithUpdateID(context.Background(), "req-1"), invalid, 1)
+	second := triggerInBackground(t, m, WithUpdateID(context.Background(), "req-2"), invalid, 2)
+	m.UnlockUpdate()
+
+	a, b := waitQueuedCall(t, first), waitQueuedCall(t, second)
+	if !errors.Is(a.err, updater.ErrInvalidTarget) || a.err != b.err {
+		t.Errorf("errors = %v / %v, want the same ErrInvalidTarget from one run", a.err, b.err)
+	}
+	if a.result == nil || a.result != b.result {
+		t.Fatalf("results = %p / %p, want one shared result", a.result, b.result)
+	}
+	if got := a.result.Coalesced; len(got) != 2 || got[0] != "req-1" || got[1] != "req-2" {
+		t.Errorf("coalesced = %v, want [req-1 req-2]", got)
+	}
+	if m.IsUpdating() || len(m.QueuedUpdates()) != 0 {
+		t.Error("update lock or queue still held after the queued run finished")
+	}
+}
+
+func TestTriggerUpdate_QueueFull(t *testing.T) {
+	m := newQueueTestManager()
+	if !m.TryLockUpdate() {
+		t.Fatal("TryLockUpdate failed on an idle manager")
+	}
+	defer m.UnlockUpdate()
+
+	for i := range maxQueuedUpdates {
+		target := updater.Target{PyPIVersion: "0.1." + string(rune('0'+i))}
+		triggerInBackground(t, m, context.Background(), target, i+1)
+	}
+	if !m.QueueFull() {
+		t.Fatal("QueueFull() = false with a full queue")
+	}
+
+	_, err := m.TriggerUpdate(context.Background(), updater.Target{Ref: "main"}, false)
+	if !errors.Is(err, ErrUpdateQueueFull) || !errors.Is(err, ErrUpdateInProgress) {
+		t.Errorf("TriggerUpdate with a full queue = %v, want ErrUpdateQueueFull (ErrUpdateInProgress)", err)
+	}
+
+	for _, item := range m.QueuedUpdates() {
+		m.DropQueuedUpdate(item.ID)
+	}
+}
+
+func TestEnqueueUpdate_ConcurrentRequestsTakeOneSlot(t *testing.T) {
+	m := newQueueTestManager()
+	if !m.TryLockUpdate() {
+		t.Fatal("TryLockUpdate failed on an idle manager")
+	}
+	defer m.UnlockUpdate()
+
+	// 队列只剩一个位置时, 同时到达的不同目标的请求只有一个被接受, 其余在入队时被拒绝
+	for i := range maxQueuedUpdates - 1 {
+		target := updater.Target{PyPIVersion: "0.1." + string(rune('0'+i))}
+		triggerInBackground(t, m, context.Background(), target, i+1)
+	}
+	const requests = 8
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted []func(context.Context) (*UpdateResult, error)
+		rejected int
+	)
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run, err := m.EnqueueUpdate(context.Background(), updater.Target{Ref: "branch-" + string(rune('a'+i))}, false)
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, ErrUpdateQueueFull) {
+				rejected++
+			} else if err == nil {
+				accepted = append(accepted, run)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(accepted) != 1 || rejected != requests-1 {
+		t.Fatalf("accepted %d, rejected %d; want 1 and %d", len(accepted), rejected, requests-1)
+	}
+	if queued := m.QueuedUpdates(); len(queued) != maxQueuedUpdates {
+		t.Errorf("queue has %d items, want %d", len(queued), maxQueuedUpdates)
+	}
+
+	// 接受的请求必须运行: ctx 已结束时离开队列
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := accepted[0](ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("run with a cancelled context = %v, want context.Canceled", err)
+	}
+	for _, item := range m.QueuedUpdates() {
+		m.DropQueuedUpdate(item.ID)
+	}
+}
@@ -0,0 +1,72 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+func TestUpdateAll_SyncsRepoAfterInstall(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	// origin 仓库和本地 clone; origin 的新提交在更新后被拉取
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin")
+	git(root, "init", "-q", "-b", "main", origin)
+	os.WriteFile(filepath.Join(origin, "README.md"), []byte("nanobot"), 0644)
+	git(origin, "add", "README.md")
+	git(origin, "commit", "-q", "-m", "Initial commit")
+	repoPath := filepath.Join(root, "nanobot")
+	git(root, "clone", "-q", origin, repoPath)
+	os.WriteFile(filepath.Join(origin, "CHANGELOG.md"), []byte("0.2.0"), 0644)
+	git(origin, "add", "CHANGELOG.md")
+	git(origin, "commit", "-q", "-m", "Release 0.2.0")
+
+	statePath, _ := setupFakeUv(t, "0.1.4", "0.2.0")
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "inst1", Port: 18991, StartCommand: startOnVersionCommand(statePath, "0.2.0", 18991), StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{})
+	m.updaterCfg.RepoPath = repoPath
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+	if result.RepoSyncError != "" {
+		t.Fatalf("RepoSyncError = %q, want none", result.RepoSyncError)
+	}
+	if want := git(origin, "rev-parse", "HEAD"); result.Repo == nil || result.Repo.Commit != want || result.Repo.Subject != "Release 0.2.0" {
+		t.Errorf("result.Repo = %+v, want the pulled commit %s", result.Repo, want)
+	}
+
+	status, err := m.RepoStatus(context.Background())
+	if err != nil || status.Branch != "main" || status.Dirty {
+		t.Errorf("RepoStatus() = %+v, %v; want clean main", status, err)
+	}
+}
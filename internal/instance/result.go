@@ -3,6 +3,10 @@ package instance
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
 )
 
 // UpdateResult 包含更新流程的所有结果
@@ -11,11 +15,232 @@ type UpdateResult struct {
 	Started     []string         `json:"started"`      // 成功启动的实例名称
 	StopFailed  []*InstanceError `json:"stop_failed"`  // 停止失败的实例错误
 	StartFailed []*InstanceError `json:"start_failed"` // 启动失败的实例错误
+	Target      string           `json:"target"`       // 请求的更新目标 (latest, ref:v0.1.4, commit:abc1234, pypi:0.1.3)
+	InstallSpec string           `json:"install_spec"` // 实际安装的 uv spec (UV 更新未执行时为空)
+	Source      string           `json:"source"`       // 安装成功的更新源 (updater.sources 中的名称或地址)
+	SourceType  string           `json:"source_type"`  // 更新源类型: git / pypi / local / upload
+	Skipped     bool             `json:"skipped"`      // 已是目标版本, 未停止实例也未安装 (force=false)
+	SkipReason  string           `json:"skip_reason"`  // 跳过原因 (如 "installed version 0.1.4 is current")
+
+	// 自动回滚 (新版本启动失败时重新安装更新前的版本)
+	PreviousVersion   string           `json:"previous_version"`    // Phase 2 之前安装的版本 (回滚点)
+	RolledBack        bool             `json:"rolled_back"`         // 是否已回滚到 PreviousVersion
+	RollbackError     string           `json:"rollback_error"`      // 回滚失败原因 (非空表示尝试回滚但失败)
+	FailedAfterUpdate []*InstanceError `json:"failed_after_update"` // 回滚前新版本下启动失败的实例
+
+	// Canary 阶段或 pre_update hook 中止 (canary 实例启动失败或观察期内不健康, pre_update hook 失败)
+	Aborted     bool     `json:"aborted"`      // 更新是否被中止
+	AbortStage  string   `json:"abort_stage"`  // 中止阶段 ("canary" / "pre_update")
+	AbortReason string   `json:"abort_reason"` // 中止原因
+	HeldBack    []string `json:"held_back"`    // 因中止保持停止的实例 (on_failure=hold 或回滚未执行)
+
+	// 在阶段之间被取消 (UpdateJob.RequestCancel): 未安装新版本, 已停止的实例已重新启动
+	Cancelled bool `json:"cancelled"`
+
+	// 更新进行中排队、合并为本次运行的请求的更新 ID (多于一个请求时)
+	Coalesced []string `json:"coalesced"`
+
+	// Dry run (InstanceManager.Plan): 只预览, 不停止实例也不安装
+	DryRun    bool                 `json:"dry_run"`   // 是否为预览结果
+	Preflight []string             `json:"preflight"` // 预检问题 (uv 未安装、正在更新等), 实际更新可能因此失败
+	Check     *updater.CheckResult `json:"check"`     // 已安装版本与上游的比较结果 (检查失败时为 nil)
+	Instances []InstanceStatusInfo `json:"instances"` // 预览时各实例的运行状态和 PID
+
+	// 配置了 updater.channels 时每个安装 (全局安装和各 channel) 的结果, 未配置时为空
+	Channels []*ChannelResult `json:"channels"`
+
+	// 各实例的停止/启动耗时、停机时间和启动期间的日志范围 (按首次停止或启动的顺序)
+	Timings []*InstanceTiming `json:"timings"`
+
+	// 执行的 hooks (hooks 配置) 及其结果, 按执行顺序
+	Hooks []*HookResult `json:"hooks"`
+
+	// 安装方式 (updater.strategy) 和 staged 策略下实例运行期间的暂存安装
+	Strategy      string `json:"strategy"`          // stop_first / staged
+	StagedDir     string `json:"staged_dir"`        // staged: 新版本安装到的槽位目录
+	StageDuration int64  `json:"stage_duration_ms"` // staged: 实例运行期间安装新版本的耗时 (毫秒)
+
+	// 安装成功后同步的本地源码仓库 (updater.repo_path): 同步后的 HEAD, 未配置时为空
+	Repo          *updater.RepoStatus `json:"repo"`
+	RepoSyncError string              `json:"repo_sync_error"` // 同步或读取 HEAD 失败的原因 (不影响更新结果)
+
+	installed bool                 // 至少一个分组安装成功 (用于安装后同步 updater.repo_path)
+	staged    *stagedInstall       // staged 策略下已切换的新版本槽位, 回滚时切回之前的安装
+	previous  updater.Installation // 更新前的安装 (spec 和源), 回滚时原样重新安装
+
+	// 本次更新的完整 uv/git 命令输出, 由调用方按 update_id 保存 (不包含在 JSON 中)
+	Output string `json:"-"`
+}
+
+// InstanceTiming 是一次更新中单个实例的耗时和日志范围.
+// 同一实例多次停止/启动 (回滚、取消后重新启动) 时耗时累计, 日志范围覆盖所有启动
+type InstanceTiming struct {
+	Name          string `json:"name"`
+	Port          uint32 `json:"port"`
+	StopDuration  int64  `json:"stop_duration_ms"`  // 停止耗时 (毫秒)
+	StartDuration int64  `json:"start_duration_ms"` // 启动耗时 (毫秒)
+	Downtime      int64  `json:"downtime_ms"`       // 从开始停止运行中的实例到重新启动成功的时间 (毫秒); 未恢复运行时不含最后一段
+	LogBufferID   string `json:"log_buffer_id"`     // 日志序号所属的 LogBuffer (logbuffer.LogBuffer.ID)
+	LogStartSeq   int64  `json:"log_start_seq"`     // 第一次启动前的 LogBuffer 序号
+	LogEndSeq     int64  `json:"log_end_seq"`       // 最后一次启动后的 LogBuffer 序号 (不含)
+
+	downSince time.Time // 停止运行中的实例的开始时间, 重新启动成功后清零
+	hasLogs   bool      // 已记录 LogStartSeq
+}
+
+// stopped 记录一次停止的耗时; down 表示运行中的实例已成功停止, 开始计算停机时间
+func (t *InstanceTiming) stopped(begin time.Time, down bool) {
+	t.StopDuration += time.Since(begin).Milliseconds()
+	if down && t.downSince.IsZero() {
+		t.downSince = begin
+	}
+}
+
+// starting 在启动前记录日志范围的起点 (只记录第一次启动)
+func (t *InstanceTiming) starting(buf *logbuffer.LogBuffer) {
+	if t.hasLogs {
+		return
+	}
+	t.hasLogs = true
+	t.LogBufferID = buf.ID()
+	t.LogStartSeq = buf.NextSeq()
+}
+
+// started 记录一次启动的耗时和日志范围的终点; 启动成功时结束停机时间
+func (t *InstanceTiming) started(begin time.Time, buf *logbuffer.LogBuffer, ok bool) {
+	t.StartDuration += time.Since(begin).Milliseconds()
+	t.LogEndSeq = buf.NextSeq()
+	if ok && !t.downSince.IsZero() {
+		t.Downtime += time.Since(t.downSince).Milliseconds()
+		t.downSince = time.Time{}
+	}
+}
+
+// timing 返回 inst 的耗时记录, 不存在时创建
+func (r *UpdateResult) timing(inst *InstanceLifecycle) *InstanceTiming {
+	for _, t := range r.Timings {
+		if t.Name == inst.Name() {
+			return t
+		}
+	}
+	t := &InstanceTiming{Name: inst.Name(), Port: inst.Port()}
+	r.Timings = append(r.Timings, t)
+	return t
+}
+
+// Timing 返回实例 name 的耗时记录, 没有时返回 nil
+func (r *UpdateResult) Timing(name string) *InstanceTiming {
+	for _, t := range r.Timings {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// ChannelResult 是一个安装分组 (全局安装或 updater.channels 中的 channel) 的更新结果
+type ChannelResult struct {
+	Channel         string               `json:"channel"`          // channel 名称, 空表示全局安装
+	InstallDir      string               `json:"install_dir"`      // 独立安装目录, 空表示全局安装
+	Instances       []string             `json:"instances"`        // 使用该安装的实例
+	Target          string               `json:"target"`           // 实际目标 (固定版本的 channel 忽略请求的目标)
+	InstallSpec     string               `json:"install_spec"`     // 实际安装的 uv spec
+	Source          string               `json:"source"`           // 安装成功的更新源
+	PreviousVersion string               `json:"previous_version"` // 更新前安装的版本
+	Skipped         bool                 `json:"skipped"`          // 已是目标版本, 该分组未停止也未安装
+	SkipReason      string               `json:"skip_reason"`      // 跳过原因
+	RolledBack      bool                 `json:"rolled_back"`      // 该分组是否已回滚
+	RollbackError   string               `json:"rollback_error"`   // 回滚失败原因
+	Aborted         bool                 `json:"aborted"`          // 该分组是否在 canary 阶段中止
+	AbortReason     string               `json:"abort_reason"`     // 中止原因
+	Cancelled       bool                 `json:"cancelled"`        // 该分组是否在阶段之间被取消
+	StagedDir       string               `json:"staged_dir"`       // staged 策略: 新版本安装到的槽位目录
+	Check           *updater.CheckResult `json:"check,omitempty"`  // Dry run: 已安装版本与上游的比较结果
+}
+
+// addChannel 将一个安装分组的结果合并到总结果: 实例列表依次追加,
+// InstallSpec/Source/PreviousVersion 取第一个有值的分组, 任一分组回滚即视为回滚
+func (r *UpdateResult) addChannel(cr *ChannelResult, groupResult *UpdateResult) {
+	r.Channels = append(r.Channels, cr)
+
+	r.Stopped = append(r.Stopped, groupResult.Stopped...)
+	r.Started = append(r.Started, groupResult.Started...)
+	r.StopFailed = append(r.StopFailed, groupResult.StopFailed...)
+	r.StartFailed = append(r.StartFailed, groupResult.StartFailed...)
+	r.FailedAfterUpdate = append(r.FailedAfterUpdate, groupResult.FailedAfterUpdate...)
+	r.HeldBack = append(r.HeldBack, groupResult.HeldBack...)
+	r.Timings = append(r.Timings, groupResult.Timings...)
+	r.Hooks = append(r.Hooks, groupResult.Hooks...)
+
+	if r.InstallSpec == "" {
+		r.InstallSpec = groupResult.InstallSpec
+		r.Source = groupResult.Source
+		r.SourceType = groupResult.SourceType
+	}
+	r.installed = r.installed || groupResult.installed
+	r.StageDuration += groupResult.StageDuration
+	if r.StagedDir == "" {
+		r.StagedDir = groupResult.StagedDir
+	}
+	if r.PreviousVersion == "" {
+		r.PreviousVersion = groupResult.PreviousVersion
+	}
+	if groupResult.RolledBack {
+		r.RolledBack = true
+	}
+	if groupResult.RollbackError != "" {
+		if r.RollbackError != "" {
+			r.RollbackError += "; "
+		}
+		r.RollbackError += groupResult.RollbackError
+	}
+	if groupResult.Cancelled {
+		r.Cancelled = true
+	}
+	if groupResult.Aborted {
+		r.Aborted = true
+		r.AbortStage = groupResult.AbortStage
+		if r.AbortReason != "" {
+			r.AbortReason += "; "
+		}
+		r.AbortReason += groupResult.AbortReason
+	}
+}
+
+// MaxDowntime 返回各实例中最长的停机时间 (毫秒), 用于比较不同安装方式的停机时间
+func (r *UpdateResult) MaxDowntime() int64 {
+	var max int64
+	for _, t := range r.Timings {
+		if t.Downtime > max {
+			max = t.Downtime
+		}
+	}
+	return max
+}
+
+// channelsSkipped 报告是否所有分组都已跳过, 并汇总跳过原因
+func (r *UpdateResult) channelsSkipped() (bool, string) {
+	if len(r.Channels) == 0 {
+		return false, ""
+	}
+	reasons := make([]string, 0, len(r.Channels))
+	for _, cr := range r.Channels {
+		if !cr.Skipped {
+			return false, ""
+		}
+		name := cr.Channel
+		if name == "" {
+			name = "global"
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s", name, cr.SkipReason))
+	}
+	return true, strings.Join(reasons, "; ")
 }
 
 // HasErrors 检查是否有任何失败
+// 回滚和 canary 中止视为失败: 请求的版本没有生效
 func (r *UpdateResult) HasErrors() bool {
-	return len(r.StopFailed) > 0 || len(r.StartFailed) > 0
+	return len(r.StopFailed) > 0 || len(r.StartFailed) > 0 || len(r.FailedAfterUpdate) > 0 || r.Aborted
 }
 
 // UpdateError 聚合所有实例错误
@@ -0,0 +1,339 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// fakeUvScript emulates `uv tool list`, `uv tool dir` and `uv tool install --force <spec>`.
+// The installed version lives in $FAKE_UV_STATE; every install spec is appended to $FAKE_UV_LOG.
+// Installing anything other than "nanobot-ai==<version>" or "<git url>@$FAKE_UV_GIT_COMMIT"
+// (which reinstalls $FAKE_UV_GIT_VERSION) installs $FAKE_UV_NEW_VERSION. Installs take $FAKE_UV_INSTALL_DELAY seconds.
+const fakeUvScript = `#!/bin/sh
+case "$1 $2" in
+"tool list")
+	if [ -s "$FAKE_UV_STATE" ]; then
+		echo "nanobot-ai v$(cat "$FAKE_UV_STATE")"
+		echo "- nanobot"
+	else
+		echo "No tools installed"
+	fi
+	;;
+"tool dir")
+	echo "$(dirname "$FAKE_UV_STATE")/tools"
+	;;
+"tool install")
+	spec="$4"
+	echo "$spec" >> "$FAKE_UV_LOG"
+	sleep "${FAKE_UV_INSTALL_DELAY:-0}"
+	case "$spec" in
+	nanobot-ai==*) echo "${spec#nanobot-ai==}" > "$FAKE_UV_STATE" ;;
+	*@"$FAKE_UV_GIT_COMMIT") echo "$FAKE_UV_GIT_VERSION" > "$FAKE_UV_STATE" ;;
+	*) echo "$FAKE_UV_NEW_VERSION" > "$FAKE_UV_STATE" ;;
+	esac
+	;;
+esac
+`
+
+// setupFakeUv puts a fake uv on PATH with installedVersion pre-installed and returns
+// the state file and install log paths.
+func setupFakeUv(t *testing.T, installedVersion, newVersion string) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "uv"), []byte(fakeUvScript), 0755); err != nil {
+		t.Fatalf("failed to write fake uv: %v", err)
+	}
+
+	statePath := filepath.Join(dir, "state")
+	logPath := filepath.Join(dir, "install.log")
+	if err := os.WriteFile(statePath, []byte(installedVersion), 0644); err != nil {
+		t.Fatalf("failed to write fake uv state: %v", err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_UV_STATE", statePath)
+	t.Setenv("FAKE_UV_LOG", logPath)
+	t.Setenv("FAKE_UV_NEW_VERSION", newVersion)
+	return statePath, logPath
+}
+
+// startOnVersionCommand returns a start command that stays alive only when version is installed.
+func startOnVersionCommand(statePath, version string, port uint32) string {
+	return fmt.Sprintf(`sh -c "grep -qx %s %s && exec sleep 30 # --port %d"`, version, statePath, port)
+}
+
+// newUpdateTestManager creates a manager for cfg whose instances are stopped at the end of the test
+func newUpdateTestManager(t *testing.T, cfg *config.Config) *InstanceManager {
+	t.Helper()
+	m := NewInstanceManager(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), newTestNotifier())
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		for _, inst := range m.instances {
+			_ = inst.StopForUpdate(ctx)
+		}
+	})
+	return m
+}
+
+func newRollbackTestManager(t *testing.T, instances []config.InstanceConfig, rollback config.RollbackConfig) *InstanceManager {
+	t.Helper()
+	return newUpdateTestManager(t, &config.Config{
+		Instances: instances,
+		Updater:   config.UpdaterConfig{Rollback: rollback},
+	})
+}
+
+func readInstallLog(t *testing.T, logPath string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read fake uv install log: %v", err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestUpdateAll_RollbackWhenAllInstancesFail(t *testing.T) {
+	statePath, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "inst1", Port: 18901, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18901), StartupTimeout: 5 * time.Second},
+		{Name: "inst2", Port: 18902, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18902), StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{Enabled: true, FailureThreshold: 1})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if result.PreviousVersion != "0.1.4" {
+		t.Errorf("PreviousVersion = %q, want %q", result.PreviousVersion, "0.1.4")
+	}
+	if !result.RolledBack {
+		t.Fatalf("expected rollback, got RolledBack=false (rollback_error=%q)", result.RollbackError)
+	}
+	if len(result.FailedAfterUpdate) != 2 {
+		t.Errorf("FailedAfterUpdate = %d, want 2", len(result.FailedAfterUpdate))
+	}
+	if len(result.Started) != 2 || len(result.StartFailed) != 0 {
+		t.Errorf("after rollback started=%v start_failed=%d, want both started", result.Started, len(result.StartFailed))
+	}
+	if result.InstallSpec != "nanobot-ai==0.1.4" {
+		t.Errorf("InstallSpec = %q, want %q", result.InstallSpec, "nanobot-ai==0.1.4")
+	}
+	if result.Source != "pypi" {
+		t.Errorf("Source = %q, want %q (rollback installs from PyPI)", result.Source, "pypi")
+	}
+	if !result.HasErrors() {
+		t.Error("a rolled back update should report errors")
+	}
+
+	installs := readInstallLog(t, logPath)
+	if len(installs) != 2 || installs[1] != "nanobot-ai==0.1.4" {
+		t.Errorf("uv installs = %v, want [<new spec> nanobot-ai==0.1.4]", installs)
+	}
+}
+
+// setupFakeGitInstall marks the installed version as a git install of repoURL at commit
+// (direct_url.json under the fake `uv tool dir`), reinstalled by the fake uv with <repoURL>@<commit>.
+func setupFakeGitInstall(t *testing.T, statePath, version, repoURL, commit string) {
+	t.Helper()
+
+	distInfo := filepath.Join(filepath.Dir(statePath), "tools", "nanobot-ai", "lib", "python3.12", "site-packages", "nanobot_ai-"+version+".dist-info")
+	if err := os.MkdirAll(distInfo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	directURL := `{"url":"` + repoURL + `","vcs_info":{"vcs":"git","commit_id":"` + commit + `"}}`
+	if err := os.WriteFile(filepath.Join(distInfo, "direct_url.json"), []byte(directURL), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FAKE_UV_GIT_COMMIT", commit)
+	t.Setenv("FAKE_UV_GIT_VERSION", version)
+}
+
+func TestUpdateAll_RollbackReinstallsPreviousGitCommit(t *testing.T) {
+	statePath, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+	repoURL := "https://example.com/fork/nanobot.git"
+	commit := "1111111111111111111111111111111111111111"
+	setupFakeGitInstall(t, statePath, "0.1.4", repoURL, commit)
+
+	// 只配置了 git 源: 回滚必须重新安装之前的 commit, 而不是 PyPI 上的同版本号
+	cfg := &config.Config{
+		Instances: []config.InstanceConfig{
+			{Name: "inst1", Port: 19009, StartCommand: startOnVersionCommand(statePath, "0.1.4", 19009), StartupTimeout: 5 * time.Second},
+		},
+		Updater: config.UpdaterConfig{
+			Sources:  []config.UpdateSourceConfig{{Name: "fork", Type: "git", URL: repoURL, Ref: "main"}},
+			Rollback: config.RollbackConfig{Enabled: true, FailureThreshold: 1},
+		},
+	}
+	m := newUpdateTestManager(t, cfg)
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if !result.RolledBack {
+		t.Fatalf("expected rollback, got RolledBack=false (rollback_error=%q)", result.RollbackError)
+	}
+	want := "git+" + repoURL + "@" + commit
+	if result.InstallSpec != want || result.Source != "fork" {
+		t.Errorf("rollback installed %q from %q, want %q from fork", result.InstallSpec, result.Source, want)
+	}
+	if len(result.Started) != 1 {
+		t.Errorf("after rollback started=%v, want [inst1]", result.Started)
+	}
+	if installs := readInstallLog(t, logPath); len(installs) != 2 || installs[1] != want {
+		t.Errorf("uv installs = %v, want [<new spec> %s]", installs, want)
+	}
+}
+
+func TestUpdateAll_NoRollbackBelowThreshold(t *testing.T) {
+	statePath, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "ok", Port: 18903, StartCommand: "sh -c \"exec sleep 30 # --port 18903\"", StartupTimeout: 5 * time.Second},
+		{Name: "broken", Port: 18904, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18904), StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{Enabled: true, FailureThreshold: 1})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if result.RolledBack || len(result.FailedAfterUpdate) != 0 {
+		t.Errorf("1/2 failures must not roll back with threshold 1, got RolledBack=%v", result.RolledBack)
+	}
+	if len(result.StartFailed) != 1 || result.StartFailed[0].InstanceName != "broken" {
+		t.Errorf("StartFailed = %v, want [broken]", extractNames(result.StartFailed))
+	}
+	if installs := readInstallLog(t, logPath); len(installs) != 1 {
+		t.Errorf("uv installs = %v, want exactly one install", installs)
+	}
+}
+
+func TestUpdateAll_RollbackAboveFraction(t *testing.T) {
+	statePath, _ := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "ok", Port: 18905, StartCommand: "sh -c \"exec sleep 30 # --port 18905\"", StartupTimeout: 5 * time.Second},
+		{Name: "broken1", Port: 18906, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18906), StartupTimeout: 5 * time.Second},
+		{Name: "broken2", Port: 18907, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18907), StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{Enabled: true, FailureThreshold: 0.5})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if !result.RolledBack {
+		t.Fatalf("2/3 failures must roll back with threshold 0.5 (rollback_error=%q)", result.RollbackError)
+	}
+	if len(result.Started) != 3 {
+		t.Errorf("after rollback started=%v, want all 3", result.Started)
+	}
+}
+
+func TestUpdateAll_RollbackWithoutPreviousVersion(t *testing.T) {
+	statePath, _ := setupFakeUv(t, "", "0.2.0")
+
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "inst1", Port: 18908, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18908), StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{Enabled: true, FailureThreshold: 1})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if result.RolledBack {
+		t.Error("rollback must not happen without a recorded previous version")
+	}
+	if result.RollbackError == "" {
+		t.Error("RollbackError should explain why rollback was skipped")
+	}
+	if len(result.StartFailed) != 1 {
+		t.Errorf("StartFailed = %d, want 1", len(result.StartFailed))
+	}
+}
+
+func TestUpdateAll_UsesConfiguredSources(t *testing.T) {
+	statePath, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	cfg := &config.Config{
+		Instances: []config.InstanceConfig{
+			{Name: "inst1", Port: 18909, StartCommand: startOnVersionCommand(statePath, "0.2.0", 18909), StartupTimeout: 5 * time.Second},
+		},
+		Updater: config.UpdaterConfig{
+			Sources: []config.UpdateSourceConfig{
+				{Name: "fork", Type: "git", URL: "https://example.com/fork/nanobot.git", Ref: "stable"},
+			},
+		},
+	}
+	m := newUpdateTestManager(t, cfg)
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if result.Source != "fork" {
+		t.Errorf("Source = %q, want %q", result.Source, "fork")
+	}
+	want := "git+https://example.com/fork/nanobot.git@stable"
+	if result.InstallSpec != want {
+		t.Errorf("InstallSpec = %q, want %q", result.InstallSpec, want)
+	}
+	if installs := readInstallLog(t, logPath); len(installs) != 1 || installs[0] != want {
+		t.Errorf("uv installs = %v, want [%s]", installs, want)
+	}
+}
+
+func TestUpdateAll_RecordsInstanceTimings(t *testing.T) {
+	statePath, _ := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	command := fmt.Sprintf(`sh -c "echo running-$(cat %s); exec sleep 30 # --port %d"`, statePath, 18971)
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "inst1", Port: 18971, StartCommand: command, StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{})
+	if err := m.instances[0].StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("failed to start instance: %v", err)
+	}
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	timing := result.Timing("inst1")
+	if timing == nil {
+		t.Fatalf("no timing recorded for inst1, timings = %+v", result.Timings)
+	}
+	if timing.Port != 18971 || timing.StartDuration <= 0 || timing.Downtime < timing.StartDuration {
+		t.Errorf("timing = %+v, want port, start duration and a downtime covering the start", timing)
+	}
+
+	buf := m.instances[0].GetLogBuffer()
+	if timing.LogBufferID != buf.ID() || timing.LogEndSeq <= timing.LogStartSeq {
+		t.Fatalf("log range = %s [%d, %d), want a non-empty range in buffer %s", timing.LogBufferID, timing.LogStartSeq, timing.LogEndSeq, buf.ID())
+	}
+	entries, complete := buf.Range(timing.LogStartSeq, timing.LogEndSeq)
+	if !complete || len(entries) == 0 || strings.TrimSpace(entries[0].Content) != "running-0.2.0" {
+		t.Errorf("logs in range = %+v (complete=%v), want the output of the new version", entries, complete)
+	}
+}
@@ -0,0 +1,180 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// staged 策略 (updater.strategy: staged): 每个安装分组在 <staging_dir>/global 或
+// <staging_dir>/channels/<name> 下有两个交替使用的槽位 a/b, 每个槽位是一个独立安装
+// (UV_TOOL_DIR=<slot>/tools, UV_TOOL_BIN_DIR=<slot>/bin). 新版本在实例运行时安装到非当前槽位,
+// 停止实例后把 current 文件原子地替换为新槽位并用新槽位启动实例; 回滚时切回之前的安装.
+// uv 的工具环境不可移动 (脚本中是绝对路径), 因此切换的是实例使用的槽位而不是移动目录.
+
+// stagingCurrentFile 记录分组当前使用的槽位名称
+const stagingCurrentFile = "current"
+
+// stagingSlotNames 是交替使用的两个槽位
+var stagingSlotNames = [2]string{"a", "b"}
+
+// stagingSlots 是一个安装分组的槽位根目录
+type stagingSlots string
+
+// stagingSlots 返回分组 g 的槽位根目录 (绝对路径, 实例和 uv 可能在其他工作目录运行)
+func (m *InstanceManager) stagingSlots(g installGroup) stagingSlots {
+	root := filepath.Join(m.updaterCfg.EffectiveStagingDir(), "global")
+	if g.channel != nil {
+		root = filepath.Join(m.updaterCfg.EffectiveStagingDir(), "channels", g.channel.Name)
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return stagingSlots(root)
+}
+
+// dir 返回槽位 slot 的安装目录
+func (s stagingSlots) dir(slot string) string {
+	return filepath.Join(string(s), slot)
+}
+
+// active 返回当前使用的槽位, 尚未切换过 (或记录无效) 时为空
+func (s stagingSlots) active() string {
+	data, err := os.ReadFile(filepath.Join(string(s), stagingCurrentFile))
+	if err != nil {
+		return ""
+	}
+	slot := strings.TrimSpace(string(data))
+	for _, name := range stagingSlotNames {
+		if slot == name {
+			return slot
+		}
+	}
+	return ""
+}
+
+// next 返回下一次安装使用的槽位 (非当前槽位)
+func (s stagingSlots) next() string {
+	if s.active() == stagingSlotNames[0] {
+		return stagingSlotNames[1]
+	}
+	return stagingSlotNames[0]
+}
+
+// activate 原子地记录当前槽位 (写临时文件后 rename); slot 为空时删除记录, 恢复使用分组配置的安装
+func (s stagingSlots) activate(slot string) error {
+	path := filepath.Join(string(s), stagingCurrentFile)
+	if slot == "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(string(s), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(slot+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// activeInstallDir 返回分组 g 的实例当前使用的安装目录: staged 策略下为当前槽位,
+// 尚未切换过槽位或未使用 staged 策略时为分组配置的安装 (全局安装为空)
+func (m *InstanceManager) activeInstallDir(g installGroup) string {
+	if m.updaterCfg.IsStaged() {
+		slots := m.stagingSlots(g)
+		if slot := slots.active(); slot != "" {
+			return slots.dir(slot)
+		}
+	}
+	return g.installDir()
+}
+
+// stagedInstall 是 staged 策略下已安装到备用槽位、等待切换的新版本
+type stagedInstall struct {
+	slots        stagingSlots
+	slot         string // 新版本所在槽位
+	previousSlot string // 切换前的槽位, 使用分组配置的安装时为空
+	previousDir  string // 切换前实例使用的安装目录 (全局安装为空)
+	instances    []*InstanceLifecycle
+}
+
+// dir 返回新版本的安装目录
+func (s *stagedInstall) dir() string {
+	return s.slots.dir(s.slot)
+}
+
+// swap 切换到新版本的槽位, 实例下次启动时使用新版本. 需在实例停止后调用
+func (s *stagedInstall) swap() error {
+	if err := s.slots.activate(s.slot); err != nil {
+		return fmt.Errorf("failed to activate staging slot %s: %w", s.slot, err)
+	}
+	for _, inst := range s.instances {
+		inst.installDir = s.dir()
+	}
+	return nil
+}
+
+// revert 切回切换前的安装 (回滚), 需在实例停止后调用
+func (s *stagedInstall) revert() error {
+	if err := s.slots.activate(s.previousSlot); err != nil {
+		return fmt.Errorf("failed to restore previous install: %w", err)
+	}
+	for _, inst := range s.instances {
+		inst.installDir = s.previousDir
+	}
+	return nil
+}
+
+// discardStaged 放弃未切换的暂存安装 (取消、停止失败或切换失败): 实例继续使用之前的安装
+func (r *UpdateResult) discardStaged() {
+	r.staged = nil
+	r.installed = false
+}
+
+// applyStagingSlots 在启动时让 staged 策略下的实例使用各分组当前的槽位
+func (m *InstanceManager) applyStagingSlots() {
+	if !m.updaterCfg.IsStaged() {
+		return
+	}
+	for _, g := range m.installGroups() {
+		dir := m.activeInstallDir(g)
+		for _, inst := range g.instances {
+			inst.installDir = dir
+		}
+	}
+}
+
+// stageInstall 在实例运行期间把 target 安装到分组的备用槽位, 安装结果写入 result.
+// 不停止实例, 失败时实例继续运行旧版本
+func (m *InstanceManager) stageInstall(ctx context.Context, g installGroup, target updater.Target, result *UpdateResult) (*stagedInstall, error) {
+	slots := m.stagingSlots(g)
+	staged := &stagedInstall{
+		slots:        slots,
+		slot:         slots.next(),
+		previousSlot: slots.active(),
+		previousDir:  m.activeInstallDir(g),
+		instances:    g.instances,
+	}
+	m.logger.Info("Staging new version while instances keep running",
+		"group", g.String(), "slot", staged.slot, "install_dir", staged.dir(), "target", target.String())
+
+	u := m.newUpdater(ctx, staged.dir())
+	u.SetHeartbeat(jobFrom(ctx).setHeartbeat)
+	begin := time.Now()
+	err := m.performUpdate(ctx, u, target, result)
+	result.StageDuration += time.Since(begin).Milliseconds()
+	if err != nil {
+		return nil, err
+	}
+	result.StagedDir = staged.dir()
+	return staged, nil
+}
@@ -0,0 +1,156 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// setupStagedTest puts fakeChannelUvScript on PATH with version pre-installed globally and
+// returns a config using the staged strategy, the staging dir, the markers dir and the install log
+func setupStagedTest(t *testing.T, version string, port uint32) (*config.Config, string, string, string) {
+	t.Helper()
+	root := t.TempDir()
+	uvDir := filepath.Join(root, "uv")
+	global := filepath.Join(root, "global")
+	markers := filepath.Join(root, "markers")
+	logPath := filepath.Join(root, "install.log")
+	for _, dir := range []string{uvDir, markers} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(uvDir, "uv"), []byte(fakeChannelUvScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fakeChannelInstall(t, global, version)
+
+	t.Setenv("PATH", uvDir+string(os.PathListSeparator)+filepath.Join(global, "bin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("UV_TOOL_DIR", "")
+	t.Setenv("UV_TOOL_BIN_DIR", "")
+	t.Setenv("FAKE_UV_GLOBAL", global)
+	t.Setenv("FAKE_UV_LOG", logPath)
+	t.Setenv("FAKE_NANOBOT_MARKERS", markers)
+
+	stagingDir := filepath.Join(root, "staging")
+	cfg := &config.Config{
+		Instances: []config.InstanceConfig{
+			{Name: "bot", Port: port, StartCommand: "nanobot gateway", StartupTimeout: 5 * time.Second},
+		},
+		Updater: config.UpdaterConfig{
+			Sources:    []config.UpdateSourceConfig{{Name: "pypi", Type: "pypi"}},
+			Rollback:   config.RollbackConfig{Enabled: true},
+			Strategy:   config.StrategyStaged,
+			StagingDir: stagingDir,
+		},
+	}
+	return cfg, stagingDir, markers, logPath
+}
+
+func readCurrentSlot(t *testing.T, stagingDir string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(stagingDir, "global", stagingCurrentFile))
+	if err != nil {
+		t.Fatalf("failed to read current slot: %v", err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestUpdateAll_StagedInstallsWhileRunning(t *testing.T) {
+	cfg, stagingDir, markers, logPath := setupStagedTest(t, "0.1.0", 18992)
+	t.Setenv("FAKE_UV_NEW_VERSION", "0.2.0")
+	t.Setenv("FAKE_UV_INSTALL_DELAY", "1")
+
+	m := newUpdateTestManager(t, cfg)
+	if err := m.instances[0].StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("failed to start bot: %v", err)
+	}
+	if v := readMarker(t, markers, 18992); v != "0.1.0" {
+		t.Fatalf("bot runs %s before the update, want 0.1.0", v)
+	}
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	slotA := filepath.Join(stagingDir, "global", "a")
+	if abs, _ := filepath.Abs(slotA); result.Strategy != config.StrategyStaged || result.StagedDir != abs {
+		t.Errorf("strategy = %q, staged dir = %q; want staged into %s", result.Strategy, result.StagedDir, abs)
+	}
+	if got := strings.TrimSpace(string(mustReadFile(t, logPath))); !strings.HasSuffix(got, filepath.Join("a", "tools")+" nanobot-ai") {
+		t.Errorf("uv installs = %q, want an install into slot a", got)
+	}
+	if slot := readCurrentSlot(t, stagingDir); slot != "a" {
+		t.Errorf("current slot = %q, want a", slot)
+	}
+	if v := readMarker(t, markers, 18992); v != "0.2.0" {
+		t.Errorf("bot runs %s after the update, want 0.2.0", v)
+	}
+
+	// 安装 (1 秒) 在实例运行期间完成, 不计入停机时间
+	if result.StageDuration < 1000 {
+		t.Errorf("stage duration = %dms, want the 1s install", result.StageDuration)
+	}
+	if timing := result.Timing("bot"); timing == nil || timing.Downtime >= timing.StopDuration+timing.StartDuration+500 {
+		t.Errorf("timing = %+v, want downtime covering only stop and start", timing)
+	}
+
+	// 重启后的管理器继续使用切换后的槽位
+	restarted := newUpdateTestManager(t, cfg)
+	if dir, _ := filepath.Abs(slotA); restarted.instances[0].installDir != dir {
+		t.Errorf("installDir after restart = %q, want %s", restarted.instances[0].installDir, dir)
+	}
+}
+
+func TestUpdateAll_StagedRollbackSwitchesBack(t *testing.T) {
+	cfg, stagingDir, markers, logPath := setupStagedTest(t, "0.1.0", 18993)
+	t.Setenv("FAKE_UV_NEW_VERSION", "0.2.0")
+	m := newUpdateTestManager(t, cfg)
+
+	if _, err := m.UpdateAll(context.Background(), updater.Target{}, true); err != nil {
+		t.Fatalf("first UpdateAll returned error: %v", err)
+	}
+	if v := readMarker(t, markers, 18993); v != "0.2.0" {
+		t.Fatalf("bot runs %s after the first update, want 0.2.0", v)
+	}
+
+	// 新版本无法启动: 切回槽位 a, 不重新安装
+	t.Setenv("FAKE_UV_NEW_VERSION", "0.3.0")
+	t.Setenv("FAKE_NANOBOT_BROKEN", "0.3.0")
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("second UpdateAll returned error: %v", err)
+	}
+	if !result.RolledBack || result.RollbackError != "" || len(result.Started) != 1 {
+		t.Errorf("result = rolled_back %v, error %q, started %v; want a successful rollback", result.RolledBack, result.RollbackError, result.Started)
+	}
+	if slot := readCurrentSlot(t, stagingDir); slot != "a" {
+		t.Errorf("current slot = %q after rollback, want a", slot)
+	}
+	if v := readMarker(t, markers, 18993); v != "0.2.0" {
+		t.Errorf("bot runs %s after rollback, want 0.2.0", v)
+	}
+
+	installs := strings.Split(strings.TrimSpace(string(mustReadFile(t, logPath))), "\n")
+	if len(installs) != 2 || !strings.HasSuffix(installs[1], filepath.Join("b", "tools")+" nanobot-ai") {
+		t.Errorf("uv installs = %q, want slot a then slot b and no reinstall on rollback", installs)
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,112 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"time"
+)
+
+// State 是实例状态机的状态. 状态变化由启动、停止和进程退出事件 (cmd.Wait) 驱动, 不轮询进程
+type State string
+
+const (
+	StateStopped  State = "stopped"  // 未启动, 或已被主动停止
+	StateStarting State = "starting" // 正在启动 (等待启动验证)
+	StateRunning  State = "running"  // 进程运行中
+	StateStopping State = "stopping" // 正在停止
+	StateCrashed  State = "crashed"  // 进程意外退出 (restart 策略可能已安排重启)
+	StateFailed   State = "failed"   // 启动失败, 或崩溃循环后放弃自动重启
+)
+
+// Initiators recorded as StateInfo.Initiator: who caused the last state transition
+const (
+	InitiatorAPI          = "api"           // POST /api/v1/instances/{name}/start|stop, 删除实例配置
+	InitiatorWeb          = "web"           // Web UI 重启按钮 (POST /api/v1/instances/{name}/restart)
+	InitiatorUpdate       = "update"        // 更新流程 (停止、启动、回滚)
+	InitiatorAutoStart    = "auto-start"    // 服务启动时的自动启动
+	InitiatorSupervisor   = "supervisor"    // 崩溃监督的自动重启和放弃
+	InitiatorProcess      = "process"       // 进程自行退出
+	InitiatorConfigReload = "config-reload" // 配置热重载替换实例时停止旧进程
+	InitiatorHealthCheck  = "health-check"  // 存活检查失败后的重启 (health_check.liveness.restart)
+)
+
+// StateInfo 是实例状态机的当前状态, 包含在 GET /api/v1/instances/status 中
+type StateInfo struct {
+	State          State      `json:"state"`
+	Since          time.Time  `json:"since"`                      // 进入当前状态的时间
+	Initiator      string     `json:"initiator,omitempty"`        // 触发最近一次状态变化的一方 (Initiator* 常量)
+	StartedAt      *time.Time `json:"started_at,omitempty"`       // 当前进程的启动时间 (running/stopping)
+	UptimeMs       int64      `json:"uptime_ms"`                  // 当前进程的运行时间, 未运行时为 0
+	LastExitCode   *int       `json:"last_exit_code,omitempty"`   // 最近一次进程退出的退出码 (被信号终止时为 -1)
+	LastExitReason string     `json:"last_exit_reason,omitempty"` // 最近一次进程退出的原因
+	LastExitAt     *time.Time `json:"last_exit_at,omitempty"`
+	Error          string     `json:"error,omitempty"` // failed 状态的原因 (启动失败或崩溃循环)
+}
+
+// stateMachine 是实例的状态, 由 InstanceLifecycle.mu 保护
+type stateMachine struct {
+	info    StateInfo
+	started time.Time
+	procGen uint64 // 当前进程启动时的 supervisor generation, 只处理该进程的退出事件
+}
+
+// setStateLocked 切换状态并记录发起方. 调用方持有 il.mu
+func (il *InstanceLifecycle) setStateLocked(state State, initiator string) {
+	from := il.st.info.State
+	il.st.info.State = state
+	il.st.info.Since = time.Now()
+	il.st.info.Initiator = initiator
+	if state != StateFailed {
+		il.st.info.Error = ""
+	}
+	if from != state {
+		il.logger.Info("Instance state changed", "from", from, "to", state, "initiator", initiator)
+	}
+}
+
+// State returns the current state of the instance state machine with uptime and last exit.
+func (il *InstanceLifecycle) State() StateInfo {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	info := il.st.info
+	if !il.st.started.IsZero() {
+		started := il.st.started
+		info.StartedAt = &started
+		info.UptimeMs = time.Since(started).Milliseconds()
+	}
+	return info
+}
+
+type initiatorContextKey struct{}
+
+// WithInitiator 返回携带发起方的 context, StartAfterUpdate/StopForUpdate 将其记录为状态变化的发起方
+func WithInitiator(ctx context.Context, initiator string) context.Context {
+	return context.WithValue(ctx, initiatorContextKey{}, initiator)
+}
+
+// withDefaultInitiator 在 ctx 没有发起方时设置 initiator
+func withDefaultInitiator(ctx context.Context, initiator string) context.Context {
+	if initiatorFrom(ctx) != "" {
+		return ctx
+	}
+	return WithInitiator(ctx, initiator)
+}
+
+// initiatorFrom 返回 WithInitiator 设置的发起方, 没有时返回空
+func initiatorFrom(ctx context.Context) string {
+	initiator, _ := ctx.Value(initiatorContextKey{}).(string)
+	return initiator
+}
+
+// exitCode 返回 cmd.Wait() 错误对应的退出码: 正常退出为 0, 被信号终止或无法确定时为 -1
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
@@ -0,0 +1,183 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+)
+
+// waitState polls the instance state until it is want
+func waitState(t *testing.T, il *InstanceLifecycle, want State, timeout time.Duration) StateInfo {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		info := il.State()
+		if info.State == want {
+			return info
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %+v, want %s within %v", info, want, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestState_RunningThenCrashed(t *testing.T) {
+	il := newSupervisedInstance(t, config.InstanceConfig{
+		Name:         "crash",
+		Port:         18997,
+		StartCommand: exitCommand(3, 3, 18997),
+	}, newTestNotifier())
+
+	if info := il.State(); info.State != StateStopped || info.Initiator != "" {
+		t.Fatalf("initial state = %+v, want stopped", info)
+	}
+
+	if err := il.StartAfterUpdate(WithInitiator(context.Background(), InitiatorAPI)); err != nil {
+		t.Fatalf("StartAfterUpdate() error = %v", err)
+	}
+	info := il.State()
+	if info.State != StateRunning || info.Initiator != InitiatorAPI || info.StartedAt == nil || info.UptimeMs <= 0 {
+		t.Fatalf("state after start = %+v, want running started by api with an uptime", info)
+	}
+	if !il.IsRunning() || il.GetPID() == 0 {
+		t.Error("IsRunning() = false or no PID after start")
+	}
+
+	// 退出事件 (cmd.Wait) 直接驱动状态变化
+	info = waitState(t, il, StateCrashed, 5*time.Second)
+	if info.Initiator != InitiatorProcess || info.LastExitCode == nil || *info.LastExitCode != 3 {
+		t.Errorf("state after exit = %+v, want crashed by the process with exit code 3", info)
+	}
+	if !strings.Contains(info.LastExitReason, "exit status 3") || info.LastExitAt == nil {
+		t.Errorf("last exit = %q at %v, want exit status 3", info.LastExitReason, info.LastExitAt)
+	}
+	if info.StartedAt != nil || info.UptimeMs != 0 || il.IsRunning() || il.GetPID() != 0 {
+		t.Errorf("state after exit = %+v (pid %d), want no process", info, il.GetPID())
+	}
+}
+
+func TestState_StopRecordsInitiator(t *testing.T) {
+	il := newSupervisedInstance(t, config.InstanceConfig{
+		Name:         "stop",
+		Port:         18998,
+		StartCommand: sleepCommand(30, 18998),
+	}, newTestNotifier())
+
+	if err := il.StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("StartAfterUpdate() error = %v", err)
+	}
+	if err := il.StopForUpdate(WithInitiator(context.Background(), InitiatorWeb)); err != nil {
+		t.Fatalf("StopForUpdate() error = %v", err)
+	}
+
+	info := il.State()
+	if info.State != StateStopped || info.Initiator != InitiatorWeb || il.IsRunning() {
+		t.Fatalf("state after stop = %+v, want stopped by web", info)
+	}
+
+	// 退出事件不会把主动停止变为 crashed
+	time.Sleep(200 * time.Millisecond)
+	if info := il.State(); info.State != StateStopped || info.Initiator != InitiatorWeb {
+		t.Errorf("state after the exit event = %+v, want still stopped by web", info)
+	}
+	if status := il.RestartStatus(); status.CrashCount != 0 {
+		t.Errorf("crash count = %d after an intentional stop, want 0", status.CrashCount)
+	}
+}
+
+func TestState_StartFailed(t *testing.T) {
+	il := newSupervisedInstance(t, config.InstanceConfig{
+		Name:         "broken",
+		Port:         18999,
+		StartCommand: exitCommand(0, 1, 18999),
+	}, newTestNotifier())
+
+	if err := il.StartAfterUpdate(WithInitiator(context.Background(), InitiatorAutoStart)); err == nil {
+		t.Fatal("StartAfterUpdate() succeeded, want an error for a process that exits immediately")
+	}
+	info := il.State()
+	if info.State != StateFailed || info.Initiator != InitiatorAutoStart || !strings.Contains(info.Error, "exited immediately") {
+		t.Errorf("state = %+v, want failed with the start error", info)
+	}
+}
+
+func TestState_ReadinessTimeout(t *testing.T) {
+	il := newSupervisedInstance(t, config.InstanceConfig{
+		Name:         "unready",
+		Port:         19007,
+		StartCommand: `sh -c "echo connecting; exec sleep 30 # --port 19007"`,
+		Readiness:    config.ReadinessConfig{LogPattern: "commands registered", Interval: 100 * time.Millisecond},
+	}, newTestNotifier())
+
+	// 进程存活但一直未就绪, startup_timeout (5s) 后启动失败
+	if err := il.StartAfterUpdate(context.Background()); err == nil {
+		t.Fatal("StartAfterUpdate() succeeded, want a readiness timeout")
+	}
+	info := il.State()
+	if info.State != StateFailed || !strings.Contains(info.Error, "not ready within 5s") || !strings.Contains(info.Error, "connecting") {
+		t.Errorf("state = %+v, want failed with the readiness timeout and the log lines", info)
+	}
+	if il.GetPID() != 0 {
+		t.Errorf("pid = %d after a failed start, want 0", il.GetPID())
+	}
+}
+
+func TestRestartInstance_SkippedDuringUpdate(t *testing.T) {
+	m := NewInstanceManager(&config.Config{Instances: []config.InstanceConfig{
+		{Name: "live", Port: 19010, StartCommand: sleepCommand(30, 19010), StartupTimeout: 5 * time.Second},
+	}}, slog.New(slog.NewTextHandler(io.Discard, nil)), newTestNotifier())
+	il := m.instances[0]
+	t.Cleanup(func() { _ = il.StopForUpdate(context.Background()) })
+
+	if err := il.StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("StartAfterUpdate() error = %v", err)
+	}
+	pid := il.GetPID()
+
+	// 更新进行中: 不停止实例, 由更新负责重启
+	m.isUpdating.Store(true)
+	if err := m.RestartInstance(context.Background(), "live", InitiatorHealthCheck); !errors.Is(err, ErrUpdateInProgress) {
+		t.Fatalf("RestartInstance() during an update error = %v, want ErrUpdateInProgress", err)
+	}
+	if il.GetPID() != pid || !il.IsRunning() {
+		t.Errorf("instance was touched during the update: pid %d -> %d", pid, il.GetPID())
+	}
+	m.UnlockUpdate()
+
+	if err := m.RestartInstance(context.Background(), "live", InitiatorHealthCheck); err != nil {
+		t.Fatalf("RestartInstance() error = %v", err)
+	}
+	if info := il.State(); info.State != StateRunning || info.Initiator != InitiatorHealthCheck || il.GetPID() == pid {
+		t.Errorf("state after restart = %+v (pid %d), want a new process started by the health check", info, il.GetPID())
+	}
+	if m.IsUpdating() {
+		t.Error("RestartInstance() did not release the update lock")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 7").Run()
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{exitErr, 7},
+		{errors.New("wait failed"), -1},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
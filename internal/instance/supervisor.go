@@ -0,0 +1,204 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+)
+
+// RestartStatus 是实例崩溃监督 (instances[].restart) 的状态, 包含在 GET /api/v1/instances/status 中
+type RestartStatus struct {
+	Policy        string     `json:"policy"`                    // never / on-failure / always
+	RestartCount  int        `json:"restart_count"`             // 自动重启次数
+	CrashCount    int        `json:"crash_count"`               // 意外退出次数 (API 停止、更新和配置重载不计)
+	LastExitAt    *time.Time `json:"last_exit_at,omitempty"`    // 最近一次意外退出的时间
+	LastExitError string     `json:"last_exit_error,omitempty"` // 最近一次意外退出的原因
+	NextRestartAt *time.Time `json:"next_restart_at,omitempty"` // 已安排的下一次重启
+	CrashLoop     bool       `json:"crash_loop"`                // 崩溃循环, 已放弃自动重启 (手动启动后恢复)
+}
+
+// supervisor 是实例的崩溃监督状态, 由 InstanceLifecycle.mu 保护.
+// 每次启动和停止都会增加 generation: 只有当前 generation 的进程退出才算崩溃,
+// 已停止或已被替换的进程退出被忽略
+type supervisor struct {
+	generation   uint64
+	restartTimer *time.Timer
+	crashes      []time.Time // crash_loop_window 内触发重启的崩溃时间
+	startCrashes int         // 最近一次手动启动时的 CrashCount
+	disabled     bool        // DisableRestart 之后不再重启, 进程退出视为停止
+	status       RestartStatus
+}
+
+// superviseLocked 在手动启动 (StartAfterUpdate) 前调用: 取消已安排的重启, 清除崩溃循环状态,
+// 返回新进程的 generation. 调用方持有 il.mu
+func (il *InstanceLifecycle) superviseLocked() uint64 {
+	il.cancelRestartLocked()
+	il.sup.generation++
+	il.sup.crashes = nil
+	il.sup.startCrashes = il.sup.status.CrashCount
+	il.sup.status.CrashLoop = false
+	return il.sup.generation
+}
+
+// releaseLocked 在主动停止前调用: 当前进程的退出不再触发重启, 已安排的重启被取消.
+// 调用方持有 il.mu
+func (il *InstanceLifecycle) releaseLocked() {
+	il.cancelRestartLocked()
+	il.sup.generation++
+}
+
+func (il *InstanceLifecycle) cancelRestartLocked() {
+	if il.sup.restartTimer != nil {
+		il.sup.restartTimer.Stop()
+		il.sup.restartTimer = nil
+	}
+	il.sup.status.NextRestartAt = nil
+}
+
+// DisableRestart stops supervising the instance without stopping its process, so processes
+// stopped by other means (e.g. the instance replace of a config reload) are not restarted.
+// Their exit puts the instance in the stopped state.
+func (il *InstanceLifecycle) DisableRestart() {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	il.releaseLocked()
+	il.sup.disabled = true
+}
+
+// RestartStatus returns the crash supervisor state of the instance.
+func (il *InstanceLifecycle) RestartStatus() RestartStatus {
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	status := il.sup.status
+	status.Policy = il.config.Restart.EffectivePolicy()
+	return status
+}
+
+// handleExit 是进程退出回调 (lifecycle.StartOptions.OnExit); err 为 cmd.Wait() 的错误.
+// 主动停止的进程进入 stopped, 其余退出进入 crashed 并按 restart 策略处理
+func (il *InstanceLifecycle) handleExit(generation uint64, err error) {
+	il.mu.Lock()
+	if generation != il.st.procGen {
+		il.mu.Unlock()
+		return // 已被新进程替换
+	}
+
+	now := time.Now()
+	code := exitCode(err)
+	reason := "exited with code 0"
+	if err != nil {
+		reason = err.Error()
+	}
+	il.st.info.LastExitCode = &code
+	il.st.info.LastExitReason = reason
+	il.st.info.LastExitAt = &now
+	il.pid = 0
+	il.st.started = time.Time{}
+
+	switch {
+	case il.st.info.State == StateStopping:
+		il.setStateLocked(StateStopped, il.st.info.Initiator)
+		il.mu.Unlock()
+		return
+	case il.sup.disabled:
+		il.setStateLocked(StateStopped, InitiatorConfigReload)
+		il.mu.Unlock()
+		return
+	case il.st.info.State == StateStopped:
+		il.mu.Unlock()
+		return // 停止已完成 (StopForUpdate 先于退出事件更新了状态)
+	}
+
+	il.setStateLocked(StateCrashed, InitiatorProcess)
+	il.crashedLocked(now, reason, err != nil) // unlocks il.mu
+}
+
+// crashedLocked 记录一次意外退出 (或自动重启失败) 并按 restart 策略安排重启,
+// crash_loop_window 内崩溃 crash_loop_count 次后放弃 (failed) 并发送通知.
+// 调用方持有 il.mu, 返回前释放
+func (il *InstanceLifecycle) crashedLocked(now time.Time, reason string, failure bool) {
+	il.sup.status.CrashCount++
+	il.sup.status.LastExitAt = &now
+	il.sup.status.LastExitError = reason
+
+	restart := il.config.Restart
+	policy := restart.EffectivePolicy()
+	if policy == config.RestartPolicyNever || (policy == config.RestartPolicyOnFailure && !failure) {
+		il.mu.Unlock()
+		il.logger.Warn("Instance exited unexpectedly, not restarting", "reason", reason, "restart_policy", policy)
+		return
+	}
+
+	// 只保留窗口内的崩溃
+	window := restart.EffectiveCrashLoopWindow()
+	recent := il.sup.crashes[:0]
+	for _, at := range il.sup.crashes {
+		if now.Sub(at) < window {
+			recent = append(recent, at)
+		}
+	}
+	il.sup.crashes = append(recent, now)
+	crashes := len(il.sup.crashes)
+
+	if crashes >= restart.EffectiveCrashLoopCount() {
+		il.sup.status.CrashLoop = true
+		il.setStateLocked(StateFailed, InitiatorSupervisor)
+		il.st.info.Error = fmt.Sprintf("crash loop: %d crashes within %v, last: %s", crashes, window, reason)
+		il.mu.Unlock()
+		il.logger.Error("Instance is crash looping, giving up restarts", "crashes", crashes, "window", window, "reason", reason)
+		il.notifyCrashLoop(crashes, window, reason)
+		return
+	}
+
+	delay := restart.BackoffFor(crashes)
+	next := now.Add(delay)
+	generation := il.sup.generation
+	il.sup.status.NextRestartAt = &next
+	il.sup.restartTimer = time.AfterFunc(delay, func() { il.restart(generation) })
+	il.mu.Unlock()
+	il.logger.Warn("Instance exited unexpectedly, scheduling restart", "reason", reason, "backoff", delay, "crashes_in_window", crashes)
+}
+
+// restart 重启崩溃的实例, 保留 LogBuffer 中崩溃前的日志. 启动失败按一次崩溃处理
+func (il *InstanceLifecycle) restart(generation uint64) {
+	il.mu.Lock()
+	if generation != il.sup.generation {
+		il.mu.Unlock()
+		return // 重启前已被停止或手动启动
+	}
+	il.sup.restartTimer = nil
+	il.sup.status.NextRestartAt = nil
+	il.sup.status.RestartCount++
+	il.sup.generation++
+	generation = il.sup.generation
+	il.mu.Unlock()
+
+	il.logger.Info("Restarting crashed instance")
+	il.stopTelegramMonitor()
+	if err := il.start(context.Background(), generation, InitiatorSupervisor); err != nil {
+		il.mu.Lock()
+		if generation != il.sup.generation || il.st.info.State != StateFailed {
+			il.mu.Unlock()
+			return // 重启期间被停止或手动启动
+		}
+		il.setStateLocked(StateCrashed, InitiatorSupervisor)
+		il.crashedLocked(time.Now(), err.Error(), true) // unlocks il.mu
+		return
+	}
+	il.startTelegramMonitor()
+}
+
+// notifyCrashLoop 发送放弃自动重启的通知
+func (il *InstanceLifecycle) notifyCrashLoop(crashes int, window time.Duration, reason string) {
+	if il.notifier == nil || !il.notifier.IsEnabled() {
+		return
+	}
+	title := fmt.Sprintf("Nanobot 实例 %s 崩溃循环", il.config.Name)
+	message := fmt.Sprintf("实例 %s 在 %v 内崩溃 %d 次, 已停止自动重启。\n最近一次退出: %s\n排查后请手动启动该实例。",
+		il.config.Name, window, crashes, reason)
+	if err := il.notifier.Notify(title, message); err != nil {
+		il.logger.Error("Failed to send crash loop notification", "error", err)
+	}
+}
@@ -0,0 +1,153 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+)
+
+// recordingNotifier records the titles of sent notifications
+type recordingNotifier struct {
+	mu     sync.Mutex
+	titles []string
+}
+
+func (n *recordingNotifier) IsEnabled() bool { return true }
+
+func (n *recordingNotifier) Notify(title, message string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.titles = append(n.titles, title)
+	return nil
+}
+
+func (n *recordingNotifier) sent() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.titles...)
+}
+
+// exitCommand returns a start command that survives the 2s start verification, then exits with code
+func exitCommand(seconds int, code int, port uint32) string {
+	return fmt.Sprintf(`sh -c "sleep %d; exit %d # --port %d"`, seconds, code, port)
+}
+
+func newSupervisedInstance(t *testing.T, cfg config.InstanceConfig, notifier Notifier) *InstanceLifecycle {
+	t.Helper()
+	cfg.StartupTimeout = 5 * time.Second
+	il := NewInstanceLifecycle(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), notifier)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = il.StopForUpdate(ctx)
+	})
+	return il
+}
+
+// waitRestartStatus polls the restart status until cond holds
+func waitRestartStatus(t *testing.T, il *InstanceLifecycle, timeout time.Duration, cond func(RestartStatus) bool) RestartStatus {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		status := il.RestartStatus()
+		if cond(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("restart status = %+v, condition not met within %v", status, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestSupervisor_RestartsUntilCrashLoop(t *testing.T) {
+	notifier := &recordingNotifier{}
+	il := newSupervisedInstance(t, config.InstanceConfig{
+		Name:         "crashy",
+		Port:         18994,
+		StartCommand: exitCommand(3, 3, 18994),
+		Restart: config.RestartConfig{
+			Policy:          config.RestartPolicyOnFailure,
+			Backoff:         100 * time.Millisecond,
+			CrashLoopCount:  2,
+			CrashLoopWindow: time.Minute,
+		},
+	}, notifier)
+
+	if err := il.StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("StartAfterUpdate() error = %v", err)
+	}
+
+	// 第一次崩溃后自动重启, 第二次崩溃达到 crash_loop_count 后放弃
+	status := waitRestartStatus(t, il, 15*time.Second, func(s RestartStatus) bool { return s.CrashLoop })
+	if status.Policy != config.RestartPolicyOnFailure || status.RestartCount != 1 || status.CrashCount != 2 {
+		t.Errorf("status = %+v, want 1 restart and 2 crashes", status)
+	}
+	if !strings.Contains(status.LastExitError, "exit status 3") || status.LastExitAt == nil || status.NextRestartAt != nil {
+		t.Errorf("status = %+v, want the last exit without a pending restart", status)
+	}
+	if sent := notifier.sent(); len(sent) != 1 || !strings.Contains(sent[0], "崩溃循环") {
+		t.Errorf("notifications = %v, want one crash loop notification", sent)
+	}
+
+	// 手动启动清除崩溃循环状态, 保留计数
+	il.config.StartCommand = sleepCommand(30, 18994)
+	if err := il.StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("manual StartAfterUpdate() error = %v", err)
+	}
+	if status := il.RestartStatus(); status.CrashLoop || status.CrashCount != 2 {
+		t.Errorf("status after manual start = %+v, want crash loop cleared and counters kept", status)
+	}
+}
+
+func TestSupervisor_StopDoesNotRestart(t *testing.T) {
+	il := newSupervisedInstance(t, config.InstanceConfig{
+		Name:         "stopped",
+		Port:         18995,
+		StartCommand: sleepCommand(30, 18995),
+		Restart:      config.RestartConfig{Policy: config.RestartPolicyAlways, Backoff: 50 * time.Millisecond},
+	}, newTestNotifier())
+
+	if err := il.StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("StartAfterUpdate() error = %v", err)
+	}
+	if err := il.StopForUpdate(context.Background()); err != nil {
+		t.Fatalf("StopForUpdate() error = %v", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	if status := il.RestartStatus(); status.CrashCount != 0 || status.RestartCount != 0 || status.NextRestartAt != nil {
+		t.Errorf("status = %+v, want no crash and no restart after an intentional stop", status)
+	}
+	if il.IsRunning() {
+		t.Error("instance restarted after an intentional stop")
+	}
+}
+
+func TestSupervisor_OnFailureIgnoresCleanExit(t *testing.T) {
+	il := newSupervisedInstance(t, config.InstanceConfig{
+		Name:         "clean",
+		Port:         18996,
+		StartCommand: exitCommand(3, 0, 18996),
+		Restart:      config.RestartConfig{Policy: config.RestartPolicyOnFailure, Backoff: 50 * time.Millisecond},
+	}, newTestNotifier())
+
+	if err := il.StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("StartAfterUpdate() error = %v", err)
+	}
+
+	status := waitRestartStatus(t, il, 10*time.Second, func(s RestartStatus) bool { return s.CrashCount == 1 })
+	time.Sleep(300 * time.Millisecond)
+	if status = il.RestartStatus(); status.RestartCount != 0 || status.NextRestartAt != nil || status.CrashLoop {
+		t.Errorf("status = %+v, want no restart after exit code 0 with on-failure", status)
+	}
+}
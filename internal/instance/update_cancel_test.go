@@ -0,0 +1,145 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+func TestUpdateAll_CancelledBeforeStop(t *testing.T) {
+	statePath, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "inst1", Port: 18961, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18961), StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{})
+	if err := m.instances[0].StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("failed to start instance: %v", err)
+	}
+
+	job := NewUpdateJob("cancel-before-stop")
+	if err := job.RequestCancel(); err != nil {
+		t.Fatalf("RequestCancel() on queued job: %v", err)
+	}
+
+	result, err := m.UpdateAll(WithJob(context.Background(), job), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+	if !result.Cancelled || len(result.Stopped) != 0 {
+		t.Errorf("result cancelled=%v stopped=%v, want cancelled without stopping", result.Cancelled, result.Stopped)
+	}
+	if !m.instances[0].IsRunning() {
+		t.Error("instance stopped although the update was cancelled before the stop phase")
+	}
+	if _, statErr := os.Stat(logPath); statErr == nil {
+		t.Error("uv tool install ran although the update was cancelled")
+	}
+}
+
+func TestCancelUpdate_RestartsStoppedInstances(t *testing.T) {
+	statePath, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "inst1", Port: 18962, StartCommand: startOnVersionCommand(statePath, "0.1.4", 18962), StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{})
+	if err := m.instances[0].StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("failed to start instance: %v", err)
+	}
+
+	// 停止阶段中请求取消: 进入安装阶段被拒绝, 已停止的实例重新启动
+	job := NewUpdateJob("cancel-after-stop")
+	ctx := WithJob(context.Background(), job)
+	result := &UpdateResult{}
+	job.enter(JobStopping)
+	m.stopInstances(ctx, m.instances, result)
+	if err := job.RequestCancel(); err != nil {
+		t.Fatalf("RequestCancel() during stop phase: %v", err)
+	}
+	if job.enter(JobInstalling) {
+		t.Fatal("enter(installing) accepted after a cancel request")
+	}
+	m.cancelUpdate(ctx, m.instances, result)
+
+	if !result.Cancelled || len(result.Started) != 1 || !m.instances[0].IsRunning() {
+		t.Errorf("result cancelled=%v started=%v running=%v, want the stopped instance started again",
+			result.Cancelled, result.Started, m.instances[0].IsRunning())
+	}
+	if got := job.Snapshot().Instances; len(got) != 1 || got[0].Status != ProgressRunning {
+		t.Errorf("instance progress = %+v, want inst1 running", got)
+	}
+	if _, statErr := os.Stat(logPath); statErr == nil {
+		t.Error("uv tool install ran although the update was cancelled")
+	}
+}
+
+func TestCancelUpdate_CoalescedRequestFollowsStartedRun(t *testing.T) {
+	_, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+	t.Setenv("FAKE_UV_INSTALL_DELAY", "1")
+
+	m := newPyPIOnlyManager(t, "0.2.0", nil)
+	// 模拟正在进行的更新, 两个请求合并为一次后续运行
+	if !m.TryLockUpdate() {
+		t.Fatal("TryLockUpdate failed on an idle manager")
+	}
+	jobs := []*UpdateJob{NewUpdateJob("req-1"), NewUpdateJob("req-2")}
+	calls := []<-chan queuedCall{
+		triggerInBackground(t, m, WithJob(context.Background(), jobs[0]), updater.Target{}, 1),
+		triggerInBackground(t, m, WithJob(context.Background(), jobs[1]), updater.Target{}, 2),
+	}
+	m.UnlockUpdate()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(logPath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("install did not start")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// 合并的更新已开始安装: 执行它的请求和合并到它的请求都不能再取消
+	for _, job := range jobs {
+		if err := job.RequestCancel(); !errors.Is(err, ErrJobNotCancellable) {
+			t.Errorf("RequestCancel(%s) during the shared run = %v, want ErrJobNotCancellable", job.ID, err)
+		}
+	}
+	for i, ch := range calls {
+		call := waitQueuedCall(t, ch)
+		if call.err != nil || call.result == nil || call.result.Cancelled || call.result.InstallSpec == "" {
+			t.Errorf("request %d returned %+v, %v; want the result of the shared run", i+1, call.result, call.err)
+		}
+	}
+}
+
+func TestTriggerUpdate_TimeoutStartsAfterQueueWait(t *testing.T) {
+	setupFakeUv(t, "0.1.4", "0.2.0")
+	m := newPyPIOnlyManager(t, "0.2.0", nil)
+	if !m.TryLockUpdate() {
+		t.Fatal("TryLockUpdate failed on an idle manager")
+	}
+
+	// 排队等待比超时更久: 超时从轮到执行时开始计算, 更新照常完成
+	ctx := WithUpdateTimeout(WithUpdateID(context.Background(), "req-1"), time.Second)
+	queued := triggerInBackground(t, m, ctx, updater.Target{}, 1)
+	time.Sleep(1500 * time.Millisecond)
+	m.UnlockUpdate()
+	if call := waitQueuedCall(t, queued); call.err != nil || call.result == nil || call.result.Skipped {
+		t.Fatalf("queued update returned %+v, %v; want an installed update", call.result, call.err)
+	}
+
+	// 执行本身超过超时时仍然中止
+	t.Setenv("FAKE_UV_INSTALL_DELAY", "2")
+	_, err := m.TriggerUpdate(WithUpdateTimeout(context.Background(), time.Second), updater.Target{}, true)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow update returned %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,185 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// newPyPIOnlyManager creates a manager that installs from PyPI and checks versions against a
+// fake PyPI JSON API reporting latestVersion
+func newPyPIOnlyManager(t *testing.T, latestVersion string, instances []config.InstanceConfig) *InstanceManager {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pypi/nanobot-ai/json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"info":{"version":%q}}`, latestVersion)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		Instances: instances,
+		Updater: config.UpdaterConfig{
+			Sources: []config.UpdateSourceConfig{{Name: "pypi", Type: "pypi"}},
+		},
+	}
+	m := newUpdateTestManager(t, cfg)
+	m.pypiJSONURL = server.URL + "/pypi"
+	return m
+}
+
+func TestUpdateAll_SkipsWhenCurrent(t *testing.T) {
+	_, logPath := setupFakeUv(t, "0.2.0", "0.2.0")
+
+	m := newPyPIOnlyManager(t, "0.2.0", []config.InstanceConfig{
+		{Name: "inst1", Port: 18911, StartCommand: "sh -c \"exec sleep 30 # --port 18911\"", StartupTimeout: 5 * time.Second},
+	})
+	if err := m.instances[0].StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("failed to start instance: %v", err)
+	}
+	pid := m.instances[0].pid
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, false)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if !result.Skipped || result.SkipReason == "" {
+		t.Fatalf("result = %+v, want skipped with reason", result)
+	}
+	if len(result.Stopped) != 0 || len(result.Started) != 0 {
+		t.Errorf("skipped update must not stop/start instances: stopped=%v started=%v", result.Stopped, result.Started)
+	}
+	if !m.instances[0].IsRunning() || m.instances[0].pid != pid {
+		t.Error("instance should keep running with the same PID")
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("uv install must not run when nanobot is current (install log: %v)", readInstallLog(t, logPath))
+	}
+}
+
+func TestUpdateAll_ForceReinstallsWhenCurrent(t *testing.T) {
+	_, logPath := setupFakeUv(t, "0.2.0", "0.2.0")
+
+	m := newPyPIOnlyManager(t, "0.2.0", []config.InstanceConfig{
+		{Name: "inst1", Port: 18912, StartCommand: "sh -c \"exec sleep 30 # --port 18912\"", StartupTimeout: 5 * time.Second},
+	})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if result.Skipped {
+		t.Error("force must not skip the update")
+	}
+	if installs := readInstallLog(t, logPath); len(installs) != 1 {
+		t.Errorf("uv installs = %v, want one install", installs)
+	}
+}
+
+func TestUpdateAll_UpdatesWhenNewVersionAvailable(t *testing.T) {
+	_, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newPyPIOnlyManager(t, "0.2.0", []config.InstanceConfig{
+		{Name: "inst1", Port: 18913, StartCommand: "sh -c \"exec sleep 30 # --port 18913\"", StartupTimeout: 5 * time.Second},
+	})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, false)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	if result.Skipped {
+		t.Fatalf("update skipped although 0.2.0 is newer than 0.1.4: %s", result.SkipReason)
+	}
+	if len(result.Started) != 1 {
+		t.Errorf("Started = %v, want [inst1]", result.Started)
+	}
+	if installs := readInstallLog(t, logPath); len(installs) != 1 || installs[0] != "nanobot-ai" {
+		t.Errorf("uv installs = %v, want [nanobot-ai]", installs)
+	}
+}
+
+func TestPlan_ReportsWithoutStoppingOrInstalling(t *testing.T) {
+	_, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newPyPIOnlyManager(t, "0.2.0", []config.InstanceConfig{
+		{Name: "inst1", Port: 18914, StartCommand: "sh -c \"exec sleep 30 # --port 18914\"", StartupTimeout: 5 * time.Second},
+		{Name: "inst2", Port: 18915, StartCommand: "sh -c \"exec sleep 30 # --port 18915\"", StartupTimeout: 5 * time.Second},
+	})
+	if err := m.instances[0].StartAfterUpdate(context.Background()); err != nil {
+		t.Fatalf("failed to start instance: %v", err)
+	}
+	pid := m.instances[0].pid
+
+	result, err := m.Plan(context.Background(), updater.Target{}, false)
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+
+	if !result.DryRun || len(result.Preflight) != 0 {
+		t.Fatalf("result = %+v, want dry run without preflight problems", result)
+	}
+	if result.Source != "pypi" || result.InstallSpec != "nanobot-ai" {
+		t.Errorf("Source/InstallSpec = %q/%q, want pypi/nanobot-ai", result.Source, result.InstallSpec)
+	}
+	if result.PreviousVersion != "0.1.4" || result.Check == nil || !result.Check.UpdateAvailable {
+		t.Errorf("PreviousVersion = %q, Check = %+v, want 0.1.4 with update available", result.PreviousVersion, result.Check)
+	}
+	if len(result.Stopped) != 1 || result.Stopped[0] != "inst1" {
+		t.Errorf("Stopped = %v, want [inst1]", result.Stopped)
+	}
+	if len(result.Started) != 2 {
+		t.Errorf("Started = %v, want both instances", result.Started)
+	}
+	if len(result.Instances) != 2 || result.Instances[0].PID != pid {
+		t.Errorf("Instances = %+v, want inst1 with PID %d", result.Instances, pid)
+	}
+
+	if !m.instances[0].IsRunning() || m.instances[0].pid != pid {
+		t.Error("plan must not stop running instances")
+	}
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("plan must not run uv install (install log: %v)", readInstallLog(t, logPath))
+	}
+}
+
+func TestPlan_PreflightProblems(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	m := newPyPIOnlyManager(t, "0.2.0", []config.InstanceConfig{
+		{Name: "inst1", Port: 18916, StartCommand: "sh -c \"exec sleep 30 # --port 18916\""},
+	})
+	if !m.TryLockUpdate() {
+		t.Fatal("failed to take update lock")
+	}
+	defer m.UnlockUpdate()
+
+	result, err := m.Plan(context.Background(), updater.Target{}, false)
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+
+	if len(result.Preflight) != 2 {
+		t.Fatalf("Preflight = %v, want update in progress and missing uv", result.Preflight)
+	}
+	if result.Check != nil || result.Skipped {
+		t.Errorf("without uv the installed version is unknown: Check = %+v, Skipped = %v", result.Check, result.Skipped)
+	}
+	if len(result.Stopped) != 0 || len(result.Started) != 1 {
+		t.Errorf("Stopped = %v, Started = %v, want nothing to stop and inst1 to start", result.Stopped, result.Started)
+	}
+}
@@ -0,0 +1,71 @@
+package instance
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+// UpdaterLogName is the log stream of uv/git command output, viewable like an instance log:
+// GET /api/v1/logs/_updater/stream and /logs/_updater
+const UpdaterLogName = "_updater"
+
+// updateOutput receives uv/git output from every Updater created by the InstanceManager
+// and writes each line to the _updater LogBuffer.
+type updateOutput struct {
+	buffer *logbuffer.LogBuffer
+}
+
+func newUpdateOutput(buffer *logbuffer.LogBuffer) *updateOutput {
+	return &updateOutput{buffer: buffer}
+}
+
+// Write implements updater.OutputWriter
+func (o *updateOutput) Write(entry logbuffer.LogEntry) error {
+	return o.buffer.Write(entry)
+}
+
+// updateRun collects the output of one update, persisted with the update log (UpdateResult.Output),
+// and forwards each line to the _updater log. Only the Updaters and hooks of that update write to it:
+// checks and plans running at the same time go to the _updater log only.
+type updateRun struct {
+	log *updateOutput
+	mu  sync.Mutex
+	out strings.Builder
+}
+
+// Write implements updater.OutputWriter
+func (r *updateRun) Write(entry logbuffer.LogEntry) error {
+	r.mu.Lock()
+	r.out.WriteString(entry.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z"))
+	r.out.WriteString(" [" + entry.Source + "] ")
+	r.out.WriteString(entry.Content)
+	r.out.WriteByte('\n')
+	r.mu.Unlock()
+	return r.log.Write(entry)
+}
+
+// String returns the output collected so far
+func (r *updateRun) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.out.String()
+}
+
+type updateRunContextKey struct{}
+
+// withUpdateRun 返回携带更新输出收集器的 context, 更新中创建的 Updater 和 hooks 写入该收集器
+func withUpdateRun(ctx context.Context, run *updateRun) context.Context {
+	return context.WithValue(ctx, updateRunContextKey{}, run)
+}
+
+// outputFor 返回代表 ctx 执行的命令的输出目标: 正在执行的更新的收集器, 不属于更新时只写入 _updater 日志
+func (m *InstanceManager) outputFor(ctx context.Context) updater.OutputWriter {
+	if run, ok := ctx.Value(updateRunContextKey{}).(*updateRun); ok {
+		return run
+	}
+	return m.output
+}
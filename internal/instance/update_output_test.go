@@ -0,0 +1,113 @@
+//go:build !windows
+
+package instance
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+func TestUpdateAll_StreamsUpdaterOutput(t *testing.T) {
+	statePath, _ := setupFakeUv(t, "0.1.4", "0.2.0")
+
+	m := newRollbackTestManager(t, []config.InstanceConfig{
+		{Name: "inst1", Port: 18951, StartCommand: startOnVersionCommand(statePath, "0.2.0", 18951), StartupTimeout: 5 * time.Second},
+	}, config.RollbackConfig{Enabled: true, FailureThreshold: 1})
+
+	result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+	if err != nil {
+		t.Fatalf("UpdateAll returned error: %v", err)
+	}
+
+	// 完整输出随 UpdateResult 返回, 由调用方按 update_id 保存
+	if !strings.Contains(result.Output, "[stdout] $ uv tool install --force ") {
+		t.Errorf("result.Output = %q, want the uv tool install command", result.Output)
+	}
+
+	buf, err := m.GetLogBuffer(UpdaterLogName)
+	if err != nil {
+		t.Fatalf("GetLogBuffer(%s) returned error: %v", UpdaterLogName, err)
+	}
+	var commands []string
+	for _, entry := range buf.GetHistory() {
+		if strings.HasPrefix(entry.Content, "$ ") {
+			commands = append(commands, entry.Content)
+		}
+	}
+	if !containsPrefix(commands, "$ uv tool install --force ") {
+		t.Errorf("_updater log commands = %v, want uv tool install", commands)
+	}
+}
+
+func TestUpdateAll_OutputExcludesConcurrentCheck(t *testing.T) {
+	_, logPath := setupFakeUv(t, "0.1.4", "0.2.0")
+	t.Setenv("FAKE_UV_INSTALL_DELAY", "1")
+
+	m := newPyPIOnlyManager(t, "0.2.0", []config.InstanceConfig{
+		{Name: "inst1", Port: 18952, StartCommand: "sh -c \"exec sleep 30 # --port 18952\"", StartupTimeout: 5 * time.Second},
+	})
+
+	type updateReturn struct {
+		result *UpdateResult
+		err    error
+	}
+	done := make(chan updateReturn, 1)
+	go func() {
+		result, err := m.UpdateAll(context.Background(), updater.Target{}, true)
+		done <- updateReturn{result, err}
+	}()
+
+	// 安装进行中时检查更新 (如 GET /api/v1/nanobot/check), 检查会运行 uv tool dir
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(logPath); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("install did not start")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if _, err := m.CheckUpdate(context.Background(), updater.Target{}); err != nil {
+		t.Fatalf("CheckUpdate returned error: %v", err)
+	}
+
+	ret := <-done
+	if ret.err != nil {
+		t.Fatalf("UpdateAll returned error: %v", ret.err)
+	}
+	if !strings.Contains(ret.result.Output, "$ uv tool install --force ") {
+		t.Errorf("result.Output = %q, want the uv tool install command", ret.result.Output)
+	}
+	if strings.Contains(ret.result.Output, "$ uv tool dir") {
+		t.Errorf("result.Output = %q, contains the output of the concurrent check", ret.result.Output)
+	}
+
+	// 检查的输出仍写入 _updater 日志流
+	buf, err := m.GetLogBuffer(UpdaterLogName)
+	if err != nil {
+		t.Fatalf("GetLogBuffer(%s) returned error: %v", UpdaterLogName, err)
+	}
+	var commands []string
+	for _, entry := range buf.GetHistory() {
+		commands = append(commands, entry.Content)
+	}
+	if !containsPrefix(commands, "$ uv tool dir") {
+		t.Errorf("_updater log = %v, want the uv tool dir command of the check", commands)
+	}
+}
+
+func containsPrefix(lines []string, prefix string) bool {
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -79,6 +79,7 @@ type NotifySender interface {
 // because they all directly or indirectly import instance -> lifecycle.
 type AppComponents struct {
 	// Components with Stop/Close methods (shutdown order matters)
+	UpdateScheduler     Stoppable // *scheduler.UpdateScheduler, set by main.go after creation
 	NotificationManager *notification.NotificationManager
 	NetworkMonitor      *network.NetworkMonitor
 	HealthMonitor       HealthMonitorControl
@@ -98,12 +99,16 @@ type AppComponents struct {
 
 // AppShutdown performs ordered shutdown of all non-nil components (D-05, D-07).
 // Components are shut down in the same order as the original main.go:
-// notificationManager -> networkMonitor -> healthMonitor -> cleanupCron -> updateLogger -> apiServer.
+// updateScheduler -> notificationManager -> networkMonitor -> healthMonitor -> cleanupCron -> updateLogger -> apiServer.
+// The update scheduler is stopped first so no scheduled update starts during shutdown.
 // Internal dependencies (Notifier, InstanceManager, SelfUpdater) do not have Stop/Close methods.
 func AppShutdown(ctx context.Context, c *AppComponents, logger *slog.Logger) {
 	if c == nil {
 		return
 	}
+	if c.UpdateScheduler != nil {
+		c.UpdateScheduler.Stop()
+	}
 	if c.NotificationManager != nil {
 		c.NotificationManager.Stop()
 	}
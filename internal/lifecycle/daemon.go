@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-
-	"golang.org/x/sys/windows"
 )
 
 // MakeDaemon restarts the current process as an independent daemon
@@ -69,10 +67,7 @@ func restartAsDaemon() (bool, error) {
 
 	// Create new independent process
 	cmd := exec.Command(exePath, args...)
-	cmd.SysProcAttr = &windows.SysProcAttr{
-		HideWindow:    true,
-		CreationFlags: windows.CREATE_NO_WINDOW | windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS,
-	}
+	SetDetached(cmd)
 
 	// Set environment variable to mark as daemon
 	cmd.Env = append(os.Environ(), "NANOBOT_UPDATER_DAEMON=1")
@@ -1,5 +1,3 @@
-//go:build windows
-
 package lifecycle
 
 import (
@@ -16,22 +14,35 @@ import (
 func FindPIDByPort(port uint32, logger *slog.Logger) (int32, error) {
 	logger.Debug("Checking port for nanobot process", "port", port)
 
-	connections, err := net.Connections("tcp")
+	pid, err := listeningPID(port)
 	if err != nil {
 		logger.Error("Failed to get network connections", "error", err)
+		return 0, err
+	}
+	if pid > 0 {
+		logger.Info("Found nanobot by port", "pid", pid, "port", port)
+		return pid, nil
+	}
+
+	logger.Debug("No process found listening on port", "port", port)
+	return 0, nil // No process found, not an error
+}
+
+// listeningPID returns the PID of the process listening on the TCP port, or 0 if none is.
+// Used by FindPIDByPort and, without its logging, by the TCP readiness probe.
+func listeningPID(port uint32) (int32, error) {
+	connections, err := net.Connections("tcp")
+	if err != nil {
 		return 0, fmt.Errorf("failed to get network connections: %w", err)
 	}
 
 	for _, conn := range connections {
 		// Check if connection is listening on the specified port
 		if conn.Status == "LISTEN" && conn.Laddr.Port == port {
-			logger.Info("Found nanobot by port", "pid", conn.Pid, "port", port)
 			return conn.Pid, nil
 		}
 	}
-
-	logger.Debug("No process found listening on port", "port", port)
-	return 0, nil // No process found, not an error
+	return 0, nil
 }
 
 // FindPIDByProcessName returns the PID of the process with the specified name.
@@ -0,0 +1,289 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
+)
+
+// readinessLogLines is the number of log lines attached to a ReadinessError
+const readinessLogLines = 50
+
+// Names of the readiness probes, reported in ReadinessError.Pending
+const (
+	ProbeTCP  = "tcp"
+	ProbeHTTP = "http"
+	ProbeLog  = "log"
+)
+
+// ErrReadinessCancelled is returned (wrapped) by StartNanobotWithOptions when ctx is cancelled while
+// waiting for readiness. The process is not stopped: its PID is returned together with the error.
+var ErrReadinessCancelled = errors.New("start cancelled while waiting for readiness")
+
+// ReadinessProbe are the readiness checks of StartNanobotWithOptions: the start succeeds once
+// every configured check has passed, see config.ReadinessConfig.
+type ReadinessProbe struct {
+	TCP        bool           // the port is listening and held by the started process (or one of its children)
+	HTTPURL    string         // GET returns 2xx or 3xx
+	LogPattern *regexp.Regexp // a line of the process output matches
+	Interval   time.Duration
+}
+
+// NewReadinessProbe builds the readiness probe of an instance on port.
+// Returns nil if rc configures no probe.
+func NewReadinessProbe(rc config.ReadinessConfig, port uint32) (*ReadinessProbe, error) {
+	if !rc.Enabled() {
+		return nil, nil
+	}
+	probe := &ReadinessProbe{
+		TCP:      rc.TCP,
+		HTTPURL:  rc.HTTPURL(port),
+		Interval: rc.EffectiveInterval(),
+	}
+	if rc.LogPattern != "" {
+		re, err := regexp.Compile(rc.LogPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid readiness log_pattern: %w", err)
+		}
+		probe.LogPattern = re
+	}
+	return probe, nil
+}
+
+// ReadinessError is returned by StartNanobotWithOptions when the process did not become ready
+// within the startup timeout or exited while waiting. The process has been stopped.
+type ReadinessError struct {
+	PID     int
+	Timeout time.Duration
+	Pending []string // probes that did not pass
+	Reason  string   // last failure of the pending probes, or the exit of the process
+	Exited  bool     // the process exited before it was ready
+	Logs    []string // last log lines of the process
+}
+
+func (e *ReadinessError) Error() string {
+	var msg string
+	if e.Exited {
+		msg = fmt.Sprintf("process exited before it was ready (PID %d): %s", e.PID, e.Reason)
+	} else {
+		msg = fmt.Sprintf("process not ready within %v (PID %d), waiting for %s: %s",
+			e.Timeout, e.PID, strings.Join(e.Pending, ", "), e.Reason)
+	}
+	if len(e.Logs) > 0 {
+		msg += fmt.Sprintf("\nlast %d log lines:\n%s", len(e.Logs), strings.Join(e.Logs, "\n"))
+	}
+	return msg
+}
+
+// readinessCheck is one pending check of a probe
+type readinessCheck struct {
+	name  string
+	check func() error
+}
+
+// waitReady polls the probe until all checks passed, the process exits or timeout elapses.
+// On failure the process is stopped and a *ReadinessError with its last log lines is returned.
+// When ctx is cancelled the process keeps running and ErrReadinessCancelled is returned.
+func waitReady(
+	ctx context.Context,
+	probe *ReadinessProbe,
+	pid int,
+	port uint32,
+	timeout time.Duration,
+	logger *slog.Logger,
+	logBuffer *logbuffer.LogBuffer,
+	fromSeq int64,
+	exited <-chan error,
+) error {
+	checks := probe.checks(pid, port, logBuffer, fromSeq)
+	interval := probe.Interval
+	if interval <= 0 {
+		interval = config.DefaultReadinessInterval
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reason := "no check run yet"
+	for {
+		// 已通过的检查不再重复
+		pending := checks[:0]
+		for _, c := range checks {
+			if err := c.check(); err != nil {
+				reason = fmt.Sprintf("%s: %v", c.name, err)
+				pending = append(pending, c)
+				continue
+			}
+			logger.Debug("Readiness check passed", "pid", pid, "check", c.name)
+		}
+		checks = pending
+		if len(checks) == 0 {
+			logger.Info("Nanobot process is ready", "pid", pid)
+			return nil
+		}
+
+		select {
+		case waitErr := <-exited:
+			reason := "exited with code 0"
+			if waitErr != nil {
+				reason = waitErr.Error()
+			}
+			// 等待输出读取完毕, 附带的日志包含退出前的最后几行
+			time.Sleep(100 * time.Millisecond)
+			logger.Error("Process exited before it was ready", "pid", pid, "error", waitErr)
+			return &ReadinessError{PID: pid, Timeout: timeout, Pending: checkNames(checks), Reason: reason,
+				Exited: true, Logs: lastLogLines(logBuffer, fromSeq, readinessLogLines)}
+		case <-ctx.Done():
+			// 调用方不再等待 (如 HTTP 客户端断开), 进程本身没有失败, 不停止它
+			logger.Warn("Start cancelled while waiting for readiness, keeping the process", "pid", pid, "error", ctx.Err())
+			return fmt.Errorf("%w (PID %d): %w", ErrReadinessCancelled, pid, ctx.Err())
+		case <-deadline.C:
+			logger.Error("Process not ready within startup timeout, stopping it", "pid", pid, "timeout", timeout, "reason", reason)
+			stopUnready(pid, exited, logger)
+			return &ReadinessError{PID: pid, Timeout: timeout, Pending: checkNames(checks), Reason: reason,
+				Logs: lastLogLines(logBuffer, fromSeq, readinessLogLines)}
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopUnready stops a process that failed its readiness checks and waits until it is reaped
+func stopUnready(pid int, exited <-chan error, logger *slog.Logger) {
+	if err := StopNanobot(context.Background(), int32(pid), 5*time.Second, logger); err != nil {
+		logger.Error("Failed to stop the unready process", "pid", pid, "error", err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+	}
+}
+
+// checks returns the configured checks of the probe
+func (p *ReadinessProbe) checks(pid int, port uint32, logBuffer *logbuffer.LogBuffer, fromSeq int64) []readinessCheck {
+	var checks []readinessCheck
+	if p.TCP {
+		checks = append(checks, readinessCheck{ProbeTCP, func() error { return checkListening(pid, port) }})
+	}
+	if p.HTTPURL != "" {
+		client := &http.Client{
+			Timeout: 2 * time.Second,
+			// 3xx 也说明网关已在响应
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		checks = append(checks, readinessCheck{ProbeHTTP, func() error { return checkHTTP(client, p.HTTPURL) }})
+	}
+	if p.LogPattern != nil {
+		scanner := &logScanner{buffer: logBuffer, next: fromSeq, pattern: p.LogPattern, partial: map[string]string{}}
+		checks = append(checks, readinessCheck{ProbeLog, scanner.check})
+	}
+	return checks
+}
+
+func checkNames(checks []readinessCheck) []string {
+	names := make([]string, len(checks))
+	for i, c := range checks {
+		names[i] = c.name
+	}
+	return names
+}
+
+// checkListening checks that port is listening and held by pid or one of its descendants
+// (start commands like "sh -c" or a launcher script fork the actual gateway)
+func checkListening(pid int, port uint32) error {
+	listener, err := listeningPID(port)
+	if err != nil {
+		return err
+	}
+	if listener == 0 {
+		return fmt.Errorf("port %d is not listening", port)
+	}
+	if !isDescendant(listener, int32(pid)) {
+		return fmt.Errorf("port %d is held by another process (PID %d)", port, listener)
+	}
+	return nil
+}
+
+// isDescendant reports whether pid is ancestor or one of its descendants
+func isDescendant(pid, ancestor int32) bool {
+	for depth := 0; depth < 32 && pid > 1; depth++ {
+		if pid == ancestor {
+			return true
+		}
+		proc, err := process.NewProcess(pid)
+		if err != nil {
+			return false
+		}
+		if pid, err = proc.Ppid(); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+func checkHTTP(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// logScanner matches the pattern against the lines written to the LogBuffer since next.
+// Output is captured in chunks, so a line may span several entries of the same source.
+type logScanner struct {
+	buffer  *logbuffer.LogBuffer
+	next    int64
+	pattern *regexp.Regexp
+	partial map[string]string // 每个 source 尚未结束的行
+}
+
+func (s *logScanner) check() error {
+	to := s.buffer.NextSeq()
+	entries, _ := s.buffer.Range(s.next, to)
+	s.next = to
+	for _, entry := range entries {
+		lines := strings.Split(s.partial[entry.Source]+entry.Content, "\n")
+		s.partial[entry.Source] = lines[len(lines)-1]
+		for _, line := range lines {
+			if s.pattern.MatchString(strings.TrimRight(line, "\r")) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no log line matches %q", s.pattern.String())
+}
+
+// lastLogLines returns up to n last non-empty lines written to the LogBuffer since fromSeq
+func lastLogLines(logBuffer *logbuffer.LogBuffer, fromSeq int64, n int) []string {
+	entries, _ := logBuffer.Range(fromSeq, logBuffer.NextSeq())
+	var text strings.Builder
+	for _, entry := range entries {
+		text.WriteString(entry.Content)
+	}
+	var lines []string
+	for _, line := range strings.Split(text.String(), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
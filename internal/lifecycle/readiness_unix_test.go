@@ -0,0 +1,187 @@
+//go:build !windows
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
+)
+
+// readinessListenEnv makes the test binary a helper process that listens on the given port
+const readinessListenEnv = "READINESS_TEST_LISTEN_PORT"
+
+// TestReadinessHelperProcess is not a real test: started by the TCP readiness tests, it listens
+// on $READINESS_TEST_LISTEN_PORT after a short delay, like a gateway that takes a while to start.
+func TestReadinessHelperProcess(t *testing.T) {
+	port := os.Getenv(readinessListenEnv)
+	if port == "" {
+		t.Skip("helper process for the TCP readiness tests")
+	}
+	time.Sleep(500 * time.Millisecond)
+	ln, err := net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		os.Exit(1)
+	}
+	defer ln.Close()
+	time.Sleep(30 * time.Second)
+	os.Exit(0)
+}
+
+// startWithReadiness starts command with probe and stops the process at the end of the test
+func startWithReadiness(t *testing.T, command string, port uint32, timeout time.Duration, probe *ReadinessProbe, env ...string) (int, error) {
+	t.Helper()
+	logger := stopperTestLogger()
+	pid, err := StartNanobotWithOptions(context.Background(), command, port, timeout, logger, logbuffer.NewLogBuffer(logger),
+		StartOptions{Env: env, Readiness: probe})
+	if err == nil {
+		t.Cleanup(func() { StopNanobot(context.Background(), int32(pid), 5*time.Second, logger) })
+	}
+	return pid, err
+}
+
+func TestStartNanobot_ReadinessLogPattern(t *testing.T) {
+	probe := &ReadinessProbe{LogPattern: regexp.MustCompile(`Telegram bot commands registered`), Interval: 100 * time.Millisecond}
+	command := `sh -c "echo starting; sleep 1; printf 'Telegram bot '; sleep 0.3; echo 'commands registered'; exec sleep 30 # --port 19001"`
+
+	start := time.Now()
+	pid, err := startWithReadiness(t, command, 19001, 10*time.Second, probe)
+	if err != nil {
+		t.Fatalf("StartNanobotWithOptions() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("start returned after %v, before the log line was written", elapsed)
+	}
+	if !processAlive(int32(pid)) {
+		t.Error("process is not running after a successful start")
+	}
+}
+
+func TestStartNanobot_ReadinessTimeoutAttachesLogs(t *testing.T) {
+	probe := &ReadinessProbe{LogPattern: regexp.MustCompile(`ready`), Interval: 100 * time.Millisecond}
+	command := `sh -c "i=1; while [ $i -le 60 ]; do echo line $i; i=$((i+1)); done; exec sleep 30 # --port 19002"`
+
+	_, err := startWithReadiness(t, command, 19002, time.Second, probe)
+	var readyErr *ReadinessError
+	if !errors.As(err, &readyErr) {
+		t.Fatalf("StartNanobotWithOptions() error = %v, want a *ReadinessError", err)
+	}
+	if readyErr.Exited || !reflect.DeepEqual(readyErr.Pending, []string{ProbeLog}) {
+		t.Errorf("error = %+v, want a timeout waiting for the log probe", readyErr)
+	}
+	if len(readyErr.Logs) != readinessLogLines || readyErr.Logs[0] != "line 11" || readyErr.Logs[49] != "line 60" {
+		t.Errorf("logs = %q, want the last 50 lines", readyErr.Logs)
+	}
+	if !strings.Contains(err.Error(), "not ready within 1s") || !strings.Contains(err.Error(), "line 60") {
+		t.Errorf("error message = %q, want the timeout with the log lines", err.Error())
+	}
+	if processAlive(int32(readyErr.PID)) {
+		t.Error("unready process was not stopped")
+	}
+}
+
+func TestStartNanobot_ReadinessProcessExits(t *testing.T) {
+	probe := &ReadinessProbe{TCP: true, Interval: 100 * time.Millisecond}
+	command := `sh -c "echo config error >&2; sleep 0.5; exit 3 # --port 19003"`
+
+	_, err := startWithReadiness(t, command, 19003, 10*time.Second, probe)
+	var readyErr *ReadinessError
+	if !errors.As(err, &readyErr) || !readyErr.Exited {
+		t.Fatalf("StartNanobotWithOptions() error = %v, want an exit before readiness", err)
+	}
+	if !strings.Contains(readyErr.Reason, "exit status 3") || !reflect.DeepEqual(readyErr.Logs, []string{"config error"}) {
+		t.Errorf("error = %+v, want exit status 3 with the stderr line", readyErr)
+	}
+}
+
+func TestStartNanobot_ReadinessTCP(t *testing.T) {
+	probe := &ReadinessProbe{TCP: true, Interval: 100 * time.Millisecond}
+	command := fmt.Sprintf(`"%s" -test.run=^TestReadinessHelperProcess$ -- --port 19004`, os.Args[0])
+
+	pid, err := startWithReadiness(t, command, 19004, 10*time.Second, probe, readinessListenEnv+"=19004")
+	if err != nil {
+		t.Fatalf("StartNanobotWithOptions() error = %v", err)
+	}
+	if err := checkListening(pid, 19004); err != nil {
+		t.Errorf("checkListening() after start = %v", err)
+	}
+}
+
+func TestStartNanobot_ReadinessTCPOtherProcess(t *testing.T) {
+	// 端口被其他进程 (测试进程本身) 占用, 不算启动的实例就绪
+	ln, err := net.Listen("tcp", "127.0.0.1:19005")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	probe := &ReadinessProbe{TCP: true, Interval: 100 * time.Millisecond}
+	_, err = startWithReadiness(t, `sh -c "exec sleep 30 # --port 19005"`, 19005, time.Second, probe)
+	var readyErr *ReadinessError
+	if !errors.As(err, &readyErr) || !strings.Contains(readyErr.Reason, "held by another process") {
+		t.Fatalf("StartNanobotWithOptions() error = %v, want the port held by another process", err)
+	}
+}
+
+func TestStartNanobot_ReadinessHTTP(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	probe := &ReadinessProbe{HTTPURL: server.URL + "/health", Interval: 100 * time.Millisecond}
+	if _, err := startWithReadiness(t, `sh -c "exec sleep 30 # --port 19006"`, 19006, 10*time.Second, probe); err != nil {
+		t.Fatalf("StartNanobotWithOptions() error = %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("HTTP probe sent %d requests, want 3 (ready on the first 200)", n)
+	}
+}
+
+func TestStartNanobot_ReadinessCancelKeepsProcess(t *testing.T) {
+	// 调用方取消 (HTTP 客户端断开) 只结束等待, 不停止进程
+	probe := &ReadinessProbe{LogPattern: regexp.MustCompile(`ready`), Interval: 100 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	logger := stopperTestLogger()
+	exited := make(chan error, 1)
+	pid, err := StartNanobotWithOptions(ctx, `sh -c "exec sleep 30 # --port 19011"`, 19011, 10*time.Second, logger,
+		logbuffer.NewLogBuffer(logger), StartOptions{Readiness: probe, OnExit: func(err error) { exited <- err }})
+	if pid != 0 {
+		t.Cleanup(func() { StopNanobot(context.Background(), int32(pid), 5*time.Second, logger) })
+	}
+	if !errors.Is(err, ErrReadinessCancelled) || pid == 0 {
+		t.Fatalf("StartNanobotWithOptions() = %d, %v, want the PID with ErrReadinessCancelled", pid, err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if !processAlive(int32(pid)) {
+		t.Fatal("process was stopped after the start was cancelled")
+	}
+
+	// 进程仍被监视, 退出时调用 OnExit
+	StopNanobot(context.Background(), int32(pid), 5*time.Second, logger)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Error("OnExit was not called when the process exited")
+	}
+}
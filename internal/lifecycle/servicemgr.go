@@ -4,6 +4,7 @@ package lifecycle
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/config"
@@ -25,6 +26,10 @@ func IsAdmin() bool {
 // RegisterService is a no-op on non-Windows platforms.
 // Logs a message indicating service registration is not supported.
 func RegisterService(cfg *config.Config, logger *slog.Logger) error {
+	// Defensive check: empty ServiceName (T-48-06), same as the Windows implementation
+	if cfg.Service.ServiceName == "" {
+		return fmt.Errorf("registerService: service_name is empty, cannot register service")
+	}
 	logger.Info("Service registration is not supported on this platform, auto_start configuration ignored")
 	return nil
 }
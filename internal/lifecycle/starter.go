@@ -1,23 +1,38 @@
-//go:build windows
-
 package lifecycle
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/process"
-	"golang.org/x/sys/windows"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
 )
 
+// StartOptions holds optional process settings for StartNanobotWithOptions
+type StartOptions struct {
+	Env    []string // Extra environment variables ("KEY=VALUE"), override the service environment
+	BinDir string   // Searched before PATH for a bare executable name (isolated nanobot install)
+	Dir    string   // Working directory of the process, "" = working directory of the service
+	Home   string   // Own HOME directory of the process (created if missing), "" = HOME of the service
+
+	// Readiness are the readiness checks awaited for up to the startup timeout.
+	// nil keeps the plain check that the process is still alive after 2 seconds.
+	Readiness *ReadinessProbe
+
+	// OnExit is called with the cmd.Wait() error when a successfully started process exits
+	// (not when the start itself fails). Used by the crash supervisor of the instance.
+	OnExit func(err error)
+}
+
 // StartNanobotWithCapture starts nanobot with log capture.
 // Returns the process ID on success.
 func StartNanobotWithCapture(
@@ -27,6 +42,22 @@ func StartNanobotWithCapture(
 	startupTimeout time.Duration,
 	logger *slog.Logger,
 	logBuffer *logbuffer.LogBuffer,
+) (int, error) {
+	return StartNanobotWithOptions(ctx, command, port, startupTimeout, logger, logBuffer, StartOptions{})
+}
+
+// StartNanobotWithOptions starts nanobot with log capture, an extended environment and an
+// optional executable directory. Returns the process ID on success.
+// When ctx is cancelled while waiting for readiness, the process keeps running and its PID is
+// returned with an error wrapping ErrReadinessCancelled.
+func StartNanobotWithOptions(
+	ctx context.Context,
+	command string,
+	port uint32,
+	startupTimeout time.Duration,
+	logger *slog.Logger,
+	logBuffer *logbuffer.LogBuffer,
+	opts StartOptions,
 ) (int, error) {
 	// Auto-append --port parameter if not already present in command
 	// This ensures nanobot uses the configured port without requiring manual configuration
@@ -42,7 +73,7 @@ func StartNanobotWithCapture(
 		return 0, fmt.Errorf("empty command")
 	}
 
-	executable := parts[0]
+	executable := resolveExecutable(parts[0], opts.BinDir)
 	args := parts[1:]
 
 	logger.Info("Starting nanobot", "command", finalCommand, "executable", executable, "args", strings.Join(args, " "), "port", port)
@@ -54,6 +85,12 @@ func StartNanobotWithCapture(
 	// The passed ctx is only used for startup timeout control in the caller.
 	detachedCtx := context.Background()
 
+	if opts.Home != "" {
+		if err := os.MkdirAll(opts.Home, 0755); err != nil {
+			return 0, fmt.Errorf("failed to create home directory: %w", err)
+		}
+	}
+
 	// Create pipes for stdout and stderr
 	stdoutReader, stdoutWriter, err := os.Pipe()
 	if err != nil {
@@ -70,15 +107,20 @@ func StartNanobotWithCapture(
 	// Prepare command with detached context
 	cmd := exec.CommandContext(detachedCtx, executable, args...)
 	cmd.Env = append(os.Environ(), "PYTHONIOENCODING=utf-8")
-	cmd.SysProcAttr = &windows.SysProcAttr{
-		HideWindow:    true,
-		CreationFlags: windows.CREATE_NO_WINDOW | windows.CREATE_NEW_PROCESS_GROUP,
+	cmd.Env = append(cmd.Env, opts.Env...)
+	if opts.Home != "" {
+		cmd.Env = append(cmd.Env, homeEnv(opts.Home)...)
 	}
+	cmd.Dir = opts.Dir
+	setNewProcessGroup(cmd)
 
 	// Set stdout and stderr
 	cmd.Stdout = stdoutWriter
 	cmd.Stderr = stderrWriter
 
+	// Output from here on belongs to this process (log lines of a ReadinessError)
+	fromSeq := logBuffer.NextSeq()
+
 	// Start process
 	if err := cmd.Start(); err != nil {
 		stdoutReader.Close()
@@ -97,46 +139,86 @@ func StartNanobotWithCapture(
 	stdoutWriter.Close()
 	stderrWriter.Close()
 
-	// Wait 2 seconds for process stabilization
-	time.Sleep(2 * time.Second)
+	// Start log capture goroutines right away, so startup output is captured with its real
+	// timestamps while the start is still in progress (also when the process exits immediately)
+	// Use detachedCtx to ensure log capture continues even if parent context is cancelled
+	go captureLogs(detachedCtx, stdoutReader, "stdout", logBuffer, logger)
+	go captureLogs(detachedCtx, stderrReader, "stderr", logBuffer, logger)
 
-	// Verify process is still running
-	proc, err := process.NewProcess(int32(pid))
-	if err != nil {
-		_ = cmd.Wait()
-		stdoutReader.Close()
-		stderrReader.Close()
-		logger.Error("Process exited immediately after start", "pid", pid)
-		return 0, fmt.Errorf("process exited immediately after start (PID %d)", pid)
-	}
+	// Reap the process as soon as it exits so an immediate crash is detected
+	// (on POSIX an unreaped child still shows up as a zombie process)
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
 
-	name, err := proc.Name()
-	if err != nil {
-		_ = cmd.Wait()
-		stdoutReader.Close()
-		stderrReader.Close()
-		logger.Error("Failed to verify process name", "pid", pid, "error", err)
-		return 0, fmt.Errorf("failed to verify process name (PID %d): %w", pid, err)
-	}
+	var readyErr error
+	if opts.Readiness != nil {
+		// Wait up to startupTimeout for the readiness checks
+		logger.Info("Waiting for nanobot to become ready", "pid", pid, "timeout", startupTimeout)
+		if err := waitReady(ctx, opts.Readiness, pid, port, startupTimeout, logger, logBuffer, fromSeq, exited); err != nil {
+			if !errors.Is(err, ErrReadinessCancelled) {
+				return 0, err
+			}
+			// The caller stopped waiting: the process keeps running and is monitored like a started one
+			readyErr = err
+		}
+	} else {
+		// Wait 2 seconds for process stabilization
+		select {
+		case waitErr := <-exited:
+			// The capture goroutines drain the remaining output and close the readers at EOF
+			logger.Error("Process exited immediately after start", "pid", pid, "error", waitErr)
+			return 0, fmt.Errorf("process exited immediately after start (PID %d): %v", pid, waitErr)
+		case <-time.After(2 * time.Second):
+		}
+
+		// Verify process is still running
+		proc, err := process.NewProcess(int32(pid))
+		if err != nil {
+			stdoutReader.Close()
+			stderrReader.Close()
+			logger.Error("Process exited immediately after start", "pid", pid)
+			return 0, fmt.Errorf("process exited immediately after start (PID %d)", pid)
+		}
 
-	logger.Info("Nanobot process verified", "pid", pid, "process_name", name)
+		name, err := proc.Name()
+		if err != nil {
+			stdoutReader.Close()
+			stderrReader.Close()
+			logger.Error("Failed to verify process name", "pid", pid, "error", err)
+			return 0, fmt.Errorf("failed to verify process name (PID %d): %w", pid, err)
+		}
 
-	// Start log capture goroutines
-	// Use detachedCtx to ensure log capture continues even if parent context is cancelled
-	go captureLogs(detachedCtx, stdoutReader, "stdout", logBuffer, logger)
-	go captureLogs(detachedCtx, stderrReader, "stderr", logBuffer, logger)
+		logger.Info("Nanobot process verified", "pid", pid, "process_name", name)
+	}
 
 	// Start monitor goroutine to handle process exit
 	go func() {
-		err := cmd.Wait()
+		err := <-exited
 		if err != nil {
 			logger.Warn("Process exited with error", "pid", pid, "error", err)
 		} else {
 			logger.Info("Process exited normally", "pid", pid)
 		}
+		if opts.OnExit != nil {
+			opts.OnExit(err)
+		}
 	}()
 
-	return pid, nil
+	return pid, readyErr
+}
+
+// resolveExecutable looks up a bare executable name in binDir before falling back to PATH,
+// so "nanobot gateway" runs the nanobot of the instance's own install
+func resolveExecutable(executable, binDir string) string {
+	if binDir == "" || strings.ContainsAny(executable, `/\"`) {
+		return executable
+	}
+	if path, err := exec.LookPath(filepath.Join(binDir, executable)); err == nil {
+		return path
+	}
+	return executable
 }
 
 // captureLogs reads from a reader and writes to LogBuffer
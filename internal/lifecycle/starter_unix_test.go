@@ -0,0 +1,40 @@
+//go:build !windows
+
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
+)
+
+func TestStartNanobot_WorkingDirAndHome(t *testing.T) {
+	// pwd -P 输出解析符号链接后的路径 (macOS 的 /var -> /private/var)
+	workDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := filepath.Join(t.TempDir(), "homes", "inst") // 不存在, 启动时创建
+
+	// 进程输出的工作目录和 HOME 与选项一致时才就绪
+	want := "dir=" + workDir + " home=" + home + " key=value"
+	probe := &ReadinessProbe{LogPattern: regexp.MustCompile("^" + regexp.QuoteMeta(want) + "$"), Interval: 100 * time.Millisecond}
+	command := `sh -c "echo dir=$(pwd -P) home=$HOME key=$INSTANCE_KEY; exec sleep 30 # --port 19008"`
+
+	logger := stopperTestLogger()
+	pid, err := StartNanobotWithOptions(context.Background(), command, 19008, 5*time.Second, logger, logbuffer.NewLogBuffer(logger),
+		StartOptions{Env: []string{"INSTANCE_KEY=value"}, Dir: workDir, Home: home, Readiness: probe})
+	if err != nil {
+		t.Fatalf("StartNanobotWithOptions() error = %v", err)
+	}
+	t.Cleanup(func() { StopNanobot(context.Background(), int32(pid), 5*time.Second, logger) })
+
+	if info, err := os.Stat(home); err != nil || !info.IsDir() {
+		t.Errorf("home directory %s was not created: %v", home, err)
+	}
+}
@@ -0,0 +1,156 @@
+//go:build !windows
+
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// nanobotProcessName is the executable name installed by `uv tool install`.
+const nanobotProcessName = "nanobot"
+
+// StopNanobot gracefully stops nanobot process, force-killing after timeout.
+// Sends SIGTERM to the process group, waits up to timeout for exit, then sends SIGKILL.
+// timeout is the maximum time to wait for graceful shutdown before force kill.
+// Returns error if stop fails completely.
+func StopNanobot(ctx context.Context, pid int32, timeout time.Duration, logger *slog.Logger) error {
+	if pid <= 0 {
+		logger.Debug("No PID provided, nothing to stop")
+		return nil // Nothing to stop
+	}
+
+	logger.Info("Stopping nanobot", "pid", pid, "timeout", timeout)
+
+	// Step 1: Try graceful termination (SIGTERM)
+	logger.Info("Attempting graceful termination", "pid", pid)
+	err := signalProcessGroup(pid, syscall.SIGTERM)
+	if errors.Is(err, syscall.ESRCH) {
+		logger.Info("Process already exited", "pid", pid)
+		return nil
+	}
+	if err == nil {
+		logger.Debug("SIGTERM sent, waiting for process exit", "pid", pid)
+		gracefulCtx, cancel := context.WithTimeout(ctx, timeout)
+		exited := waitForProcessExit(gracefulCtx, pid, 100*time.Millisecond, logger)
+		cancel()
+		if exited {
+			logger.Info("Nanobot stopped gracefully", "pid", pid)
+			return nil
+		}
+		logger.Warn("Graceful termination timed out, proceeding to force kill", "pid", pid)
+	} else {
+		logger.Warn("Graceful termination failed", "pid", pid, "error", err)
+	}
+
+	// Step 2: Force kill (SIGKILL)
+	logger.Info("Attempting force kill", "pid", pid)
+	if err := signalProcessGroup(pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		logger.Error("Force kill failed", "pid", pid, "error", err)
+		return fmt.Errorf("force kill failed: %w", err)
+	}
+
+	// Verify process is gone
+	logger.Debug("Verifying process termination", "pid", pid)
+	verifyCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if !waitForProcessExit(verifyCtx, pid, 100*time.Millisecond, logger) {
+		logger.Error("Process did not terminate after force kill", "pid", pid)
+		return fmt.Errorf("process %d did not terminate after force kill", pid)
+	}
+
+	logger.Info("Nanobot stopped (force killed)", "pid", pid)
+	return nil
+}
+
+// signalProcessGroup sends sig to the whole process group when pid is a group leader
+// (StartNanobotWithCapture starts nanobot with Setpgid), otherwise to pid alone.
+// Signalling the group also reaches the Python interpreter behind the uv shim.
+func signalProcessGroup(pid int32, sig syscall.Signal) error {
+	if pgid, err := syscall.Getpgid(int(pid)); err == nil && pgid == int(pid) {
+		return syscall.Kill(-pgid, sig)
+	}
+	return syscall.Kill(int(pid), sig)
+}
+
+// findNanobotProcesses finds all nanobot processes using gopsutil
+func findNanobotProcesses(logger *slog.Logger) ([]int32, error) {
+	processes, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	self := int32(os.Getpid())
+	var pids []int32
+	for _, p := range processes {
+		if p.Pid == self {
+			continue
+		}
+		if isNanobotProcess(p) {
+			logger.Debug("Found nanobot process", "pid", p.Pid)
+			pids = append(pids, p.Pid)
+		}
+	}
+
+	return pids, nil
+}
+
+// isNanobotProcess reports whether p is a nanobot process.
+// The uv tool entry point is a Python script, so besides the process name we also
+// accept "python .../bin/nanobot ..." command lines.
+func isNanobotProcess(p *process.Process) bool {
+	if name, err := p.Name(); err == nil && name == nanobotProcessName {
+		return true
+	}
+
+	args, err := p.CmdlineSlice()
+	if err != nil || len(args) < 2 {
+		return false
+	}
+	return strings.HasPrefix(filepath.Base(args[0]), "python") &&
+		filepath.Base(args[1]) == nanobotProcessName
+}
+
+// waitForProcessExit polls until the process exits or context is done
+func waitForProcessExit(ctx context.Context, pid int32, pollInterval time.Duration, logger *slog.Logger) bool {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if !processAlive(pid) {
+			logger.Debug("Process has exited", "pid", pid)
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			logger.Debug("Wait for process exit timed out", "pid", pid)
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// processAlive reports whether pid still refers to a live (non-zombie) process.
+func processAlive(pid int32) bool {
+	if err := syscall.Kill(int(pid), 0); err != nil {
+		// EPERM means the process exists but belongs to another user
+		return !errors.Is(err, syscall.ESRCH)
+	}
+
+	// A zombie still accepts signal 0 until its parent reaps it
+	if p, err := process.NewProcess(pid); err == nil {
+		if status, err := p.Status(); err == nil && len(status) > 0 && status[0] == process.Zombie {
+			return false
+		}
+	}
+	return true
+}
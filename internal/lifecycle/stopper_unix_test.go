@@ -0,0 +1,76 @@
+//go:build !windows
+
+package lifecycle
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func stopperTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// startProcessGroup starts script via sh in its own process group, like StartNanobotWithCapture.
+// script must print a line once it is ready (e.g. after installing traps).
+// The process is reaped in the background so it does not linger as a zombie.
+func startProcessGroup(t *testing.T, script string) int32 {
+	t.Helper()
+
+	cmd := exec.Command("sh", "-c", script)
+	setNewProcessGroup(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to create stdout pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start test process: %v", err)
+	}
+	if _, err := bufio.NewReader(stdout).ReadString('\n'); err != nil {
+		t.Fatalf("test process did not become ready: %v", err)
+	}
+	go cmd.Wait()
+
+	pid := int32(cmd.Process.Pid)
+	t.Cleanup(func() { signalProcessGroup(pid, syscall.SIGKILL) })
+	return pid
+}
+
+func TestStopNanobot_NoPID(t *testing.T) {
+	if err := StopNanobot(context.Background(), 0, time.Second, stopperTestLogger()); err != nil {
+		t.Errorf("expected nil error for pid 0, got %v", err)
+	}
+}
+
+func TestStopNanobot_GracefulStopsProcessGroup(t *testing.T) {
+	pid := startProcessGroup(t, "sleep 30 & echo ready; sleep 30")
+
+	if err := StopNanobot(context.Background(), pid, 5*time.Second, stopperTestLogger()); err != nil {
+		t.Fatalf("StopNanobot failed: %v", err)
+	}
+	if processAlive(pid) {
+		t.Errorf("process %d still alive after StopNanobot", pid)
+	}
+}
+
+func TestStopNanobot_ForceKillAfterTimeout(t *testing.T) {
+	// Ignore SIGTERM so only SIGKILL can stop the process
+	pid := startProcessGroup(t, "trap '' TERM; echo ready; while :; do sleep 0.1; done")
+
+	start := time.Now()
+	if err := StopNanobot(context.Background(), pid, 300*time.Millisecond, stopperTestLogger()); err != nil {
+		t.Fatalf("StopNanobot failed: %v", err)
+	}
+	if processAlive(pid) {
+		t.Errorf("process %d still alive after force kill", pid)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("expected graceful timeout to elapse before force kill, took %v", elapsed)
+	}
+}
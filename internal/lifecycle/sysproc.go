@@ -0,0 +1,30 @@
+//go:build !windows
+
+package lifecycle
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setHiddenWindow is a no-op on non-Windows platforms (there is no console window to hide).
+func setHiddenWindow(cmd *exec.Cmd) {}
+
+// setNewProcessGroup places the command in a new process group whose PGID equals its PID.
+// StopNanobot signals the whole group, so the uv shim and the Python interpreter it
+// launches are stopped together.
+func setNewProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// homeEnv returns the environment variables that point the home directory of a process to dir.
+func homeEnv(dir string) []string {
+	return []string{"HOME=" + dir}
+}
+
+// SetDetached configures cmd to run in a new session, detached from the current
+// process and its controlling terminal.
+// Used when the updater re-spawns itself (self-update restart, crash recovery).
+func SetDetached(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
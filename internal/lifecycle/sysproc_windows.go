@@ -0,0 +1,41 @@
+//go:build windows
+
+package lifecycle
+
+import (
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// setHiddenWindow hides the console window of a helper command (taskkill, tasklist).
+func setHiddenWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &windows.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: windows.CREATE_NO_WINDOW,
+	}
+}
+
+// setNewProcessGroup starts the command without a console window in its own process group,
+// so the nanobot process is not affected by console events sent to the updater.
+func setNewProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &windows.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: windows.CREATE_NO_WINDOW | windows.CREATE_NEW_PROCESS_GROUP,
+	}
+}
+
+// homeEnv returns the environment variables that point the home directory of a process to dir.
+// Python's Path.home() reads USERPROFILE on Windows, HOME is set for tools like git.
+func homeEnv(dir string) []string {
+	return []string{"USERPROFILE=" + dir, "HOME=" + dir}
+}
+
+// SetDetached configures cmd to run fully detached from the current process.
+// Used when the updater re-spawns itself (self-update restart, crash recovery).
+func SetDetached(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &windows.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: windows.CREATE_NO_WINDOW | windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS,
+	}
+}
@@ -1,5 +1,3 @@
-//go:build windows
-
 package lifecycle
 
 import (
@@ -10,8 +8,6 @@ import (
 	"os"
 	"os/exec"
 	"time"
-
-	"golang.org/x/sys/windows"
 )
 
 // checkUpdateStateInternal returns the action and performs cleanup if needed.
@@ -52,10 +48,7 @@ func CheckUpdateStateForPath(exePath string, logger *slog.Logger) {
 		}
 		logger.Info("restored from .old backup, restarting")
 		cmd := exec.Command(exePath, os.Args[1:]...)
-		cmd.SysProcAttr = &windows.SysProcAttr{
-			HideWindow:    true,
-			CreationFlags: windows.CREATE_NO_WINDOW | windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS,
-		}
+		SetDetached(cmd)
 		cmd.Start()
 		os.Exit(0)
 	}
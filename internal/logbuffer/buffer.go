@@ -3,6 +3,7 @@ package logbuffer
 import (
 	"context"
 	"log/slog"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -13,6 +14,7 @@ type LogEntry struct {
 	Timestamp time.Time // Millisecond precision
 	Source    string    // "stdout" or "stderr"
 	Content   string
+	Seq       int64 // Stable sequence number assigned by Write; not reset by Clear
 }
 
 // LogBuffer is a thread-safe circular buffer for storing log entries
@@ -24,6 +26,8 @@ type LogBuffer struct {
 	entries     [5000]LogEntry                   // Fixed capacity of 5000 entries (BUFF-02)
 	head        int                              // Next write position (0-4999)
 	size        int                              // Current entry count (0-5000)
+	nextSeq     int64                            // Seq of the next written entry; only ever grows
+	id          string                           // Identifies this buffer, so sequence numbers of another buffer (or process) are not mixed up
 	subscribers map[chan LogEntry]context.CancelFunc // Subscriber channels with cancel funcs
 	logger      *slog.Logger
 }
@@ -33,10 +37,17 @@ func NewLogBuffer(logger *slog.Logger) *LogBuffer {
 	return &LogBuffer{
 		entries:     [5000]LogEntry{}, // Pre-allocate 5000 entry capacity
 		subscribers: make(map[chan LogEntry]context.CancelFunc),
+		id:          strconv.FormatInt(time.Now().UnixNano(), 36),
 		logger:      logger.With("component", "logbuffer"),
 	}
 }
 
+// ID identifies this buffer instance. Sequence numbers are only meaningful together with
+// the ID of the buffer that assigned them: a new buffer (e.g. after a restart) starts again at 0.
+func (lb *LogBuffer) ID() string {
+	return lb.id
+}
+
 // Write writes a log entry to the circular buffer.
 // BUFF-03: Thread-safe implementation using mutex
 // BUFF-04: Automatic FIFO overwrite when buffer is full
@@ -49,6 +60,10 @@ func (lb *LogBuffer) Write(entry LogEntry) error {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
+	// Assign the stable sequence number (positions in entries change on overwrite and Clear)
+	entry.Seq = lb.nextSeq
+	lb.nextSeq++
+
 	// Write to circular buffer (FIFO overwrite handled automatically)
 	lb.entries[lb.head] = entry
 	lb.head = (lb.head + 1) % 5000
@@ -100,9 +115,47 @@ func (lb *LogBuffer) GetHistory() []LogEntry {
 	return result
 }
 
+// LastWrite returns the timestamp of the newest entry in the buffer, or the zero time if it is empty.
+// Used by the log silence liveness probe.
+func (lb *LogBuffer) LastWrite() time.Time {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	if lb.size == 0 {
+		return time.Time{}
+	}
+	return lb.entries[(lb.head+5000-1)%5000].Timestamp
+}
+
+// NextSeq returns the sequence number the next written entry will get.
+// Taken before and after an operation, it delimits the lines the operation produced (see Range).
+func (lb *LogBuffer) NextSeq() int64 {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.nextSeq
+}
+
+// Range returns the entries with from <= Seq < to that are still in the buffer, in order.
+// complete is false if some of them were already overwritten or cleared.
+func (lb *LogBuffer) Range(from, to int64) (entries []LogEntry, complete bool) {
+	entries = []LogEntry{}
+	if to > lb.NextSeq() {
+		to = lb.NextSeq()
+	}
+	if from >= to {
+		return entries, true
+	}
+	for _, entry := range lb.GetHistory() {
+		if entry.Seq >= from && entry.Seq < to {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, int64(len(entries)) == to-from
+}
+
 // Clear resets the buffer to empty state
 // INST-05: Support instance restart behavior - old logs discarded before restart
 // Note: Subscribers continue receiving new logs after Clear() (subscribers map unchanged)
+// Sequence numbers continue after Clear(), so ranges taken before it stay valid.
 func (lb *LogBuffer) Clear() {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
@@ -578,3 +578,67 @@ func TestLogBuffer_Clear_WriteAfterClear(t *testing.T) {
 		}
 	}
 }
+
+// TestSeq_StableAcrossClearAndOverwrite tests that sequence numbers keep growing
+// and Range returns exactly the entries written between two NextSeq calls
+func TestSeq_StableAcrossClearAndOverwrite(t *testing.T) {
+	lb := NewLogBuffer(slog.Default())
+
+	for i := 0; i < 3; i++ {
+		lb.Write(LogEntry{Timestamp: time.Now(), Source: "stdout", Content: "before-" + toString(i)})
+	}
+	lb.Clear()
+
+	from := lb.NextSeq()
+	if from != 3 {
+		t.Fatalf("NextSeq() after Clear = %d, want 3", from)
+	}
+	for i := 0; i < 2; i++ {
+		lb.Write(LogEntry{Timestamp: time.Now(), Source: "stdout", Content: "during-" + toString(i)})
+	}
+	to := lb.NextSeq()
+	lb.Write(LogEntry{Timestamp: time.Now(), Source: "stdout", Content: "after"})
+
+	entries, complete := lb.Range(from, to)
+	if !complete || len(entries) != 2 || entries[0].Content != "during-0" || entries[1].Seq != 4 {
+		t.Errorf("Range(%d, %d) = %+v (complete=%v), want the 2 entries written in between", from, to, entries, complete)
+	}
+
+	// Entries cleared before the range was read are reported as incomplete
+	lb.Clear()
+	entries, complete = lb.Range(from, to)
+	if complete || len(entries) != 0 {
+		t.Errorf("Range after Clear = %+v (complete=%v), want no entries and complete=false", entries, complete)
+	}
+
+	// Overwritten entries (more than 5000 writes later) are missing too
+	from = lb.NextSeq()
+	for i := 0; i < 5010; i++ {
+		lb.Write(LogEntry{Timestamp: time.Now(), Source: "stdout", Content: "x"})
+	}
+	entries, complete = lb.Range(from, lb.NextSeq())
+	if complete || len(entries) != 5000 || entries[0].Seq != from+10 {
+		t.Errorf("Range over overwritten entries: len=%d complete=%v, want the last 5000 and complete=false", len(entries), complete)
+	}
+}
+
+// TestLogBuffer_LastWrite tests that LastWrite returns the newest entry, also after wrapping around
+func TestLogBuffer_LastWrite(t *testing.T) {
+	lb := NewLogBuffer(createTestLogger())
+	if last := lb.LastWrite(); !last.IsZero() {
+		t.Fatalf("LastWrite() of an empty buffer = %v, want zero", last)
+	}
+
+	base := time.Now()
+	for i := 0; i < 5001; i++ {
+		lb.Write(LogEntry{Timestamp: base.Add(time.Duration(i) * time.Millisecond), Source: "stdout", Content: "line"})
+	}
+	if want := base.Add(5000 * time.Millisecond); !lb.LastWrite().Equal(want) {
+		t.Errorf("LastWrite() = %v, want %v", lb.LastWrite(), want)
+	}
+
+	lb.Clear()
+	if last := lb.LastWrite(); !last.IsZero() {
+		t.Errorf("LastWrite() after Clear = %v, want zero", last)
+	}
+}
@@ -35,29 +35,56 @@ func NewConfigManager(logger *slog.Logger) *ConfigManager {
 // resolveWorkspace returns the workspace path for a nanobot instance based on its start_command.
 // With --config: uses ~/.nanobot-{instanceName} (instance-specific directory).
 // Without --config: uses ~/.nanobot (nanobot's default directory).
-func resolveWorkspace(startCommand, instanceName string) string {
-	matches := configPathRegex.FindStringSubmatch(startCommand)
-	if len(matches) >= 2 {
-		return "~/.nanobot-" + instanceName
+// homeDir is the own HOME of the instance (own_home); when set, the workspace is the absolute
+// path inside it instead of the ~ form.
+func resolveWorkspace(startCommand, instanceName, homeDir string) string {
+	dir := ".nanobot"
+	if matches := configPathRegex.FindStringSubmatch(startCommand); len(matches) >= 2 {
+		dir = ".nanobot-" + instanceName
 	}
-	return "~/.nanobot"
+	if homeDir != "" {
+		return filepath.Join(homeDir, dir)
+	}
+	return "~/" + dir
+}
+
+// InstanceDirs are the directories the nanobot paths of an instance are resolved against
+type InstanceDirs struct {
+	HomeDir    string // Own HOME of the instance (own_home), "" = the home directory of the service
+	WorkingDir string // Working directory of the instance (working_dir), "" = the working directory of the service
+}
+
+// resolveHome returns homeDir, or the home directory of the service when homeDir is empty.
+func resolveHome(homeDir string) (string, error) {
+	if homeDir != "" {
+		return homeDir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	return home, nil
 }
 
 // ParseConfigPath extracts the nanobot config.json path from a start_command.
 // D-01: Uses regex to extract --config parameter value from startCommand.
 // D-02: Falls back to ~/.nanobot/config.json when --config is absent (nanobot gateway default).
 // D-03: Resolves ~ using os.UserHomeDir() and constructs paths with filepath.Join.
-func ParseConfigPath(startCommand, instanceName string) (string, error) {
+// dirs.HomeDir is the own HOME of the instance (own_home) used for ~ instead, "" = os.UserHomeDir().
+// A relative --config is relative to the working directory of the instance process (dirs.WorkingDir).
+func ParseConfigPath(startCommand, instanceName string, dirs InstanceDirs) (string, error) {
 	matches := configPathRegex.FindStringSubmatch(startCommand)
 	if len(matches) >= 2 {
 		configPath := matches[1]
 		// Expand ~ to home directory using os.UserHomeDir()
 		if len(configPath) > 0 && configPath[0] == '~' {
-			homeDir, err := os.UserHomeDir()
+			home, err := resolveHome(dirs.HomeDir)
 			if err != nil {
-				return "", fmt.Errorf("failed to resolve home directory: %w", err)
+				return "", err
 			}
-			configPath = filepath.Join(homeDir, configPath[1:])
+			configPath = filepath.Join(home, configPath[1:])
+		} else if !filepath.IsAbs(configPath) && dirs.WorkingDir != "" {
+			configPath = filepath.Join(dirs.WorkingDir, configPath)
 		}
 		// Return absolute path
 		absPath, err := filepath.Abs(configPath)
@@ -70,11 +97,11 @@ func ParseConfigPath(startCommand, instanceName string) (string, error) {
 	// Fallback: ~/.nanobot/config.json
 	// When start_command has no --config (e.g., "nanobot gateway"), nanobot uses
 	// ~/.nanobot/config.json as its default config path.
-	homeDir, err := os.UserHomeDir()
+	home, err := resolveHome(dirs.HomeDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		return "", err
 	}
-	return filepath.Join(homeDir, ".nanobot", "config.json"), nil
+	return filepath.Join(home, ".nanobot", "config.json"), nil
 }
 
 // GenerateDefaultConfig creates a default nanobot configuration map.
@@ -190,8 +217,9 @@ func (cm *ConfigManager) WriteConfig(configPath string, data map[string]interfac
 // NC-01: Auto-creates nanobot config directory and default config file.
 // Uses ~/.nanobot-{instanceName} form for workspace in the config (nanobot reads this form),
 // but resolves actual paths via os.UserHomeDir() for file operations.
-func (cm *ConfigManager) CreateDefaultConfig(instanceName string, port uint32, startCommand string) error {
-	configPath, err := ParseConfigPath(startCommand, instanceName)
+// dirs are the own HOME and working directory of the instance, see ParseConfigPath.
+func (cm *ConfigManager) CreateDefaultConfig(instanceName string, port uint32, startCommand string, dirs InstanceDirs) error {
+	configPath, err := ParseConfigPath(startCommand, instanceName, dirs)
 	if err != nil {
 		return fmt.Errorf("failed to parse config path for instance %q: %w", instanceName, err)
 	}
@@ -199,11 +227,7 @@ func (cm *ConfigManager) CreateDefaultConfig(instanceName string, port uint32, s
 	// Determine workspace based on whether start_command has --config.
 	// With --config: workspace matches the config directory (~/.nanobot-{name}).
 	// Without --config: nanobot default workspace is ~/.nanobot.
-	matches := configPathRegex.FindStringSubmatch(startCommand)
-	workspace := "~/.nanobot"
-	if len(matches) >= 2 {
-		workspace = "~/.nanobot-" + instanceName
-	}
+	workspace := resolveWorkspace(startCommand, instanceName, dirs.HomeDir)
 
 	defaultConfig := GenerateDefaultConfig(port, workspace)
 
@@ -220,13 +244,14 @@ func (cm *ConfigManager) CreateDefaultConfig(instanceName string, port uint32, s
 // If source config file does not exist, generates a default config instead (assumption A2).
 // The nanobot config.json does NOT have a top-level "name" field; only gateway.port
 // and agents.defaults.workspace are updated during cloning.
-func (cm *ConfigManager) CloneConfig(sourceStartCommand, sourceInstanceName, targetInstanceName string, targetPort uint32, targetStartCommand string) error {
-	sourceConfigPath, err := ParseConfigPath(sourceStartCommand, sourceInstanceName)
+// sourceDirs and targetDirs are the own HOME and working directory of the instances.
+func (cm *ConfigManager) CloneConfig(sourceStartCommand, sourceInstanceName string, sourceDirs InstanceDirs, targetInstanceName string, targetPort uint32, targetStartCommand string, targetDirs InstanceDirs) error {
+	sourceConfigPath, err := ParseConfigPath(sourceStartCommand, sourceInstanceName, sourceDirs)
 	if err != nil {
 		return fmt.Errorf("failed to parse source config path: %w", err)
 	}
 
-	targetConfigPath, err := ParseConfigPath(targetStartCommand, targetInstanceName)
+	targetConfigPath, err := ParseConfigPath(targetStartCommand, targetInstanceName, targetDirs)
 	if err != nil {
 		return fmt.Errorf("failed to parse target config path: %w", err)
 	}
@@ -249,7 +274,7 @@ func (cm *ConfigManager) CloneConfig(sourceStartCommand, sourceInstanceName, tar
 		if os.IsNotExist(err) {
 			cm.logger.Warn("Source nanobot config not found, generating default",
 				"source_instance", sourceInstanceName, "source_path", sourceConfigPath)
-			workspace := resolveWorkspace(targetStartCommand, targetInstanceName)
+			workspace := resolveWorkspace(targetStartCommand, targetInstanceName, targetDirs.HomeDir)
 			configData = GenerateDefaultConfig(targetPort, workspace)
 		} else {
 			return fmt.Errorf("failed to read source nanobot config: %w", err)
@@ -264,7 +289,7 @@ func (cm *ConfigManager) CloneConfig(sourceStartCommand, sourceInstanceName, tar
 	// Update agents.defaults.workspace to target instance name
 	if agents, ok := configData["agents"].(map[string]interface{}); ok {
 		if defaults, ok := agents["defaults"].(map[string]interface{}); ok {
-			defaults["workspace"] = resolveWorkspace(targetStartCommand, targetInstanceName)
+			defaults["workspace"] = resolveWorkspace(targetStartCommand, targetInstanceName, targetDirs.HomeDir)
 		}
 	}
 
@@ -296,13 +321,14 @@ func UpdateStartCommandConfig(startCommand, configPath string) string {
 // If the config path changed (startCommand modified), the old config is read and written to the
 // new location with updated port and workspace. The old config file is preserved (not deleted).
 // If the config path is unchanged, only gateway.port and agents.defaults.workspace are updated.
-func (cm *ConfigManager) UpdateInstanceConfig(instanceName string, oldPort uint32, oldStartCommand string, newPort uint32, newStartCommand string) error {
-	oldPath, err := ParseConfigPath(oldStartCommand, instanceName)
+// oldDirs and newDirs are the own HOME and working directory of the instance before and after the change.
+func (cm *ConfigManager) UpdateInstanceConfig(instanceName string, oldPort uint32, oldStartCommand string, oldDirs InstanceDirs, newPort uint32, newStartCommand string, newDirs InstanceDirs) error {
+	oldPath, err := ParseConfigPath(oldStartCommand, instanceName, oldDirs)
 	if err != nil {
 		return fmt.Errorf("failed to parse old config path: %w", err)
 	}
 
-	newPath, err := ParseConfigPath(newStartCommand, instanceName)
+	newPath, err := ParseConfigPath(newStartCommand, instanceName, newDirs)
 	if err != nil {
 		return fmt.Errorf("failed to parse new config path: %w", err)
 	}
@@ -319,7 +345,7 @@ func (cm *ConfigManager) UpdateInstanceConfig(instanceName string, oldPort uint3
 			// No existing config: generate a default at the new path
 			cm.logger.Warn("Nanobot config not found during update, generating default",
 				"instance", instanceName, "path", readPath)
-			workspace := resolveWorkspace(newStartCommand, instanceName)
+			workspace := resolveWorkspace(newStartCommand, instanceName, newDirs.HomeDir)
 			configData = GenerateDefaultConfig(newPort, workspace)
 		} else {
 			return fmt.Errorf("failed to read nanobot config for update: %w", err)
@@ -334,7 +360,7 @@ func (cm *ConfigManager) UpdateInstanceConfig(instanceName string, oldPort uint3
 	// Update agents.defaults.workspace
 	if agents, ok := configData["agents"].(map[string]interface{}); ok {
 		if defaults, ok := agents["defaults"].(map[string]interface{}); ok {
-			defaults["workspace"] = resolveWorkspace(newStartCommand, instanceName)
+			defaults["workspace"] = resolveWorkspace(newStartCommand, instanceName, newDirs.HomeDir)
 		}
 	}
 
@@ -355,15 +381,16 @@ func (cm *ConfigManager) UpdateInstanceConfig(instanceName string, oldPort uint3
 // For instance-specific directories (e.g., ~/.nanobot-{name}/), removes the entire directory.
 // For the default ~/.nanobot/ directory, only removes the config.json file to preserve
 // other nanobot data (workspace, etc.) that may be shared.
-func (cm *ConfigManager) CleanupConfig(startCommand, instanceName string) error {
-	configPath, err := ParseConfigPath(startCommand, instanceName)
+// dirs are the own HOME and working directory of the instance, see ParseConfigPath.
+func (cm *ConfigManager) CleanupConfig(startCommand, instanceName string, dirs InstanceDirs) error {
+	configPath, err := ParseConfigPath(startCommand, instanceName, dirs)
 	if err != nil {
 		return fmt.Errorf("failed to parse config path: %w", err)
 	}
 
 	// Check if this is the default ~/.nanobot/config.json path
-	homeDir, _ := os.UserHomeDir()
-	defaultConfigPath := filepath.Join(homeDir, ".nanobot", "config.json")
+	home, _ := resolveHome(dirs.HomeDir)
+	defaultConfigPath := filepath.Join(home, ".nanobot", "config.json")
 
 	if configPath == defaultConfigPath {
 		// Default path: only remove the config file, not the shared directory
@@ -15,15 +15,8 @@ import (
 
 // --- ParseConfigPath tests ---
 
-func TestParseConfigPath_WithConfigFlag(t *testing.T) {
-	path, err := ParseConfigPath("nanobot gateway --config C:/Users/test/.nanobot-helper/config.json --port 18792", "test")
-	require.NoError(t, err)
-	// filepath.Abs normalizes slashes on Windows
-	assert.Equal(t, filepath.FromSlash("C:/Users/test/.nanobot-helper/config.json"), path)
-}
-
 func TestParseConfigPath_WithTildePath(t *testing.T) {
-	path, err := ParseConfigPath("nanobot gateway --config ~/.nanobot-test/config.json", "test")
+	path, err := ParseConfigPath("nanobot gateway --config ~/.nanobot-test/config.json", "test", InstanceDirs{})
 	require.NoError(t, err)
 	homeDir, err := os.UserHomeDir()
 	require.NoError(t, err)
@@ -32,7 +25,7 @@ func TestParseConfigPath_WithTildePath(t *testing.T) {
 }
 
 func TestParseConfigPath_WithoutConfigFlag(t *testing.T) {
-	path, err := ParseConfigPath("nanobot gateway", "my-instance")
+	path, err := ParseConfigPath("nanobot gateway", "my-instance", InstanceDirs{})
 	require.NoError(t, err)
 	homeDir, err := os.UserHomeDir()
 	require.NoError(t, err)
@@ -40,14 +33,8 @@ func TestParseConfigPath_WithoutConfigFlag(t *testing.T) {
 	assert.Equal(t, expected, path)
 }
 
-func TestParseConfigPath_WithQuotedPath(t *testing.T) {
-	path, err := ParseConfigPath(`nanobot gateway --config "C:/path_with_spaces/config.json"`, "test")
-	require.NoError(t, err)
-	assert.Equal(t, filepath.FromSlash("C:/path_with_spaces/config.json"), path)
-}
-
 func TestParseConfigPath_EmptyCommand(t *testing.T) {
-	path, err := ParseConfigPath("", "test")
+	path, err := ParseConfigPath("", "test", InstanceDirs{})
 	require.NoError(t, err)
 	homeDir, err := os.UserHomeDir()
 	require.NoError(t, err)
@@ -55,17 +42,45 @@ func TestParseConfigPath_EmptyCommand(t *testing.T) {
 	assert.Equal(t, expected, path)
 }
 
-func TestParseConfigPath_WindowsBackslashPath(t *testing.T) {
-	path, err := ParseConfigPath(`nanobot gateway --config C:\Users\test\.nanobot-helper\config.json`, "test")
+func TestParseConfigPath_OwnHome(t *testing.T) {
+	home := t.TempDir()
+	path, err := ParseConfigPath("nanobot gateway", "my-instance", InstanceDirs{HomeDir: home})
 	require.NoError(t, err)
-	assert.Equal(t, `C:\Users\test\.nanobot-helper\config.json`, path)
+	assert.Equal(t, filepath.Join(home, ".nanobot", "config.json"), path)
+
+	path, err = ParseConfigPath("nanobot gateway --config ~/.nanobot-test/config.json", "test", InstanceDirs{HomeDir: home})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".nanobot-test", "config.json"), path)
+
+	assert.Equal(t, filepath.Join(home, ".nanobot-test"), resolveWorkspace("nanobot gateway --config ~/.nanobot-test/config.json", "test", home))
+	assert.Equal(t, "~/.nanobot", resolveWorkspace("nanobot gateway", "test", ""))
 }
 
-func TestParseConfigPath_WindowsForwardSlashInCommand(t *testing.T) {
-	path, err := ParseConfigPath("nanobot gateway --config C:/Users/test/.nanobot-helper/config.json", "test")
+func TestParseConfigPath_RelativeToWorkingDir(t *testing.T) {
+	workDir := t.TempDir()
+	dirs := InstanceDirs{WorkingDir: workDir}
+
+	path, err := ParseConfigPath("nanobot gateway --config conf/config.json", "test", dirs)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(workDir, "conf", "config.json"), path)
+
+	// ~ 和绝对路径不受工作目录影响
+	home := t.TempDir()
+	path, err = ParseConfigPath("nanobot gateway --config ~/.nanobot-test/config.json", "test", InstanceDirs{HomeDir: home, WorkingDir: workDir})
 	require.NoError(t, err)
-	// filepath.Abs normalizes to backslash on Windows
-	assert.Equal(t, filepath.FromSlash("C:/Users/test/.nanobot-helper/config.json"), path)
+	assert.Equal(t, filepath.Join(home, ".nanobot-test", "config.json"), path)
+
+	abs := filepath.Join(t.TempDir(), "config.json")
+	path, err = ParseConfigPath("nanobot gateway --config "+abs, "test", dirs)
+	require.NoError(t, err)
+	assert.Equal(t, abs, path)
+
+	// 未设置工作目录时相对于服务的工作目录
+	path, err = ParseConfigPath("nanobot gateway --config conf/config.json", "test", InstanceDirs{})
+	require.NoError(t, err)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(wd, "conf", "config.json"), path)
 }
 
 // --- GenerateDefaultConfig tests ---
@@ -257,7 +272,7 @@ func TestCreateDefaultConfig_CreatesDirectoryAndFile(t *testing.T) {
 	logger := newTestLogger()
 	cm := NewConfigManager(logger)
 
-	err := cm.CreateDefaultConfig("test-instance", 18792, startCommand)
+	err := cm.CreateDefaultConfig("test-instance", 18792, startCommand, InstanceDirs{})
 	require.NoError(t, err)
 
 	// Verify directory exists
@@ -281,6 +296,23 @@ func TestCreateDefaultConfig_CreatesDirectoryAndFile(t *testing.T) {
 	assert.Equal(t, float64(18792), gateway["port"])
 }
 
+func TestCreateDefaultConfig_OwnHome(t *testing.T) {
+	home := t.TempDir()
+	cm := NewConfigManager(newTestLogger())
+
+	require.NoError(t, cm.CreateDefaultConfig("own", 18793, "nanobot gateway", InstanceDirs{HomeDir: home}))
+
+	// 独立 HOME 下的配置, workspace 使用该 HOME 的绝对路径
+	data, err := cm.ReadConfig(filepath.Join(home, ".nanobot", "config.json"))
+	require.NoError(t, err)
+	defaults := data["agents"].(map[string]interface{})["defaults"].(map[string]interface{})
+	assert.Equal(t, filepath.Join(home, ".nanobot"), defaults["workspace"])
+
+	require.NoError(t, cm.CleanupConfig("nanobot gateway", "own", InstanceDirs{HomeDir: home}))
+	_, err = os.Stat(filepath.Join(home, ".nanobot", "config.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
 func TestCreateDefaultConfig_UsesUserHomeDir(t *testing.T) {
 	// Verify workspace field uses ~/.nanobot-{name} form
 	cfg := GenerateDefaultConfig(18790, "~/.nanobot-mynode")
@@ -317,9 +349,9 @@ func TestCloneConfig_CopiesAndUpdates(t *testing.T) {
 
 	// Clone with target port 18791
 	err = cm.CloneConfig(
-		"nanobot gateway --config "+sourcePath, "source",
+		"nanobot gateway --config "+sourcePath, "source", InstanceDirs{},
 		"target", 18791,
-		"nanobot gateway --config "+targetPath,
+		"nanobot gateway --config "+targetPath, InstanceDirs{},
 	)
 	require.NoError(t, err)
 
@@ -348,9 +380,9 @@ func TestCloneConfig_SourceNotFound(t *testing.T) {
 
 	// Clone from nonexistent source -- should generate default config for target
 	err := cm.CloneConfig(
-		"nanobot gateway --config "+sourcePath, "source",
+		"nanobot gateway --config "+sourcePath, "source", InstanceDirs{},
 		"target", 18791,
-		"nanobot gateway --config "+targetPath,
+		"nanobot gateway --config "+targetPath, InstanceDirs{},
 	)
 	require.NoError(t, err)
 
@@ -395,9 +427,9 @@ func TestCloneConfig_OnlyUpdatesPortAndWorkspace(t *testing.T) {
 	require.NoError(t, err)
 
 	err = cm.CloneConfig(
-		"nanobot gateway --config "+sourcePath, "source",
+		"nanobot gateway --config "+sourcePath, "source", InstanceDirs{},
 		"target", 18795,
-		"nanobot gateway --config "+targetPath,
+		"nanobot gateway --config "+targetPath, InstanceDirs{},
 	)
 	require.NoError(t, err)
 
@@ -443,7 +475,7 @@ func TestCleanupConfig_RemovesDirectory(t *testing.T) {
 	require.NoError(t, err)
 
 	// Cleanup
-	err = cm.CleanupConfig("nanobot gateway --config "+configPath, "test-cleanup")
+	err = cm.CleanupConfig("nanobot gateway --config "+configPath, "test-cleanup", InstanceDirs{})
 	require.NoError(t, err)
 
 	// Verify directory no longer exists
@@ -460,7 +492,7 @@ func TestCleanupConfig_NonexistentDirectory(t *testing.T) {
 	logger := newTestLogger()
 	cm := NewConfigManager(logger)
 
-	err := cm.CleanupConfig("nanobot gateway --config "+configPath, "nonexistent")
+	err := cm.CleanupConfig("nanobot gateway --config "+configPath, "nonexistent", InstanceDirs{})
 	assert.NoError(t, err)
 }
 
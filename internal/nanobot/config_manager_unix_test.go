@@ -0,0 +1,24 @@
+//go:build !windows
+
+package nanobot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- ParseConfigPath tests (POSIX paths) ---
+
+func TestParseConfigPath_WithConfigFlag(t *testing.T) {
+	path, err := ParseConfigPath("nanobot gateway --config /home/test/.nanobot-helper/config.json --port 18792", "test", InstanceDirs{})
+	require.NoError(t, err)
+	assert.Equal(t, "/home/test/.nanobot-helper/config.json", path)
+}
+
+func TestParseConfigPath_WithQuotedPath(t *testing.T) {
+	path, err := ParseConfigPath(`nanobot gateway --config "/opt/path_with_spaces/config.json"`, "test", InstanceDirs{})
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/path_with_spaces/config.json", path)
+}
@@ -0,0 +1,39 @@
+//go:build windows
+
+package nanobot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- ParseConfigPath tests (Windows drive-letter paths) ---
+
+func TestParseConfigPath_WithConfigFlag(t *testing.T) {
+	path, err := ParseConfigPath("nanobot gateway --config C:/Users/test/.nanobot-helper/config.json --port 18792", "test", InstanceDirs{})
+	require.NoError(t, err)
+	// filepath.Abs normalizes slashes on Windows
+	assert.Equal(t, filepath.FromSlash("C:/Users/test/.nanobot-helper/config.json"), path)
+}
+
+func TestParseConfigPath_WithQuotedPath(t *testing.T) {
+	path, err := ParseConfigPath(`nanobot gateway --config "C:/path_with_spaces/config.json"`, "test", InstanceDirs{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.FromSlash("C:/path_with_spaces/config.json"), path)
+}
+
+func TestParseConfigPath_WindowsBackslashPath(t *testing.T) {
+	path, err := ParseConfigPath(`nanobot gateway --config C:\Users\test\.nanobot-helper\config.json`, "test", InstanceDirs{})
+	require.NoError(t, err)
+	assert.Equal(t, `C:\Users\test\.nanobot-helper\config.json`, path)
+}
+
+func TestParseConfigPath_WindowsForwardSlashInCommand(t *testing.T) {
+	path, err := ParseConfigPath("nanobot gateway --config C:/Users/test/.nanobot-helper/config.json", "test", InstanceDirs{})
+	require.NoError(t, err)
+	// filepath.Abs normalizes to backslash on Windows
+	assert.Equal(t, filepath.FromSlash("C:/Users/test/.nanobot-helper/config.json"), path)
+}
@@ -111,6 +111,34 @@ func (n *Notifier) Notify(title, message string) error {
 	return nil
 }
 
+// NotifyWithURL sends a notification with a supplementary link (e.g. an approval link).
+// urlTitle is the link text shown instead of the URL; an empty url sends a plain notification.
+// Returns nil if notifications are disabled (no error)
+func (n *Notifier) NotifyWithURL(title, message, url, urlTitle string) error {
+	if !n.enabled {
+		n.logger.Debug("Notification skipped (not configured)", "title", title)
+		return nil
+	}
+
+	msg := pushover.NewMessageWithTitle(message, title)
+	if url != "" {
+		msg.URL = url
+		msg.URLTitle = urlTitle
+	}
+	response, err := n.client.SendMessage(msg, n.recipient)
+	if err != nil {
+		n.logger.Error("Failed to send notification",
+			"title", title,
+			"error", err)
+		return fmt.Errorf("pushover notification failed: %w", err)
+	}
+
+	n.logger.Info("Notification sent successfully",
+		"title", title,
+		"id", response.ID)
+	return nil
+}
+
 // NotifyFailure is a convenience method for sending failure notifications
 func (n *Notifier) NotifyFailure(operation string, err error) error {
 	title := fmt.Sprintf("Nanobot Update Failed: %s", operation)
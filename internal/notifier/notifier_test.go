@@ -162,3 +162,16 @@ func TestNotify_Enabled(t *testing.T) {
 		t.Errorf("Expected Notify() to succeed with real credentials, got: %v", err)
 	}
 }
+
+// TestNotifyWithURL_Disabled verifies no error when disabled
+func TestNotifyWithURL_Disabled(t *testing.T) {
+	os.Unsetenv("PUSHOVER_TOKEN")
+	os.Unsetenv("PUSHOVER_USER")
+
+	logger, _ := newTestLogger()
+	n := New(logger)
+
+	if err := n.NotifyWithURL("Test Title", "Test Message", "https://example.com/approve", "Approve"); err != nil {
+		t.Errorf("Expected NotifyWithURL() to return nil when disabled, got: %v", err)
+	}
+}
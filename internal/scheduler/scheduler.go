@@ -0,0 +1,323 @@
+// Package scheduler runs nanobot updates on a cron schedule (update_schedule config).
+// Scheduled runs go through InstanceManager.TriggerUpdate, so they share the update lock
+// with the trigger-update API and self-update.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/approval"
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+const (
+	// TriggeredBy is recorded in UpdateLog.TriggeredBy for scheduled updates
+	TriggeredBy = "scheduler"
+	// DefaultUpdateTimeout bounds a scheduled update; no HTTP client is waiting, so it is
+	// longer than api.timeout to leave room for every update source and its retries
+	DefaultUpdateTimeout = 30 * time.Minute
+)
+
+// UpdateTrigger runs and checks nanobot updates.
+// Satisfied by *instance.InstanceManager via duck typing.
+type UpdateTrigger interface {
+	TriggerUpdate(ctx context.Context, target updater.Target, force bool) (*instance.UpdateResult, error)
+	CheckUpdate(ctx context.Context, target updater.Target) (*updater.CheckResult, error)
+}
+
+// UpdateRecorder records completed updates and their uv/git command output.
+// Satisfied by *updatelog.UpdateLogger via duck typing.
+type UpdateRecorder interface {
+	Record(log updatelog.UpdateLog) error
+	RecordOutput(id, output string) error
+}
+
+// Notifier sends notifications.
+// Satisfied by *notifier.Notifier via duck typing.
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// Approvals creates pending updates when updates need approval (approval config).
+// Satisfied by *approval.Store via duck typing.
+type Approvals interface {
+	Enabled() bool
+	Create(id string, req approval.Request) (approval.Update, bool)
+}
+
+// UpdateScheduler triggers nanobot updates from update_schedule cron expressions
+type UpdateScheduler struct {
+	mu        sync.Mutex
+	cfg       config.UpdateScheduleConfig
+	cron      *cron.Cron
+	trigger   UpdateTrigger
+	recorder  UpdateRecorder
+	notifier  Notifier
+	approvals Approvals     // Scheduled updates wait for approval when enabled (nil = run directly)
+	timeout   time.Duration // Timeout of a single scheduled update
+	logger    *slog.Logger
+
+	ctx    context.Context // cancelled by Stop to abort a running update
+	cancel context.CancelFunc
+	now    func() time.Time // overrideable for tests
+}
+
+// NewUpdateScheduler creates a scheduler. Call Start to schedule the jobs.
+// recorder and notifier may be nil.
+func NewUpdateScheduler(cfg config.UpdateScheduleConfig, trigger UpdateTrigger, recorder UpdateRecorder, notifier Notifier, timeout time.Duration, logger *slog.Logger) *UpdateScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &UpdateScheduler{
+		cfg:      cfg,
+		trigger:  trigger,
+		recorder: recorder,
+		notifier: notifier,
+		timeout:  timeout,
+		logger:   logger.With("source", "update-scheduler"),
+		ctx:      ctx,
+		cancel:   cancel,
+		now:      time.Now,
+	}
+}
+
+// Start schedules the configured cron jobs. Does nothing when the schedule is disabled.
+func (s *UpdateScheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scheduleLocked()
+}
+
+// Stop removes all cron jobs and aborts a running scheduled update between phases.
+func (s *UpdateScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopCronLocked()
+	s.cancel()
+	s.logger.Info("Update scheduler stopped")
+}
+
+// Reload replaces the schedule (hot reload). The previous jobs are removed first; a scheduled
+// update that is already running is not interrupted.
+func (s *UpdateScheduler) Reload(cfg config.UpdateScheduleConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopCronLocked()
+	s.cfg = cfg
+	return s.scheduleLocked()
+}
+
+// SetTrigger replaces the update trigger (used when the InstanceManager is rebuilt by hot reload).
+func (s *UpdateScheduler) SetTrigger(trigger UpdateTrigger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trigger = trigger
+}
+
+// SetApprovals makes scheduled updates wait for approval when approvals is enabled: instead of
+// updating, the scheduler creates a pending update that runs once approved.
+func (s *UpdateScheduler) SetApprovals(approvals Approvals) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.approvals = approvals
+}
+
+// scheduleLocked creates and starts the cron jobs for s.cfg. Caller holds s.mu.
+func (s *UpdateScheduler) scheduleLocked() error {
+	if !s.cfg.Enabled {
+		s.logger.Info("Scheduled updates disabled")
+		return nil
+	}
+
+	c := cron.New()
+	for _, expr := range s.cfg.Cron {
+		if _, err := c.AddFunc(expr, s.run); err != nil {
+			return fmt.Errorf("invalid update_schedule cron expression %q: %w", expr, err)
+		}
+	}
+	c.Start()
+	s.cron = c
+
+	s.logger.Info("Update scheduler started",
+		"cron", s.cfg.Cron,
+		"mode", s.cfg.EffectiveMode(),
+		"maintenance_window", s.cfg.MaintenanceWindow,
+		"blackout_dates", s.cfg.BlackoutDates)
+	return nil
+}
+
+// stopCronLocked stops the cron jobs without waiting for a running job. Caller holds s.mu.
+func (s *UpdateScheduler) stopCronLocked() {
+	if s.cron != nil {
+		s.cron.Stop()
+		s.cron = nil
+	}
+}
+
+// run is the cron job: applies blackout dates and the maintenance window, then updates
+// according to the configured mode.
+func (s *UpdateScheduler) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Scheduled update panic",
+				"panic", r,
+				"stack", string(debug.Stack()))
+		}
+	}()
+
+	s.mu.Lock()
+	cfg := s.cfg
+	trigger := s.trigger
+	approvals := s.approvals
+	s.mu.Unlock()
+
+	now := s.now()
+	if cfg.IsBlackout(now) {
+		s.logger.Info("Scheduled update skipped: blackout date", "date", now.Local().Format("2006-01-02"))
+		return
+	}
+	if !cfg.InMaintenanceWindow(now) {
+		s.logger.Info("Scheduled update skipped: outside maintenance window",
+			"time", now.Local().Format("15:04"),
+			"maintenance_window", cfg.MaintenanceWindow)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+
+	mode := cfg.EffectiveMode()
+	if mode == config.ScheduleModeNotifyOnly {
+		s.checkAndNotify(ctx, trigger)
+		return
+	}
+	if approvals != nil && approvals.Enabled() {
+		s.requestApproval(ctx, trigger, approvals, mode == config.ScheduleModeAlways)
+		return
+	}
+	s.update(ctx, trigger, mode == config.ScheduleModeAlways)
+}
+
+// requestApproval creates a pending update instead of updating (approval.enabled). In
+// only-if-new-version mode nothing is requested while nanobot is up to date. A failed check
+// still requests the update, like the update itself never skips because of a network error.
+func (s *UpdateScheduler) requestApproval(ctx context.Context, trigger UpdateTrigger, approvals Approvals, force bool) {
+	check, err := trigger.CheckUpdate(ctx, updater.Target{})
+	if err != nil {
+		s.logger.Warn("Scheduled update check failed, requesting approval anyway", "error", err)
+		check = nil
+	}
+	if !force && check != nil && !check.UpdateAvailable {
+		s.logger.Info("Scheduled update skipped: nanobot is up to date", "reason", check.Reason)
+		return
+	}
+
+	update, created := approvals.Create("", approval.Request{
+		Force:       force,
+		TriggeredBy: TriggeredBy,
+		Check:       check,
+	})
+	if !created {
+		s.logger.Info("Scheduled update still waiting for approval", "update_id", update.ID, "expires_at", update.ExpiresAt)
+		return
+	}
+	s.logger.Info("Scheduled update waiting for approval", "update_id", update.ID, "new_version", update.NewVersion, "expires_at", update.ExpiresAt)
+}
+
+// checkAndNotify implements notify-only mode: notify when an update is available, install nothing
+func (s *UpdateScheduler) checkAndNotify(ctx context.Context, trigger UpdateTrigger) {
+	check, err := trigger.CheckUpdate(ctx, updater.Target{})
+	if err != nil {
+		s.logger.Error("Scheduled update check failed", "error", err)
+		return
+	}
+	if !check.UpdateAvailable {
+		s.logger.Info("Scheduled update check: nanobot is up to date", "reason", check.Reason)
+		return
+	}
+
+	s.logger.Info("Scheduled update check: update available", "reason", check.Reason)
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("已安装版本: %s\n", check.InstalledVersion))
+	if check.LatestVersion != "" {
+		msg.WriteString(fmt.Sprintf("PyPI 最新版本: %s\n", check.LatestVersion))
+	}
+	if check.LatestCommit != "" {
+		msg.WriteString(fmt.Sprintf("最新提交: %s\n", check.LatestCommit))
+	}
+	msg.WriteString(fmt.Sprintf("说明: %s\n", check.Reason))
+	msg.WriteString("当前为 notify-only 模式, 请通过 /api/v1/trigger-update 手动更新")
+	s.notify("Nanobot 有可用更新", msg.String())
+}
+
+// update runs TriggerUpdate, records the result and notifies when something was installed
+func (s *UpdateScheduler) update(ctx context.Context, trigger UpdateTrigger, force bool) {
+	updateID := uuid.New().String()
+	startTime := time.Now().UTC()
+	s.logger.Info("Scheduled update started", "update_id", updateID, "force", force)
+
+	result, err := trigger.TriggerUpdate(instance.WithUpdateID(ctx, updateID), updater.Target{}, force)
+	endTime := time.Now().UTC()
+
+	log := updatelog.RecordUpdate(s.recorder, s.logger, updateID, TriggeredBy, startTime, endTime, result, err)
+	if err != nil {
+		if log.Status == updatelog.StatusRejected {
+			// Another update is queued or running, it will be notified by whoever triggered it
+			return
+		}
+		s.notify("Nanobot 定时更新失败", fmt.Sprintf("触发来源: %s\n状态: %s\n错误: %v", TriggeredBy, log.Status, err))
+		return
+	}
+
+	if result.Skipped {
+		s.logger.Info("Scheduled update skipped: nanobot is up to date", "reason", result.SkipReason, "update_id", updateID)
+		return
+	}
+
+	s.logger.Info("Scheduled update completed", "status", log.Status, "update_id", updateID)
+	s.notify(fmt.Sprintf("Nanobot 定时更新完成 (%s)", log.Status), formatResult(result, endTime.Sub(startTime)))
+}
+
+// notify sends a notification if a notifier is configured
+func (s *UpdateScheduler) notify(title, message string) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.Notify(title, message); err != nil {
+		s.logger.Error("Failed to send scheduled update notification", "error", err)
+	}
+}
+
+// formatResult builds the completion notification message
+func formatResult(result *instance.UpdateResult, elapsed time.Duration) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("触发来源: %s\n", TriggeredBy))
+	msg.WriteString(fmt.Sprintf("耗时: %.1fs\n", elapsed.Seconds()))
+	if result.InstallSpec != "" {
+		msg.WriteString(fmt.Sprintf("安装: %s\n", result.InstallSpec))
+	}
+	msg.WriteString(fmt.Sprintf("成功启动: %d\n", len(result.Started)))
+	if failed := len(result.StopFailed) + len(result.StartFailed); failed > 0 {
+		msg.WriteString(fmt.Sprintf("失败: %d\n", failed))
+	}
+	if result.Aborted {
+		msg.WriteString(fmt.Sprintf("更新在 %s 阶段中止: %s\n", result.AbortStage, result.AbortReason))
+		if len(result.HeldBack) > 0 {
+			msg.WriteString(fmt.Sprintf("保持停止的实例: %s\n", strings.Join(result.HeldBack, ", ")))
+		}
+	}
+	if result.RolledBack {
+		msg.WriteString(fmt.Sprintf("新版本启动失败, 已回滚到: %s\n", result.PreviousVersion))
+	}
+	return msg.String()
+}
@@ -0,0 +1,299 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/approval"
+	"github.com/HQGroup/nanobot-auto-updater/internal/config"
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
+)
+
+type mockTrigger struct {
+	mu          sync.Mutex
+	result      *instance.UpdateResult
+	err         error
+	check       *updater.CheckResult
+	updateCalls int
+	checkCalls  int
+	lastForce   bool
+}
+
+func (m *mockTrigger) TriggerUpdate(ctx context.Context, target updater.Target, force bool) (*instance.UpdateResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateCalls++
+	m.lastForce = force
+	return m.result, m.err
+}
+
+func (m *mockTrigger) CheckUpdate(ctx context.Context, target updater.Target) (*updater.CheckResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkCalls++
+	return m.check, nil
+}
+
+type mockRecorder struct {
+	logs    []updatelog.UpdateLog
+	outputs map[string]string
+}
+
+func (m *mockRecorder) Record(log updatelog.UpdateLog) error {
+	m.logs = append(m.logs, log)
+	return nil
+}
+
+func (m *mockRecorder) RecordOutput(id, output string) error {
+	if m.outputs == nil {
+		m.outputs = make(map[string]string)
+	}
+	m.outputs[id] = output
+	return nil
+}
+
+type mockNotifier struct {
+	titles []string
+}
+
+func (m *mockNotifier) Notify(title, message string) error {
+	m.titles = append(m.titles, title)
+	return nil
+}
+
+// at returns a local time on 2026-03-10 at hh:mm
+func at(hh, mm int) time.Time {
+	return time.Date(2026, 3, 10, hh, mm, 0, 0, time.Local)
+}
+
+func newTestScheduler(cfg config.UpdateScheduleConfig, trigger *mockTrigger, now time.Time) (*UpdateScheduler, *mockRecorder, *mockNotifier) {
+	rec := &mockRecorder{}
+	notif := &mockNotifier{}
+	s := NewUpdateScheduler(cfg, trigger, rec, notif, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.now = func() time.Time { return now }
+	return s, rec, notif
+}
+
+func TestRun_Modes(t *testing.T) {
+	updated := &instance.UpdateResult{Target: "latest", Stopped: []string{"a"}, Started: []string{"a"}, InstallSpec: "nanobot-ai",
+		Output: "$ uv tool install --force nanobot-ai\n"}
+
+	tests := []struct {
+		mode      string
+		wantForce bool
+	}{
+		{config.ScheduleModeAlways, true},
+		{config.ScheduleModeOnlyIfNewVersion, false},
+		{"", false}, // default mode
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			trigger := &mockTrigger{result: updated}
+			s, rec, notif := newTestScheduler(config.UpdateScheduleConfig{Enabled: true, Mode: tt.mode}, trigger, at(3, 0))
+
+			s.run()
+
+			if trigger.updateCalls != 1 || trigger.lastForce != tt.wantForce {
+				t.Errorf("TriggerUpdate calls=%d force=%v, want 1 call with force=%v", trigger.updateCalls, trigger.lastForce, tt.wantForce)
+			}
+			if len(rec.logs) != 1 || rec.logs[0].TriggeredBy != "scheduler" || rec.logs[0].Status != updatelog.StatusSuccess {
+				t.Errorf("recorded logs = %+v, want one successful scheduler log", rec.logs)
+			}
+			if len(rec.logs) == 1 && rec.outputs[rec.logs[0].ID] != updated.Output {
+				t.Errorf("recorded outputs = %v, want the update output keyed by update_id", rec.outputs)
+			}
+			if len(notif.titles) != 1 {
+				t.Errorf("notifications = %v, want one completion notification", notif.titles)
+			}
+		})
+	}
+}
+
+func TestRun_SkippedUpdateIsRecordedWithoutNotification(t *testing.T) {
+	trigger := &mockTrigger{result: &instance.UpdateResult{Target: "latest", Skipped: true, SkipReason: "installed version 0.1.4 is current"}}
+	s, rec, notif := newTestScheduler(config.UpdateScheduleConfig{Enabled: true}, trigger, at(3, 0))
+
+	s.run()
+
+	if len(rec.logs) != 1 || rec.logs[0].Status != updatelog.StatusSkipped {
+		t.Errorf("recorded logs = %+v, want one skipped log", rec.logs)
+	}
+	if len(notif.titles) != 0 {
+		t.Errorf("notifications = %v, want none for a skipped update", notif.titles)
+	}
+}
+
+func TestRun_NotifyOnly(t *testing.T) {
+	trigger := &mockTrigger{check: &updater.CheckResult{InstalledVersion: "0.1.4", LatestVersion: "0.2.0", UpdateAvailable: true, Reason: "new version 0.2.0 (installed 0.1.4)"}}
+	s, rec, notif := newTestScheduler(config.UpdateScheduleConfig{Enabled: true, Mode: config.ScheduleModeNotifyOnly}, trigger, at(3, 0))
+
+	s.run()
+
+	if trigger.updateCalls != 0 {
+		t.Errorf("notify-only must not update, TriggerUpdate called %d times", trigger.updateCalls)
+	}
+	if trigger.checkCalls != 1 || len(notif.titles) != 1 || notif.titles[0] != "Nanobot 有可用更新" {
+		t.Errorf("checks=%d notifications=%v, want one check and one availability notification", trigger.checkCalls, notif.titles)
+	}
+	if len(rec.logs) != 0 {
+		t.Errorf("notify-only must not record update logs, got %d", len(rec.logs))
+	}
+
+	// Nothing new: no notification
+	trigger.check = &updater.CheckResult{InstalledVersion: "0.2.0", LatestVersion: "0.2.0"}
+	s.run()
+	if len(notif.titles) != 1 {
+		t.Errorf("notifications = %v, want no notification when up to date", notif.titles)
+	}
+}
+
+func TestRun_WaitsForApproval(t *testing.T) {
+	upToDate := &updater.CheckResult{InstalledVersion: "0.2.0", LatestVersion: "0.2.0", Reason: "installed version 0.2.0 is current"}
+	newVersion := &updater.CheckResult{SourceType: updater.SourcePyPI, InstalledVersion: "0.1.4", LatestVersion: "0.2.0", UpdateAvailable: true}
+
+	tests := []struct {
+		name        string
+		mode        string
+		check       *updater.CheckResult
+		wantPending bool
+	}{
+		{"new version", config.ScheduleModeOnlyIfNewVersion, newVersion, true},
+		{"up to date", config.ScheduleModeOnlyIfNewVersion, upToDate, false},
+		{"always", config.ScheduleModeAlways, upToDate, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := &mockTrigger{check: tt.check}
+			s, rec, _ := newTestScheduler(config.UpdateScheduleConfig{Enabled: true, Mode: tt.mode}, trigger, at(3, 0))
+			store := approval.NewStore(config.ApprovalConfig{Enabled: true}, rec, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			s.SetApprovals(store)
+
+			s.run()
+
+			if trigger.updateCalls != 0 {
+				t.Errorf("TriggerUpdate calls = %d, want none before approval", trigger.updateCalls)
+			}
+			pending := store.List()
+			if !tt.wantPending {
+				if len(pending) != 0 {
+					t.Errorf("pending updates = %+v, want none when nanobot is up to date", pending)
+				}
+				return
+			}
+			if len(pending) != 1 || pending[0].State != approval.StatePending || pending[0].TriggeredBy != TriggeredBy || pending[0].Force != (tt.mode == config.ScheduleModeAlways) {
+				t.Fatalf("pending updates = %+v, want one pending scheduler update", pending)
+			}
+			if len(rec.logs) != 1 || rec.logs[0].Status != updatelog.StatusPendingApproval {
+				t.Errorf("recorded logs = %+v, want one pending_approval record", rec.logs)
+			}
+
+			// 下一次定时运行时仍在等待审批, 不重复创建
+			s.run()
+			if len(store.List()) != 1 {
+				t.Errorf("pending updates after a second run = %d, want still 1", len(store.List()))
+			}
+		})
+	}
+}
+
+func TestRun_MaintenanceWindowAndBlackout(t *testing.T) {
+	cfg := config.UpdateScheduleConfig{
+		Enabled:           true,
+		MaintenanceWindow: "02:00-05:00",
+		BlackoutDates:     []string{"2026-03-11"},
+	}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		wantUpdate bool
+	}{
+		{"inside window", at(2, 30), true},
+		{"before window", at(1, 59), false},
+		{"window end is exclusive", at(5, 0), false},
+		{"blackout date", at(3, 0).AddDate(0, 0, 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := &mockTrigger{result: &instance.UpdateResult{}}
+			s, _, _ := newTestScheduler(cfg, trigger, tt.now)
+
+			s.run()
+
+			if (trigger.updateCalls == 1) != tt.wantUpdate {
+				t.Errorf("TriggerUpdate calls = %d, want update=%v", trigger.updateCalls, tt.wantUpdate)
+			}
+		})
+	}
+}
+
+func TestRun_UpdateInProgress(t *testing.T) {
+	trigger := &mockTrigger{err: instance.ErrUpdateInProgress}
+	s, rec, notif := newTestScheduler(config.UpdateScheduleConfig{Enabled: true}, trigger, at(3, 0))
+
+	s.run()
+
+	if len(notif.titles) != 0 {
+		t.Errorf("a run rejected by the update lock must not notify: notifications=%v", notif.titles)
+	}
+	if len(rec.logs) != 1 || rec.logs[0].Status != updatelog.StatusRejected || rec.logs[0].RejectReason == "" {
+		t.Errorf("recorded logs = %+v, want one rejected log with a reason", rec.logs)
+	}
+}
+
+func TestRun_FailedUpdateIsRecorded(t *testing.T) {
+	trigger := &mockTrigger{err: errors.New("uv tool install failed: exit status 1")}
+	s, rec, notif := newTestScheduler(config.UpdateScheduleConfig{Enabled: true}, trigger, at(3, 0))
+
+	s.run()
+
+	if len(rec.logs) != 1 {
+		t.Fatalf("recorded logs = %+v, want one failed log", rec.logs)
+	}
+	log := rec.logs[0]
+	if log.Status != updatelog.StatusFailed || log.Error != trigger.err.Error() ||
+		log.Operation != updatelog.OperationNanobotUpdate || log.TriggeredBy != "scheduler" {
+		t.Errorf("recorded log = %+v, want a failed scheduler nanobot-update log with the error", log)
+	}
+	if len(notif.titles) != 1 {
+		t.Errorf("notifications = %v, want one failure notification", notif.titles)
+	}
+}
+
+func TestStartAndReload(t *testing.T) {
+	trigger := &mockTrigger{result: &instance.UpdateResult{}}
+	s, _, _ := newTestScheduler(config.UpdateScheduleConfig{}, trigger, at(3, 0))
+	defer s.Stop()
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() with disabled schedule: %v", err)
+	}
+	if s.cron != nil {
+		t.Error("disabled schedule must not create cron jobs")
+	}
+
+	if err := s.Reload(config.UpdateScheduleConfig{Enabled: true, Cron: []string{"0 3 * * *", "30 4 * * 1-5"}}); err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	if s.cron == nil || len(s.cron.Entries()) != 2 {
+		t.Fatalf("expected 2 cron entries after reload")
+	}
+
+	if err := s.Reload(config.UpdateScheduleConfig{Enabled: true, Cron: []string{"not a cron"}}); err == nil {
+		t.Error("Reload() should reject an invalid cron expression")
+	}
+
+	if err := s.Reload(config.UpdateScheduleConfig{Enabled: false, Cron: []string{"0 3 * * *"}}); err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	if s.cron != nil {
+		t.Error("disabling the schedule must remove the cron jobs")
+	}
+}
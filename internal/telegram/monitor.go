@@ -41,6 +41,7 @@ type TelegramMonitor struct {
 	instanceName string
 	timeout      time.Duration
 	startTime    time.Time // TELE-08: filter entries before this timestamp
+	failure      string    // Last connection failure/timeout, cleared by a successful connection
 	logger       *slog.Logger
 	ctx          context.Context
 	cancel       context.CancelFunc
@@ -54,6 +55,7 @@ func NewTelegramMonitor(logBuffer LogSubscriber, notifier Notifier, instanceName
 		notifier:     notifier,
 		instanceName: instanceName,
 		timeout:      timeout,
+		startTime:    time.Now(), // TELE-08: set before Start's goroutine runs so early entries are not dropped
 		logger:       logger.With("component", "telegram-monitor", "instance", instanceName),
 		ctx:          ctx,
 		cancel:       cancel,
@@ -65,8 +67,6 @@ func (m *TelegramMonitor) Start(ctx context.Context) {
 	ch := m.logBuffer.Subscribe()
 	defer m.logBuffer.Unsubscribe(ch)
 
-	m.startTime = time.Now() // TELE-08: only process entries after this time
-
 	for {
 		select {
 		case entry, ok := <-ch:
@@ -106,6 +106,7 @@ func (m *TelegramMonitor) processEntry(entry logbuffer.LogEntry) {
 		if IsSuccess(entry.Content) { // TELE-02
 			m.timer.Stop()
 			m.state = stateIdle
+			m.failure = ""
 			title := "Telegram Connected"
 			message := fmt.Sprintf("Instance %s: Telegram bot connected successfully", m.instanceName)
 			go m.sendNotification(title, message) // TELE-05
@@ -113,6 +114,7 @@ func (m *TelegramMonitor) processEntry(entry logbuffer.LogEntry) {
 		} else if IsFailure(entry.Content) { // TELE-03
 			m.timer.Stop()
 			m.state = stateIdle
+			m.failure = "telegram connection failed: " + entry.Content
 			title := "Telegram Connection Failed"
 			message := fmt.Sprintf("Instance %s: httpx.ConnectError detected in log output", m.instanceName)
 			go m.sendNotification(title, message) // TELE-06
@@ -134,6 +136,7 @@ func (m *TelegramMonitor) startTimer() {
 			return // Already resolved, ignore stale timeout
 		}
 		m.state = stateIdle
+		m.failure = fmt.Sprintf("telegram connection timeout after %v", m.timeout)
 		title := "Telegram Connection Timeout"
 		message := fmt.Sprintf("Instance %s: connection timeout, no response within %v", m.instanceName, m.timeout)
 		go m.sendNotification(title, message) // TELE-04
@@ -163,6 +166,14 @@ func (m *TelegramMonitor) sendNotification(title, message string) {
 	}
 }
 
+// Failure returns the last Telegram connection failure or timeout, or "" when the bot has not
+// failed (or connected successfully afterwards). Used by the canary soak check.
+func (m *TelegramMonitor) Failure() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.failure
+}
+
 // Stop cancels the monitor, stopping any active timer and the context.
 func (m *TelegramMonitor) Stop() {
 	m.mu.Lock()
@@ -136,6 +136,13 @@ func TestMonitor_FailureDetected(t *testing.T) {
 	calls := notif.getCalls()
 	require.Len(t, calls, 1, "expected exactly one failure notification")
 	assert.Contains(t, calls[0].Title, "Failed")
+	assert.Contains(t, m.Failure(), "httpx.ConnectError")
+
+	// A later successful connection clears the failure
+	sub.writeEntry("Starting Telegram bot...")
+	sub.writeEntry("Telegram bot commands registered")
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, m.Failure())
 }
 
 func TestMonitor_TimeoutFires(t *testing.T) {
@@ -158,6 +165,7 @@ func TestMonitor_TimeoutFires(t *testing.T) {
 	calls := notif.getCalls()
 	require.Len(t, calls, 1, "expected exactly one timeout notification")
 	assert.Contains(t, calls[0].Title, "Timeout")
+	assert.Contains(t, m.Failure(), "timeout")
 }
 
 func TestMonitor_SuccessNotification(t *testing.T) {
@@ -17,9 +17,10 @@ type UpdateLogger struct {
 	logs     []UpdateLog
 	mu       sync.RWMutex
 	logger   *slog.Logger
-	filePath string     // JSONL file path (e.g. "./logs/updates.jsonl")
-	file     *os.File   // Open file handle (nil = memory-only mode or not yet opened)
-	fileMu   sync.Mutex // Mutex for file operations (separate from mu to avoid blocking GetAll)
+	filePath string            // JSONL file path (e.g. "./logs/updates.jsonl")
+	file     *os.File          // Open file handle (nil = memory-only mode or not yet opened)
+	fileMu   sync.Mutex        // Mutex for file operations (separate from mu to avoid blocking GetAll)
+	outputs  map[string]string // Command output by update ID (memory-only mode, see RecordOutput)
 }
 
 // NewUpdateLogger creates a new UpdateLogger with an empty logs slice and the given file path.
@@ -111,7 +112,21 @@ func (ul *UpdateLogger) GetAll() []UpdateLog {
 	return result
 }
 
-// CleanupOldLogs removes records older than 7 days from the JSONL file.
+// Get returns the log with the given update_id.
+// Thread-safe read using RWMutex. Not blocked by file operations.
+func (ul *UpdateLogger) Get(id string) (UpdateLog, bool) {
+	ul.mu.RLock()
+	defer ul.mu.RUnlock()
+	for i := len(ul.logs) - 1; i >= 0; i-- {
+		if ul.logs[i].ID == id {
+			return ul.logs[i], true
+		}
+	}
+	return UpdateLog{}, false
+}
+
+// CleanupOldLogs removes records older than 7 days from the JSONL file,
+// together with the command output files of those updates.
 // Uses temp file + atomic rename pattern for safe cleanup.
 // This method closes the file handle before rename (Windows compatibility).
 func (ul *UpdateLogger) CleanupOldLogs() error {
@@ -120,6 +135,8 @@ func (ul *UpdateLogger) CleanupOldLogs() error {
 	ul.fileMu.Lock()
 	defer ul.fileMu.Unlock()
 
+	ul.cleanupOldOutputs(cutoff)
+
 	// Close file handle (Windows requires this for rename)
 	if ul.file != nil {
 		ul.file.Close()
@@ -249,6 +266,10 @@ func (ul *UpdateLogger) LoadFromFile() error {
 			ul.logger.Warn("Skipping invalid JSON line during LoadFromFile", "error", err)
 			continue
 		}
+		if log.Operation == "" {
+			// Records written before operation types existed are all nanobot updates
+			log.Operation = OperationNanobotUpdate
+		}
 		ul.logs = append(ul.logs, log)
 		loaded++
 	}
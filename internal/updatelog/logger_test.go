@@ -852,6 +852,13 @@ func TestLoadFromFile_ValidFile(t *testing.T) {
 			t.Errorf("Expected to find ID '%s' in loaded logs", rec.ID)
 		}
 	}
+
+	// Records written before the operation field existed are nanobot updates
+	for _, l := range logs {
+		if l.Operation != OperationNanobotUpdate {
+			t.Errorf("Log %s operation = %q, want %q", l.ID, l.Operation, OperationNanobotUpdate)
+		}
+	}
 }
 
 func TestLoadFromFile_NonExistentFile(t *testing.T) {
@@ -0,0 +1,150 @@
+package updatelog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+)
+
+// StatusForError maps the error that ended an operation to its status:
+// another update in progress is a rejection, an exceeded deadline a timeout, anything else a failure.
+func StatusForError(err error) UpdateStatus {
+	switch {
+	case errors.Is(err, instance.ErrUpdateInProgress):
+		return StatusRejected
+	case errors.Is(err, context.DeadlineExceeded):
+		return StatusTimeout
+	default:
+		return StatusFailed
+	}
+}
+
+// BuildFailedUpdateLog creates the UpdateLog record for a nanobot update that returned an error
+// (uv install failed, timeout, update lock held). result may be nil; when set, the instance
+// details collected before the error are kept.
+func BuildFailedUpdateLog(id string, startTime, endTime time.Time, triggeredBy string, result *instance.UpdateResult, err error) UpdateLog {
+	log := UpdateLog{
+		ID:          id,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Duration:    endTime.Sub(startTime).Milliseconds(),
+		Operation:   OperationNanobotUpdate,
+		Instances:   []InstanceUpdateDetail{},
+		TriggeredBy: triggeredBy,
+	}
+	if result != nil {
+		log = BuildUpdateLog(id, startTime, endTime, triggeredBy, result)
+	}
+	log.Status = StatusForError(err)
+	log.Error = err.Error()
+	if log.Status == StatusRejected {
+		log.RejectReason = "update already in progress"
+	}
+	return log
+}
+
+// Recorder persists update logs and the uv/git output of updates.
+// Satisfied by *UpdateLogger.
+type Recorder interface {
+	Record(log UpdateLog) error
+	RecordOutput(id, output string) error
+}
+
+// RecordUpdate persists the outcome of one nanobot update, whoever triggered it (API, scheduler):
+// the uv/git output when there is a result, then the UpdateLog record, failed and rejected runs
+// included so the update log is a complete history. Failures are logged with their status.
+// rec may be nil, recording errors are only logged. Returns the record that was built.
+func RecordUpdate(rec Recorder, logger *slog.Logger, id, triggeredBy string, startTime, endTime time.Time, result *instance.UpdateResult, err error) UpdateLog {
+	var log UpdateLog
+	if err != nil {
+		log = BuildFailedUpdateLog(id, startTime, endTime, triggeredBy, result, err)
+		switch log.Status {
+		case StatusRejected:
+			logger.Warn("Update rejected", "error", err, "update_id", id, "triggered_by", triggeredBy)
+		case StatusTimeout:
+			logger.Error("Update operation timed out", "error", err, "elapsed", endTime.Sub(startTime), "update_id", id, "triggered_by", triggeredBy)
+		default:
+			logger.Error("Update operation failed", "error", err, "update_id", id, "triggered_by", triggeredBy)
+		}
+	} else {
+		log = BuildUpdateLog(id, startTime, endTime, triggeredBy, result)
+	}
+	if rec == nil {
+		return log
+	}
+
+	if result != nil {
+		if outputErr := rec.RecordOutput(id, result.Output); outputErr != nil {
+			logger.Error("Failed to record update output", "error", outputErr, "update_id", id)
+		}
+	}
+	if recordErr := rec.Record(log); recordErr != nil {
+		logger.Error("Failed to record update log", "error", recordErr, "update_id", id)
+	}
+	return log
+}
+
+// BuildOperationLog creates the UpdateLog record for a self-update or a manual instance
+// operation (restart, start, stop). err is nil on success.
+func BuildOperationLog(id, operation, triggeredBy string, startTime, endTime time.Time, instances []InstanceUpdateDetail, err error) UpdateLog {
+	if instances == nil {
+		instances = []InstanceUpdateDetail{}
+	}
+	log := UpdateLog{
+		ID:          id,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Duration:    endTime.Sub(startTime).Milliseconds(),
+		Operation:   operation,
+		Status:      StatusSuccess,
+		Instances:   instances,
+		TriggeredBy: triggeredBy,
+	}
+	if err != nil {
+		log.Status = StatusForError(err)
+		log.Error = err.Error()
+	}
+	return log
+}
+
+// BuildRejectedLog creates the UpdateLog record for an operation refused before it started,
+// e.g. because another update holds the update lock or the instance is already running.
+func BuildRejectedLog(id, operation, triggeredBy string, at time.Time, reason string, instances []InstanceUpdateDetail) UpdateLog {
+	if instances == nil {
+		instances = []InstanceUpdateDetail{}
+	}
+	return UpdateLog{
+		ID:           id,
+		StartTime:    at,
+		EndTime:      at,
+		Operation:    operation,
+		Status:       StatusRejected,
+		RejectReason: reason,
+		Instances:    instances,
+		TriggeredBy:  triggeredBy,
+	}
+}
+
+// BuildApprovalLog creates the update-approval record of a pending update: status is
+// pending_approval when it is created, then approved, rejected or expired. The records share
+// the update id with the nanobot-update record written once an approved update has run.
+func BuildApprovalLog(id, triggeredBy, target string, status UpdateStatus, at time.Time, reason string, info ApprovalInfo) UpdateLog {
+	log := UpdateLog{
+		ID:          id,
+		StartTime:   at,
+		EndTime:     at,
+		Operation:   OperationUpdateApproval,
+		Status:      status,
+		Instances:   []InstanceUpdateDetail{},
+		TriggeredBy: triggeredBy,
+		Target:      target,
+		Approval:    &info,
+	}
+	if status == StatusRejected {
+		log.RejectReason = reason
+	}
+	return log
+}
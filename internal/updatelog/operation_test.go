@@ -0,0 +1,147 @@
+package updatelog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+)
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want UpdateStatus
+	}{
+		{instance.ErrUpdateInProgress, StatusRejected},
+		{fmt.Errorf("update: %w", context.DeadlineExceeded), StatusTimeout},
+		{errors.New("uv tool install failed"), StatusFailed},
+	}
+	for _, tt := range tests {
+		if got := StatusForError(tt.err); got != tt.want {
+			t.Errorf("StatusForError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBuildFailedUpdateLog(t *testing.T) {
+	start := time.Now().UTC()
+	end := start.Add(2 * time.Second)
+
+	// Without a result
+	log := BuildFailedUpdateLog("id-1", start, end, "api-trigger", nil, errors.New("uv failed"))
+	if log.Status != StatusFailed || log.Error != "uv failed" || log.Operation != OperationNanobotUpdate {
+		t.Errorf("log = %+v, want a failed nanobot-update with the error", log)
+	}
+	if log.Duration != 2000 || log.Instances == nil {
+		t.Errorf("duration = %d, instances = %v; want 2000 and an empty list", log.Duration, log.Instances)
+	}
+
+	// With a partial result: instance details are kept
+	result := &instance.UpdateResult{Target: "v0.2.0", Stopped: []string{"a"}}
+	log = BuildFailedUpdateLog("id-2", start, end, "scheduler", result, instance.ErrUpdateInProgress)
+	if log.Status != StatusRejected || log.RejectReason == "" || log.Target != "v0.2.0" || len(log.Instances) != 1 {
+		t.Errorf("log = %+v, want a rejected log keeping the target and instance details", log)
+	}
+	// Hooks that ran before the failure are kept
+	result = &instance.UpdateResult{Hooks: []*instance.HookResult{{Hook: "on_failure", Name: "alert", ExitCode: 0}}}
+	log = BuildFailedUpdateLog("id-3", start, end, "api-trigger", result, errors.New("uv failed"))
+	if len(log.Hooks) != 1 || log.Hooks[0].Name != "alert" {
+		t.Errorf("hooks = %+v, want the on_failure hook", log.Hooks)
+	}
+}
+
+func TestBuildOperationLog(t *testing.T) {
+	start := time.Now().UTC()
+	end := start.Add(time.Second)
+	detail := InstanceUpdateDetail{Name: "a", Port: 18790, Status: "success", StopDuration: 300, StartDuration: 700}
+
+	log := BuildOperationLog("id-1", OperationInstanceRestart, "api-instance-restart", start, end, []InstanceUpdateDetail{detail}, nil)
+	if log.Status != StatusSuccess || log.Operation != OperationInstanceRestart || log.Error != "" || len(log.Instances) != 1 {
+		t.Errorf("log = %+v, want a successful instance-restart with one instance", log)
+	}
+
+	log = BuildOperationLog("id-2", OperationSelfUpdate, "api-self-update", start, end, nil, errors.New("download failed"))
+	if log.Status != StatusFailed || log.Error != "download failed" || log.Instances == nil {
+		t.Errorf("log = %+v, want a failed self-update with an empty instance list", log)
+	}
+}
+
+func TestBuildRejectedLog(t *testing.T) {
+	at := time.Now().UTC()
+	log := BuildRejectedLog("id-1", OperationInstanceStart, "api-instance-lifecycle", at, "instance already running", nil)
+	if log.Status != StatusRejected || log.RejectReason != "instance already running" || log.Duration != 0 || !log.StartTime.Equal(at) {
+		t.Errorf("log = %+v, want a rejected instance-start with its reason", log)
+	}
+}
+
+func TestBuildApprovalLog(t *testing.T) {
+	at := time.Now().UTC()
+	info := ApprovalInfo{NewVersion: "0.2.1", ExpiresAt: at.Add(time.Hour)}
+	log := BuildApprovalLog("id-1", "scheduler", "latest", StatusPendingApproval, at, "", info)
+	if log.Operation != OperationUpdateApproval || log.Status != StatusPendingApproval || log.Approval == nil || log.Approval.NewVersion != "0.2.1" || log.Target != "latest" {
+		t.Errorf("log = %+v, want a pending update-approval record for 0.2.1", log)
+	}
+
+	info.DecidedBy = "api"
+	log = BuildApprovalLog("id-1", "scheduler", "latest", StatusRejected, at, "not during business hours", info)
+	if log.Status != StatusRejected || log.RejectReason != "not during business hours" || log.Approval.DecidedBy != "api" {
+		t.Errorf("log = %+v, want a rejected update-approval record with its reason", log)
+	}
+}
+
+// fakeRecorder records in memory what RecordUpdate persists
+type fakeRecorder struct {
+	logs    []UpdateLog
+	outputs map[string]string
+}
+
+func (r *fakeRecorder) Record(log UpdateLog) error {
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *fakeRecorder) RecordOutput(id, output string) error {
+	if r.outputs == nil {
+		r.outputs = make(map[string]string)
+	}
+	r.outputs[id] = output
+	return nil
+}
+
+func TestRecordUpdate(t *testing.T) {
+	start := time.Now().UTC()
+	end := start.Add(time.Second)
+	logger := slog.Default()
+	rec := &fakeRecorder{}
+
+	// Success: output and record persisted
+	result := &instance.UpdateResult{Output: "$ uv tool install nanobot-ai\n"}
+	log := RecordUpdate(rec, logger, "id-1", "scheduler", start, end, result, nil)
+	if log.Status != StatusSuccess || rec.outputs["id-1"] != result.Output {
+		t.Errorf("log = %+v, outputs = %v; want a successful record with its output", log, rec.outputs)
+	}
+
+	// Timeout with a partial result: output kept, status timeout
+	log = RecordUpdate(rec, logger, "id-2", "api-trigger", start, end, result, fmt.Errorf("update: %w", context.DeadlineExceeded))
+	if log.Status != StatusTimeout || rec.outputs["id-2"] != result.Output {
+		t.Errorf("log = %+v, outputs = %v; want a timeout record with its output", log, rec.outputs)
+	}
+
+	// Rejected without a result: no output
+	log = RecordUpdate(rec, logger, "id-3", "scheduler", start, end, nil, instance.ErrUpdateInProgress)
+	if _, ok := rec.outputs["id-3"]; ok || log.Status != StatusRejected {
+		t.Errorf("log = %+v, outputs = %v; want a rejected record without output", log, rec.outputs)
+	}
+	if len(rec.logs) != 3 {
+		t.Fatalf("recorded %d logs, want 3", len(rec.logs))
+	}
+
+	// No recorder: the record is still built
+	if log := RecordUpdate(nil, logger, "id-4", "scheduler", start, end, result, nil); log.ID != "id-4" {
+		t.Errorf("log = %+v, want the record built without a recorder", log)
+	}
+}
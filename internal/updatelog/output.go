@@ -0,0 +1,107 @@
+package updatelog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrOutputNotFound is returned by GetOutput when no output was recorded for an update
+var ErrOutputNotFound = errors.New("update output not found")
+
+// outputDirName is the directory next to the JSONL file holding one <update_id>.log per update
+const outputDirName = "update-output"
+
+// outputPath returns the file holding the command output of update id
+func (ul *UpdateLogger) outputPath(id string) string {
+	return filepath.Join(filepath.Dir(ul.filePath), outputDirName, id+".log")
+}
+
+// validOutputID rejects ids that could escape the output directory
+func validOutputID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
+
+// RecordOutput stores the full uv/git command output of update id next to the update log
+// (<log dir>/update-output/<id>.log). In memory-only mode the output is kept in memory.
+// Empty output is not recorded.
+func (ul *UpdateLogger) RecordOutput(id, output string) error {
+	if output == "" {
+		return nil
+	}
+	if !validOutputID(id) {
+		return fmt.Errorf("invalid update id %q", id)
+	}
+
+	if ul.filePath == "" {
+		ul.mu.Lock()
+		if ul.outputs == nil {
+			ul.outputs = make(map[string]string)
+		}
+		ul.outputs[id] = output
+		ul.mu.Unlock()
+		return nil
+	}
+
+	path := ul.outputPath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create update output directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+		return fmt.Errorf("failed to write update output: %w", err)
+	}
+	ul.logger.Debug("Recorded update output", "update_id", id, "bytes", len(output))
+	return nil
+}
+
+// GetOutput returns the command output recorded for update id.
+// Returns ErrOutputNotFound if none was recorded.
+func (ul *UpdateLogger) GetOutput(id string) (string, error) {
+	if !validOutputID(id) {
+		return "", ErrOutputNotFound
+	}
+
+	if ul.filePath == "" {
+		ul.mu.RLock()
+		defer ul.mu.RUnlock()
+		output, ok := ul.outputs[id]
+		if !ok {
+			return "", ErrOutputNotFound
+		}
+		return output, nil
+	}
+
+	data, err := os.ReadFile(ul.outputPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrOutputNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read update output: %w", err)
+	}
+	return string(data), nil
+}
+
+// cleanupOldOutputs removes output files last written before cutoff
+func (ul *UpdateLogger) cleanupOldOutputs(cutoff time.Time) {
+	dir := filepath.Join(filepath.Dir(ul.filePath), outputDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return // No outputs recorded yet
+	}
+	removed := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err == nil {
+			removed++
+		}
+	}
+	if removed > 0 {
+		ul.logger.Info("Cleaned up old update outputs", "removed", removed)
+	}
+}
@@ -0,0 +1,86 @@
+package updatelog
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordOutput_File(t *testing.T) {
+	tmpDir := t.TempDir()
+	ul := NewUpdateLogger(slog.Default(), filepath.Join(tmpDir, "updates.jsonl"))
+	defer ul.Close()
+
+	output := "[stderr] error: No solution found when resolving dependencies\n"
+	if err := ul.RecordOutput("a1b2", output); err != nil {
+		t.Fatalf("RecordOutput failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "update-output", "a1b2.log"))
+	if err != nil || string(data) != output {
+		t.Fatalf("output file = %q, %v; want %q", data, err, output)
+	}
+	got, err := ul.GetOutput("a1b2")
+	if err != nil || got != output {
+		t.Errorf("GetOutput = %q, %v; want %q", got, err, output)
+	}
+
+	if _, err := ul.GetOutput("missing"); !errors.Is(err, ErrOutputNotFound) {
+		t.Errorf("GetOutput(missing) error = %v, want ErrOutputNotFound", err)
+	}
+	if _, err := ul.GetOutput("../updates"); !errors.Is(err, ErrOutputNotFound) {
+		t.Errorf("GetOutput(../updates) error = %v, want ErrOutputNotFound", err)
+	}
+	if err := ul.RecordOutput("../escape", output); err == nil {
+		t.Error("RecordOutput accepted an id with a path separator")
+	}
+}
+
+func TestRecordOutput_MemoryOnly(t *testing.T) {
+	ul := NewUpdateLogger(slog.Default(), "")
+
+	if err := ul.RecordOutput("a1b2", "line\n"); err != nil {
+		t.Fatalf("RecordOutput failed: %v", err)
+	}
+	if got, err := ul.GetOutput("a1b2"); err != nil || got != "line\n" {
+		t.Errorf("GetOutput = %q, %v; want %q", got, err, "line\n")
+	}
+
+	// Empty output is not recorded
+	if err := ul.RecordOutput("empty", ""); err != nil {
+		t.Fatalf("RecordOutput failed: %v", err)
+	}
+	if _, err := ul.GetOutput("empty"); !errors.Is(err, ErrOutputNotFound) {
+		t.Errorf("GetOutput(empty) error = %v, want ErrOutputNotFound", err)
+	}
+}
+
+func TestCleanupOldLogs_RemovesOldOutputs(t *testing.T) {
+	tmpDir := t.TempDir()
+	ul := NewUpdateLogger(slog.Default(), filepath.Join(tmpDir, "updates.jsonl"))
+	defer ul.Close()
+
+	if err := ul.RecordOutput("old", "old output\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ul.RecordOutput("new", "new output\n"); err != nil {
+		t.Fatal(err)
+	}
+	oldTime := time.Now().Add(-8 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(tmpDir, "update-output", "old.log"), oldTime, oldTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ul.CleanupOldLogs(); err != nil {
+		t.Fatalf("CleanupOldLogs failed: %v", err)
+	}
+	if _, err := ul.GetOutput("old"); !errors.Is(err, ErrOutputNotFound) {
+		t.Errorf("old output still present: %v", err)
+	}
+	if _, err := ul.GetOutput("new"); err != nil {
+		t.Errorf("new output removed: %v", err)
+	}
+}
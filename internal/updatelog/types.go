@@ -13,33 +13,133 @@ const (
 	StatusSuccess        UpdateStatus = "success"
 	StatusPartialSuccess UpdateStatus = "partial_success"
 	StatusFailed         UpdateStatus = "failed"
+	StatusRolledBack     UpdateStatus = "rolled_back" // New version failed to start, previous version reinstalled
+	StatusSkipped        UpdateStatus = "skipped"     // Already up to date, instances were not touched
+	StatusAborted        UpdateStatus = "aborted"     // A canary instance failed, remaining instances held back or rolled back
+	StatusCancelled      UpdateStatus = "cancelled"   // Cancelled between phases, nothing was installed
+	StatusRejected       UpdateStatus = "rejected"    // Refused before anything ran (e.g. another update in progress)
+	StatusTimeout        UpdateStatus = "timeout"     // Did not finish in time
+
+	// Update approval (operation update-approval); rejection reuses StatusRejected
+	StatusPendingApproval UpdateStatus = "pending_approval" // Update created, waiting for approval
+	StatusApproved        UpdateStatus = "approved"         // Approved, the nanobot-update record with the same id follows
+	StatusExpired         UpdateStatus = "expired"          // Not approved in time
+)
+
+// Operation types recorded in UpdateLog.Operation
+const (
+	OperationNanobotUpdate   = "nanobot-update"   // Stop instances, uv install, start instances
+	OperationSelfUpdate      = "self-update"      // Replace the updater binary
+	OperationInstanceRestart = "instance-restart" // Manual restart of a single instance
+	OperationInstanceStart   = "instance-start"   // Manual start of a single instance
+	OperationInstanceStop    = "instance-stop"    // Manual stop of a single instance
+	OperationUpdateApproval  = "update-approval"  // Pending update created, approved, rejected or expired (approval.enabled)
 )
 
 // InstanceUpdateDetail contains per-instance update result details
 type InstanceUpdateDetail struct {
 	Name          string `json:"name"`
 	Port          uint32 `json:"port"`
-	Status        string `json:"status"`            // "success" or "failed"
-	ErrorMessage  string `json:"error_message"`     // non-empty if failed
-	LogStartIndex int    `json:"log_start_index"`   // LogBuffer start index (Phase 33 integration)
-	LogEndIndex   int    `json:"log_end_index"`     // LogBuffer end index (Phase 33 integration)
-	StopDuration  int64  `json:"stop_duration_ms"`  // Stop operation duration in milliseconds
-	StartDuration int64  `json:"start_duration_ms"` // Start operation duration in milliseconds
+	Status        string `json:"status"`                  // "success", "failed", "rolled_back" or "held_back"; dry run: "would_restart", "would_start" or "unchanged"
+	ErrorMessage  string `json:"error_message"`           // non-empty if failed
+	PID           int32  `json:"pid,omitempty"`           // Dry run: current PID (0 if not started)
+	LogBufferID   string `json:"log_buffer_id,omitempty"` // LogBuffer the indexes refer to (logs are kept in memory only)
+	LogStartIndex int    `json:"log_start_index"`         // LogBuffer sequence number before the (first) start
+	LogEndIndex   int    `json:"log_end_index"`           // LogBuffer sequence number after the (last) start, exclusive
+	StopDuration  int64  `json:"stop_duration_ms"`        // Stop operation duration in milliseconds
+	StartDuration int64  `json:"start_duration_ms"`       // Start operation duration in milliseconds
+	Downtime      int64  `json:"downtime_ms"`             // Time the instance was not running (stop begin until started again)
 }
 
 // UpdateLog represents a complete update operation record
 type UpdateLog struct {
-	ID          string                 `json:"id"`            // UUID v4
-	StartTime   time.Time              `json:"start_time"`    // RFC 3339, UTC
-	EndTime     time.Time              `json:"end_time"`      // RFC 3339, UTC
-	Duration    int64                  `json:"duration_ms"`   // Total duration in milliseconds
-	Status      UpdateStatus           `json:"status"`        // success/partial_success/failed
-	Instances   []InstanceUpdateDetail `json:"instances"`     // Per-instance details
-	TriggeredBy string                 `json:"triggered_by"`  // "api-trigger"
+	ID              string                    `json:"id"`                          // UUID v4
+	StartTime       time.Time                 `json:"start_time"`                  // RFC 3339, UTC
+	EndTime         time.Time                 `json:"end_time"`                    // RFC 3339, UTC
+	Duration        int64                     `json:"duration_ms"`                 // Total duration in milliseconds
+	Operation       string                    `json:"operation"`                   // nanobot-update/self-update/instance-restart/instance-start/instance-stop/update-approval (older records without it are loaded as nanobot-update)
+	Status          UpdateStatus              `json:"status"`                      // success/partial_success/failed/rolled_back/skipped/aborted/cancelled/rejected/timeout; update-approval: pending_approval/approved/rejected/expired
+	Error           string                    `json:"error,omitempty"`             // Error that ended the operation (status failed/timeout/rejected)
+	RejectReason    string                    `json:"reject_reason,omitempty"`     // Why the operation was refused (status=rejected)
+	Instances       []InstanceUpdateDetail    `json:"instances"`                   // Per-instance details
+	TriggeredBy     string                    `json:"triggered_by"`                // "api-trigger" / "scheduler" / "api-self-update" / "api-instance-lifecycle" / "api-instance-restart"
+	Target          string                    `json:"target,omitempty"`            // Requested target: latest, ref:<ref>, commit:<sha>, pypi:<version>
+	InstallSpec     string                    `json:"install_spec,omitempty"`      // uv spec actually installed (e.g. git+...@v0.1.4, nanobot-ai==0.1.3)
+	Source          string                    `json:"source,omitempty"`            // Update source that succeeded (updater.sources name or location)
+	SourceType      string                    `json:"source_type,omitempty"`       // Type of that source: git, pypi, local or upload (API wheel upload)
+	SkipReason      string                    `json:"skip_reason,omitempty"`       // Why the update was skipped (status=skipped)
+	PreviousVersion string                    `json:"previous_version,omitempty"`  // Version installed before the update (rollback point)
+	RollbackError   string                    `json:"rollback_error,omitempty"`    // Non-empty if an automatic rollback was attempted and failed
+	AbortStage      string                    `json:"abort_stage,omitempty"`       // Stage at which the update was aborted ("canary", "pre_update")
+	AbortReason     string                    `json:"abort_reason,omitempty"`      // Why the update was aborted (status=aborted)
+	Channels        []*instance.ChannelResult `json:"channels,omitempty"`          // Per-install results when updater.channels is configured
+	Hooks           []*instance.HookResult    `json:"hooks,omitempty"`             // Hooks that ran (pre_update/post_install/post_start/on_failure), output is in /output
+	Strategy        string                    `json:"strategy,omitempty"`          // updater.strategy used: stop_first or staged
+	StageDuration   int64                     `json:"stage_duration_ms,omitempty"` // staged: time spent installing while the instances kept running
+	Downtime        int64                     `json:"downtime_ms,omitempty"`       // Longest instance downtime of the update (see instances[].downtime_ms)
+	RepoCommit      string                    `json:"repo_commit,omitempty"`       // HEAD of updater.repo_path after the post-install sync
+	RepoSubject     string                    `json:"repo_subject,omitempty"`      // Subject of that commit
+	RepoSyncError   string                    `json:"repo_sync_error,omitempty"`   // Why the repo sync failed (the update itself is unaffected)
+	Approval        *ApprovalInfo             `json:"approval,omitempty"`          // Pending update details (operation=update-approval)
+}
+
+// ApprovalInfo describes a pending update in update-approval records
+type ApprovalInfo struct {
+	NewVersion   string    `json:"new_version,omitempty"`   // Version or commit the update would install
+	ChangelogURL string    `json:"changelog_url,omitempty"` // What the update changes
+	ExpiresAt    time.Time `json:"expires_at"`              // When the pending update expires
+	DecidedBy    string    `json:"decided_by,omitempty"`    // Who approved or rejected it ("api", "link")
+}
+
+// BuildUpdateLog creates the UpdateLog record for a completed update.
+// triggeredBy identifies the caller ("api-trigger", "scheduler").
+func BuildUpdateLog(id string, startTime, endTime time.Time, triggeredBy string, result *instance.UpdateResult) UpdateLog {
+	log := UpdateLog{
+		ID:              id,
+		StartTime:       startTime,
+		EndTime:         endTime,
+		Duration:        endTime.Sub(startTime).Milliseconds(),
+		Operation:       OperationNanobotUpdate,
+		Status:          DetermineStatus(result),
+		Instances:       BuildInstanceDetails(result),
+		TriggeredBy:     triggeredBy,
+		Target:          result.Target,
+		InstallSpec:     result.InstallSpec,
+		Source:          result.Source,
+		SourceType:      result.SourceType,
+		SkipReason:      result.SkipReason,
+		PreviousVersion: result.PreviousVersion,
+		RollbackError:   result.RollbackError,
+		AbortStage:      result.AbortStage,
+		AbortReason:     result.AbortReason,
+		Channels:        result.Channels,
+		Hooks:           result.Hooks,
+		Strategy:        result.Strategy,
+		StageDuration:   result.StageDuration,
+		Downtime:        result.MaxDowntime(),
+		RepoSyncError:   result.RepoSyncError,
+	}
+	if result.Repo != nil {
+		log.RepoCommit = result.Repo.Commit
+		log.RepoSubject = result.Repo.Subject
+	}
+	return log
 }
 
 // DetermineStatus determines the overall update status based on UpdateResult
 func DetermineStatus(result *instance.UpdateResult) UpdateStatus {
+	if result.Cancelled {
+		return StatusCancelled
+	}
+	if result.Aborted {
+		return StatusAborted
+	}
+	if result.RolledBack {
+		return StatusRolledBack
+	}
+	if result.Skipped {
+		return StatusSkipped
+	}
 	if result.HasErrors() {
 		if len(result.Started) > 0 || len(result.Stopped) > 0 {
 			return StatusPartialSuccess
@@ -50,9 +150,12 @@ func DetermineStatus(result *instance.UpdateResult) UpdateStatus {
 }
 
 // BuildInstanceDetails creates InstanceUpdateDetail slice from UpdateResult.
-// For Phase 30, LogStartIndex and LogEndIndex are set to 0 (Phase 33 integration).
-// For Phase 30, StopDuration and StartDuration are set to 0 (Phase 33 integration).
+// Port, durations, downtime and log range come from result.Timings.
 func BuildInstanceDetails(result *instance.UpdateResult) []InstanceUpdateDetail {
+	if result.DryRun {
+		return buildPlanDetails(result)
+	}
+
 	details := []InstanceUpdateDetail{}
 
 	// Track which instances have been added to avoid duplicates
@@ -84,6 +187,31 @@ func BuildInstanceDetails(result *instance.UpdateResult) []InstanceUpdateDetail
 		added[err.InstanceName] = true
 	}
 
+	// Add instances that failed on the new version but started again after rollback
+	for _, err := range result.FailedAfterUpdate {
+		if added[err.InstanceName] {
+			continue
+		}
+		details = append(details, InstanceUpdateDetail{
+			Name:         err.InstanceName,
+			Port:         err.Port,
+			Status:       "rolled_back",
+			ErrorMessage: err.Error(),
+		})
+		added[err.InstanceName] = true
+	}
+
+	// Add instances that were stopped but not started again because a canary failed
+	for _, name := range result.HeldBack {
+		if !added[name] {
+			details = append(details, InstanceUpdateDetail{
+				Name:   name,
+				Status: "held_back",
+			})
+			added[name] = true
+		}
+	}
+
 	// Add successful instances from Stopped
 	for _, name := range result.Stopped {
 		if !added[name] {
@@ -106,5 +234,46 @@ func BuildInstanceDetails(result *instance.UpdateResult) []InstanceUpdateDetail
 		}
 	}
 
+	for i := range details {
+		applyTiming(&details[i], result.Timing(details[i].Name))
+	}
+	return details
+}
+
+// applyTiming copies the measured durations and log range of an instance into its detail
+func applyTiming(detail *InstanceUpdateDetail, t *instance.InstanceTiming) {
+	if t == nil {
+		return
+	}
+	if detail.Port == 0 {
+		detail.Port = t.Port
+	}
+	detail.StopDuration = t.StopDuration
+	detail.StartDuration = t.StartDuration
+	detail.Downtime = t.Downtime
+	detail.LogBufferID = t.LogBufferID
+	detail.LogStartIndex = int(t.LogStartSeq)
+	detail.LogEndIndex = int(t.LogEndSeq)
+}
+
+// buildPlanDetails creates InstanceUpdateDetail slice for a dry run (InstanceManager.Plan):
+// running instances would be stopped and started again, stopped ones would only be started.
+func buildPlanDetails(result *instance.UpdateResult) []InstanceUpdateDetail {
+	details := make([]InstanceUpdateDetail, 0, len(result.Instances))
+	for _, inst := range result.Instances {
+		status := "unchanged"
+		if !result.Skipped {
+			status = "would_start"
+			if inst.PID != 0 {
+				status = "would_restart"
+			}
+		}
+		details = append(details, InstanceUpdateDetail{
+			Name:   inst.Name,
+			Port:   inst.Port,
+			Status: status,
+			PID:    inst.PID,
+		})
+	}
 	return details
 }
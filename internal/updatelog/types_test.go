@@ -1,10 +1,12 @@
 package updatelog
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updater"
 )
 
 func TestUpdateLogStruct(t *testing.T) {
@@ -164,6 +166,38 @@ func TestDetermineStatus(t *testing.T) {
 			},
 			expected: StatusPartialSuccess,
 		},
+		{
+			name: "rolled back after new version failed to start",
+			result: &instance.UpdateResult{
+				Stopped:    []string{"gateway"},
+				Started:    []string{"gateway"},
+				RolledBack: true,
+				FailedAfterUpdate: []*instance.InstanceError{
+					{InstanceName: "gateway", Operation: "start", Port: 18790, Err: nil},
+				},
+			},
+			expected: StatusRolledBack,
+		},
+		{
+			name: "skipped because already up to date",
+			result: &instance.UpdateResult{
+				Skipped:    true,
+				SkipReason: "installed version 0.1.4 is current",
+			},
+			expected: StatusSkipped,
+		},
+		{
+			name: "aborted at canary stage",
+			result: &instance.UpdateResult{
+				Stopped:     []string{"canary", "gateway"},
+				Started:     []string{},
+				Aborted:     true,
+				AbortStage:  instance.AbortStageCanary,
+				AbortReason: "canary canary unhealthy during soak period: process exited",
+				HeldBack:    []string{"gateway"},
+			},
+			expected: StatusAborted,
+		},
 	}
 
 	for _, tt := range tests {
@@ -226,4 +260,109 @@ func TestBuildInstanceDetails(t *testing.T) {
 			t.Error("Expected failed instance detail for 'worker'")
 		}
 	})
+
+	t.Run("held back after canary failure", func(t *testing.T) {
+		result := &instance.UpdateResult{
+			Stopped: []string{"canary", "gateway"},
+			StartFailed: []*instance.InstanceError{
+				{InstanceName: "canary", Operation: "canary", Port: 18790, Err: errors.New("process exited")},
+			},
+			Aborted:  true,
+			HeldBack: []string{"gateway"},
+		}
+		details := BuildInstanceDetails(result)
+		if len(details) != 2 {
+			t.Fatalf("Expected 2 details, got %d", len(details))
+		}
+		if details[0].Name != "canary" || details[0].Status != "failed" {
+			t.Errorf("canary detail = %+v, want failed", details[0])
+		}
+		if details[1].Name != "gateway" || details[1].Status != "held_back" {
+			t.Errorf("gateway detail = %+v, want held_back", details[1])
+		}
+	})
+
+	t.Run("timings", func(t *testing.T) {
+		result := &instance.UpdateResult{
+			Stopped: []string{"gateway"},
+			Started: []string{"gateway"},
+			Timings: []*instance.InstanceTiming{{
+				Name: "gateway", Port: 18790, StopDuration: 120, StartDuration: 2100, Downtime: 5400,
+				LogBufferID: "buf", LogStartSeq: 7, LogEndSeq: 12,
+			}},
+		}
+		details := BuildInstanceDetails(result)
+		if len(details) != 1 {
+			t.Fatalf("Expected 1 detail, got %d", len(details))
+		}
+		d := details[0]
+		if d.Port != 18790 || d.StopDuration != 120 || d.StartDuration != 2100 || d.Downtime != 5400 {
+			t.Errorf("detail = %+v, want port, durations and downtime from the timing", d)
+		}
+		if d.LogBufferID != "buf" || d.LogStartIndex != 7 || d.LogEndIndex != 12 {
+			t.Errorf("detail log range = %s [%d, %d), want buf [7, 12)", d.LogBufferID, d.LogStartIndex, d.LogEndIndex)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		result := &instance.UpdateResult{
+			DryRun: true,
+			Instances: []instance.InstanceStatusInfo{
+				{Name: "gateway", Port: 18790, Running: true, PID: 4242},
+				{Name: "worker", Port: 18791},
+			},
+		}
+		details := BuildInstanceDetails(result)
+		if len(details) != 2 {
+			t.Fatalf("Expected 2 details, got %d", len(details))
+		}
+		if d := details[0]; d.Status != "would_restart" || d.PID != 4242 || d.Port != 18790 {
+			t.Errorf("gateway detail = %+v, want would_restart with PID 4242", d)
+		}
+		if d := details[1]; d.Status != "would_start" || d.PID != 0 {
+			t.Errorf("worker detail = %+v, want would_start without PID", d)
+		}
+
+		result.Skipped = true
+		for _, d := range BuildInstanceDetails(result) {
+			if d.Status != "unchanged" {
+				t.Errorf("%s status = %q when skipped, want unchanged", d.Name, d.Status)
+			}
+		}
+	})
+}
+
+func TestBuildUpdateLog_Repo(t *testing.T) {
+	start := time.Now().UTC()
+	result := &instance.UpdateResult{
+		Repo: &updater.RepoStatus{Path: "/opt/nanobot", Branch: "main", Commit: "2222222222222222222222222222222222222222", Subject: "Release 0.2.0"},
+	}
+	log := BuildUpdateLog("id-1", start, start.Add(time.Second), "scheduler", result)
+	if log.RepoCommit != result.Repo.Commit || log.RepoSubject != "Release 0.2.0" || log.RepoSyncError != "" {
+		t.Errorf("log repo = %q %q %q, want the synced HEAD", log.RepoCommit, log.RepoSubject, log.RepoSyncError)
+	}
+
+	// A failed sync is recorded without the HEAD
+	result = &instance.UpdateResult{RepoSyncError: "git pull failed: exit status 1"}
+	log = BuildUpdateLog("id-2", start, start.Add(time.Second), "scheduler", result)
+	if log.RepoCommit != "" || log.RepoSyncError != result.RepoSyncError || log.Status != StatusSuccess {
+		t.Errorf("log = %+v, want a successful update with the sync error", log)
+	}
+}
+
+func TestBuildUpdateLog_Strategy(t *testing.T) {
+	start := time.Now().UTC()
+	result := &instance.UpdateResult{
+		Strategy:      "staged",
+		StageDuration: 95000,
+		Timings: []*instance.InstanceTiming{
+			{Name: "gateway", Downtime: 2100},
+			{Name: "worker", Downtime: 3400},
+		},
+	}
+	log := BuildUpdateLog("id-1", start, start.Add(time.Minute), "scheduler", result)
+	if log.Strategy != "staged" || log.StageDuration != 95000 || log.Downtime != 3400 {
+		t.Errorf("log strategy = %q stage = %d downtime = %d, want staged, 95000 and the longest downtime 3400",
+			log.Strategy, log.StageDuration, log.Downtime)
+	}
 }
@@ -0,0 +1,419 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPyPIJSONURL is the base of the PyPI JSON API (<base>/<package>/json)
+	DefaultPyPIJSONURL = "https://pypi.org/pypi"
+	// checkHTTPTimeout bounds each PyPI / git remote request made by Check
+	checkHTTPTimeout = 15 * time.Second
+	userAgent        = "nanobot-auto-updater"
+)
+
+// CheckResult compares the installed nanobot with what an update would install
+type CheckResult struct {
+	Target           string     `json:"target"`                     // Requested target (latest, ref:..., commit:..., pypi:...)
+	Source           string     `json:"source"`                     // Source an update would install from (first supporting source)
+	SourceType       SourceType `json:"source_type"`                // git, pypi, local or upload
+	InstalledVersion string     `json:"installed_version"`          // Version reported by `uv tool list` ("" = not installed)
+	InstalledCommit  string     `json:"installed_commit,omitempty"` // Commit recorded in direct_url.json for git installs
+	LatestVersion    string     `json:"latest_version,omitempty"`   // Latest PyPI release (or the pinned pypi_version)
+	LatestCommit     string     `json:"latest_commit,omitempty"`    // HEAD/ref commit of the configured git source (or the pinned commit)
+	UpdateAvailable  bool       `json:"update_available"`           // An update would change the installed nanobot
+	Reason           string     `json:"reason"`                     // Why an update is or is not needed
+	ChangelogURL     string     `json:"changelog_url,omitempty"`    // GitHub compare/commit page or PyPI release page of the update
+	Errors           []string   `json:"errors,omitempty"`           // Non-fatal lookup failures (network, uv)
+}
+
+// SetPyPIJSONURL overrides the PyPI JSON API base URL (used by tests and private indexes).
+// Note: This method is not thread-safe and should only be called during initialization.
+func (u *Updater) SetPyPIJSONURL(url string) {
+	u.pypiJSONURL = strings.TrimSuffix(url, "/")
+}
+
+// Check reports whether installing target would change the installed nanobot.
+// The decision is made against the source an update would actually use: git sources compare
+// the installed commit with the remote ref, PyPI sources compare versions, local and uploaded
+// files are always considered new. When the upstream state cannot be determined an update is reported
+// as available, so callers never skip an update because of a network error.
+func (u *Updater) Check(ctx context.Context, target Target) (*CheckResult, error) {
+	candidates, err := u.candidates(target)
+	if err != nil {
+		return nil, err
+	}
+	source := candidates[0].source
+
+	result := &CheckResult{
+		Target:     target.String(),
+		Source:     source.Label(),
+		SourceType: source.Type,
+	}
+
+	installedVersion, err := u.InstalledVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.InstalledVersion = installedVersion
+
+	installedURL, installedCommit, err := u.installedOrigin(ctx)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.InstalledCommit = installedCommit
+
+	// Latest PyPI release and git commit, reported for information even when not used for the decision
+	if target.PyPIVersion != "" {
+		result.LatestVersion = target.PyPIVersion
+	} else if target.IsLatest() {
+		if version, err := u.LatestPyPIVersion(ctx, u.pypiPackage); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.LatestVersion = version
+		}
+	}
+
+	gitSource, hasGit := u.firstSource(SourceGit)
+	if target.Commit != "" {
+		result.LatestCommit = target.Commit
+	} else if hasGit && target.PyPIVersion == "" && target.File == "" {
+		ref := gitSource.Ref
+		if target.Ref != "" {
+			ref = target.Ref
+		}
+		if commit, err := u.RemoteCommit(ctx, gitSource.URL, ref); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.LatestCommit = commit
+		}
+	}
+
+	switch {
+	case installedVersion == "":
+		result.UpdateAvailable, result.Reason = true, "nanobot is not installed"
+	case source.Type == SourceGit:
+		switch {
+		case result.LatestCommit == "":
+			result.UpdateAvailable, result.Reason = true, "unable to resolve the remote commit"
+		case installedCommit == "":
+			result.UpdateAvailable, result.Reason = true, "installed nanobot was not installed from git"
+		case !sameRepo(installedURL, source.URL):
+			result.UpdateAvailable, result.Reason = true, fmt.Sprintf("installed from a different repository (%s)", installedURL)
+		case sameCommit(installedCommit, result.LatestCommit):
+			result.Reason = fmt.Sprintf("installed commit %s is current", shortCommit(installedCommit))
+		default:
+			result.UpdateAvailable = true
+			result.Reason = fmt.Sprintf("new commit %s (installed %s)", shortCommit(result.LatestCommit), shortCommit(installedCommit))
+		}
+	case source.Type == SourcePyPI:
+		switch {
+		case result.LatestVersion == "":
+			result.UpdateAvailable, result.Reason = true, "unable to determine the latest PyPI version"
+		case installedCommit != "":
+			// A git build can carry the same version string as a release, reinstall from PyPI
+			result.UpdateAvailable, result.Reason = true, "installed nanobot is a git build"
+		case installedVersion == result.LatestVersion:
+			result.Reason = fmt.Sprintf("installed version %s is current", installedVersion)
+		default:
+			result.UpdateAvailable = true
+			result.Reason = fmt.Sprintf("new version %s (installed %s)", result.LatestVersion, installedVersion)
+		}
+	default:
+		result.UpdateAvailable, result.Reason = true, fmt.Sprintf("%s sources are always reinstalled", source.Type)
+	}
+
+	if result.UpdateAvailable {
+		result.ChangelogURL = u.changelogURL(source, installedURL, result)
+	}
+
+	u.logger.Info("Checked nanobot update",
+		"target", result.Target,
+		"source", result.Source,
+		"installed_version", result.InstalledVersion,
+		"installed_commit", result.InstalledCommit,
+		"latest_version", result.LatestVersion,
+		"latest_commit", result.LatestCommit,
+		"update_available", result.UpdateAvailable,
+		"reason", result.Reason)
+	return result, nil
+}
+
+// changelogURL returns a page describing what the update changes: the GitHub compare view
+// (or the new commit) for GitHub git sources, the release page for pypi.org. "" otherwise.
+func (u *Updater) changelogURL(source Source, installedURL string, result *CheckResult) string {
+	switch source.Type {
+	case SourceGit:
+		repo, ok := githubRepoURL(source.URL)
+		if !ok || result.LatestCommit == "" {
+			return ""
+		}
+		if result.InstalledCommit != "" && sameRepo(installedURL, source.URL) {
+			return fmt.Sprintf("%s/compare/%s...%s", repo, result.InstalledCommit, result.LatestCommit)
+		}
+		return fmt.Sprintf("%s/commit/%s", repo, result.LatestCommit)
+	case SourcePyPI:
+		if result.LatestVersion == "" || source.IndexURL != "" {
+			return ""
+		}
+		return fmt.Sprintf("https://pypi.org/project/%s/%s/", source.packageName(), result.LatestVersion)
+	}
+	return ""
+}
+
+// githubRepoURL returns https://github.com/<owner>/<repo> for a GitHub repository URL
+func githubRepoURL(url string) (string, bool) {
+	url = strings.TrimPrefix(url, "git+")
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	rest, ok := strings.CutPrefix(url, "https://github.com/")
+	if !ok {
+		return "", false
+	}
+	if parts := strings.Split(rest, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return url, true
+}
+
+// firstSource returns the first configured source of type t
+func (u *Updater) firstSource(t SourceType) (Source, bool) {
+	for _, s := range u.sources {
+		if s.Type == t {
+			return s, true
+		}
+	}
+	return Source{}, false
+}
+
+// LatestPyPIVersion returns the latest release of pkg from the PyPI JSON API
+func (u *Updater) LatestPyPIVersion(ctx context.Context, pkg string) (string, error) {
+	url := fmt.Sprintf("%s/%s/json", u.pypiJSONURL, pkg)
+	body, err := u.httpGet(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("PyPI request failed: %w", err)
+	}
+
+	var payload struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", fmt.Errorf("decode PyPI JSON: %w", err)
+	}
+	if payload.Info.Version == "" {
+		return "", fmt.Errorf("PyPI response for %s has no version", pkg)
+	}
+	return payload.Info.Version, nil
+}
+
+// RemoteCommit resolves ref ("" = HEAD) on the git remote at url.
+// HTTP(S) remotes are queried with the smart HTTP protocol so git does not need to be installed;
+// other remotes (ssh, file) fall back to `git ls-remote`.
+func (u *Updater) RemoteCommit(ctx context.Context, url, ref string) (string, error) {
+	url = strings.TrimPrefix(url, "git+")
+
+	var refs map[string]string
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		body, err := u.httpGet(ctx, strings.TrimSuffix(url, "/")+"/info/refs?service=git-upload-pack")
+		if err != nil {
+			return "", fmt.Errorf("git remote request failed: %w", err)
+		}
+		refs, err = parseAdvertisedRefs(body)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		output, err := u.runCommand(ctx, "git", "ls-remote", url)
+		if err != nil {
+			return "", fmt.Errorf("git ls-remote failed: %w (output: %s)", err, truncateOutput(output))
+		}
+		refs = parseLsRemote(output)
+	}
+
+	if commit, ok := resolveRef(refs, ref); ok {
+		return commit, nil
+	}
+	if commitPattern.MatchString(ref) {
+		// A pinned commit is not advertised as a ref
+		return ref, nil
+	}
+	return "", fmt.Errorf("ref %q not found on %s", ref, url)
+}
+
+// httpGet performs a GET request and returns the body of a 200 response
+func (u *Updater) httpGet(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, checkHTTPTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// parseAdvertisedRefs parses a git smart HTTP ref advertisement (pkt-line format)
+// into a ref name -> commit map
+func parseAdvertisedRefs(body []byte) (map[string]string, error) {
+	refs := make(map[string]string)
+	for len(body) > 0 {
+		if len(body) < 4 {
+			return nil, fmt.Errorf("malformed git ref advertisement")
+		}
+		n, err := strconv.ParseUint(string(body[:4]), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("malformed git ref advertisement: %w", err)
+		}
+		if n == 0 {
+			// flush-pkt
+			body = body[4:]
+			continue
+		}
+		if n < 4 || int(n) > len(body) {
+			return nil, fmt.Errorf("malformed git ref advertisement")
+		}
+		line := strings.TrimSuffix(string(body[4:n]), "\n")
+		body = body[n:]
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		// The first ref carries the capability list after a NUL byte
+		if i := strings.IndexByte(line, 0); i >= 0 {
+			line = line[:i]
+		}
+		if fields := strings.Fields(line); len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+	return refs, nil
+}
+
+// parseLsRemote parses `git ls-remote` output ("<commit>\t<ref>" per line)
+func parseLsRemote(output string) map[string]string {
+	refs := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+	return refs
+}
+
+// resolveRef finds ref in an advertised ref map, preferring the peeled commit of annotated tags
+func resolveRef(refs map[string]string, ref string) (string, bool) {
+	if ref == "" || ref == "HEAD" {
+		commit, ok := refs["HEAD"]
+		return commit, ok
+	}
+	for _, name := range []string{"refs/tags/" + ref + "^{}", "refs/tags/" + ref, "refs/heads/" + ref, ref} {
+		if commit, ok := refs[name]; ok {
+			return commit, true
+		}
+	}
+	return "", false
+}
+
+// installedOrigin returns the repository URL and commit of a git install, read from the
+// PEP 610 direct_url.json that uv writes into the tool environment.
+// Returns empty strings without error for non-git installs or when nanobot is not installed.
+func (u *Updater) installedOrigin(ctx context.Context) (string, string, error) {
+	output, err := u.runCommand(ctx, "uv", "tool", "dir")
+	if err != nil {
+		return "", "", fmt.Errorf("uv tool dir failed: %w (output: %s)", err, truncateOutput(output))
+	}
+	toolDir := strings.TrimSpace(output)
+
+	distInfo := strings.ReplaceAll(u.pypiPackage, "-", "_") + "-*.dist-info"
+	patterns := []string{
+		filepath.Join(toolDir, u.pypiPackage, "lib", "python*", "site-packages", distInfo, "direct_url.json"),
+		filepath.Join(toolDir, u.pypiPackage, "Lib", "site-packages", distInfo, "direct_url.json"), // Windows venv layout
+	}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		if len(matches) == 0 {
+			continue
+		}
+		data, err := os.ReadFile(matches[0])
+		if err != nil {
+			return "", "", fmt.Errorf("read direct_url.json: %w", err)
+		}
+		url, commit := parseDirectURL(data)
+		return url, commit, nil
+	}
+	return "", "", nil
+}
+
+// parseDirectURL extracts the VCS URL and commit from a direct_url.json document
+func parseDirectURL(data []byte) (string, string) {
+	var d struct {
+		URL     string `json:"url"`
+		VCSInfo *struct {
+			VCS      string `json:"vcs"`
+			CommitID string `json:"commit_id"`
+		} `json:"vcs_info"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &d); err != nil || d.VCSInfo == nil || d.VCSInfo.VCS != "git" {
+		return "", ""
+	}
+	return d.URL, d.VCSInfo.CommitID
+}
+
+// sameCommit compares two commit hashes, allowing either to be abbreviated
+func sameCommit(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if len(a) < 7 || len(b) < 7 {
+		return false
+	}
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// sameRepo compares repository URLs ignoring the git+ prefix, trailing slash and .git suffix.
+// An unknown installed URL is treated as the same repository.
+func sameRepo(installed, configured string) bool {
+	if installed == "" {
+		return true
+	}
+	normalize := func(s string) string {
+		s = strings.TrimPrefix(s, "git+")
+		s = strings.TrimSuffix(s, "/")
+		return strings.ToLower(strings.TrimSuffix(s, ".git"))
+	}
+	return normalize(installed) == normalize(configured)
+}
+
+// shortCommit abbreviates a commit hash for messages
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,175 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testHeadCommit = "1111111111111111111111111111111111111111"
+	testTagCommit  = "2222222222222222222222222222222222222222"
+	testPeeled     = "3333333333333333333333333333333333333333"
+)
+
+// pktLine encodes s as a git pkt-line
+func pktLine(s string) string {
+	return fmt.Sprintf("%04x%s", len(s)+4, s)
+}
+
+// newFakeUpstream serves a PyPI JSON API under /pypi and a git smart HTTP ref
+// advertisement for /nanobot.git
+func newFakeUpstream(t *testing.T, pypiVersion string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pypi/nanobot-ai/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"info":{"name":"nanobot-ai","version":%q},"releases":{}}`, pypiVersion)
+	})
+	mux.HandleFunc("GET /nanobot.git/info/refs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("service") != "git-upload-pack" {
+			http.Error(w, "dumb protocol not supported", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		io.WriteString(w, pktLine("# service=git-upload-pack\n")+"0000"+
+			pktLine(testHeadCommit+" HEAD\x00multi_ack symref=HEAD:refs/heads/main\n")+
+			pktLine(testHeadCommit+" refs/heads/main\n")+
+			pktLine(testTagCommit+" refs/heads/release/0.2\n")+
+			pktLine(testTagCommit+" refs/tags/v0.1.4\n")+
+			pktLine(testPeeled+" refs/tags/v0.1.4^{}\n")+
+			"0000")
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLatestPyPIVersion(t *testing.T) {
+	server := newFakeUpstream(t, "0.2.1")
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetPyPIJSONURL(server.URL + "/pypi/")
+
+	version, err := u.LatestPyPIVersion(context.Background(), "nanobot-ai")
+	if err != nil {
+		t.Fatalf("LatestPyPIVersion() error: %v", err)
+	}
+	if version != "0.2.1" {
+		t.Errorf("LatestPyPIVersion() = %q, want %q", version, "0.2.1")
+	}
+
+	if _, err := u.LatestPyPIVersion(context.Background(), "unknown-package"); err == nil {
+		t.Error("LatestPyPIVersion() should fail for a 404 response")
+	}
+}
+
+func TestRemoteCommit(t *testing.T) {
+	server := newFakeUpstream(t, "0.2.1")
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	url := "git+" + server.URL + "/nanobot.git"
+
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{"", testHeadCommit},
+		{"main", testHeadCommit},
+		{"release/0.2", testTagCommit},
+		{"v0.1.4", testPeeled}, // annotated tag resolves to the peeled commit
+		{"abcdef1", "abcdef1"}, // commits are not advertised, returned as-is
+	}
+	for _, tt := range tests {
+		commit, err := u.RemoteCommit(context.Background(), url, tt.ref)
+		if err != nil {
+			t.Errorf("RemoteCommit(%q) error: %v", tt.ref, err)
+			continue
+		}
+		if commit != tt.expected {
+			t.Errorf("RemoteCommit(%q) = %q, want %q", tt.ref, commit, tt.expected)
+		}
+	}
+
+	if _, err := u.RemoteCommit(context.Background(), url, "no-such-branch"); err == nil {
+		t.Error("RemoteCommit() should fail for an unknown ref")
+	}
+}
+
+func TestParseAdvertisedRefs_Malformed(t *testing.T) {
+	for _, body := range []string{"zz", "00ffshort", "0003"} {
+		if _, err := parseAdvertisedRefs([]byte(body)); err == nil {
+			t.Errorf("parseAdvertisedRefs(%q) should fail", body)
+		}
+	}
+}
+
+func TestParseDirectURL(t *testing.T) {
+	url, commit := parseDirectURL([]byte(`{"url":"https://github.com/HKUDS/nanobot.git","vcs_info":{"vcs":"git","requested_revision":"main","commit_id":"` + testHeadCommit + `"}}`))
+	if url != "https://github.com/HKUDS/nanobot.git" || commit != testHeadCommit {
+		t.Errorf("parseDirectURL() = (%q, %q)", url, commit)
+	}
+
+	// PyPI installs have no direct_url.json; local wheels have archive_info instead of vcs_info
+	if url, commit := parseDirectURL([]byte(`{"url":"file:///wheels/nanobot.whl","archive_info":{}}`)); url != "" || commit != "" {
+		t.Errorf("parseDirectURL(archive) = (%q, %q), want empty", url, commit)
+	}
+}
+
+func TestSameCommitAndRepo(t *testing.T) {
+	if !sameCommit(testHeadCommit, "1111111") || !sameCommit("1111111", testHeadCommit) {
+		t.Error("sameCommit() should accept abbreviated hashes")
+	}
+	if sameCommit(testHeadCommit, testTagCommit) || sameCommit("", "") {
+		t.Error("sameCommit() should reject different or empty hashes")
+	}
+	if !sameRepo("https://github.com/HKUDS/nanobot.git", "git+https://github.com/HKUDS/nanobot") {
+		t.Error("sameRepo() should ignore git+ prefix and .git suffix")
+	}
+	if sameRepo("https://github.com/fork/nanobot.git", DefaultGitURL) {
+		t.Error("sameRepo() should detect a different repository")
+	}
+	if !strings.HasPrefix(shortCommit(testHeadCommit), "1111111") || len(shortCommit(testHeadCommit)) != 7 {
+		t.Errorf("shortCommit() = %q", shortCommit(testHeadCommit))
+	}
+}
+
+func TestChangelogURL(t *testing.T) {
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	github := Source{Type: SourceGit, URL: "git+https://github.com/HKUDS/nanobot.git"}
+
+	tests := []struct {
+		name      string
+		source    Source
+		installed string
+		result    CheckResult
+		expected  string
+	}{
+		{"compare", github, "https://github.com/HKUDS/nanobot.git",
+			CheckResult{InstalledCommit: testTagCommit, LatestCommit: testHeadCommit},
+			"https://github.com/HKUDS/nanobot/compare/" + testTagCommit + "..." + testHeadCommit},
+		{"installed from pypi", github, "",
+			CheckResult{LatestCommit: testHeadCommit},
+			"https://github.com/HKUDS/nanobot/commit/" + testHeadCommit},
+		{"installed from a fork", github, "https://github.com/fork/nanobot.git",
+			CheckResult{InstalledCommit: testTagCommit, LatestCommit: testHeadCommit},
+			"https://github.com/HKUDS/nanobot/commit/" + testHeadCommit},
+		{"not github", Source{Type: SourceGit, URL: "https://gitee.com/mirror/nanobot.git"}, "",
+			CheckResult{LatestCommit: testHeadCommit}, ""},
+		{"pypi", Source{Type: SourcePyPI}, "",
+			CheckResult{LatestVersion: "0.2.1"}, "https://pypi.org/project/nanobot-ai/0.2.1/"},
+		{"pypi mirror", Source{Type: SourcePyPI, IndexURL: "https://mirrors.example.com/simple"}, "",
+			CheckResult{LatestVersion: "0.2.1"}, ""},
+		{"local", Source{Type: SourceLocal, Path: "/wheels/nanobot.whl"}, "", CheckResult{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := u.changelogURL(tt.source, tt.installed, &tt.result); got != tt.expected {
+				t.Errorf("changelogURL() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
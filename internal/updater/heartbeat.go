@@ -0,0 +1,56 @@
+package updater
+
+import (
+	"sync"
+	"time"
+)
+
+// heartbeatInterval is how often UpdateTo reports that an install is still running.
+// Variable so tests can shorten it.
+var heartbeatInterval = 10 * time.Second
+
+// Heartbeat is reported periodically while UpdateTo is installing
+type Heartbeat struct {
+	Time        time.Time `json:"time"`
+	ElapsedMs   int64     `json:"elapsed_ms"`   // Time since UpdateTo started
+	Target      string    `json:"target"`       // Requested target (latest, ref:..., commit:..., pypi:...)
+	Source      string    `json:"source"`       // Source of the running attempt (Source.Label)
+	Attempt     int       `json:"attempt"`      // Attempt number on that source, starting at 1
+	MaxAttempts int       `json:"max_attempts"` // Retries+1 of that source
+}
+
+// SetHeartbeat registers fn to receive the install heartbeats of UpdateTo (besides the log message).
+// Note: This method is not thread-safe and should only be called during initialization
+// before any concurrent access to the Updater instance.
+func (u *Updater) SetHeartbeat(fn func(Heartbeat)) {
+	u.heartbeat = fn
+}
+
+// installProgress tracks the running install attempt for the heartbeat goroutine
+type installProgress struct {
+	mu          sync.Mutex
+	source      string
+	attempt     int
+	maxAttempts int
+}
+
+func (p *installProgress) set(source string, attempt, maxAttempts int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.source, p.attempt, p.maxAttempts = source, attempt, maxAttempts
+}
+
+// heartbeatAt builds the heartbeat for the given start time and target
+func (p *installProgress) heartbeatAt(start time.Time, target string) Heartbeat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	return Heartbeat{
+		Time:        now.UTC(),
+		ElapsedMs:   now.Sub(start).Milliseconds(),
+		Target:      target,
+		Source:      p.source,
+		Attempt:     p.attempt,
+		MaxAttempts: p.maxAttempts,
+	}
+}
@@ -0,0 +1,124 @@
+package updater
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
+)
+
+// OutputWriter receives the output of uv/git commands line by line.
+// Satisfied by *logbuffer.LogBuffer via duck typing.
+type OutputWriter interface {
+	Write(entry logbuffer.LogEntry) error
+}
+
+// SetOutput streams the full stdout/stderr of every uv/git command to w, one entry per line,
+// preceded by a "$ <command>" line. Log messages keep the truncated output.
+// Note: This method is not thread-safe and should only be called during initialization
+// before any concurrent access to the Updater instance.
+func (u *Updater) SetOutput(w OutputWriter) {
+	u.output = w
+}
+
+// writeOutput writes a single line to the output stream, if one is configured
+func (u *Updater) writeOutput(source, content string) {
+	if u.output == nil {
+		return
+	}
+	_ = u.output.Write(logbuffer.LogEntry{
+		Timestamp: time.Now(),
+		Source:    source,
+		Content:   content,
+	})
+}
+
+// LineWriter splits written bytes into lines and forwards each complete line to an OutputWriter.
+// Call Flush after the command exits to forward a trailing line without newline.
+// Also used for the output of update hooks.
+type LineWriter struct {
+	out     OutputWriter
+	source  string
+	pending []byte
+}
+
+// NewLineWriter returns a LineWriter forwarding to out with the given source (stdout/stderr)
+func NewLineWriter(out OutputWriter, source string) *LineWriter {
+	return &LineWriter{out: out, source: source}
+}
+
+// Write implements io.Writer
+func (lw *LineWriter) Write(p []byte) (int, error) {
+	lw.pending = append(lw.pending, p...)
+	for {
+		i := bytes.IndexByte(lw.pending, '\n')
+		if i < 0 {
+			break
+		}
+		lw.emit(lw.pending[:i])
+		lw.pending = lw.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush forwards the remaining partial line
+func (lw *LineWriter) Flush() {
+	if len(lw.pending) > 0 {
+		lw.emit(lw.pending)
+		lw.pending = nil
+	}
+}
+
+func (lw *LineWriter) emit(line []byte) {
+	_ = lw.out.Write(logbuffer.LogEntry{
+		Timestamp: time.Now(),
+		Source:    lw.source,
+		Content:   strings.TrimRight(string(line), "\r"),
+	})
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes from the stdout and stderr copiers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// commandWriters returns the stdout/stderr writers of a command: both collect into combined,
+// and are streamed line by line to the output stream when one is configured.
+// The returned flush function must be called after the command exits.
+func (u *Updater) commandWriters(combined io.Writer) (stdout, stderr io.Writer, flush func()) {
+	if u.output == nil {
+		return combined, combined, func() {}
+	}
+	outLines := NewLineWriter(u.output, "stdout")
+	errLines := NewLineWriter(u.output, "stderr")
+	return io.MultiWriter(combined, outLines), io.MultiWriter(combined, errLines), func() {
+		outLines.Flush()
+		errLines.Flush()
+	}
+}
+
+// commandLine formats a command for the "$ <command>" header line
+func commandLine(dir, name string, args ...string) string {
+	line := "$ " + strings.TrimSpace(name+" "+strings.Join(args, " "))
+	if dir != "" {
+		line = fmt.Sprintf("%s (in %s)", line, dir)
+	}
+	return line
+}
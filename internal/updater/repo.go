@@ -0,0 +1,64 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrRepoNotConfigured is returned by RepoStatus when no local repo path is set (updater.repo_path)
+var ErrRepoNotConfigured = errors.New("updater.repo_path is not configured")
+
+// RepoStatus describes the local nanobot source repository kept in sync by SyncRepo
+type RepoStatus struct {
+	Path    string `json:"path"`    // Repository path (updater.repo_path)
+	Branch  string `json:"branch"`  // Current branch, "HEAD" when detached
+	Commit  string `json:"commit"`  // HEAD commit hash
+	Subject string `json:"subject"` // Subject line of the HEAD commit
+	Dirty   bool   `json:"dirty"`   // Uncommitted changes or untracked files
+}
+
+// RepoStatus returns the branch, HEAD commit and dirty state of the local repository.
+// Runs read-only git commands; their output is not written to the update output stream.
+func (u *Updater) RepoStatus(ctx context.Context) (*RepoStatus, error) {
+	if u.repoPath == "" {
+		return nil, ErrRepoNotConfigured
+	}
+	status := &RepoStatus{Path: u.repoPath}
+
+	branch, err := u.repoGit(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	status.Branch = branch
+
+	head, err := u.repoGit(ctx, "log", "-1", "--format=%H%x00%s")
+	if err != nil {
+		return nil, err
+	}
+	status.Commit, status.Subject, _ = strings.Cut(head, "\x00")
+
+	changes, err := u.repoGit(ctx, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	status.Dirty = changes != ""
+	return status, nil
+}
+
+// repoGit runs a git command in the local repository and returns its trimmed stdout
+func (u *Updater) repoGit(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", u.repoPath}, args...)...)
+	setHiddenWindow(cmd)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
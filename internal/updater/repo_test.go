@@ -0,0 +1,101 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGitRepo creates a git repository with one commit and returns its path.
+// Skips the test when git is not installed.
+func newGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	dir := filepath.Join(t.TempDir(), "origin")
+	runGit(t, "", "init", "-q", "-b", "main", dir)
+	commitFile(t, dir, "README.md", "nanobot", "Initial commit")
+	return dir
+}
+
+func commitFile(t *testing.T, dir, name, content, subject string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "-q", "-m", subject)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestRepoStatus_NotConfigured(t *testing.T) {
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if _, err := u.RepoStatus(context.Background()); !errors.Is(err, ErrRepoNotConfigured) {
+		t.Errorf("RepoStatus() error = %v, want ErrRepoNotConfigured", err)
+	}
+}
+
+func TestSyncRepo_ClonesPullsAndReportsHead(t *testing.T) {
+	origin := newGitRepo(t)
+	local := filepath.Join(t.TempDir(), "nanobot")
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetSources([]Source{{Type: SourceGit, URL: origin}})
+	u.SetRepoPath(local)
+	ctx := context.Background()
+
+	// First sync clones the repository
+	if err := u.SyncRepo(ctx); err != nil {
+		t.Fatalf("SyncRepo() clone: %v", err)
+	}
+	status, err := u.RepoStatus(ctx)
+	if err != nil {
+		t.Fatalf("RepoStatus(): %v", err)
+	}
+	if status.Branch != "main" || status.Subject != "Initial commit" || status.Dirty || status.Path != local {
+		t.Errorf("status = %+v, want clean main at the initial commit", status)
+	}
+
+	// Later syncs pull new commits
+	commitFile(t, origin, "CHANGELOG.md", "0.2.0", "Release 0.2.0")
+	if err := u.SyncRepo(ctx); err != nil {
+		t.Fatalf("SyncRepo() pull: %v", err)
+	}
+	status, err = u.RepoStatus(ctx)
+	if err != nil {
+		t.Fatalf("RepoStatus(): %v", err)
+	}
+	if want := runGit(t, origin, "rev-parse", "HEAD"); status.Commit != want || status.Subject != "Release 0.2.0" {
+		t.Errorf("status = %+v, want commit %s \"Release 0.2.0\"", status, want)
+	}
+
+	// Local modifications make the repository dirty
+	if err := os.WriteFile(filepath.Join(local, "notes.txt"), []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, err = u.RepoStatus(ctx); err != nil || !status.Dirty {
+		t.Errorf("status = %+v, err = %v; want dirty", status, err)
+	}
+}
@@ -0,0 +1,157 @@
+package updater
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SourceType identifies where nanobot is installed from
+type SourceType string
+
+const (
+	// SourceGit installs from a git repository (uv tool install git+<url>[@<ref>])
+	SourceGit SourceType = "git"
+	// SourcePyPI installs a package from PyPI or a PyPI-compatible mirror
+	SourcePyPI SourceType = "pypi"
+	// SourceLocal installs a local wheel or sdist file
+	SourceLocal SourceType = "local"
+	// SourceUpload installs a wheel or sdist uploaded through the API (Target.File)
+	SourceUpload SourceType = "upload"
+)
+
+const (
+	// DefaultGitURL is the upstream nanobot repository
+	DefaultGitURL = "git+https://github.com/HKUDS/nanobot.git"
+	// DefaultPackage is the nanobot package name on PyPI
+	DefaultPackage = "nanobot-ai"
+	// DefaultSourceTimeout is the per-attempt timeout when a source does not set one
+	DefaultSourceTimeout = 5 * time.Minute
+	// retryDelay is the pause between attempts on the same source
+	retryDelay = 2 * time.Second
+)
+
+// Source is one entry of the ordered update source list
+type Source struct {
+	Name     string        // Optional label used in logs and update records
+	Type     SourceType    // git, pypi, local or upload
+	URL      string        // git: repository URL ("git+" prefix optional)
+	Ref      string        // git: default tag/branch/commit when the target does not pin one
+	Package  string        // pypi: package name (default nanobot-ai)
+	IndexURL string        // pypi: optional index URL, e.g. a Tsinghua/Aliyun mirror
+	Path     string        // local/upload: wheel or sdist path
+	Timeout  time.Duration // Per-attempt timeout (0 = Updater default, DefaultSourceTimeout)
+	Retries  int           // Extra attempts after the first failure
+}
+
+// DefaultSources returns the historical GitHub main -> PyPI order
+func DefaultSources() []Source {
+	return []Source{
+		{Name: "github", Type: SourceGit, URL: DefaultGitURL},
+		{Name: "pypi", Type: SourcePyPI, Package: DefaultPackage},
+	}
+}
+
+// Label returns the source name, or a description derived from its location
+func (s Source) Label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	switch s.Type {
+	case SourceGit:
+		return "git:" + strings.TrimPrefix(s.URL, "git+")
+	case SourcePyPI:
+		if s.IndexURL != "" {
+			return "pypi:" + s.packageName() + "@" + s.IndexURL
+		}
+		return "pypi:" + s.packageName()
+	case SourceLocal:
+		return "local:" + s.Path
+	case SourceUpload:
+		return "upload:" + filepath.Base(s.Path)
+	default:
+		return string(s.Type)
+	}
+}
+
+// timeout returns the per-attempt timeout, or def when the source does not set one
+func (s Source) timeout(def time.Duration) time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return def
+}
+
+// packageName returns the PyPI package name, defaulting to nanobot-ai
+func (s Source) packageName() string {
+	if s.Package != "" {
+		return s.Package
+	}
+	return DefaultPackage
+}
+
+// normalizePackage returns the PEP 503 normalized package name (lowercase, runs of "-", "_" and "."
+// replaced by "-"), the name uv uses in `uv tool list` and for the tool environment directory
+func normalizePackage(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	}), "-")
+}
+
+// Supports reports whether the source can install target.
+// Git refs/commits need a git source and PyPI versions a PyPI source; a local file has a
+// fixed version and is only used for the "latest" target. An uploaded file is only installed
+// by the upload source.
+func (s Source) Supports(target Target) bool {
+	switch {
+	case target.File != "":
+		return s.Type == SourceUpload
+	case target.Ref != "" || target.Commit != "":
+		return s.Type == SourceGit
+	case target.PyPIVersion != "":
+		return s.Type == SourcePyPI
+	default:
+		return s.Type != SourceUpload
+	}
+}
+
+// installArgs returns the spec and the `uv tool install` arguments for target
+func (s Source) installArgs(target Target) (string, []string, error) {
+	var spec string
+	var extra []string
+
+	switch s.Type {
+	case SourceGit:
+		url := s.URL
+		if !strings.HasPrefix(url, "git+") {
+			url = "git+" + url
+		}
+		ref := s.Ref
+		if target.Ref != "" {
+			ref = target.Ref
+		} else if target.Commit != "" {
+			ref = target.Commit
+		}
+		spec = url
+		if ref != "" {
+			spec += "@" + ref
+		}
+	case SourcePyPI:
+		spec = s.packageName()
+		if target.PyPIVersion != "" {
+			spec += "==" + target.PyPIVersion
+		}
+		if s.IndexURL != "" {
+			extra = append(extra, "--index-url", s.IndexURL)
+		}
+	case SourceLocal, SourceUpload:
+		spec = s.Path
+	default:
+		return "", nil, fmt.Errorf("unknown source type %q", s.Type)
+	}
+
+	args := append([]string{"tool", "install", "--force"}, extra...)
+	args = append(args, spec)
+	return spec, args, nil
+}
@@ -0,0 +1,142 @@
+package updater
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSourceLabel(t *testing.T) {
+	tests := []struct {
+		source   Source
+		expected string
+	}{
+		{Source{Name: "tsinghua", Type: SourcePyPI}, "tsinghua"},
+		{Source{Type: SourceGit, URL: "git+https://github.com/HKUDS/nanobot.git"}, "git:https://github.com/HKUDS/nanobot.git"},
+		{Source{Type: SourcePyPI}, "pypi:nanobot-ai"},
+		{Source{Type: SourcePyPI, IndexURL: "https://mirrors.aliyun.com/pypi/simple"}, "pypi:nanobot-ai@https://mirrors.aliyun.com/pypi/simple"},
+		{Source{Type: SourceLocal, Path: "/opt/wheels/nanobot.whl"}, "local:/opt/wheels/nanobot.whl"},
+		{Source{Type: SourceUpload, Path: "/srv/uploads/id/nanobot.whl"}, "upload:nanobot.whl"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.source.Label(); got != tt.expected {
+			t.Errorf("Label() = %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+func TestSourceSupports(t *testing.T) {
+	git := Source{Type: SourceGit, URL: DefaultGitURL}
+	pypi := Source{Type: SourcePyPI}
+	local := Source{Type: SourceLocal, Path: "nanobot.whl"}
+
+	tests := []struct {
+		name   string
+		target Target
+		want   [3]bool // git, pypi, local
+	}{
+		{"latest", Target{}, [3]bool{true, true, true}},
+		{"ref", Target{Ref: "v0.1.4"}, [3]bool{true, false, false}},
+		{"commit", Target{Commit: "abc1234"}, [3]bool{true, false, false}},
+		{"pypi version", Target{PyPIVersion: "0.1.3"}, [3]bool{false, true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := [3]bool{git.Supports(tt.target), pypi.Supports(tt.target), local.Supports(tt.target)}
+			if got != tt.want {
+				t.Errorf("Supports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceInstallArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   Source
+		target   Target
+		wantSpec string
+		wantArgs []string
+	}{
+		{
+			name:     "git without git+ prefix uses source ref",
+			source:   Source{Type: SourceGit, URL: "https://github.com/HKUDS/nanobot.git", Ref: "main"},
+			target:   Target{},
+			wantSpec: "git+https://github.com/HKUDS/nanobot.git@main",
+			wantArgs: []string{"tool", "install", "--force", "git+https://github.com/HKUDS/nanobot.git@main"},
+		},
+		{
+			name:     "target commit overrides source ref",
+			source:   Source{Type: SourceGit, URL: DefaultGitURL, Ref: "main"},
+			target:   Target{Commit: "abc1234"},
+			wantSpec: DefaultGitURL + "@abc1234",
+			wantArgs: []string{"tool", "install", "--force", DefaultGitURL + "@abc1234"},
+		},
+		{
+			name:     "pypi mirror with pinned version",
+			source:   Source{Type: SourcePyPI, IndexURL: "https://pypi.tuna.tsinghua.edu.cn/simple"},
+			target:   Target{PyPIVersion: "0.1.3"},
+			wantSpec: "nanobot-ai==0.1.3",
+			wantArgs: []string{"tool", "install", "--force", "--index-url", "https://pypi.tuna.tsinghua.edu.cn/simple", "nanobot-ai==0.1.3"},
+		},
+		{
+			name:     "local wheel",
+			source:   Source{Type: SourceLocal, Path: "/opt/wheels/nanobot_ai-0.1.4-py3-none-any.whl"},
+			target:   Target{},
+			wantSpec: "/opt/wheels/nanobot_ai-0.1.4-py3-none-any.whl",
+			wantArgs: []string{"tool", "install", "--force", "/opt/wheels/nanobot_ai-0.1.4-py3-none-any.whl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, args, err := tt.source.installArgs(tt.target)
+			if err != nil {
+				t.Fatalf("installArgs() unexpected error: %v", err)
+			}
+			if spec != tt.wantSpec {
+				t.Errorf("spec = %q, want %q", spec, tt.wantSpec)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCandidates_FiltersByTarget(t *testing.T) {
+	u := NewUpdater(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	u.SetSources([]Source{
+		{Name: "local", Type: SourceLocal, Path: "nanobot.whl"},
+		{Name: "aliyun", Type: SourcePyPI, IndexURL: "https://mirrors.aliyun.com/pypi/simple"},
+		{Name: "github", Type: SourceGit, URL: DefaultGitURL},
+	})
+
+	latest, err := u.candidates(Target{})
+	if err != nil || len(latest) != 3 || latest[0].source.Name != "local" {
+		t.Errorf("latest candidates = %+v, err = %v; want all three in order", latest, err)
+	}
+
+	pinned, err := u.candidates(Target{PyPIVersion: "0.1.3"})
+	if err != nil || len(pinned) != 1 || pinned[0].source.Name != "aliyun" {
+		t.Errorf("pypi_version candidates = %+v, err = %v; want only aliyun", pinned, err)
+	}
+
+	// An uploaded file bypasses the configured sources
+	upload, err := u.candidates(Target{File: "/srv/uploads/id/nanobot_ai-0.2.0-py3-none-any.whl"})
+	if err != nil || len(upload) != 1 || upload[0].source.Type != SourceUpload {
+		t.Fatalf("upload candidates = %+v, err = %v; want only the uploaded file", upload, err)
+	}
+	wantArgs := []string{"tool", "install", "--force", "/srv/uploads/id/nanobot_ai-0.2.0-py3-none-any.whl"}
+	if !reflect.DeepEqual(upload[0].args, wantArgs) {
+		t.Errorf("upload args = %v, want %v", upload[0].args, wantArgs)
+	}
+
+	u.SetSources([]Source{{Type: SourcePyPI}})
+	if _, err := u.candidates(Target{Ref: "v0.1.4"}); err == nil {
+		t.Error("expected error when no source supports a git ref")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package updater
+
+import "os/exec"
+
+// setHiddenWindow is a no-op on non-Windows platforms (there is no console window to hide).
+func setHiddenWindow(cmd *exec.Cmd) {}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package updater
+
+import (
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// setHiddenWindow hides the console window of uv/git commands.
+func setHiddenWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &windows.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: windows.CREATE_NO_WINDOW,
+	}
+}
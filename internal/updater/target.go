@@ -0,0 +1,143 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidTarget is returned when an update target is malformed
+var ErrInvalidTarget = errors.New("invalid update target")
+
+var (
+	// gitRefPattern accepts tag and branch names (e.g. "v0.1.4", "release/0.2")
+	gitRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+	// commitPattern accepts abbreviated or full SHA-1 commit hashes
+	commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+	// pypiVersionPattern accepts PEP 440 version strings (e.g. "0.1.3", "0.2.0rc1", "1!2.0.post1")
+	pypiVersionPattern = regexp.MustCompile(`^[0-9][0-9A-Za-z.!+_-]*$`)
+)
+
+// Target selects which nanobot version to install.
+// At most one field may be set; the zero value means "latest" (GitHub main with PyPI fallback).
+type Target struct {
+	Ref         string `json:"ref,omitempty"`          // Git tag or branch, installed from GitHub
+	Commit      string `json:"commit,omitempty"`       // Git commit hash, installed from GitHub
+	PyPIVersion string `json:"pypi_version,omitempty"` // Exact PyPI release of nanobot-ai
+
+	// File is a staged wheel or sdist uploaded with POST /api/v1/trigger-update/upload.
+	// It is installed as-is, bypassing the configured sources, and is never read from request JSON
+	File string `json:"-"`
+}
+
+// IsLatest reports whether no specific version was requested
+func (t Target) IsLatest() bool {
+	return t.Ref == "" && t.Commit == "" && t.PyPIVersion == "" && t.File == ""
+}
+
+// Validate checks that at most one field is set and that it is well-formed.
+// The value ends up in a uv command line, so only a conservative character set is allowed.
+func (t Target) Validate() error {
+	set := 0
+	for _, v := range []string{t.Ref, t.Commit, t.PyPIVersion, t.File} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("%w: only one of ref, commit, pypi_version, file may be specified", ErrInvalidTarget)
+	}
+
+	switch {
+	case t.Ref != "":
+		if !gitRefPattern.MatchString(t.Ref) || strings.Contains(t.Ref, "..") {
+			return fmt.Errorf("%w: ref %q is not a valid git tag or branch name", ErrInvalidTarget, t.Ref)
+		}
+	case t.Commit != "":
+		if !commitPattern.MatchString(t.Commit) {
+			return fmt.Errorf("%w: commit %q must be 7-40 hex characters", ErrInvalidTarget, t.Commit)
+		}
+	case t.PyPIVersion != "":
+		if !pypiVersionPattern.MatchString(t.PyPIVersion) {
+			return fmt.Errorf("%w: pypi_version %q is not a valid version", ErrInvalidTarget, t.PyPIVersion)
+		}
+	}
+	return nil
+}
+
+// String returns a short description of the target for logs and update records
+// (e.g. "latest", "ref:v0.1.4", "commit:abc1234", "pypi:0.1.3", "upload:nanobot_ai-0.1.4-py3-none-any.whl").
+func (t Target) String() string {
+	switch {
+	case t.Ref != "":
+		return "ref:" + t.Ref
+	case t.Commit != "":
+		return "commit:" + t.Commit
+	case t.PyPIVersion != "":
+		return "pypi:" + t.PyPIVersion
+	case t.File != "":
+		return "upload:" + filepath.Base(t.File)
+	default:
+		return "latest"
+	}
+}
+
+// candidate is a source that supports the target, with its resolved install spec
+type candidate struct {
+	source Source
+	spec   string
+	args   []string
+}
+
+// candidates returns the sources able to install target, in configured order
+func (u *Updater) candidates(target Target) ([]candidate, error) {
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+
+	sources := u.sources
+	if target.File != "" {
+		// An uploaded file is the only source: the configured sources may not be reachable
+		sources = []Source{{Type: SourceUpload, Path: target.File}}
+	}
+
+	var result []candidate
+	for _, s := range sources {
+		if !s.Supports(target) {
+			continue
+		}
+		spec, args, err := s.installArgs(target)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, candidate{source: s, spec: spec, args: args})
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: no configured source can install %s", ErrInvalidTarget, target)
+	}
+	return result, nil
+}
+
+// PlanInstall returns the source and spec UpdateTo would try first for target, without
+// running anything (dry run). Attempts is 0.
+func (u *Updater) PlanInstall(target Target) (UpdateResult, error) {
+	candidates, err := u.candidates(target)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	c := candidates[0]
+	return UpdateResult{Source: c.source.Label(), SourceType: c.source.Type, Spec: c.spec}, nil
+}
+
+// BuildSpec returns the `uv tool install` spec of the first source that supports target.
+// Git targets are pinned with "@<ref>", PyPI targets with "==<version>".
+func (u *Updater) BuildSpec(target Target) (string, error) {
+	candidates, err := u.candidates(target)
+	if err != nil {
+		return "", err
+	}
+	return candidates[0].spec, nil
+}
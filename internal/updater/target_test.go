@@ -0,0 +1,122 @@
+package updater
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestTargetValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  Target
+		wantErr bool
+	}{
+		{name: "latest", target: Target{}},
+		{name: "tag", target: Target{Ref: "v0.1.4"}},
+		{name: "branch with slash", target: Target{Ref: "release/0.2"}},
+		{name: "short commit", target: Target{Commit: "abc1234"}},
+		{name: "full commit", target: Target{Commit: "0123456789abcdef0123456789abcdef01234567"}},
+		{name: "pypi version", target: Target{PyPIVersion: "0.1.3"}},
+		{name: "pypi pre-release", target: Target{PyPIVersion: "0.2.0rc1"}},
+		{name: "uploaded file", target: Target{File: "/srv/uploads/id/nanobot_ai-0.2.0-py3-none-any.whl"}},
+		{name: "multiple fields", target: Target{Ref: "v0.1.4", Commit: "abc1234"}, wantErr: true},
+		{name: "file and pypi version", target: Target{File: "nanobot.whl", PyPIVersion: "0.1.3"}, wantErr: true},
+		{name: "ref with space", target: Target{Ref: "v0.1.4 --index-url http://evil"}, wantErr: true},
+		{name: "ref starting with dash", target: Target{Ref: "-rf"}, wantErr: true},
+		{name: "ref with double dot", target: Target{Ref: "main..dev"}, wantErr: true},
+		{name: "commit too short", target: Target{Commit: "abc12"}, wantErr: true},
+		{name: "commit not hex", target: Target{Commit: "xyz1234"}, wantErr: true},
+		{name: "pypi version with specifier", target: Target{PyPIVersion: ">=0.1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.target.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTarget) {
+					t.Errorf("Validate() error = %v, want ErrInvalidTarget", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestTargetString(t *testing.T) {
+	tests := []struct {
+		target   Target
+		expected string
+	}{
+		{Target{}, "latest"},
+		{Target{Ref: "v0.1.4"}, "ref:v0.1.4"},
+		{Target{Commit: "abc1234"}, "commit:abc1234"},
+		{Target{PyPIVersion: "0.1.3"}, "pypi:0.1.3"},
+		{Target{File: "/srv/uploads/id/nanobot_ai-0.2.0-py3-none-any.whl"}, "upload:nanobot_ai-0.2.0-py3-none-any.whl"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.target.String(); got != tt.expected {
+			t.Errorf("String() = %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+func TestBuildSpec(t *testing.T) {
+	u := NewUpdater(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	tests := []struct {
+		name     string
+		target   Target
+		expected string
+	}{
+		{"latest", Target{}, "git+https://github.com/HKUDS/nanobot.git"},
+		{"ref", Target{Ref: "v0.1.4"}, "git+https://github.com/HKUDS/nanobot.git@v0.1.4"},
+		{"commit", Target{Commit: "abc1234"}, "git+https://github.com/HKUDS/nanobot.git@abc1234"},
+		{"pypi", Target{PyPIVersion: "0.1.3"}, "nanobot-ai==0.1.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := u.BuildSpec(tt.target)
+			if err != nil {
+				t.Fatalf("BuildSpec() unexpected error: %v", err)
+			}
+			if spec != tt.expected {
+				t.Errorf("BuildSpec() = %q, want %q", spec, tt.expected)
+			}
+		})
+	}
+
+	if _, err := u.BuildSpec(Target{Ref: "bad ref"}); err == nil {
+		t.Error("BuildSpec() should reject invalid target")
+	}
+}
+
+func TestPlanInstall(t *testing.T) {
+	u := NewUpdater(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	u.SetSources([]Source{
+		{Name: "mirror", Type: SourcePyPI, IndexURL: "https://pypi.tuna.tsinghua.edu.cn/simple"},
+		{Name: "github", Type: SourceGit, URL: "https://github.com/HKUDS/nanobot.git"},
+	})
+
+	plan, err := u.PlanInstall(Target{})
+	if err != nil {
+		t.Fatalf("PlanInstall() unexpected error: %v", err)
+	}
+	if plan.Source != "mirror" || plan.Spec != "nanobot-ai" || plan.Attempts != 0 {
+		t.Errorf("PlanInstall(latest) = %+v, want first source without attempts", plan)
+	}
+
+	plan, err = u.PlanInstall(Target{Ref: "v0.1.4"})
+	if err != nil {
+		t.Fatalf("PlanInstall() unexpected error: %v", err)
+	}
+	if plan.Source != "github" || plan.Spec != "git+https://github.com/HKUDS/nanobot.git@v0.1.4" {
+		t.Errorf("PlanInstall(ref) = %+v, want git source", plan)
+	}
+}
@@ -0,0 +1,47 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Isolated installs (updater.channels): uv installs nanobot into <install_dir>/tools and links
+// the executable into <install_dir>/bin instead of the global uv tool directories, so instances
+// on different channels can run different nanobot versions.
+
+// ToolDir returns the uv tool environment directory (UV_TOOL_DIR) of an isolated install
+func ToolDir(installDir string) string {
+	return filepath.Join(installDir, "tools")
+}
+
+// ToolBinDir returns the executable directory (UV_TOOL_BIN_DIR) of an isolated install
+func ToolBinDir(installDir string) string {
+	return filepath.Join(installDir, "bin")
+}
+
+// ToolEnv returns the environment variables that point uv at an isolated install and put its
+// executables first on PATH. Returns nil for the global install (empty installDir).
+func ToolEnv(installDir string) []string {
+	if installDir == "" {
+		return nil
+	}
+	binDir := ToolBinDir(installDir)
+	path := binDir
+	if current := os.Getenv("PATH"); current != "" {
+		path = strings.Join([]string{binDir, current}, string(os.PathListSeparator))
+	}
+	return []string{
+		"UV_TOOL_DIR=" + ToolDir(installDir),
+		"UV_TOOL_BIN_DIR=" + binDir,
+		"PATH=" + path,
+	}
+}
+
+// SetInstallDir makes all uv commands operate on the isolated install rooted at dir.
+// An empty dir uses the global uv tool directories.
+// Note: This method is not thread-safe and should only be called during initialization
+// before any concurrent access to the Updater instance.
+func (u *Updater) SetInstallDir(dir string) {
+	u.installDir = dir
+}
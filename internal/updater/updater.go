@@ -1,5 +1,3 @@
-//go:build windows
-
 package updater
 
 import (
@@ -7,44 +5,74 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
-
-	"golang.org/x/sys/windows"
 )
 
-// UpdateResult represents the outcome of an update attempt
-type UpdateResult string
-
-const (
-	// ResultSuccess indicates GitHub update succeeded
-	ResultSuccess UpdateResult = "success"
-	// ResultFallback indicates PyPI fallback succeeded
-	ResultFallback UpdateResult = "fallback"
-	// ResultFailed indicates both GitHub and PyPI failed
-	ResultFailed UpdateResult = "failed"
-)
+// UpdateResult describes the source that successfully installed nanobot
+type UpdateResult struct {
+	Source     string     `json:"source"`      // Label of the source that succeeded (Source.Label)
+	SourceType SourceType `json:"source_type"` // git, pypi, local or upload
+	Spec       string     `json:"spec"`        // uv tool install spec that was installed
+	Attempts   int        `json:"attempts"`    // Total install attempts across all sources
+}
 
-// Updater manages nanobot updates with GitHub primary and PyPI fallback
+// Updater installs nanobot from an ordered list of sources, falling back to the next
+// source when one fails (default: GitHub main, then PyPI)
 type Updater struct {
 	logger        *slog.Logger
 	githubURL     string
 	pypiPackage   string
-	updateTimeout time.Duration
+	updateTimeout time.Duration // Default per-attempt timeout for sources without their own
 	repoPath      string
+	sources       []Source
+	httpClient    *http.Client    // PyPI JSON API / git smart HTTP requests made by Check
+	pypiJSONURL   string          // defaults to DefaultPyPIJSONURL, overrideable for tests
+	installDir    string          // Isolated install root (updater.channels), empty for the global install
+	output        OutputWriter    // Receives the full uv/git command output line by line (optional)
+	heartbeat     func(Heartbeat) // Receives install heartbeats (optional, see SetHeartbeat)
 }
 
 // NewUpdater creates a new Updater with default settings
 func NewUpdater(logger *slog.Logger) *Updater {
 	return &Updater{
 		logger:        logger,
-		githubURL:     "git+https://github.com/HKUDS/nanobot.git",
-		pypiPackage:   "nanobot-ai",
-		updateTimeout: 5 * time.Minute,
+		githubURL:     DefaultGitURL,
+		pypiPackage:   DefaultPackage,
+		updateTimeout: DefaultSourceTimeout,
 		repoPath:      "",
+		sources:       DefaultSources(),
+		httpClient:    &http.Client{Timeout: checkHTTPTimeout},
+		pypiJSONURL:   DefaultPyPIJSONURL,
+	}
+}
+
+// SetSources replaces the ordered source list. An empty list keeps the defaults.
+// The first git source also becomes the clone URL for SyncRepo, and the package of the first
+// PyPI source the package looked up in `uv tool list` and the tool environment.
+// Note: This method is not thread-safe and should only be called during initialization
+// before any concurrent access to the Updater instance.
+func (u *Updater) SetSources(sources []Source) {
+	if len(sources) == 0 {
+		return
+	}
+	u.sources = sources
+	for _, s := range sources {
+		if s.Type == SourceGit {
+			u.githubURL = s.URL
+			if !strings.HasPrefix(u.githubURL, "git+") {
+				u.githubURL = "git+" + u.githubURL
+			}
+			break
+		}
+	}
+	u.pypiPackage = DefaultPackage
+	if s, ok := u.firstSource(SourcePyPI); ok {
+		u.pypiPackage = normalizePackage(s.packageName())
 	}
 }
 
@@ -58,10 +86,7 @@ func (u *Updater) SetRepoPath(path string) {
 // GetUvVersion returns the installed uv version for diagnostic purposes
 func GetUvVersion() string {
 	cmd := exec.Command("uv", "--version")
-	cmd.SysProcAttr = &windows.SysProcAttr{
-		HideWindow:    true,
-		CreationFlags: windows.CREATE_NO_WINDOW,
-	}
+	setHiddenWindow(cmd)
 	output, err := cmd.Output()
 	if err != nil {
 		return fmt.Sprintf("error: %v", err)
@@ -71,17 +96,32 @@ func GetUvVersion() string {
 
 // runCommand executes a command with hidden window and returns combined output
 func (u *Updater) runCommand(ctx context.Context, name string, args ...string) (string, error) {
+	return u.runCommandIn(ctx, "", name, args...)
+}
+
+// runCommandIn executes a command in dir (empty for the current directory) and returns combined
+// output. The output is also streamed line by line to the output stream set by SetOutput.
+func (u *Updater) runCommandIn(ctx context.Context, dir, name string, args ...string) (string, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.SysProcAttr = &windows.SysProcAttr{
-		HideWindow:    true,
-		CreationFlags: windows.CREATE_NO_WINDOW,
+	cmd.Dir = dir
+	setHiddenWindow(cmd)
+	if env := ToolEnv(u.installDir); env != nil {
+		cmd.Env = append(os.Environ(), env...)
 	}
+	// Don't let child processes (git, python) holding the output pipe outlive a source timeout
+	cmd.WaitDelay = 5 * time.Second
 
-	var output bytes.Buffer
-	cmd.Stdout = &output
-	cmd.Stderr = &output
+	var output syncBuffer
+	stdout, stderr, flush := u.commandWriters(&output)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
+	u.writeOutput("stdout", commandLine(dir, name, args...))
 	err := cmd.Run()
+	flush()
+	if err != nil {
+		u.writeOutput("stderr", fmt.Sprintf("%s failed: %v", name, err))
+	}
 	return output.String(), err
 }
 
@@ -94,18 +134,33 @@ func truncateOutput(s string) string {
 	return s[:maxLength] + "... (truncated)"
 }
 
-// Update attempts to update nanobot from GitHub main branch first,
-// falling back to PyPI stable version if GitHub fails.
+// Update installs the latest nanobot, trying each configured source in order.
 // Uses --force flag to ensure updates work even when already installed.
 func (u *Updater) Update(ctx context.Context) (UpdateResult, error) {
-	ctx, cancel := context.WithTimeout(ctx, u.updateTimeout)
-	defer cancel()
+	return u.UpdateTo(ctx, Target{})
+}
 
+// UpdateTo installs the requested target with `uv tool install --force`, trying each
+// source that supports the target in order. Each source gets its own timeout and retries.
+// A pinned ref/commit is only tried on git sources and a pinned pypi_version only on PyPI
+// sources, so a failed pin never silently installs a different version.
+func (u *Updater) UpdateTo(ctx context.Context, target Target) (UpdateResult, error) {
+	candidates, err := u.candidates(target)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return u.install(ctx, target.String(), candidates)
+}
+
+// install runs `uv tool install` for each candidate in order until one succeeds.
+// target describes what is installed in logs and heartbeats.
+func (u *Updater) install(ctx context.Context, target string, candidates []candidate) (UpdateResult, error) {
 	// Start heartbeat logging goroutine to track update progress
+	progress := &installProgress{}
 	heartbeatCtx, heartbeatCancel := context.WithCancel(context.Background())
 	defer heartbeatCancel()
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(heartbeatInterval)
 		defer ticker.Stop()
 		startTime := time.Now()
 		for {
@@ -113,63 +168,88 @@ func (u *Updater) Update(ctx context.Context) (UpdateResult, error) {
 			case <-heartbeatCtx.Done():
 				return
 			case <-ticker.C:
-				elapsed := time.Since(startTime).Round(time.Second)
+				hb := progress.heartbeatAt(startTime, target)
 				u.logger.Info("Update in progress - heartbeat",
-					"elapsed", elapsed.String(),
-					"timeout", u.updateTimeout.String())
+					"elapsed", (time.Duration(hb.ElapsedMs) * time.Millisecond).Round(time.Second).String(),
+					"target", hb.Target,
+					"source", hb.Source,
+					"attempt", hb.Attempt)
+				if u.heartbeat != nil {
+					u.heartbeat(hb)
+				}
 			}
 		}
 	}()
 
-	// Primary: Try GitHub main branch
-	u.logger.Info("Starting forced update from GitHub main branch",
-		"command", "uv tool install --force "+u.githubURL,
-		"timeout", u.updateTimeout.String())
-
-	output, err := u.runCommand(ctx, "uv", "tool", "install", "--force", u.githubURL)
-
-	// Always log command completion for debugging
-	u.logger.Info("GitHub update command completed",
-		"success", err == nil,
-		"error", err,
-		"output_length", len(output),
-		"output", truncateOutput(output))
-
-	if err == nil {
-		u.logger.Info("Update successful from GitHub",
-			"source", "github",
-			"output", truncateOutput(output))
-		return ResultSuccess, nil
-	}
-
-	u.logger.Warn("GitHub forced update failed, attempting PyPI fallback",
-		"error", err.Error(),
-		"github_output", truncateOutput(output))
-
-	// Fallback: Try PyPI stable version
-	u.logger.Info("Attempting PyPI fallback",
-		"command", "uv tool install --force "+u.pypiPackage)
-
-	output, err = u.runCommand(ctx, "uv", "tool", "install", "--force", u.pypiPackage)
+	attempts := 0
+	var lastErr error
+	for i, c := range candidates {
+		for try := 0; try <= c.source.Retries; try++ {
+			if try > 0 {
+				select {
+				case <-ctx.Done():
+					return UpdateResult{Attempts: attempts}, ctx.Err()
+				case <-time.After(retryDelay):
+				}
+			}
+			attempts++
+			progress.set(c.source.Label(), try+1, c.source.Retries+1)
+
+			timeout := c.source.timeout(u.updateTimeout)
+			u.logger.Info("Installing nanobot",
+				"source", c.source.Label(),
+				"target", target,
+				"attempt", try+1,
+				"max_attempts", c.source.Retries+1,
+				"command", "uv "+strings.Join(c.args, " "),
+				"timeout", timeout.String())
+
+			attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+			output, err := u.runCommand(attemptCtx, "uv", c.args...)
+			cancel()
+
+			// Always log command completion for debugging
+			u.logger.Info("Install command completed",
+				"source", c.source.Label(),
+				"success", err == nil,
+				"error", err,
+				"output_length", len(output),
+				"output", truncateOutput(output))
+
+			if err == nil {
+				u.logger.Info("Update successful",
+					"source", c.source.Label(),
+					"spec", c.spec,
+					"fallback", i > 0)
+				return UpdateResult{
+					Source:     c.source.Label(),
+					SourceType: c.source.Type,
+					Spec:       c.spec,
+					Attempts:   attempts,
+				}, nil
+			}
 
-	// Always log command completion for debugging
-	u.logger.Info("PyPI fallback command completed",
-		"success", err == nil,
-		"error", err,
-		"output_length", len(output),
-		"output", truncateOutput(output))
+			lastErr = fmt.Errorf("%s: %w", c.source.Label(), err)
+			if ctx.Err() != nil {
+				// Overall deadline reached, no point trying further sources
+				u.logger.Error("Update aborted", "error", ctx.Err())
+				return UpdateResult{Attempts: attempts}, fmt.Errorf("update aborted: %w", ctx.Err())
+			}
+		}
 
-	if err == nil {
-		u.logger.Info("Update successful from PyPI fallback",
-			"source", "pypi",
-			"output", truncateOutput(output))
-		return ResultFallback, nil
+		if i < len(candidates)-1 {
+			u.logger.Warn("Source failed, trying next source",
+				"source", c.source.Label(),
+				"next", candidates[i+1].source.Label(),
+				"error", lastErr)
+		}
 	}
 
-	u.logger.Error("Update failed - both GitHub and PyPI attempts failed",
-		"pypi_output", truncateOutput(output),
-		"error", err.Error())
-	return ResultFailed, fmt.Errorf("update failed (GitHub and PyPI): %w", err)
+	u.logger.Error("Update failed - all sources failed",
+		"sources", len(candidates),
+		"attempts", attempts,
+		"error", lastErr)
+	return UpdateResult{Attempts: attempts}, fmt.Errorf("update failed (all %d sources): %w", len(candidates), lastErr)
 }
 
 // SyncRepo pulls the latest changes from the local git repository.
@@ -209,19 +289,7 @@ func (u *Updater) SyncRepo(ctx context.Context) error {
 	u.logger.Info("Syncing local git repo", "path", u.repoPath)
 
 	// Run git pull
-	cmd := exec.CommandContext(ctx, "git", "pull")
-	cmd.Dir = u.repoPath
-	cmd.SysProcAttr = &windows.SysProcAttr{
-		HideWindow:    true,
-		CreationFlags: windows.CREATE_NO_WINDOW,
-	}
-
-	var output bytes.Buffer
-	cmd.Stdout = &output
-	cmd.Stderr = &output
-
-	err = cmd.Run()
-	outputStr := output.String()
+	outputStr, err := u.runCommandIn(ctx, u.repoPath, "git", "pull")
 
 	if err != nil {
 		u.logger.Error("Failed to sync repo",
@@ -284,18 +352,7 @@ func (u *Updater) cloneRepo(ctx context.Context) error {
 	}
 
 	// Run git clone
-	cmd := exec.CommandContext(ctx, "git", "clone", repoURL, u.repoPath)
-	cmd.SysProcAttr = &windows.SysProcAttr{
-		HideWindow:    true,
-		CreationFlags: windows.CREATE_NO_WINDOW,
-	}
-
-	var output bytes.Buffer
-	cmd.Stdout = &output
-	cmd.Stderr = &output
-
-	err := cmd.Run()
-	outputStr := output.String()
+	outputStr, err := u.runCommandIn(ctx, "", "git", "clone", repoURL, u.repoPath)
 
 	if err != nil {
 		u.logger.Error("Failed to clone repo",
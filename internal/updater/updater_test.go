@@ -1,12 +1,13 @@
-//go:build windows
-
 package updater
 
 import (
+	"io"
 	"log/slog"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
 )
 
 func TestNewUpdater(t *testing.T) {
@@ -79,34 +80,59 @@ func TestTruncateOutput(t *testing.T) {
 	}
 }
 
-func TestUpdateResultConstants(t *testing.T) {
-	tests := []struct {
-		name     string
-		result   UpdateResult
-		expected string
-	}{
-		{
-			name:     "success constant",
-			result:   ResultSuccess,
-			expected: "success",
-		},
-		{
-			name:     "fallback constant",
-			result:   ResultFallback,
-			expected: "fallback",
-		},
-		{
-			name:     "failed constant",
-			result:   ResultFailed,
-			expected: "failed",
-		},
+func TestSetSources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	u := NewUpdater(logger)
+
+	if len(u.sources) != 2 || u.sources[0].Type != SourceGit || u.sources[1].Type != SourcePyPI {
+		t.Fatalf("default sources = %+v, want [git pypi]", u.sources)
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if string(tt.result) != tt.expected {
-				t.Errorf("Expected %q, got %q", tt.expected, tt.result)
-			}
-		})
+	u.SetSources(nil)
+	if len(u.sources) != 2 {
+		t.Error("SetSources(nil) should keep the default sources")
+	}
+
+	u.SetSources([]Source{
+		{Type: SourcePyPI, IndexURL: "https://pypi.tuna.tsinghua.edu.cn/simple"},
+		{Type: SourceGit, URL: "https://gitee.com/mirror/nanobot.git"},
+	})
+	if len(u.sources) != 2 || u.sources[0].Type != SourcePyPI {
+		t.Errorf("sources = %+v, want configured order", u.sources)
+	}
+	if u.githubURL != "git+https://gitee.com/mirror/nanobot.git" {
+		t.Errorf("githubURL = %q, want first git source URL", u.githubURL)
+	}
+	if u.pypiPackage != DefaultPackage {
+		t.Errorf("pypiPackage = %q, want %q for a pypi source without package", u.pypiPackage, DefaultPackage)
+	}
+
+	u.SetSources([]Source{
+		{Type: SourceGit, URL: "https://example.com/fork/nanobot.git"},
+		{Type: SourcePyPI, Package: "Nanobot_Fork"},
+		{Type: SourcePyPI, Package: "nanobot-other"},
+	})
+	if u.pypiPackage != "nanobot-fork" {
+		t.Errorf("pypiPackage = %q, want the normalized package of the first pypi source", u.pypiPackage)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	buf := logbuffer.NewLogBuffer(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	lw := NewLineWriter(buf, "stderr")
+
+	lw.Write([]byte("Resolved 3 packages\r\nerror: no solution"))
+	lw.Write([]byte(" found\npartial"))
+	lw.Flush()
+
+	history := buf.GetHistory()
+	want := []string{"Resolved 3 packages", "error: no solution found", "partial"}
+	if len(history) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(history), len(want), history)
+	}
+	for i, entry := range history {
+		if entry.Content != want[i] || entry.Source != "stderr" {
+			t.Errorf("line %d = %q (%s), want %q (stderr)", i, entry.Content, entry.Source, want[i])
+		}
 	}
 }
@@ -0,0 +1,424 @@
+//go:build !windows
+
+package updater
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/logbuffer"
+)
+
+// installFakeUv puts a fake uv on PATH. Every invocation appends its last argument (the spec)
+// to the returned log file; specs containing "fail" exit 1 and specs containing "hang" sleep.
+func installFakeUv(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "calls.log")
+	script := `#!/bin/sh
+for last; do :; done
+echo "$last" >> "` + logPath + `"
+case "$last" in
+*fail*) echo "error: failed to fetch" >&2; exit 1 ;;
+*hang*) exec sleep 10 ;;
+esac
+echo "Installed 1 executable: nanobot"
+`
+	if err := os.WriteFile(filepath.Join(dir, "uv"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake uv: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return logPath
+}
+
+func readCalls(t *testing.T, logPath string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read fake uv log: %v", err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestUpdateTo_FallsBackThroughSourcesWithRetries(t *testing.T) {
+	logPath := installFakeUv(t)
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetSources([]Source{
+		{Name: "github", Type: SourceGit, URL: "https://example.com/fail.git", Retries: 1},
+		{Name: "tsinghua", Type: SourcePyPI, IndexURL: "https://pypi.tuna.tsinghua.edu.cn/simple"},
+	})
+
+	result, err := u.UpdateTo(context.Background(), Target{})
+	if err != nil {
+		t.Fatalf("UpdateTo returned error: %v", err)
+	}
+
+	if result.Source != "tsinghua" || result.SourceType != SourcePyPI || result.Spec != "nanobot-ai" {
+		t.Errorf("result = %+v, want tsinghua pypi source", result)
+	}
+	if result.Attempts != 3 {
+		t.Errorf("Attempts = %d, want 3 (2 git tries + 1 pypi)", result.Attempts)
+	}
+	if calls := readCalls(t, logPath); len(calls) != 3 {
+		t.Errorf("uv calls = %v, want 3", calls)
+	}
+}
+
+func TestUpdateTo_PerSourceTimeout(t *testing.T) {
+	installFakeUv(t)
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetSources([]Source{
+		{Name: "slow", Type: SourceLocal, Path: "/wheels/hang.whl", Timeout: 200 * time.Millisecond},
+		{Name: "fast", Type: SourceLocal, Path: "/wheels/nanobot.whl"},
+	})
+
+	start := time.Now()
+	result, err := u.UpdateTo(context.Background(), Target{})
+	if err != nil {
+		t.Fatalf("UpdateTo returned error: %v", err)
+	}
+	if result.Source != "fast" {
+		t.Errorf("Source = %q, want %q", result.Source, "fast")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("slow source should time out after 200ms, took %v", elapsed)
+	}
+}
+
+func TestUpdateTo_AllSourcesFail(t *testing.T) {
+	installFakeUv(t)
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetSources([]Source{
+		{Name: "a", Type: SourceLocal, Path: "/wheels/fail-a.whl"},
+		{Name: "b", Type: SourceLocal, Path: "/wheels/fail-b.whl"},
+	})
+
+	result, err := u.UpdateTo(context.Background(), Target{})
+	if err == nil {
+		t.Fatal("expected error when all sources fail")
+	}
+	if result.Source != "" || result.Attempts != 2 {
+		t.Errorf("result = %+v, want no source and 2 attempts", result)
+	}
+}
+
+func TestSetOutput_StreamsFullCommandOutput(t *testing.T) {
+	installFakeUv(t)
+
+	buf := logbuffer.NewLogBuffer(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetOutput(buf)
+	u.SetSources([]Source{
+		{Name: "a", Type: SourceLocal, Path: "/wheels/fail-a.whl"},
+		{Name: "b", Type: SourceLocal, Path: "/wheels/b.whl"},
+	})
+
+	if _, err := u.UpdateTo(context.Background(), Target{}); err != nil {
+		t.Fatalf("UpdateTo returned error: %v", err)
+	}
+
+	var lines []string
+	for _, entry := range buf.GetHistory() {
+		lines = append(lines, entry.Source+": "+entry.Content)
+	}
+	want := []string{
+		"stdout: $ uv tool install --force /wheels/fail-a.whl",
+		"stderr: error: failed to fetch",
+		"stderr: uv failed: exit status 1",
+		"stdout: $ uv tool install --force /wheels/b.whl",
+		"stdout: Installed 1 executable: nanobot",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output stream =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// installFakeUvTool puts a fake uv on PATH that reports version as installed by `uv tool list`
+// and, when commit is set, a git install of repoURL@commit via direct_url.json under `uv tool dir`.
+func installFakeUvTool(t *testing.T, version, repoURL, commit string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	toolDir := filepath.Join(dir, "tools")
+	if commit != "" {
+		distInfo := filepath.Join(toolDir, "nanobot-ai", "lib", "python3.12", "site-packages", "nanobot_ai-"+version+".dist-info")
+		if err := os.MkdirAll(distInfo, 0755); err != nil {
+			t.Fatal(err)
+		}
+		directURL := `{"url":"` + repoURL + `","vcs_info":{"vcs":"git","commit_id":"` + commit + `"}}`
+		if err := os.WriteFile(filepath.Join(distInfo, "direct_url.json"), []byte(directURL), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := `#!/bin/sh
+case "$1 $2" in
+"tool list") echo "nanobot-ai v` + version + `"; echo "- nanobot" ;;
+"tool dir") echo "` + toolDir + `" ;;
+*) exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "uv"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake uv: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCheck(t *testing.T) {
+	server := newFakeUpstream(t, "0.2.1")
+	repoURL := server.URL + "/nanobot.git"
+	gitFirst := []Source{{Name: "github", Type: SourceGit, URL: repoURL}, {Name: "pypi", Type: SourcePyPI}}
+	pypiFirst := []Source{{Name: "pypi", Type: SourcePyPI}, {Name: "github", Type: SourceGit, URL: repoURL}}
+
+	tests := []struct {
+		name          string
+		sources       []Source
+		version       string
+		commit        string
+		target        Target
+		wantAvailable bool
+	}{
+		{"git install at HEAD", gitFirst, "0.2.1", testHeadCommit, Target{}, false},
+		{"git install behind HEAD", gitFirst, "0.2.1", testTagCommit, Target{}, true},
+		{"pypi install with git source first", gitFirst, "0.2.1", "", Target{}, true},
+		{"pypi install is latest", pypiFirst, "0.2.1", "", Target{}, false},
+		{"pypi install is outdated", pypiFirst, "0.2.0", "", Target{}, true},
+		{"git build with pypi source first", pypiFirst, "0.2.1", testHeadCommit, Target{}, true},
+		{"pinned pypi version installed", gitFirst, "0.1.3", "", Target{PyPIVersion: "0.1.3"}, false},
+		{"pinned commit installed", gitFirst, "0.2.1", testTagCommit, Target{Commit: "2222222"}, false},
+		{"pinned tag differs", gitFirst, "0.2.1", testHeadCommit, Target{Ref: "v0.1.4"}, true},
+		{"local source", []Source{{Type: SourceLocal, Path: "/wheels/nanobot.whl"}}, "0.2.1", "", Target{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installFakeUvTool(t, tt.version, repoURL, tt.commit)
+
+			u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			u.SetSources(tt.sources)
+			u.SetPyPIJSONURL(server.URL + "/pypi")
+
+			result, err := u.Check(context.Background(), tt.target)
+			if err != nil {
+				t.Fatalf("Check() error: %v", err)
+			}
+			if result.UpdateAvailable != tt.wantAvailable {
+				t.Errorf("UpdateAvailable = %v, want %v (reason: %s)", result.UpdateAvailable, tt.wantAvailable, result.Reason)
+			}
+			if result.InstalledVersion != tt.version || result.InstalledCommit != tt.commit {
+				t.Errorf("installed = (%q, %q), want (%q, %q)", result.InstalledVersion, result.InstalledCommit, tt.version, tt.commit)
+			}
+			if len(result.Errors) != 0 {
+				t.Errorf("unexpected lookup errors: %v", result.Errors)
+			}
+		})
+	}
+}
+
+func TestCurrentInstall(t *testing.T) {
+	repoURL := "https://example.com/fork/nanobot.git"
+	fork := Source{Name: "fork", Type: SourceGit, URL: "git+" + repoURL, Ref: "main", Retries: 2}
+	mirror := Source{Name: "mirror", Type: SourcePyPI, IndexURL: "https://pypi.tuna.tsinghua.edu.cn/simple"}
+
+	tests := []struct {
+		name     string
+		sources  []Source
+		commit   string
+		wantSpec string
+		wantFrom string
+	}{
+		{"git install pinned to its commit", []Source{fork}, testTagCommit, "git+" + repoURL + "@" + testTagCommit, "fork"},
+		{"git install from an unconfigured repository", []Source{mirror}, testTagCommit, "git+" + repoURL + "@" + testTagCommit, "git:" + repoURL},
+		{"pypi install from the configured mirror", []Source{fork, mirror}, "", "nanobot-ai==0.1.4", "mirror"},
+		{"pypi install without a pypi source", []Source{fork}, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			installFakeUvTool(t, "0.1.4", repoURL, tt.commit)
+
+			u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			u.SetSources(tt.sources)
+
+			install, err := u.CurrentInstall(context.Background())
+			if err != nil {
+				t.Fatalf("CurrentInstall() error: %v", err)
+			}
+			if install.Version != "0.1.4" || install.Spec != tt.wantSpec || install.Source != tt.wantFrom {
+				t.Errorf("install = %+v, want version 0.1.4, spec %q from %q", install, tt.wantSpec, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func TestCurrentInstall_ConfiguredPackage(t *testing.T) {
+	// A fork published under its own package name: uv lists and installs it under that name
+	dir := t.TempDir()
+	toolDir := filepath.Join(dir, "tools")
+	distInfo := filepath.Join(toolDir, "nanobot-fork", "lib", "python3.12", "site-packages", "nanobot_fork-0.3.0.dist-info")
+	if err := os.MkdirAll(distInfo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	directURL := `{"url":"https://example.com/fork/nanobot.git","vcs_info":{"vcs":"git","commit_id":"` + testTagCommit + `"}}`
+	if err := os.WriteFile(filepath.Join(distInfo, "direct_url.json"), []byte(directURL), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := `#!/bin/sh
+case "$1 $2" in
+"tool list") echo "nanobot-ai v0.1.4"; echo "- nanobot"; echo "nanobot-fork v0.3.0"; echo "- nanobot-fork" ;;
+"tool dir") echo "` + toolDir + `" ;;
+*) exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "uv"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake uv: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetSources([]Source{{Name: "fork", Type: SourcePyPI, Package: "nanobot_fork"}})
+
+	install, err := u.CurrentInstall(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentInstall() error: %v", err)
+	}
+	want := "git+https://example.com/fork/nanobot.git@" + testTagCommit
+	if install.Version != "0.3.0" || install.Spec != want {
+		t.Errorf("install = %+v, want version 0.3.0 of nanobot-fork pinned to %s", install, want)
+	}
+}
+
+func TestRollback_ReinstallsRecordedInstall(t *testing.T) {
+	repoURL := "https://example.com/fork/nanobot.git"
+	installFakeUvTool(t, "0.1.4", repoURL, testTagCommit)
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetSources([]Source{{Name: "fork", Type: SourceGit, URL: repoURL, Ref: "main"}})
+	previous, err := u.CurrentInstall(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentInstall() error: %v", err)
+	}
+
+	logPath := installFakeUv(t)
+	result, err := u.Rollback(context.Background(), previous)
+	if err != nil {
+		t.Fatalf("Rollback() error: %v", err)
+	}
+	want := "git+" + repoURL + "@" + testTagCommit
+	if result.Spec != want || result.Source != "fork" {
+		t.Errorf("result = %+v, want %s from fork", result, want)
+	}
+	if calls := readCalls(t, logPath); len(calls) != 1 || calls[0] != want {
+		t.Errorf("uv calls = %v, want [%s]", calls, want)
+	}
+
+	if _, err := u.Rollback(context.Background(), Installation{Version: "0.1.4"}); err == nil {
+		t.Error("Rollback() of an install without spec should fail")
+	}
+}
+
+func TestCheck_UpstreamUnavailable(t *testing.T) {
+	installFakeUvTool(t, "0.2.1", "", "")
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetSources([]Source{{Type: SourceGit, URL: server.URL + "/nanobot.git"}, {Type: SourcePyPI}})
+	u.SetPyPIJSONURL(server.URL + "/pypi")
+
+	result, err := u.Check(context.Background(), Target{})
+	if err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+	if !result.UpdateAvailable {
+		t.Error("an unreachable upstream must not report the installation as current")
+	}
+	if len(result.Errors) != 2 {
+		t.Errorf("Errors = %v, want PyPI and git failures", result.Errors)
+	}
+}
+
+func TestSetInstallDir_PointsUvAtIsolatedInstall(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "$UV_TOOL_DIR|$UV_TOOL_BIN_DIR"
+`
+	if err := os.WriteFile(filepath.Join(dir, "uv"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake uv: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("UV_TOOL_DIR", "")
+	t.Setenv("UV_TOOL_BIN_DIR", "")
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	output, err := u.runCommand(context.Background(), "uv", "tool", "dir")
+	if err != nil {
+		t.Fatalf("runCommand() error: %v", err)
+	}
+	if got := strings.TrimSpace(output); got != "|" {
+		t.Errorf("global install env = %q, want no uv tool overrides", got)
+	}
+
+	installDir := filepath.Join(t.TempDir(), "canary")
+	u.SetInstallDir(installDir)
+	output, err = u.runCommand(context.Background(), "uv", "tool", "dir")
+	if err != nil {
+		t.Fatalf("runCommand() error: %v", err)
+	}
+	want := filepath.Join(installDir, "tools") + "|" + filepath.Join(installDir, "bin")
+	if got := strings.TrimSpace(output); got != want {
+		t.Errorf("isolated install env = %q, want %q", got, want)
+	}
+
+	if env := ToolEnv(""); env != nil {
+		t.Errorf("ToolEnv(\"\") = %v, want nil", env)
+	}
+}
+
+func TestSetHeartbeat_ReportsRunningAttempt(t *testing.T) {
+	installFakeUv(t)
+	defer func(orig time.Duration) { heartbeatInterval = orig }(heartbeatInterval)
+	heartbeatInterval = 50 * time.Millisecond
+
+	u := NewUpdater(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetSources([]Source{
+		{Name: "slow", Type: SourceLocal, Path: "/wheels/hang.whl", Timeout: 300 * time.Millisecond},
+		{Name: "fast", Type: SourceLocal, Path: "/wheels/nanobot.whl"},
+	})
+	var (
+		mu    sync.Mutex
+		beats []Heartbeat
+	)
+	u.SetHeartbeat(func(hb Heartbeat) {
+		mu.Lock()
+		defer mu.Unlock()
+		beats = append(beats, hb)
+	})
+
+	if _, err := u.UpdateTo(context.Background(), Target{}); err != nil {
+		t.Fatalf("UpdateTo returned error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(beats) == 0 {
+		t.Fatal("no heartbeat reported during a 300ms install")
+	}
+	first := beats[0]
+	if first.Source != "slow" || first.Attempt != 1 || first.MaxAttempts != 1 || first.Target != "latest" || first.ElapsedMs <= 0 {
+		t.Errorf("first heartbeat = %+v, want attempt 1/1 on the slow source", first)
+	}
+}
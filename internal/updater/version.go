@@ -0,0 +1,112 @@
+package updater
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// InstalledVersion returns the currently installed nanobot version as reported by
+// `uv tool list` (e.g. "0.1.4"). Returns "" without error when nanobot is not installed.
+// Used to record the rollback point before an update.
+func (u *Updater) InstalledVersion(ctx context.Context) (string, error) {
+	output, err := u.runCommand(ctx, "uv", "tool", "list")
+	if err != nil {
+		return "", fmt.Errorf("uv tool list failed: %w (output: %s)", err, truncateOutput(output))
+	}
+
+	version := parseToolVersion(output, u.pypiPackage)
+	u.logger.Info("Detected installed nanobot version", "package", u.pypiPackage, "version", version)
+	return version, nil
+}
+
+// Installation is the installed nanobot, recorded before an update as the rollback point.
+// Spec and the source reproduce exactly that install; Spec is empty when the configured
+// sources cannot reproduce it.
+type Installation struct {
+	Version    string     // Installed version reported by `uv tool list`, "" when not installed
+	Spec       string     // uv tool install spec that reinstalls this install
+	Source     string     // Label of the source Spec is installed from
+	SourceType SourceType // git or pypi
+	candidate  candidate  // Source and uv arguments of the reinstall
+}
+
+// CurrentInstall records the installed nanobot so Rollback can reinstall it exactly.
+// A git install (PEP 610 direct_url.json) is pinned to its installed commit on the same
+// repository, any other install to its version on the first configured PyPI source.
+func (u *Updater) CurrentInstall(ctx context.Context) (Installation, error) {
+	version, err := u.InstalledVersion(ctx)
+	if err != nil || version == "" {
+		return Installation{}, err
+	}
+	install := Installation{Version: version}
+
+	url, commit, err := u.installedOrigin(ctx)
+	if err != nil {
+		return install, err
+	}
+
+	var source Source
+	var target Target
+	switch {
+	case commit != "":
+		// Keep name, timeout and retries of the configured source for the same repository
+		source = Source{Type: SourceGit}
+		for _, s := range u.sources {
+			if s.Type == SourceGit && sameRepo(url, s.URL) {
+				source = s
+				break
+			}
+		}
+		source.URL = url
+		target = Target{Commit: commit}
+	default:
+		s, ok := u.firstSource(SourcePyPI)
+		if !ok {
+			u.logger.Warn("Installed nanobot cannot be reinstalled from the configured sources",
+				"version", version)
+			return install, nil
+		}
+		source = s
+		target = Target{PyPIVersion: version}
+	}
+
+	spec, args, err := source.installArgs(target)
+	if err != nil {
+		return install, err
+	}
+	install.Spec = spec
+	install.Source = source.Label()
+	install.SourceType = source.Type
+	install.candidate = candidate{source: source, spec: spec, args: args}
+	return install, nil
+}
+
+// Rollback reinstalls a previous install recorded with CurrentInstall, using the same
+// spec and source it was recorded with.
+func (u *Updater) Rollback(ctx context.Context, previous Installation) (UpdateResult, error) {
+	if previous.Version == "" {
+		return UpdateResult{}, fmt.Errorf("no previous version recorded, cannot roll back")
+	}
+	if previous.Spec == "" {
+		return UpdateResult{}, fmt.Errorf("previous version %s cannot be reinstalled from the configured sources", previous.Version)
+	}
+
+	u.logger.Warn("Rolling back nanobot", "version", previous.Version, "spec", previous.Spec, "source", previous.Source)
+	return u.install(ctx, "rollback:"+previous.Version, []candidate{previous.candidate})
+}
+
+// parseToolVersion extracts the version of pkg from `uv tool list` output.
+// The output lists each tool as "<package> v<version>" followed by "- <executable>" lines.
+func parseToolVersion(output, pkg string) string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != pkg {
+			continue
+		}
+		return strings.TrimPrefix(fields[1], "v")
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package updater
+
+import "testing"
+
+func TestParseToolVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name:     "single tool",
+			output:   "nanobot-ai v0.1.4\n- nanobot\n",
+			expected: "0.1.4",
+		},
+		{
+			name:     "multiple tools",
+			output:   "black v24.1.0\n- black\n- blackd\nnanobot-ai v0.1.4.post3\n- nanobot\nruff v0.5.0\n- ruff\n",
+			expected: "0.1.4.post3",
+		},
+		{
+			name:     "windows line endings",
+			output:   "nanobot-ai v0.2.0\r\n- nanobot.exe\r\n",
+			expected: "0.2.0",
+		},
+		{
+			name:     "not installed",
+			output:   "No tools installed\n",
+			expected: "",
+		},
+		{
+			name:     "similar package name",
+			output:   "nanobot-ai-extras v1.0.0\n- extras\n",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseToolVersion(tt.output, "nanobot-ai"); got != tt.expected {
+				t.Errorf("parseToolVersion() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
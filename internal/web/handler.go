@@ -1,14 +1,20 @@
 package web
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/HQGroup/nanobot-auto-updater/internal/health"
 	"github.com/HQGroup/nanobot-auto-updater/internal/instance"
+	"github.com/HQGroup/nanobot-auto-updater/internal/updatelog"
 )
 
 //go:embed static/*
@@ -84,13 +90,19 @@ func NewInstanceListHandler(im *instance.InstanceManager, logger *slog.Logger) h
 
 // InstanceStatus represents the status of a single instance
 type InstanceStatus struct {
-	Name    string `json:"name"`
-	Port    uint32 `json:"port"`
-	Running bool   `json:"running"`
+	Name               string                 `json:"name"`
+	Port               uint32                 `json:"port"`
+	Running            bool                   `json:"running"`
+	PID                int32                  `json:"pid,omitempty"`
+	Restart            instance.RestartStatus `json:"restart"`                  // restart 策略和重启/崩溃计数
+	LastOutputAt       *time.Time             `json:"last_output_at,omitempty"` // 最近一次输出的时间
+	Liveness           *health.LivenessStatus `json:"liveness,omitempty"`       // health_check.liveness 探针结果和阈值计数
+	instance.StateInfo                        // state, since, initiator, started_at, uptime_ms, last_exit_*
 }
 
 // NewInstanceStatusHandler creates handler for GET /api/v1/instances/status
-// Returns instance list with name, port, and running status using PID-based detection.
+// Returns instance list with name, port, the state machine (state, initiator, start time, uptime,
+// last exit code and reason) and the crash supervisor state (restart policy, restart and crash counters).
 // Uses InstanceManager.GetInstanceStatuses() for accurate multi-instance status.
 func NewInstanceStatusHandler(im *instance.InstanceManager, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -99,9 +111,14 @@ func NewInstanceStatusHandler(im *instance.InstanceManager, logger *slog.Logger)
 
 		for _, info := range statusInfos {
 			statuses = append(statuses, InstanceStatus{
-				Name:    info.Name,
-				Port:    info.Port,
-				Running: info.Running,
+				Name:         info.Name,
+				Port:         info.Port,
+				Running:      info.Running,
+				PID:          info.PID,
+				Restart:      info.Restart,
+				LastOutputAt: info.LastOutputAt,
+				Liveness:     info.Liveness,
+				StateInfo:    info.StateInfo,
 			})
 		}
 
@@ -155,8 +172,27 @@ func NewVersionHandler(version string, logger *slog.Logger) http.HandlerFunc {
 }
 
 // NewInstanceRestartHandler creates handler for POST /api/v1/instances/{name}/restart
-// Restarts a specific instance by calling StopForUpdate then StartAfterUpdate
-func NewInstanceRestartHandler(im *instance.InstanceManager, logger *slog.Logger) http.HandlerFunc {
+// Restarts a specific instance by calling StopForUpdate then StartAfterUpdate.
+// Every restart attempt is recorded in updateLogger (operation "instance-restart") when it is non-nil.
+func NewInstanceRestartHandler(im *instance.InstanceManager, updateLogger *updatelog.UpdateLogger, logger *slog.Logger) http.HandlerFunc {
+	record := func(inst *instance.InstanceLifecycle, startTime time.Time, detail updatelog.InstanceUpdateDetail, err error) {
+		if updateLogger == nil {
+			return
+		}
+		detail.Name = inst.Name()
+		detail.Port = inst.Port()
+		detail.Status = "success"
+		if err != nil {
+			detail.Status = "failed"
+			detail.ErrorMessage = err.Error()
+		}
+		log := updatelog.BuildOperationLog(uuid.New().String(), updatelog.OperationInstanceRestart, "api-instance-restart",
+			startTime, time.Now().UTC(), []updatelog.InstanceUpdateDetail{detail}, err)
+		if recordErr := updateLogger.Record(log); recordErr != nil {
+			logger.Error("Failed to record instance restart log", "error", recordErr, "instance", inst.Name())
+		}
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract instance name from URL path
 		instanceName := r.PathValue("name")
@@ -175,8 +211,16 @@ func NewInstanceRestartHandler(im *instance.InstanceManager, logger *slog.Logger
 
 		logger.Info("Restarting instance", "instance", instanceName)
 
+		startTime := time.Now().UTC()
+		var detail updatelog.InstanceUpdateDetail
+
 		// Stop the instance
-		if err := inst.StopForUpdate(r.Context()); err != nil {
+		// 客户端断开 (关闭页面) 不中断重启, 否则实例可能停止后不再启动
+		ctx := instance.WithInitiator(context.WithoutCancel(r.Context()), instance.InitiatorWeb)
+		err = inst.StopForUpdate(ctx)
+		detail.StopDuration = time.Since(startTime).Milliseconds()
+		if err != nil {
+			record(inst, startTime, detail, fmt.Errorf("stop: %w", err))
 			logger.Error("Failed to stop instance", "instance", instanceName, "error", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -188,7 +232,16 @@ func NewInstanceRestartHandler(im *instance.InstanceManager, logger *slog.Logger
 		}
 
 		// Start the instance
-		if err := inst.StartAfterUpdate(r.Context()); err != nil {
+		startedAt := time.Now()
+		detail.LogBufferID = inst.GetLogBuffer().ID()
+		detail.LogStartIndex = int(inst.GetLogBuffer().NextSeq())
+		startCtx, cancel := inst.StartContext(ctx)
+		err = inst.StartAfterUpdate(startCtx)
+		cancel()
+		detail.StartDuration = time.Since(startedAt).Milliseconds()
+		detail.LogEndIndex = int(inst.GetLogBuffer().NextSeq())
+		if err != nil {
+			record(inst, startTime, detail, fmt.Errorf("start: %w", err))
 			logger.Error("Failed to start instance", "instance", instanceName, "error", err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -199,6 +252,8 @@ func NewInstanceRestartHandler(im *instance.InstanceManager, logger *slog.Logger
 			return
 		}
 
+		detail.Downtime = time.Since(startTime).Milliseconds()
+		record(inst, startTime, detail, nil)
 		logger.Info("Instance restarted successfully", "instance", instanceName)
 
 		// Return success response
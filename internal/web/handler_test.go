@@ -227,3 +227,44 @@ func TestInstanceListHandler(t *testing.T) {
 		}
 	}
 }
+
+// TestInstanceStatusHandler tests GET /api/v1/instances/status returns the state machine fields
+func TestInstanceStatusHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	cfg := &config.Config{
+		Instances: []config.InstanceConfig{
+			{Name: "idle", Port: 8080, StartCommand: "cmd1"},
+			{Name: "busy", Port: 8081, StartCommand: "cmd2", Restart: config.RestartConfig{Policy: config.RestartPolicyAlways}},
+		},
+	}
+	im := instance.NewInstanceManager(cfg, logger, nil)
+	busy, err := im.GetLifecycle("busy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy.SetPIDForTest(int32(os.Getpid()))
+
+	rec := httptest.NewRecorder()
+	NewInstanceStatusHandler(im, logger)(rec, httptest.NewRequest("GET", "/api/v1/instances/status", nil))
+
+	var response struct {
+		Instances []InstanceStatus `json:"instances"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if len(response.Instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(response.Instances))
+	}
+
+	idle, running := response.Instances[0], response.Instances[1]
+	if idle.State != instance.StateStopped || idle.Running || idle.StartedAt != nil || idle.Restart.Policy != config.RestartPolicyNever {
+		t.Errorf("idle = %+v, want stopped with the never restart policy", idle)
+	}
+	if running.State != instance.StateRunning || !running.Running || running.PID != int32(os.Getpid()) || running.StartedAt == nil {
+		t.Errorf("busy = %+v, want running with its PID and start time", running)
+	}
+	if running.Restart.Policy != config.RestartPolicyAlways {
+		t.Errorf("busy restart policy = %q, want always", running.Restart.Policy)
+	}
+}